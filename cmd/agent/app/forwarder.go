@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
+	"github.com/spf13/cobra"
+)
+
+var (
+	forwarderReplayDryRun    bool
+	forwarderReplayEndpoints []string
+
+	forwarderPayloadsEndpoints    []string
+	forwarderPayloadsIgnoreFields []string
+)
+
+func init() {
+	AgentCmd.AddCommand(forwarderCmd)
+	forwarderCmd.AddCommand(forwarderReplayCmd)
+	forwarderReplayCmd.Flags().BoolVarP(&forwarderReplayDryRun, "dry-run", "", false, "Print the transactions which would be replayed without sending them.")
+	forwarderReplayCmd.Flags().StringSliceVarP(&forwarderReplayEndpoints, "endpoint", "e", nil, "Only replay the transactions of these endpoint names (for example series_v2). Can be repeated.")
+
+	forwarderCmd.AddCommand(forwarderPayloadsCmd)
+	forwarderPayloadsCmd.AddCommand(forwarderPayloadsPrintCmd)
+	forwarderPayloadsCmd.AddCommand(forwarderPayloadsDiffCmd)
+	forwarderPayloadsCmd.PersistentFlags().StringSliceVarP(&forwarderPayloadsEndpoints, "endpoint", "e", nil, "Only use the payloads of these endpoint names (for example series_v2). Can be repeated.")
+	forwarderPayloadsDiffCmd.Flags().StringSliceVarP(&forwarderPayloadsIgnoreFields, "ignore-field", "i", nil, "Ignore the payload fields with this name (for example timestamp). Can be repeated.")
+}
+
+var forwarderCmd = &cobra.Command{
+	Use:   "forwarder",
+	Short: "Forwarder related commands",
+	Long:  ``,
+}
+
+var forwarderReplayCmd = &cobra.Command{
+	Use:   "replay <dir>",
+	Short: "Send again the transactions archived in a forwarder dead-letter directory",
+	Long: `Send again the transactions archived in the dead-letter directory <dir> (see forwarder_dead_letter_path).
+The transactions successfully sent are removed from the directory, the other ones are kept for a later replay.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := common.SetupConfig(confFilePath)
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+
+		err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "info"), "", "", false, true, false)
+		if err != nil {
+			fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+			return err
+		}
+
+		return forwarderReplay(args[0])
+	},
+}
+
+var forwarderPayloadsCmd = &cobra.Command{
+	Use:   "payloads",
+	Short: "Read the payloads written by the file sink of the forwarder (see forwarder_file_sink_path)",
+	Long:  ``,
+}
+
+var forwarderPayloadsPrintCmd = &cobra.Command{
+	Use:   "print <path>",
+	Short: "Pretty-print the payloads of a file sink directory or of a single payload file",
+	Long:  ``,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		payloads, err := readForwarderPayloads(args[0])
+		if err != nil {
+			return err
+		}
+		for _, payload := range payloads {
+			fmt.Printf("=== %s (%s, %s)\n", payload.Path, payload.Endpoint, payload.Timestamp.Format(time.RFC3339Nano))
+			if len(payload.Payload) == 0 {
+				fmt.Printf("%d bytes of undecoded payload\n\n", len(payload.RawPayload))
+				continue
+			}
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, payload.Payload, "", "  "); err != nil {
+				return err
+			}
+			fmt.Printf("%s\n\n", indented.String())
+		}
+		return nil
+	},
+}
+
+var forwarderPayloadsDiffCmd = &cobra.Command{
+	Use:   "diff <expected> <actual>",
+	Short: "Compare the payloads of two file sink directories or payload files",
+	Long: `Compare the payloads of each endpoint of two file sink directories or payload files.
+The order of the payloads and of the elements of the arrays is not significant. The command fails
+when the payloads differ.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		expected, err := readForwarderPayloads(args[0])
+		if err != nil {
+			return err
+		}
+		actual, err := readForwarderPayloads(args[1])
+		if err != nil {
+			return err
+		}
+		diff, err := forwarder.DiffFilePayloads(expected, actual, forwarderPayloadsIgnoreFields)
+		if err != nil {
+			return err
+		}
+		if diff != "" {
+			fmt.Print(diff)
+			return errors.New("the payloads differ")
+		}
+		fmt.Println("The payloads are identical.")
+		return nil
+	},
+}
+
+// readForwarderPayloads reads the payloads of path, filtered by endpoint.
+func readForwarderPayloads(path string) ([]forwarder.FilePayload, error) {
+	payloads, err := forwarder.ReadFilePayloads(path)
+	if err != nil || len(forwarderPayloadsEndpoints) == 0 {
+		return payloads, err
+	}
+
+	filtered := payloads[:0]
+	for _, payload := range payloads {
+		for _, endpoint := range forwarderPayloadsEndpoints {
+			if payload.Endpoint == endpoint {
+				filtered = append(filtered, payload)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+func forwarderReplay(path string) error {
+	keysPerDomain, err := config.GetMultipleEndpoints()
+	if err != nil {
+		return fmt.Errorf("misconfiguration of agent endpoints: %v", err)
+	}
+
+	// The forwarder is not started: the transactions are sent synchronously, and the core features
+	// are not enabled so that this process does not use the retry files of the running Agent.
+	f := forwarder.NewDefaultForwarder(forwarder.NewOptions(keysPerDomain))
+	report, err := f.ReplayDeadLetters(path, forwarder.ReplayOptions{
+		DryRun:    forwarderReplayDryRun,
+		Endpoints: forwarderReplayEndpoints,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Archives: %d (%d skipped)\n", report.Archives, report.SkippedArchives)
+	fmt.Printf("Transactions: %d\n", report.Transactions)
+	endpoints := make([]string, 0, len(report.TransactionsByEndpoint))
+	for endpoint := range report.TransactionsByEndpoint {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		fmt.Printf("  %s: %d\n", endpoint, report.TransactionsByEndpoint[endpoint])
+	}
+	if forwarderReplayDryRun {
+		fmt.Println("Dry run: no transaction was sent.")
+	} else {
+		fmt.Printf("Sent: %d\n", report.Sent)
+		fmt.Printf("Failed: %d (kept in %s)\n", report.Failed, path)
+	}
+	return nil
+}
@@ -334,6 +334,18 @@ func start(cmd *cobra.Command, args []string) error {
 		}()
 	}
 
+	// Kubernetes audit webhook
+	if config.Datadog.GetBool("kubernetes_audit_webhook.enabled") {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := runKubernetesAuditWebhook(mainCtx); err != nil {
+				log.Errorf("Error while running the Kubernetes audit webhook: %v", err)
+			}
+		}()
+	}
+
 	if config.Datadog.GetBool("admission_controller.enabled") {
 		admissionCtx := admissionpkg.ControllerContext{
 			IsLeaderFunc:        le.IsLeader,
@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build kubeapiserver
+// +build kubeapiserver
+
+package app
+
+import (
+	"context"
+	"errors"
+
+	coreconfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs"
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/kubeaudit"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/startstop"
+)
+
+const (
+	kubernetesAuditSourceName = "kubernetes.audit"
+	kubernetesAuditService    = "kube-apiserver"
+)
+
+func runKubernetesAuditWebhook(ctx context.Context) error {
+	stopper := startstop.NewSerialStopper()
+	if err := startKubernetesAuditWebhook(stopper); err != nil {
+		stopper.Stop()
+		return err
+	}
+
+	<-ctx.Done()
+
+	stopper.Stop()
+	return nil
+}
+
+// startKubernetesAuditWebhook receives the audit events sent by the webhook backend of
+// the API server and sends them to the logs intake.
+func startKubernetesAuditWebhook(stopper startstop.Stopper) error {
+	source := sources.NewLogSource(kubernetesAuditSourceName, &config.LogsConfig{
+		Type:              config.KubernetesAuditType,
+		Port:              coreconfig.Datadog.GetInt("kubernetes_audit_webhook.port"),
+		TLSCertFile:       coreconfig.Datadog.GetString("kubernetes_audit_webhook.tls_cert_file"),
+		TLSKeyFile:        coreconfig.Datadog.GetString("kubernetes_audit_webhook.tls_key_file"),
+		TLSClientCAFile:   coreconfig.Datadog.GetString("kubernetes_audit_webhook.tls_client_ca_file"),
+		BearerTokenFile:   coreconfig.Datadog.GetString("kubernetes_audit_webhook.bearer_token_file"),
+		AllowInsecureHTTP: coreconfig.Datadog.GetBool("kubernetes_audit_webhook.allow_insecure_http"),
+		Source:            kubernetesAuditSourceName,
+		Service:           kubernetesAuditService,
+	})
+	if err := source.Config.Validate(); err != nil {
+		return log.Errorf("Invalid Kubernetes audit webhook configuration: %v", err)
+	}
+
+	endpoints, err := config.BuildHTTPEndpoints("logs", logs.AgentJSONIntakeProtocol, config.DefaultIntakeOrigin)
+	if err != nil {
+		return log.Errorf("Invalid endpoints: %v", err)
+	}
+	for _, status := range endpoints.GetStatus() {
+		log.Info(status)
+	}
+
+	destinationsCtx := client.NewDestinationsContext()
+	destinationsCtx.Start()
+	auditor := auditor.NewNullAuditor()
+	auditor.Start()
+	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, nil, nil, endpoints, destinationsCtx)
+	pipelineProvider.Start()
+
+	server := kubeaudit.NewWebhookServer(source, pipelineProvider.NextPipelineChan())
+	server.Start()
+
+	stopper.Add(server)
+	stopper.Add(pipelineProvider)
+	stopper.Add(auditor)
+	stopper.Add(destinationsCtx)
+
+	if source.Status.IsError() {
+		return errors.New(source.Status.GetError())
+	}
+	return nil
+}
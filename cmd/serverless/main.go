@@ -240,7 +240,7 @@ func runAgent(stopCh chan struct{}) (serverlessDaemon *daemon.Daemon, err error)
 		if logRegistrationError != nil {
 			log.Error("Can't subscribe to logs:", logRegistrationError)
 		} else {
-			serverlessLogs.SetupLogAgent(logChannel)
+			serverlessLogs.SetupLogAgent(logChannel, metricAgent.Demux)
 		}
 	}()
 
@@ -232,6 +232,29 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 		c.RareSamplerCardinality = coreconfig.Datadog.GetInt("apm_config.rare_sampler.cardinality")
 	}
 
+	if coreconfig.Datadog.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSampling.Enabled = coreconfig.Datadog.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if coreconfig.Datadog.IsSet("apm_config.tail_sampling.decision_wait") {
+		c.TailSampling.DecisionWait = coreconfig.Datadog.GetDuration("apm_config.tail_sampling.decision_wait")
+	}
+	if coreconfig.Datadog.IsSet("apm_config.tail_sampling.max_buffer_bytes") {
+		c.TailSampling.MaxBufferBytes = coreconfig.Datadog.GetInt("apm_config.tail_sampling.max_buffer_bytes")
+	}
+	if k := "apm_config.tail_sampling.policies"; coreconfig.Datadog.IsSet(k) {
+		var policies []config.TailSamplingPolicy
+		if err := coreconfig.Datadog.UnmarshalKey(k, &policies); err != nil {
+			log.Errorf("Bad format for %q: %v", k, err)
+		}
+		for i, p := range policies {
+			if err := p.Validate(); err != nil {
+				log.Errorf("Ignoring the tail sampling policy #%d of %q: %v", i, k, err)
+				continue
+			}
+			c.TailSampling.Policies = append(c.TailSampling.Policies, p)
+		}
+	}
+
 	if coreconfig.Datadog.IsSet("apm_config.max_remote_traces_per_second") {
 		c.MaxRemoteTPS = coreconfig.Datadog.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
@@ -288,6 +311,8 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 		SpanNameRemappings:     coreconfig.Datadog.GetStringMapString("otlp_config.traces.span_name_remappings"),
 		SpanNameAsResourceName: coreconfig.Datadog.GetBool("otlp_config.traces.span_name_as_resource_name"),
 	}
+	c.ZipkinReceiverEnabled = coreconfig.Datadog.GetBool("apm_config.zipkin_receiver_enabled")
+	c.JaegerReceiverEnabled = coreconfig.Datadog.GetBool("apm_config.jaeger_receiver_enabled")
 
 	if coreconfig.Datadog.GetBool("apm_config.telemetry.enabled") {
 		c.TelemetryConfig.Enabled = true
@@ -332,6 +357,36 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 			c.RejectTags = append(c.RejectTags, splitTag(tag))
 		}
 	}
+	if k := "apm_config.filter_rules"; coreconfig.Datadog.IsSet(k) {
+		var rules []*config.FilterRule
+		if err := coreconfig.Datadog.UnmarshalKey(k, &rules); err != nil {
+			log.Errorf("Bad format for %q: %v", k, err)
+		} else {
+			c.FilterRules = rules
+		}
+	}
+	if k := "apm_config.stats_tag_dimensions"; coreconfig.Datadog.IsSet(k) {
+		var dims []config.StatsTagDimension
+		if err := coreconfig.Datadog.UnmarshalKey(k, &dims); err != nil {
+			log.Errorf("Bad format for %q: %v", k, err)
+		}
+		seen := make(map[string]bool, len(dims))
+		for i, d := range dims {
+			if d.Tag == "" || seen[d.Tag] {
+				log.Errorf("Ignoring the stats tag dimension #%d of %q: empty or duplicate tag %q", i, k, d.Tag)
+				continue
+			}
+			if len(c.StatsTagDimensions) == config.MaxStatsTagDimensions {
+				log.Warnf("Ignoring the stats tag dimensions of %q past the first %d.", k, config.MaxStatsTagDimensions)
+				break
+			}
+			if d.MaxCardinality <= 0 {
+				d.MaxCardinality = config.DefaultStatsTagCardinality
+			}
+			seen[d.Tag] = true
+			c.StatsTagDimensions = append(c.StatsTagDimensions, d)
+		}
+	}
 
 	// undocumented
 	if coreconfig.Datadog.IsSet("apm_config.max_cpu_percent") {
@@ -451,6 +451,34 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.ElementsMatch([]*config.Tag{{K: "env", V: "prod"}, {K: "db", V: "mongodb"}}, c.RequireTags)
 	assert.ElementsMatch([]*config.Tag{{K: "outcome", V: "success"}}, c.RejectTags)
 
+	assert.Equal([]*config.FilterRule{
+		{
+			Name:            "health",
+			Action:          "drop",
+			Operation:       "http.request",
+			Tags:            []string{"http.url:.*/health"},
+			HTTPStatusCodes: []string{"2xx"},
+			MaxDuration:     100 * time.Millisecond,
+		},
+		{Name: "gold", Action: "keep", Tags: []string{"customer_tier:gold"}, AnySpan: true},
+	}, c.FilterRules)
+
+	assert.Equal([]config.StatsTagDimension{
+		{Tag: "peer.service", MaxCardinality: 100},
+		{Tag: "region", MaxCardinality: 20},
+	}, c.StatsTagDimensions)
+
+	assert.Equal(config.TailSamplingConfig{
+		Enabled:        true,
+		DecisionWait:   5 * time.Second,
+		MaxBufferBytes: 1000000,
+		Policies: []config.TailSamplingPolicy{
+			{Name: "slow", Type: "latency", Latency: 2 * time.Second},
+			{Name: "error", Type: "error"},
+			{Name: "tag", Type: "tag", Key: "customer", Value: "vip"},
+		},
+	}, c.TailSampling)
+
 	assert.ElementsMatch([]*config.ReplaceRule{
 		{
 			Name:    "http.method",
@@ -825,6 +853,47 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_FILTER_RULES"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `[{"name":"errors","action":"keep","http_status_codes":["5xx"],"min_duration":"1s"}]`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]*config.FilterRule{
+			{Name: "errors", Action: "keep", HTTPStatusCodes: []string{"5xx"}, MinDuration: time.Second},
+		}, cfg.FilterRules)
+	})
+
+	env = "DD_APM_STATS_TAG_DIMENSIONS"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `[{"tag":"db.instance","max_cardinality":10}]`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]config.StatsTagDimension{{Tag: "db.instance", MaxCardinality: 10}}, cfg.StatsTagDimensions)
+	})
+
+	env = "DD_APM_TAIL_SAMPLING_POLICIES"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `[{"type":"latency","latency":"500ms"},{"type":"service_rate","service":"web","rate":0.5}]`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]config.TailSamplingPolicy{
+			{Name: "latency", Type: "latency", Latency: 500 * time.Millisecond},
+			{Name: "service_rate", Type: "service_rate", Service: "web", Rate: 0.5},
+		}, cfg.TailSampling.Policies)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		defer cleanConfig()()
@@ -244,6 +244,11 @@ type BufferedAggregator struct {
 	agentTags               func(collectors.TagCardinality) ([]string, error) // This function gets the agent tags from the tagger (defined as a struct field to ease testing)
 
 	flushAndSerializeInParallel FlushAndSerializeInParallel
+
+	// restoredStates holds the saved states of the check samplers not registered yet,
+	// they are restored until restoredStatesDeadline. Protected by mu.
+	restoredStates         map[check.ID][]contextState
+	restoredStatesDeadline time.Time
 }
 
 // FlushAndSerializeInParallel contains options for flushing metrics and serializing in parallel.
@@ -350,12 +355,20 @@ func (agg *BufferedAggregator) registerSender(id check.ID) error {
 	if _, ok := agg.checkSamplers[id]; ok {
 		return fmt.Errorf("Sender with ID '%s' has already been registered, will use existing sampler", id)
 	}
-	agg.checkSamplers[id] = newCheckSampler(
+	sampler := newCheckSampler(
 		config.Datadog.GetInt("check_sampler_bucket_commits_count_expiry"),
 		config.Datadog.GetBool("check_sampler_expire_metrics"),
 		config.Datadog.GetDuration("check_sampler_stateful_metric_expiration_time"),
 		agg.tagsStore,
+		newContextLimiterFromConfig(),
 	)
+	if states, ok := agg.restoredStates[id]; ok {
+		if time.Now().Before(agg.restoredStatesDeadline) {
+			sampler.restoreState(states)
+		}
+		delete(agg.restoredStates, id)
+	}
+	agg.checkSamplers[id] = sampler
 	return nil
 }
 
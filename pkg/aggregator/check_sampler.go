@@ -28,11 +28,11 @@ type CheckSampler struct {
 }
 
 // newCheckSampler returns a newly initialized CheckSampler
-func newCheckSampler(expirationCount int, expireMetrics bool, statefulTimeout time.Duration, cache *tags.Store) *CheckSampler {
+func newCheckSampler(expirationCount int, expireMetrics bool, statefulTimeout time.Duration, cache *tags.Store, limiter *contextLimiter) *CheckSampler {
 	return &CheckSampler{
 		series:          make([]*metrics.Serie, 0),
 		sketches:        make(metrics.SketchSeriesList, 0),
-		contextResolver: newCountBasedContextResolver(expirationCount, cache),
+		contextResolver: newCountBasedContextResolver(expirationCount, cache, limiter),
 		metrics:         metrics.NewCheckMetrics(expireMetrics, statefulTimeout),
 		sketchMap:       make(sketchMap),
 		lastBucketValue: make(map[ckey.ContextKey]int64),
@@ -191,3 +191,44 @@ func (cs *CheckSampler) flush() (metrics.Series, metrics.SketchSeriesList) {
 func (cs *CheckSampler) release() {
 	cs.contextResolver.release()
 }
+
+// saveState returns the states of the stateful metrics and of the monotonic
+// histogram buckets, to restore them after a restart.
+func (cs *CheckSampler) saveState() []contextState {
+	var states []contextState
+	for key, state := range cs.metrics.SaveStates() {
+		if context, ok := cs.contextResolver.get(key); ok && !context.overflow {
+			states = append(states, newContextState(context, state.Mtype, state.Value, state.Timestamp))
+		}
+	}
+	for key, value := range cs.lastBucketValue {
+		if context, ok := cs.contextResolver.get(key); ok && !context.overflow {
+			states = append(states, newContextState(context, metrics.HistogramType, float64(value), 0))
+		}
+	}
+	return states
+}
+
+// restoreState tracks the contexts of the states returned by saveState and restores their metrics.
+func (cs *CheckSampler) restoreState(states []contextState) {
+	for i := range states {
+		state := &states[i]
+		mtype, err := state.metricType()
+		if err != nil {
+			log.Debugf("Ignoring the saved state of metric '%s': %s", state.Name, err)
+			continue
+		}
+
+		contextKey := cs.contextResolver.trackContext(state)
+		if context, _ := cs.contextResolver.get(contextKey); context.overflow {
+			continue
+		}
+		if mtype == metrics.HistogramType {
+			cs.lastBucketValue[contextKey] = int64(state.Value)
+			continue
+		}
+		if err := cs.metrics.RestoreState(contextKey, metrics.MetricState{Mtype: mtype, Value: state.Value, Timestamp: state.Timestamp}); err != nil {
+			log.Debugf("Ignoring the saved state of metric '%s': %s", state.Name, err)
+		}
+	}
+}
@@ -34,7 +34,7 @@ func generateContextKey(sample metrics.MetricSampleContext) ckey.ContextKey {
 }
 
 func testCheckGaugeSampling(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, 1*time.Second, store)
+	checkSampler := newCheckSampler(1, true, 1*time.Second, store, nil)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -96,7 +96,7 @@ func TestCheckGaugeSampling(t *testing.T) {
 }
 
 func testCheckRateSampling(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, 1*time.Second, store)
+	checkSampler := newCheckSampler(1, true, 1*time.Second, store, nil)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -148,7 +148,7 @@ func TestCheckRateSampling(t *testing.T) {
 }
 
 func testHistogramCountSampling(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, 1*time.Second, store)
+	checkSampler := newCheckSampler(1, true, 1*time.Second, store, nil)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -212,7 +212,7 @@ func TestHistogramCountSampling(t *testing.T) {
 }
 
 func testCheckHistogramBucketSampling(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, 1*time.Second, store)
+	checkSampler := newCheckSampler(1, true, 1*time.Second, store, nil)
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:            "my.histogram",
@@ -288,7 +288,7 @@ func TestCheckHistogramBucketSampling(t *testing.T) {
 }
 
 func testCheckHistogramBucketDontFlushFirstValue(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, 1*time.Second, store)
+	checkSampler := newCheckSampler(1, true, 1*time.Second, store, nil)
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:            "my.histogram",
@@ -343,7 +343,7 @@ func TestCheckHistogramBucketDontFlushFirstValue(t *testing.T) {
 }
 
 func testCheckHistogramBucketInfinityBucket(t *testing.T, store *tags.Store) {
-	checkSampler := newCheckSampler(1, true, 1*time.Second, store)
+	checkSampler := newCheckSampler(1, true, 1*time.Second, store, nil)
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:       "my.histogram",
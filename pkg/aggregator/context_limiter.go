@@ -0,0 +1,232 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+const (
+	// overflowTag is the only tag of the contexts receiving the samples of the
+	// contexts exceeding the limits.
+	overflowTag = "overflow:true"
+
+	overflowReasonPerMetric  = "per_metric"
+	overflowReasonPerSampler = "per_sampler"
+
+	// maxOverflowStatsNames bounds the number of metric names tracked for the status page
+	maxOverflowStatsNames = 1000
+	// overflowTopOffendersCount is the number of metric names shown in the status page
+	// and reported by the telemetry
+	overflowTopOffendersCount = 10
+	// overflowTelemetryInterval is the minimum interval between two updates of the top offenders telemetry
+	overflowTelemetryInterval = 10 * time.Second
+)
+
+var (
+	tlmContextsOverflow = telemetry.NewCounter("aggregator", "contexts_overflow",
+		[]string{"limit"}, "Count of samples folded into an overflow context because of the contexts limits")
+	// only the top offenders are reported by metric name to keep the cardinality of the telemetry bounded
+	tlmContextsOverflowTopOffenders = telemetry.NewGauge("aggregator", "contexts_overflow_top_offenders",
+		[]string{"metric_name"}, "Count of samples folded into an overflow context for the metric names with the most overflowed samples")
+
+	contextsOverflowStats = newOverflowStats()
+)
+
+func init() {
+	aggregatorExpvars.Set("ContextsLimits", expvar.Func(expContextsLimits))
+}
+
+// contextLimiter bounds the number of contexts tracked by a contextResolver, in
+// total and per metric name. The overflow contexts are not counted.
+//
+// Each sampler (each DogStatsD time sampler and each check sampler) has its own
+// contextLimiter, so the limits apply per sampler and not to the whole Agent.
+type contextLimiter struct {
+	maxContexts          int
+	maxContextsPerMetric int
+	total                int
+	countsByName         map[string]int
+}
+
+// newContextLimiter returns a contextLimiter, or nil when there is no limit. A
+// limit of 0 means unlimited.
+func newContextLimiter(maxContexts, maxContextsPerMetric int) *contextLimiter {
+	if maxContexts <= 0 && maxContextsPerMetric <= 0 {
+		return nil
+	}
+	return &contextLimiter{
+		maxContexts:          maxContexts,
+		maxContextsPerMetric: maxContextsPerMetric,
+		countsByName:         make(map[string]int),
+	}
+}
+
+// newContextLimiterFromConfig returns a contextLimiter with the limits from the configuration.
+func newContextLimiterFromConfig() *contextLimiter {
+	return newContextLimiter(config.Datadog.GetInt("aggregator_max_contexts"), config.Datadog.GetInt("aggregator_max_contexts_per_metric"))
+}
+
+// track tracks a new context for the metric name. It returns false, without
+// tracking the context, when a limit is reached.
+func (l *contextLimiter) track(name string) bool {
+	reason := ""
+	if l.maxContexts > 0 && l.total >= l.maxContexts {
+		reason = overflowReasonPerSampler
+	} else if l.maxContextsPerMetric > 0 && l.countsByName[name] >= l.maxContextsPerMetric {
+		reason = overflowReasonPerMetric
+	}
+	if reason != "" {
+		tlmContextsOverflow.Inc(reason)
+		contextsOverflowStats.add(name, reason)
+		return false
+	}
+
+	l.total++
+	l.countsByName[name]++
+	return true
+}
+
+// remove stops tracking a context of the metric name.
+func (l *contextLimiter) remove(name string) {
+	l.total--
+	if count := l.countsByName[name]; count > 1 {
+		l.countsByName[name] = count - 1
+	} else {
+		delete(l.countsByName, name)
+	}
+}
+
+// overflowStats counts the samples folded into overflow contexts, for the status page
+// and the telemetry. It is shared by all the samplers.
+//
+// Once maxOverflowStatsNames names are tracked, a new name replaces the name with the fewest
+// samples and inherits its count, so that a metric starting to overflow late still shows up
+// in the top offenders. The count of such a name is then an upper bound.
+type overflowStats struct {
+	mutex  sync.Mutex
+	total  int64
+	byName map[string]*overflowedMetric
+
+	// telemetryNames are the metric names reported by the top offenders telemetry
+	telemetryNames  map[string]struct{}
+	telemetryUpdate time.Time
+}
+
+type overflowedMetric struct {
+	Name    string
+	Limit   string
+	Samples int64
+}
+
+func newOverflowStats() *overflowStats {
+	return &overflowStats{
+		byName:         make(map[string]*overflowedMetric),
+		telemetryNames: make(map[string]struct{}),
+	}
+}
+
+func (s *overflowStats) add(name, reason string) {
+	s.addAt(name, reason, time.Now())
+}
+
+func (s *overflowStats) addAt(name, reason string, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.total++
+	stat, found := s.byName[name]
+	if !found {
+		stat = &overflowedMetric{Name: name}
+		if len(s.byName) >= maxOverflowStatsNames {
+			evicted := s.leastOverflowed()
+			delete(s.byName, evicted.Name)
+			stat.Samples = evicted.Samples
+		}
+		s.byName[name] = stat
+	}
+	stat.Limit = reason
+	stat.Samples++
+
+	if now.Sub(s.telemetryUpdate) >= overflowTelemetryInterval {
+		s.updateTelemetry(now)
+	}
+}
+
+// leastOverflowed returns the tracked metric name with the fewest overflowed samples.
+func (s *overflowStats) leastOverflowed() *overflowedMetric {
+	var least *overflowedMetric
+	for _, stat := range s.byName {
+		if least == nil || stat.Samples < least.Samples || (stat.Samples == least.Samples && stat.Name > least.Name) {
+			least = stat
+		}
+	}
+	return least
+}
+
+// updateTelemetry reports the top offenders by metric name, the names which are not
+// top offenders anymore are removed from the telemetry.
+func (s *overflowStats) updateTelemetry(now time.Time) {
+	s.telemetryUpdate = now
+	names := make(map[string]struct{}, overflowTopOffendersCount)
+	for _, offender := range s.sortedOffenders(overflowTopOffendersCount) {
+		tlmContextsOverflowTopOffenders.Set(float64(offender.Samples), offender.Name)
+		names[offender.Name] = struct{}{}
+	}
+	for name := range s.telemetryNames {
+		if _, found := names[name]; !found {
+			tlmContextsOverflowTopOffenders.Delete(name)
+		}
+	}
+	s.telemetryNames = names
+}
+
+// topOffenders returns the metric names with the most overflowed samples.
+func (s *overflowStats) topOffenders(count int) (int64, []overflowedMetric) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.total, s.sortedOffenders(count)
+}
+
+func (s *overflowStats) sortedOffenders(count int) []overflowedMetric {
+	offenders := make([]overflowedMetric, 0, len(s.byName))
+	for _, stat := range s.byName {
+		offenders = append(offenders, *stat)
+	}
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].Samples == offenders[j].Samples {
+			return offenders[i].Name < offenders[j].Name
+		}
+		return offenders[i].Samples > offenders[j].Samples
+	})
+	if len(offenders) > count {
+		offenders = offenders[:count]
+	}
+	return offenders
+}
+
+func expContextsLimits() interface{} {
+	maxContexts := config.Datadog.GetInt("aggregator_max_contexts")
+	maxContextsPerMetric := config.Datadog.GetInt("aggregator_max_contexts_per_metric")
+	if maxContexts <= 0 && maxContextsPerMetric <= 0 {
+		return nil
+	}
+
+	total, offenders := contextsOverflowStats.topOffenders(overflowTopOffendersCount)
+	return map[string]interface{}{
+		"MaxContexts":          maxContexts,
+		"MaxContextsPerMetric": maxContextsPerMetric,
+		"OverflowedSamples":    total,
+		"TopOffenders":         offenders,
+	}
+}
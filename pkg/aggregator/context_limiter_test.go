@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestNewContextLimiter(t *testing.T) {
+	assert.Nil(t, newContextLimiter(0, 0))
+	assert.NotNil(t, newContextLimiter(10, 0))
+	assert.NotNil(t, newContextLimiter(0, 10))
+}
+
+func testContextLimiterPerMetric(t *testing.T, store *tags.Store) {
+	contextResolver := newTimestampContextResolver(store, newContextLimiter(0, 2))
+
+	sample := func(name string, id int) *metrics.MetricSample {
+		return &metrics.MetricSample{
+			Name:       name,
+			Value:      1,
+			Mtype:      metrics.CountType,
+			Tags:       []string{"env:prod", fmt.Sprintf("request_id:%d", id)},
+			Host:       "host",
+			SampleRate: 1,
+		}
+	}
+
+	key1 := contextResolver.trackContext(sample("my.metric", 1), 1)
+	key2 := contextResolver.trackContext(sample("my.metric", 2), 1)
+	overflowKey := contextResolver.trackContext(sample("my.metric", 3), 1)
+	assert.Equal(t, overflowKey, contextResolver.trackContext(sample("my.metric", 4), 1))
+	// the limit is per metric name
+	contextResolver.trackContext(sample("other.metric", 1), 1)
+	// the contexts already tracked are not folded
+	assert.Equal(t, key1, contextResolver.trackContext(sample("my.metric", 1), 2))
+	assert.Equal(t, 4, contextResolver.length())
+
+	context, found := contextResolver.get(overflowKey)
+	require.True(t, found)
+	assertContext(t, context, "my.metric", []string{overflowTag}, "host")
+	assert.True(t, context.overflow)
+
+	// the expired contexts free some room
+	contextResolver.expireContexts(2, func(k ckey.ContextKey) bool { return k == overflowKey })
+	assert.Equal(t, 2, contextResolver.length())
+	key5 := contextResolver.trackContext(sample("my.metric", 5), 3)
+	assert.NotEqual(t, overflowKey, key5)
+	assert.NotEqual(t, key2, key5)
+}
+
+func TestContextLimiterPerMetric(t *testing.T) {
+	testWithTagsStore(t, testContextLimiterPerMetric)
+}
+
+func testContextLimiterGlobal(t *testing.T, store *tags.Store) {
+	contextResolver := newCountBasedContextResolver(2, store, newContextLimiter(2, 0))
+
+	for i := 0; i < 4; i++ {
+		contextResolver.trackContext(&metrics.MetricSample{
+			Name:       fmt.Sprintf("my.metric.%d", i),
+			Value:      1,
+			Mtype:      metrics.GaugeType,
+			Tags:       []string{"foo"},
+			SampleRate: 1,
+		})
+	}
+
+	// 2 contexts and the overflow contexts of the 2 other metric names
+	assert.Len(t, contextResolver.resolver.contextsByKey, 4)
+	overflows := 0
+	for _, context := range contextResolver.resolver.contextsByKey {
+		if context.overflow {
+			overflows++
+			assertContext(t, context, context.Name, []string{overflowTag}, "")
+		}
+	}
+	assert.Equal(t, 2, overflows)
+}
+
+func TestContextLimiterGlobal(t *testing.T) {
+	testWithTagsStore(t, testContextLimiterGlobal)
+}
+
+func TestTimeSamplerContextLimit(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("aggregator_max_contexts_per_metric", 1)
+	sampler := testTimeSampler()
+
+	for i := 0; i < 3; i++ {
+		sampler.sample(&metrics.MetricSample{
+			Name:       "my.counter",
+			Value:      1,
+			Mtype:      metrics.CountType,
+			Tags:       []string{fmt.Sprintf("request_id:%d", i)},
+			SampleRate: 1,
+		}, 12345.0)
+	}
+
+	series, _ := flushSerie(sampler, 12360.0)
+	require.Len(t, series, 2)
+	values := map[string]float64{}
+	for _, serie := range series {
+		values[serie.Tags.Join(",")] = serie.Points[0].Value
+	}
+	assert.Equal(t, map[string]float64{"request_id:0": 1, overflowTag: 2}, values)
+
+	total, offenders := contextsOverflowStats.topOffenders(overflowTopOffendersCount)
+	assert.GreaterOrEqual(t, total, int64(2))
+	assert.Contains(t, offenders, overflowedMetric{Name: "my.counter", Limit: overflowReasonPerMetric, Samples: 2})
+}
+
+func TestOverflowStats(t *testing.T) {
+	stats := newOverflowStats()
+	for i := 0; i < overflowTopOffendersCount+2; i++ {
+		for j := 0; j <= i; j++ {
+			stats.add(fmt.Sprintf("metric.%d", i), overflowReasonPerSampler)
+		}
+	}
+
+	total, offenders := stats.topOffenders(3)
+	assert.Equal(t, int64(78), total)
+	assert.Equal(t, []overflowedMetric{
+		{Name: "metric.11", Limit: overflowReasonPerSampler, Samples: 12},
+		{Name: "metric.10", Limit: overflowReasonPerSampler, Samples: 11},
+		{Name: "metric.9", Limit: overflowReasonPerSampler, Samples: 10},
+	}, offenders)
+}
+
+func TestOverflowStatsEviction(t *testing.T) {
+	stats := newOverflowStats()
+	for i := 0; i < maxOverflowStatsNames; i++ {
+		stats.add(fmt.Sprintf("metric.%d", i), overflowReasonPerMetric)
+		if i > 0 {
+			stats.add(fmt.Sprintf("metric.%d", i), overflowReasonPerMetric)
+		}
+	}
+
+	// a new name replaces the name with the fewest samples and inherits its count
+	for i := 0; i < 5; i++ {
+		stats.add("new.metric", overflowReasonPerSampler)
+	}
+	assert.Len(t, stats.byName, maxOverflowStatsNames)
+	assert.NotContains(t, stats.byName, "metric.0")
+
+	total, offenders := stats.topOffenders(1)
+	assert.Equal(t, int64(2*maxOverflowStatsNames-1+5), total)
+	assert.Equal(t, []overflowedMetric{{Name: "new.metric", Limit: overflowReasonPerSampler, Samples: 6}}, offenders)
+}
+
+func TestOverflowStatsTelemetry(t *testing.T) {
+	stats := newOverflowStats()
+	now := time.Now()
+	for i := 0; i < overflowTopOffendersCount; i++ {
+		stats.addAt(fmt.Sprintf("metric.%d", i), overflowReasonPerMetric, now)
+	}
+	stats.addAt("new.metric", overflowReasonPerMetric, now)
+	assert.Len(t, stats.telemetryNames, 1)
+
+	// the top offenders are reported once the interval is elapsed
+	stats.addAt("new.metric", overflowReasonPerMetric, now.Add(overflowTelemetryInterval))
+	assert.Len(t, stats.telemetryNames, overflowTopOffendersCount)
+	assert.Contains(t, stats.telemetryNames, "new.metric")
+	assert.Contains(t, stats.telemetryNames, "metric.0")
+	assert.NotContains(t, stats.telemetryNames, "metric.9")
+}
@@ -21,6 +21,8 @@ type Context struct {
 	mtype      metrics.MetricType
 	taggerTags *tags.Entry
 	metricTags *tags.Entry
+	// overflow is true for the contexts receiving the samples of the contexts exceeding the limits
+	overflow bool
 }
 
 // Tags returns tags for the context.
@@ -41,6 +43,8 @@ type contextResolver struct {
 	keyGenerator  *ckey.KeyGenerator
 	taggerBuffer  *tagset.HashingTagsAccumulator
 	metricBuffer  *tagset.HashingTagsAccumulator
+	// limiter is nil when the number of contexts is unlimited
+	limiter *contextLimiter
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -48,7 +52,7 @@ func (cr *contextResolver) generateContextKey(metricSampleContext metrics.Metric
 	return cr.keyGenerator.GenerateWithTags2(metricSampleContext.GetName(), metricSampleContext.GetHost(), cr.taggerBuffer, cr.metricBuffer)
 }
 
-func newContextResolver(cache *tags.Store) *contextResolver {
+func newContextResolver(cache *tags.Store, limiter *contextLimiter) *contextResolver {
 	return &contextResolver{
 		contextsByKey: make(map[ckey.ContextKey]*Context),
 		countsByMtype: make([]uint64, metrics.NumMetricTypes),
@@ -56,6 +60,7 @@ func newContextResolver(cache *tags.Store) *contextResolver {
 		keyGenerator:  ckey.NewKeyGenerator(),
 		taggerBuffer:  tagset.NewHashingTagsAccumulator(),
 		metricBuffer:  tagset.NewHashingTagsAccumulator(),
+		limiter:       limiter,
 	}
 }
 
@@ -65,15 +70,28 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
 
 	if _, ok := cr.contextsByKey[contextKey]; !ok {
-		mtype := metricSampleContext.GetMetricType()
-		cr.contextsByKey[contextKey] = &Context{
-			Name:       metricSampleContext.GetName(),
-			taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
-			metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
-			Host:       metricSampleContext.GetHost(),
-			mtype:      mtype,
+		overflow := false
+		if cr.limiter != nil && !cr.limiter.track(metricSampleContext.GetName()) {
+			// fold the sample into the overflow context of the metric
+			overflow = true
+			cr.taggerBuffer.Reset()
+			cr.metricBuffer.Reset()
+			cr.metricBuffer.Append(overflowTag)
+			contextKey, taggerKey, metricKey = cr.generateContextKey(metricSampleContext)
+		}
+
+		if _, ok := cr.contextsByKey[contextKey]; !ok {
+			mtype := metricSampleContext.GetMetricType()
+			cr.contextsByKey[contextKey] = &Context{
+				Name:       metricSampleContext.GetName(),
+				taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
+				metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
+				Host:       metricSampleContext.GetHost(),
+				mtype:      mtype,
+				overflow:   overflow,
+			}
+			cr.countsByMtype[mtype]++
 		}
-		cr.countsByMtype[mtype]++
 	}
 
 	cr.taggerBuffer.Reset()
@@ -98,6 +116,9 @@ func (cr *contextResolver) removeKeys(expiredContextKeys []ckey.ContextKey) {
 
 		if context != nil {
 			cr.countsByMtype[context.mtype]--
+			if cr.limiter != nil && !context.overflow {
+				cr.limiter.remove(context.Name)
+			}
 			context.release()
 		}
 	}
@@ -115,9 +136,9 @@ type timestampContextResolver struct {
 	lastSeenByKey map[ckey.ContextKey]float64
 }
 
-func newTimestampContextResolver(cache *tags.Store) *timestampContextResolver {
+func newTimestampContextResolver(cache *tags.Store, limiter *contextLimiter) *timestampContextResolver {
 	return &timestampContextResolver{
-		resolver:      newContextResolver(cache),
+		resolver:      newContextResolver(cache, limiter),
 		lastSeenByKey: make(map[ckey.ContextKey]float64),
 	}
 }
@@ -184,9 +205,9 @@ type countBasedContextResolver struct {
 	expireCountInterval int64
 }
 
-func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store) *countBasedContextResolver {
+func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store, limiter *contextLimiter) *countBasedContextResolver {
 	return &countBasedContextResolver{
-		resolver:            newContextResolver(cache),
+		resolver:            newContextResolver(cache, limiter),
 		expireCountByKey:    make(map[ckey.ContextKey]int64),
 		expireCount:         0,
 		expireCountInterval: int64(expireCountInterval),
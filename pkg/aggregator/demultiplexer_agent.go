@@ -120,6 +120,8 @@ func initAgentDemultiplexer(options DemultiplexerOptions, hostname string) *Agen
 	var sharedForwarder forwarder.Forwarder
 	if options.UseNoopForwarder {
 		sharedForwarder = forwarder.NoopForwarder{}
+	} else if fileSinkPath := config.Datadog.GetString("forwarder_file_sink_path"); fileSinkPath != "" {
+		sharedForwarder = forwarder.NewFileForwarder(fileSinkPath, config.Datadog.GetInt("forwarder_file_sink_max_files"))
 	} else {
 		sharedForwarder = forwarder.NewDefaultForwarder(options.SharedForwarderOptions)
 	}
@@ -189,6 +191,10 @@ func initAgentDemultiplexer(options DemultiplexerOptions, hostname string) *Agen
 		},
 	}
 
+	if config.Datadog.GetBool("aggregator_persist_state") {
+		demux.restoreState()
+	}
+
 	return demux
 }
 
@@ -327,6 +333,9 @@ func (d *AgentDemultiplexer) Stop(flush bool) {
 	}
 	if d.aggregator != nil {
 		d.aggregator.Stop()
+		if config.Datadog.GetBool("aggregator_persist_state") {
+			d.saveState()
+		}
 	}
 	d.aggregator = nil
 
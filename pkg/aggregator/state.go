@@ -0,0 +1,210 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// aggregatorStateVersion is the version of the format of the state file. The
+	// files written with another version are ignored.
+	aggregatorStateVersion  = 1
+	aggregatorStateFileName = "aggregator_state.json"
+)
+
+// aggregatorState is the state of the stateful metrics of the samplers, saved on
+// a graceful stop of the agent and restored on the next start.
+type aggregatorState struct {
+	Version int `json:"version"`
+	// Timestamp is the time of the snapshot, in seconds since epoch
+	Timestamp int64 `json:"timestamp"`
+	// TimeSamplers holds the counters of each time sampler, by TimeSamplerID
+	TimeSamplers [][]contextState `json:"time_samplers"`
+	// CheckSamplers holds the stateful metrics of each check sampler
+	CheckSamplers map[check.ID][]contextState `json:"check_samplers"`
+}
+
+// contextState is the state of the metric of one context. It implements
+// metrics.MetricSampleContext to track the context again once restored.
+type contextState struct {
+	Name string   `json:"name"`
+	Host string   `json:"host,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// Type is the name of the metric type, the buckets of the monotonic histograms
+	// use the Histogram type.
+	Type      string  `json:"type"`
+	Value     float64 `json:"value"`
+	Timestamp float64 `json:"timestamp,omitempty"`
+}
+
+func newContextState(context *Context, mtype metrics.MetricType, value, timestamp float64) contextState {
+	return contextState{
+		Name:      context.Name,
+		Host:      context.Host,
+		Tags:      append([]string(nil), context.Tags().UnsafeToReadOnlySliceString()...),
+		Type:      mtype.String(),
+		Value:     value,
+		Timestamp: timestamp,
+	}
+}
+
+// GetName implements metrics.MetricSampleContext#GetName.
+func (s *contextState) GetName() string {
+	return s.Name
+}
+
+// GetHost implements metrics.MetricSampleContext#GetHost.
+func (s *contextState) GetHost() string {
+	return s.Host
+}
+
+// GetTags implements metrics.MetricSampleContext#GetTags. The saved tags already
+// contain the tags from origin detection.
+func (s *contextState) GetTags(taggerBuffer, metricBuffer *tagset.HashingTagsAccumulator) {
+	metricBuffer.Append(s.Tags...)
+}
+
+// GetMetricType implements metrics.MetricSampleContext#GetMetricType.
+func (s *contextState) GetMetricType() metrics.MetricType {
+	mtype, _ := s.metricType()
+	return mtype
+}
+
+func (s *contextState) metricType() (metrics.MetricType, error) {
+	for mtype := metrics.MetricType(0); mtype < metrics.NumMetricTypes; mtype++ {
+		if mtype.String() == s.Type {
+			return mtype, nil
+		}
+	}
+	return metrics.GaugeType, fmt.Errorf("unknown metric type %q", s.Type)
+}
+
+func aggregatorStatePath() string {
+	return filepath.Join(config.Datadog.GetString("run_path"), aggregatorStateFileName)
+}
+
+// saveAggregatorState writes the state to path. The file is replaced atomically.
+func saveAggregatorState(path string, state *aggregatorState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// loadAggregatorState reads and removes the state file. It returns nil when there
+// is no state file, or when the state is older than maxAge.
+func loadAggregatorState(path string, maxAge time.Duration, now time.Time) (*aggregatorState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	// the state is restored only once
+	if err := os.Remove(path); err != nil {
+		log.Warnf("Can't remove the aggregator state file %s: %s", path, err)
+	}
+
+	state := &aggregatorState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("can't parse the aggregator state file %s: %s", path, err)
+	}
+	if state.Version != aggregatorStateVersion {
+		return nil, fmt.Errorf("unsupported version %d of the aggregator state file %s", state.Version, path)
+	}
+	if age := now.Sub(time.Unix(state.Timestamp, 0)); age > maxAge {
+		log.Infof("Ignoring the aggregator state saved %s ago", age)
+		return nil, nil
+	}
+	return state, nil
+}
+
+// saveCheckSamplersState returns the states of the check samplers. The aggregator must be stopped.
+func (agg *BufferedAggregator) saveCheckSamplersState() map[check.ID][]contextState {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	states := make(map[check.ID][]contextState, len(agg.checkSamplers))
+	for id, sampler := range agg.checkSamplers {
+		if samplerStates := sampler.saveState(); len(samplerStates) > 0 {
+			states[id] = samplerStates
+		}
+	}
+	return states
+}
+
+// setRestoredCheckSamplersState keeps the states of the check samplers, to restore
+// them when the checks register their sender.
+func (agg *BufferedAggregator) setRestoredCheckSamplersState(states map[check.ID][]contextState, deadline time.Time) {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	agg.restoredStates = states
+	agg.restoredStatesDeadline = deadline
+}
+
+// saveState saves the state of the stateful metrics of the samplers under run_path.
+// The time samplers workers and the aggregator must be stopped.
+func (d *AgentDemultiplexer) saveState() {
+	state := &aggregatorState{
+		Version:       aggregatorStateVersion,
+		Timestamp:     time.Now().Unix(),
+		TimeSamplers:  make([][]contextState, len(d.statsd.workers)),
+		CheckSamplers: d.aggregator.saveCheckSamplersState(),
+	}
+	for i, worker := range d.statsd.workers {
+		state.TimeSamplers[i] = worker.sampler.saveState()
+	}
+
+	path := aggregatorStatePath()
+	if err := saveAggregatorState(path, state); err != nil {
+		log.Errorf("Can't save the aggregator state to %s: %s", path, err)
+		return
+	}
+	log.Infof("Saved the aggregator state to %s", path)
+}
+
+// restoreState restores the state saved by saveState on the last stop of the agent.
+// The time samplers workers and the aggregator must not be started yet.
+func (d *AgentDemultiplexer) restoreState() {
+	maxAge := config.Datadog.GetDuration("aggregator_persist_state_max_age") * time.Second
+	path := aggregatorStatePath()
+	state, err := loadAggregatorState(path, maxAge, time.Now())
+	if err != nil {
+		log.Warnf("Can't restore the aggregator state: %s", err)
+		return
+	}
+	if state == nil {
+		return
+	}
+
+	// the contexts are dispatched to the time samplers depending on the number of pipelines
+	if len(state.TimeSamplers) == len(d.statsd.workers) {
+		for i, worker := range d.statsd.workers {
+			worker.sampler.restoreState(state.TimeSamplers[i])
+		}
+	} else {
+		log.Infof("Not restoring the state of the DogStatsD counters: the number of pipelines changed from %d to %d", len(state.TimeSamplers), len(d.statsd.workers))
+	}
+	d.aggregator.setRestoredCheckSamplersState(state.CheckSamplers, time.Unix(state.Timestamp, 0).Add(maxAge))
+	log.Infof("Restored the aggregator state from %s", path)
+}
@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package aggregator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func seriesValues(series metrics.Series) map[string]float64 {
+	values := make(map[string]float64, len(series))
+	for _, serie := range series {
+		values[serie.Name] = serie.Points[0].Value
+	}
+	return values
+}
+
+func testCheckSamplerState(t *testing.T, store *tags.Store) {
+	checkSampler := newCheckSampler(1, true, time.Hour, store, nil)
+	checkSampler.addSample(&metrics.MetricSample{Name: "my.rate", Value: 10, Mtype: metrics.RateType, Tags: []string{"foo"}, SampleRate: 1, Timestamp: 12340})
+	checkSampler.addSample(&metrics.MetricSample{Name: "my.rate", Value: 20, Mtype: metrics.RateType, Tags: []string{"foo"}, SampleRate: 1, Timestamp: 12345})
+	checkSampler.addSample(&metrics.MetricSample{Name: "my.monotonic_count", Value: 5, Mtype: metrics.MonotonicCountType, SampleRate: 1, Timestamp: 12345})
+	checkSampler.addSample(&metrics.MetricSample{Name: "my.gauge", Value: 1, Mtype: metrics.GaugeType, SampleRate: 1, Timestamp: 12345})
+	checkSampler.addBucket(&metrics.HistogramBucket{Name: "my.histogram", Value: 4, LowerBound: 0, UpperBound: 10, Monotonic: true, Tags: []string{"bar"}, Timestamp: 12345})
+	checkSampler.commit(12350)
+	checkSampler.flush()
+
+	states := checkSampler.saveState()
+	assert.ElementsMatch(t, []contextState{
+		{Name: "my.rate", Tags: []string{"foo"}, Type: "Rate", Value: 20, Timestamp: 12345},
+		{Name: "my.monotonic_count", Type: "MonotonicCount", Value: 5},
+		{Name: "my.histogram", Tags: []string{"bar"}, Type: "Histogram", Value: 4},
+	}, states)
+
+	restored := newCheckSampler(1, true, time.Hour, store, nil)
+	restored.restoreState(states)
+	restored.addSample(&metrics.MetricSample{Name: "my.rate", Value: 50, Mtype: metrics.RateType, Tags: []string{"foo"}, SampleRate: 1, Timestamp: 12355})
+	restored.addSample(&metrics.MetricSample{Name: "my.monotonic_count", Value: 8, Mtype: metrics.MonotonicCountType, SampleRate: 1, Timestamp: 12355})
+	restored.addBucket(&metrics.HistogramBucket{Name: "my.histogram", Value: 6, LowerBound: 0, UpperBound: 10, Monotonic: true, Tags: []string{"bar"}, Timestamp: 12355})
+	restored.commit(12360)
+
+	// the first samples after the restore are used
+	series, sketches := restored.flush()
+	assert.Equal(t, map[string]float64{"my.rate": 3, "my.monotonic_count": 3}, seriesValues(series))
+	require.Len(t, sketches, 1)
+	assert.EqualValues(t, 2, sketches[0].Points[0].Sketch.Basic.Cnt)
+}
+
+func TestCheckSamplerState(t *testing.T) {
+	testWithTagsStore(t, testCheckSamplerState)
+}
+
+func TestTimeSamplerState(t *testing.T) {
+	sampler := testTimeSampler()
+	sampler.sample(&metrics.MetricSample{Name: "my.counter", Value: 1, Mtype: metrics.CounterType, Tags: []string{"foo"}, SampleRate: 1}, 12345)
+	sampler.sample(&metrics.MetricSample{Name: "my.gauge", Value: 1, Mtype: metrics.GaugeType, SampleRate: 1}, 12345)
+	flushSerie(sampler, 12360)
+
+	states := sampler.saveState()
+	assert.Equal(t, []contextState{{Name: "my.counter", Tags: []string{"foo"}, Type: "Counter", Timestamp: 12345}}, states)
+
+	// the counter is flushed with zero values after the restore
+	restored := testTimeSampler()
+	restored.restoreState(states)
+	series, _ := flushSerie(restored, 12380)
+	require.Len(t, series, 1)
+	assert.Equal(t, "my.counter", series[0].Name)
+	assert.Equal(t, []string{"foo"}, series[0].Tags.UnsafeToReadOnlySliceString())
+	assert.Equal(t, 0.0, series[0].Points[0].Value)
+}
+
+func TestAggregatorStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), aggregatorStateFileName)
+	now := time.Now()
+
+	state, err := loadAggregatorState(path, time.Minute, now)
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+
+	saved := &aggregatorState{
+		Version:   aggregatorStateVersion,
+		Timestamp: now.Unix(),
+		CheckSamplers: map[check.ID][]contextState{
+			"my_check:123": {{Name: "my.rate", Type: "Rate", Value: 1, Timestamp: 12345}},
+		},
+	}
+	require.NoError(t, saveAggregatorState(path, saved))
+	state, err = loadAggregatorState(path, time.Minute, now)
+	assert.NoError(t, err)
+	assert.Equal(t, saved, state)
+	// the state is restored only once
+	assert.NoFileExists(t, path)
+
+	require.NoError(t, saveAggregatorState(path, saved))
+	state, err = loadAggregatorState(path, time.Minute, now.Add(2*time.Minute))
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+
+	saved.Version = aggregatorStateVersion + 1
+	require.NoError(t, saveAggregatorState(path, saved))
+	_, err = loadAggregatorState(path, time.Minute, now)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+	_, err = loadAggregatorState(path, time.Minute, now)
+	assert.Error(t, err)
+}
+
+func TestDemuxPersistState(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("run_path", t.TempDir())
+	mockConfig.Set("aggregator_persist_state", true)
+	checkID := check.ID("my_check:123")
+
+	demux := initAgentDemultiplexer(demuxTestOptions(), "")
+	require.NoError(t, demux.aggregator.registerSender(checkID))
+	checkSampler := demux.aggregator.checkSamplers[checkID]
+	checkSampler.addSample(&metrics.MetricSample{Name: "my.monotonic_count", Value: 5, Mtype: metrics.MonotonicCountType, SampleRate: 1, Timestamp: 12345})
+	checkSampler.commit(12350)
+	demux.saveState()
+	assert.FileExists(t, aggregatorStatePath())
+
+	restored := initAgentDemultiplexer(demuxTestOptions(), "")
+	assert.NoFileExists(t, aggregatorStatePath())
+	require.NoError(t, restored.aggregator.registerSender(checkID))
+	checkSampler = restored.aggregator.checkSamplers[checkID]
+	checkSampler.addSample(&metrics.MetricSample{Name: "my.monotonic_count", Value: 7, Mtype: metrics.MonotonicCountType, SampleRate: 1, Timestamp: 12355})
+	checkSampler.commit(12360)
+	series, _ := checkSampler.flush()
+	assert.Equal(t, map[string]float64{"my.monotonic_count": 2}, seriesValues(series))
+	assert.Empty(t, restored.aggregator.restoredStates)
+}
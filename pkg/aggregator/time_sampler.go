@@ -47,7 +47,7 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store) *TimeSa
 
 	s := &TimeSampler{
 		interval:                    interval,
-		contextResolver:             newTimestampContextResolver(cache),
+		contextResolver:             newTimestampContextResolver(cache, newContextLimiterFromConfig()),
 		metricsByTimestamp:          map[int64]metrics.ContextMetrics{},
 		counterLastSampledByContext: map[ckey.ContextKey]float64{},
 		sketchMap:                   make(sketchMap),
@@ -274,3 +274,31 @@ func (s *TimeSampler) countersSampleZeroValue(timestamp int64, contextMetrics me
 		}
 	}
 }
+
+// saveState returns the states of the counters, which are flushed with zero
+// values until they expire, to restore them after a restart.
+func (s *TimeSampler) saveState() []contextState {
+	states := make([]contextState, 0, len(s.counterLastSampledByContext))
+	for key, lastSampled := range s.counterLastSampledByContext {
+		if context, ok := s.contextResolver.get(key); ok && !context.overflow {
+			states = append(states, newContextState(context, metrics.CounterType, 0, lastSampled))
+		}
+	}
+	return states
+}
+
+// restoreState tracks the contexts of the counters returned by saveState.
+func (s *TimeSampler) restoreState(states []contextState) {
+	for i := range states {
+		state := &states[i]
+		if state.Type != metrics.CounterType.String() {
+			continue
+		}
+
+		contextKey := s.contextResolver.trackContext(state, state.Timestamp)
+		if context, _ := s.contextResolver.get(contextKey); context.overflow {
+			continue
+		}
+		s.counterLastSampledByContext[contextKey] = state.Timestamp
+	}
+}
@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// timeSamplerSink submits metric samples to the first time sampler of a demultiplexer,
+// in batches from its metric sample pool.
+type timeSamplerSink struct {
+	demux Demultiplexer
+}
+
+// NewTimeSamplerSink returns a sink submitting the metric samples to the first time
+// sampler of the demultiplexer, which aggregates them as DogStatsD samples.
+func NewTimeSamplerSink(demux Demultiplexer) metrics.MetricSampleSink {
+	return &timeSamplerSink{demux: demux}
+}
+
+// AddMetricSamples copies the samples in batches of the metric sample pool, which are
+// put back in the pool by the time sampler. Implements `metrics.MetricSampleSink`.
+func (s *timeSamplerSink) AddMetricSamples(samples []metrics.MetricSample) {
+	pool := s.demux.GetMetricSamplePool()
+	for len(samples) > 0 {
+		batch := pool.GetBatch()
+		n := copy(batch, samples)
+		s.demux.AddTimeSampleBatch(TimeSamplerID(0), batch[:n])
+		samples = samples[n:]
+	}
+}
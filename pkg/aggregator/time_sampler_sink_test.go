@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestTimeSamplerSink(t *testing.T) {
+	demux := initAgentDemultiplexer(demuxTestOptions(), "")
+	sink := NewTimeSamplerSink(demux)
+
+	samples := make([]metrics.MetricSample, MetricSamplePoolBatchSize+5)
+	for i := range samples {
+		samples[i] = metrics.MetricSample{Name: fmt.Sprintf("metric.%d", i), Value: 1, Mtype: metrics.CountType}
+	}
+	sink.AddMetricSamples(samples)
+	// the caller can reuse its slice
+	samples[0].Name = "reused"
+
+	// the samples are sent to the first time sampler in batches of the pool
+	samplesChan := demux.statsd.workers[0].samplesChan
+	require.Len(t, samplesChan, 2)
+	batch := <-samplesChan
+	assert.Len(t, batch, MetricSamplePoolBatchSize)
+	assert.Equal(t, "metric.0", batch[0].Name)
+	batch = <-samplesChan
+	assert.Len(t, batch, 5)
+	assert.Equal(t, fmt.Sprintf("metric.%d", MetricSamplePoolBatchSize+4), batch[4].Name)
+}
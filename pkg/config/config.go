@@ -171,6 +171,7 @@ type MappingProfile struct {
 	Name     string          `mapstructure:"name" json:"name"`
 	Prefix   string          `mapstructure:"prefix" json:"prefix"`
 	Mappings []MetricMapping `mapstructure:"mappings" json:"mappings"`
+	Rules    []MetricRule    `mapstructure:"rules" json:"rules"`
 }
 
 // MetricMapping represent one mapping rule
@@ -181,6 +182,17 @@ type MetricMapping struct {
 	Tags      map[string]string `mapstructure:"tags" json:"tags"`
 }
 
+// MetricRule represent a rule dropping a metric or transforming its tags
+type MetricRule struct {
+	Match        string            `mapstructure:"match" json:"match"`
+	MatchType    string            `mapstructure:"match_type" json:"match_type"`
+	Drop         bool              `mapstructure:"drop" json:"drop"`
+	DropTags     []string          `mapstructure:"drop_tags" json:"drop_tags"`
+	RenameTags   map[string]string `mapstructure:"rename_tags" json:"rename_tags"`
+	StaticTags   map[string]string `mapstructure:"static_tags" json:"static_tags"`
+	MaxTagValues map[string]int    `mapstructure:"max_tag_values" json:"max_tag_values"`
+}
+
 // Endpoint represent a datadog endpoint
 type Endpoint struct {
 	Site   string `mapstructure:"site" json:"site"`
@@ -423,6 +435,10 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("histogram_percentiles", []string{"0.95"})
 	config.BindEnvAndSetDefault("aggregator_stop_timeout", 2)
 	config.BindEnvAndSetDefault("aggregator_buffer_size", 100)
+	config.BindEnvAndSetDefault("aggregator_max_contexts", 0)            // 0 means no limit
+	config.BindEnvAndSetDefault("aggregator_max_contexts_per_metric", 0) // 0 means no limit
+	config.BindEnvAndSetDefault("aggregator_persist_state", false)
+	config.BindEnvAndSetDefault("aggregator_persist_state_max_age", 300) // in seconds
 	config.BindEnvAndSetDefault("aggregator_use_tags_store", true)
 	config.BindEnvAndSetDefault("basic_telemetry_add_container_tags", false) // configure adding the agent container tags to the basic agent telemetry metrics (e.g. `datadog.agent.running`)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_chan_size", 200)
@@ -434,6 +450,11 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("enable_events_stream_payload_serialization", true)
 	config.BindEnvAndSetDefault("enable_sketch_stream_payload_serialization", true)
 	config.BindEnvAndSetDefault("enable_json_stream_shared_compressor_buffers", true)
+	// Compression used for the payloads: "zlib", "gzip", "zstd" or "none". Empty uses the compression the agent was built with.
+	config.BindEnvAndSetDefault("serializer_compressor_kind", "")
+	config.BindEnvAndSetDefault("serializer_compressor_level", 1) // Only used by the gzip and zstd compressors
+	// Overrides `serializer_compressor_kind` for some endpoints, keyed by endpoint name (e.g. `series_v2: zstd`)
+	config.BindEnvAndSetDefault("serializer_compressor_kind_per_endpoint", map[string]string{})
 
 	// Warning: do not change the following values. Your payloads will get dropped by Datadog's intake.
 	config.BindEnvAndSetDefault("serializer_max_payload_size", 2*megaByte+megaByte/2)
@@ -465,14 +486,24 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_backoff_max", 64)
 	config.BindEnvAndSetDefault("forwarder_recovery_interval", DefaultForwarderRecoveryInterval)
 	config.BindEnvAndSetDefault("forwarder_recovery_reset", false)
+	config.BindEnvAndSetDefault("forwarder_retry_max_age", 0)
+	config.BindEnv("forwarder_endpoint_retry_policies")
+	config.SetEnvKeyTransformer("forwarder_endpoint_retry_policies", forwarderRetryPoliciesTransformer("forwarder_endpoint_retry_policies"))
+	config.BindEnv("forwarder_domain_retry_policies")
+	config.SetEnvKeyTransformer("forwarder_domain_retry_policies", forwarderRetryPoliciesTransformer("forwarder_domain_retry_policies"))
 
 	// Forwarder storage on disk
 	config.BindEnvAndSetDefault("forwarder_storage_path", "")
 	config.BindEnvAndSetDefault("forwarder_outdated_file_in_days", 10)
 	config.BindEnvAndSetDefault("forwarder_flush_to_disk_mem_ratio", 0.5)
-	config.BindEnvAndSetDefault("forwarder_storage_max_size_in_bytes", 0)                // 0 means disabled. This is a BETA feature.
-	config.BindEnvAndSetDefault("forwarder_storage_max_disk_ratio", 0.80)                // Do not store transactions on disk when the disk usage exceeds 80% of the disk capacity. Use 80% as some applications do not behave well when the disk space is very small.
+	config.BindEnvAndSetDefault("forwarder_storage_max_size_in_bytes", 0) // 0 means disabled. This is a BETA feature.
+	config.BindEnvAndSetDefault("forwarder_storage_max_disk_ratio", 0.80) // Do not store transactions on disk when the disk usage exceeds 80% of the disk capacity. Use 80% as some applications do not behave well when the disk space is very small.
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_key", "")
 	config.BindEnvAndSetDefault("forwarder_retry_queue_capacity_time_interval_sec", 900) // 15 mins
+	config.BindEnvAndSetDefault("forwarder_dead_letter_path", "")
+	config.BindEnvAndSetDefault("forwarder_dead_letter_max_size_in_bytes", 100*1024*1024)
+	config.BindEnvAndSetDefault("forwarder_file_sink_path", "")
+	config.BindEnvAndSetDefault("forwarder_file_sink_max_files", 1000)
 
 	// Forwarder channels buffer size
 	config.BindEnvAndSetDefault("forwarder_high_prio_buffer_size", 100)
@@ -498,7 +529,14 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("dogstatsd_queue_size", 1024)
 
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
-	config.BindEnvAndSetDefault("dogstatsd_socket", "") // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_socket", "")        // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)       // Notice: 0 means TCP port closed
+	config.BindEnvAndSetDefault("dogstatsd_stream_socket", "") // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_stream_framing", "newline")
+	config.BindEnvAndSetDefault("dogstatsd_stream_max_connections", 1024) // 0 means no limit
+	config.BindEnvAndSetDefault("dogstatsd_openmetrics_port", 0)          // Notice: 0 means the OpenMetrics push endpoint is disabled
+	config.BindEnvAndSetDefault("dogstatsd_openmetrics_max_payload_size", 10*1024*1024)
+	config.BindEnvAndSetDefault("dogstatsd_openmetrics_max_series", 100000)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust", false)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_count", 1)
 	config.BindEnvAndSetDefault("dogstatsd_stats_port", 5000)
@@ -849,6 +887,16 @@ func InitConfig(config Config) {
 	// DEPRECATED in favor of `logs_config.force_use_tcp`.
 	config.BindEnvAndSetDefault("logs_config.use_tcp", false)
 	config.BindEnvAndSetDefault("logs_config.force_use_tcp", false)
+	// Spool the payloads on disk while the HTTP intake can't be reached
+	config.BindEnvAndSetDefault("logs_config.disk_spool.enabled", false)
+	config.BindEnvAndSetDefault("logs_config.disk_spool.path", "") // defaults to <logs_config.run_path>/spool
+	config.BindEnvAndSetDefault("logs_config.disk_spool.max_size_in_bytes", 100*1024*1024)
+	config.BindEnvAndSetDefault("logs_config.disk_spool.max_age", 86400) // in seconds
+	// Write the logs to a local file or to stdout instead of sending them to the intake
+	config.BindEnvAndSetDefault("logs_config.local_destination.type", "") // file or stdout
+	config.BindEnvAndSetDefault("logs_config.local_destination.path", "")
+	config.BindEnvAndSetDefault("logs_config.local_destination.max_size_in_bytes", 100*1024*1024)
+	config.BindEnvAndSetDefault("logs_config.local_destination.max_files", 5)
 
 	bindEnvAndSetLogsConfigKeys(config, "logs_config.")
 	bindEnvAndSetLogsConfigKeys(config, "database_monitoring.samples.")
@@ -864,6 +912,9 @@ func InitConfig(config Config) {
 	// maximum time that the windows tailer will hold a log file open, while waiting for
 	// the downstream logs pipeline to be ready to accept more data
 	config.BindEnvAndSetDefault("logs_config.windows_open_file_timeout", 5)
+	// identify the tailed files by a fingerprint of their first bytes in addition to their path
+	config.BindEnvAndSetDefault("logs_config.fingerprint.enabled", false)
+	config.BindEnvAndSetDefault("logs_config.fingerprint.size", 1024)
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line_detection", false)
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line_extra_patterns", []string{})
 	// The following auto_multi_line settings are experimental and may change
@@ -939,6 +990,14 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("clc_runner_port", 5005)
 	config.BindEnvAndSetDefault("clc_runner_server_write_timeout", 15)
 	config.BindEnvAndSetDefault("clc_runner_server_readheader_timeout", 10)
+	// Kubernetes audit webhook
+	config.BindEnvAndSetDefault("kubernetes_audit_webhook.enabled", false)
+	config.BindEnvAndSetDefault("kubernetes_audit_webhook.port", 8444)
+	config.BindEnvAndSetDefault("kubernetes_audit_webhook.tls_cert_file", "")
+	config.BindEnvAndSetDefault("kubernetes_audit_webhook.tls_key_file", "")
+	config.BindEnvAndSetDefault("kubernetes_audit_webhook.tls_client_ca_file", "")
+	config.BindEnvAndSetDefault("kubernetes_audit_webhook.bearer_token_file", "")
+	config.BindEnvAndSetDefault("kubernetes_audit_webhook.allow_insecure_http", false)
 	// Admission controller
 	config.BindEnvAndSetDefault("admission_controller.enabled", false)
 	config.BindEnvAndSetDefault("admission_controller.mutate_unlabelled", false)
@@ -1483,6 +1542,7 @@ func bindEnvAndSetLogsConfigKeys(config Config, prefix string) {
 	config.BindEnv(prefix + "additional_endpoints")
 	config.BindEnvAndSetDefault(prefix+"use_compression", true)
 	config.BindEnvAndSetDefault(prefix+"compression_level", 6) // Default level for the gzip/deflate algorithm
+	config.BindEnvAndSetDefault(prefix+"compression_kind", "gzip")
 	config.BindEnvAndSetDefault(prefix+"batch_wait", DefaultBatchWait)
 	config.BindEnvAndSetDefault(prefix+"connection_reset_interval", 0) // in seconds, 0 means disabled
 	config.BindEnvAndSetDefault(prefix+"logs_no_ssl", false)
@@ -1730,6 +1790,17 @@ func getDogstatsdMappingProfilesConfig(config Config) ([]MappingProfile, error)
 	return mappings, nil
 }
 
+// forwarderRetryPoliciesTransformer parses the JSON value of the environment variable of a retry policies setting.
+func forwarderRetryPoliciesTransformer(key string) func(string) interface{} {
+	return func(in string) interface{} {
+		var policies map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &policies); err != nil {
+			log.Errorf(`"%s" can not be parsed: %v`, key, err)
+		}
+		return policies
+	}
+}
+
 // IsCLCRunner returns whether the Agent is in cluster check runner mode
 func IsCLCRunner() bool {
 	if !Datadog.GetBool("clc_runner_enabled") {
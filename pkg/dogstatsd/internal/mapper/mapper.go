@@ -29,11 +29,12 @@ type MetricMapper struct {
 	cache    *mapperCache
 }
 
-// MappingProfile represent a group of mappings
+// MappingProfile represent a group of mappings and rules
 type MappingProfile struct {
 	Name     string
 	Prefix   string
 	Mappings []*MetricMapping
+	Rules    []*MetricRule
 }
 
 // MetricMapping represent one mapping rule
@@ -45,9 +46,21 @@ type MetricMapping struct {
 
 // MapResult represent the outcome of the mapping
 type MapResult struct {
-	Name    string
-	Tags    []string
+	Name string
+	Tags []string
+	// Drop is true when a rule drops the metric
+	Drop    bool
 	matched bool
+	rules   []*MetricRule
+}
+
+// ProcessTags applies the rules matching the metric to its tags. metricName is
+// the name of the metric after the mapping. The tags are modified in place.
+func (r *MapResult) ProcessTags(metricName string, tags []string) []string {
+	for _, rule := range r.rules {
+		tags = rule.processTags(metricName, tags)
+	}
+	return tags
 }
 
 // NewMetricMapper creates, validates, prepares a new MetricMapper
@@ -81,6 +94,13 @@ func NewMetricMapper(configProfiles []config.MappingProfile, cacheSize int) (*Me
 			}
 			profile.Mappings = append(profile.Mappings, &MetricMapping{name: currentMapping.Name, tags: currentMapping.Tags, regex: regex})
 		}
+		for i, configRule := range configProfile.Rules {
+			rule, err := newMetricRule(profile.Name, i, configRule)
+			if err != nil {
+				return nil, err
+			}
+			profile.Rules = append(profile.Rules, rule)
+		}
 		profiles = append(profiles, profile)
 	}
 	cache, err := newMapperCache(cacheSize)
@@ -115,38 +135,59 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 			continue
 		}
 		result, cached := m.cache.get(metricName)
-		if cached {
-			if result.matched {
-				return result
-			}
+		if !cached {
+			result = profile.mapMetric(metricName)
+			m.cache.add(metricName, result)
+		}
+		if !result.matched {
 			return nil
 		}
-		for _, mapping := range profile.Mappings {
-			matches := mapping.regex.FindStringSubmatchIndex(metricName)
-			if len(matches) == 0 {
-				continue
-			}
+		if result.Drop {
+			tlmDroppedMetrics.Inc(profile.Name)
+		}
+		return result
+	}
+	return nil
+}
 
-			name := string(mapping.regex.ExpandString(
-				[]byte{},
-				mapping.name,
-				metricName,
-				matches,
-			))
-
-			var tags []string
-			for tagKey, tagValueExpr := range mapping.tags {
-				tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
-				tags = append(tags, tagKey+":"+tagValue)
-			}
+// mapMetric returns the result of the first mapping and of all the rules matching metricName
+func (p *MappingProfile) mapMetric(metricName string) *MapResult {
+	mapResult := &MapResult{}
+	for _, mapping := range p.Mappings {
+		matches := mapping.regex.FindStringSubmatchIndex(metricName)
+		if len(matches) == 0 {
+			continue
+		}
+
+		name := string(mapping.regex.ExpandString(
+			[]byte{},
+			mapping.name,
+			metricName,
+			matches,
+		))
+
+		var tags []string
+		for tagKey, tagValueExpr := range mapping.tags {
+			tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
+			tags = append(tags, tagKey+":"+tagValue)
+		}
+
+		mapResult.Name = name
+		mapResult.Tags = tags
+		mapResult.matched = true
+		break
+	}
 
-			mapResult := &MapResult{Name: name, matched: true, Tags: tags}
-			m.cache.add(metricName, mapResult)
-			return mapResult
+	for _, rule := range p.Rules {
+		if !rule.regex.MatchString(metricName) {
+			continue
+		}
+		if !mapResult.matched {
+			mapResult.Name = metricName
+			mapResult.matched = true
 		}
-		mapResult := &MapResult{matched: false}
-		m.cache.add(metricName, mapResult)
-		return nil
+		mapResult.Drop = mapResult.Drop || rule.drop
+		mapResult.rules = append(mapResult.rules, rule)
 	}
-	return nil
+	return mapResult
 }
@@ -6,12 +6,14 @@
 package mapper
 
 import (
+	"fmt"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMappings(t *testing.T) {
@@ -499,6 +501,41 @@ dogstatsd_mapper_profiles:
 			},
 			expectedError: "missing prefix for profile",
 		},
+		{
+			name: "Rule without action",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+`,
+			expectedError: "one of drop, drop_tags, rename_tags, static_tags or max_tag_values is required",
+		},
+		{
+			name: "Rule without match",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - drop: true
+`,
+			expectedError: "rule num 0: match is required",
+		},
+		{
+			name: "Rule with invalid max_tag_values",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    rules:
+      - match: "test.*"
+        max_tag_values:
+          user: 0
+`,
+			expectedError: "max_tag_values of `user` must be positive",
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -510,6 +547,98 @@ dogstatsd_mapper_profiles:
 	}
 }
 
+func TestRules(t *testing.T) {
+	mapper, err := getMapper(`
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration.*"
+        name: "test.job.duration"
+        tags:
+          job_type: "$1"
+    rules:
+      - match: "test.debug.*"
+        drop: true
+      - match: "test.job.*.*"
+        drop_tags: ["pod_name"]
+        rename_tags:
+          user_id: user
+        static_tags:
+          team: jobs
+      - match: 'test\.job\..*'
+        match_type: regex
+        max_tag_values:
+          user: 2
+`)
+	require.NoError(t, err)
+
+	assert.Nil(t, mapper.Map("test.other"))
+	assert.Nil(t, mapper.Map("other.debug.foo"))
+
+	result := mapper.Map("test.debug.foo")
+	require.NotNil(t, result)
+	assert.True(t, result.Drop)
+
+	// the rules are applied to the tags extracted by the mapping
+	result = mapper.Map("test.job.duration.batch")
+	require.NotNil(t, result)
+	assert.False(t, result.Drop)
+	assert.Equal(t, "test.job.duration", result.Name)
+	tags := result.ProcessTags(result.Name, append([]string{"pod_name:pod1", "user_id:a", "env:prod"}, result.Tags...))
+	assert.Equal(t, []string{"user:a", "env:prod", "job_type:batch", "team:jobs"}, tags)
+
+	// the number of values of a tag is limited per metric name
+	result = mapper.Map("test.job.size")
+	require.NotNil(t, result)
+	assert.Equal(t, "test.job.size", result.Name)
+	assert.Nil(t, result.Tags)
+	for _, user := range []string{"a", "b", "a"} {
+		assert.Equal(t, []string{"user:" + user}, result.ProcessTags(result.Name, []string{"user:" + user}))
+	}
+	assert.Equal(t, []string{"user:" + OverflowTagValue}, result.ProcessTags(result.Name, []string{"user:c"}))
+	assert.Equal(t, []string{"user:b"}, result.ProcessTags(result.Name, []string{"user:b"}))
+	assert.Equal(t, []string{"user:c"}, result.ProcessTags("test.job.count", []string{"user:c"}))
+}
+
+func TestTagValuesLimiterExpiration(t *testing.T) {
+	now := time.Now()
+	limiter := newTagValuesLimiter(map[string]int{"user": 2})
+	limiter.now = func() time.Time { return now }
+
+	assert.True(t, limiter.allow("test.job", "user", "a"))
+	assert.True(t, limiter.allow("test.job", "user", "b"))
+	assert.False(t, limiter.allow("test.job", "user", "c"))
+
+	// the values not seen anymore are forgotten
+	now = now.Add(tagValuesExpiration / 2)
+	assert.True(t, limiter.allow("test.job", "user", "b"))
+	now = now.Add(tagValuesExpiration / 2)
+	assert.True(t, limiter.allow("test.job", "user", "c"))
+	assert.False(t, limiter.allow("test.job", "user", "a"))
+
+	// so are the metric names
+	now = now.Add(2 * tagValuesExpiration)
+	assert.True(t, limiter.allow("test.other", "user", "a"))
+	assert.Len(t, limiter.values, 1)
+}
+
+func TestTagValuesLimiterMaxMetricNames(t *testing.T) {
+	now := time.Now()
+	limiter := newTagValuesLimiter(map[string]int{"user": 1})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < maxTrackedMetricNames; i++ {
+		assert.True(t, limiter.allow(fmt.Sprintf("test.job.%d", i), "user", "a"))
+	}
+	assert.False(t, limiter.allow("test.job.new", "user", "a"))
+	assert.True(t, limiter.allow("test.job.new", "other", "a"))
+	assert.True(t, limiter.allow("test.job.0", "user", "a"))
+
+	now = now.Add(tagValuesExpiration)
+	assert.True(t, limiter.allow("test.job.new", "user", "a"))
+}
+
 func getMapper(configString string) (*MetricMapper, error) {
 	var profiles []config.MappingProfile
 	config.Datadog.SetConfigType("yaml")
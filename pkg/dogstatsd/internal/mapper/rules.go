@@ -0,0 +1,213 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package mapper
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+// OverflowTagValue is the value of the tags whose number of distinct values
+// exceeds the `max_tag_values` of a rule.
+const OverflowTagValue = "overflow"
+
+const (
+	// tagValuesExpiration is the duration after which a tag value which is not seen
+	// anymore is forgotten, freeing a slot for a new value.
+	tagValuesExpiration = time.Hour
+	// tagValuesPurgeInterval is the interval between the purges of the expired tag values.
+	tagValuesPurgeInterval = time.Minute
+	// maxTrackedMetricNames is the maximum number of metric names whose tag values are
+	// tracked by a rule. The limited tags of the other metric names are replaced by
+	// `overflow` until some tracked metric names expire.
+	maxTrackedMetricNames = 10000
+)
+
+var (
+	tlmDroppedMetrics = telemetry.NewCounter("dogstatsd", "mapper_dropped_metrics",
+		[]string{"profile"}, "Count of metrics dropped by the rules of the mapper profiles")
+	tlmOverflowTags = telemetry.NewCounter("dogstatsd", "mapper_overflow_tags",
+		[]string{"profile"}, "Count of tag values replaced because of the max_tag_values of the mapper profiles rules")
+)
+
+// MetricRule represent one rule dropping the matching metrics or transforming their tags
+type MetricRule struct {
+	profile    string
+	regex      *regexp.Regexp
+	drop       bool
+	dropTags   map[string]struct{}
+	renameTags map[string]string
+	staticTags []string
+	limiter    *tagValuesLimiter
+}
+
+func newMetricRule(profile string, index int, configRule config.MetricRule) (*MetricRule, error) {
+	matchType := configRule.MatchType
+	if matchType == "" {
+		matchType = matchTypeWildcard
+	}
+	if matchType != matchTypeWildcard && matchType != matchTypeRegex {
+		return nil, fmt.Errorf("profile: %s, rule num %d: invalid match type, must be `wildcard` or `regex`", profile, index)
+	}
+	if configRule.Match == "" {
+		return nil, fmt.Errorf("profile: %s, rule num %d: match is required", profile, index)
+	}
+	if !configRule.Drop && len(configRule.DropTags) == 0 && len(configRule.RenameTags) == 0 && len(configRule.StaticTags) == 0 && len(configRule.MaxTagValues) == 0 {
+		return nil, fmt.Errorf("profile: %s, rule num %d: one of drop, drop_tags, rename_tags, static_tags or max_tag_values is required", profile, index)
+	}
+	regex, err := buildRegex(configRule.Match, matchType)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &MetricRule{
+		profile:    profile,
+		regex:      regex,
+		drop:       configRule.Drop,
+		dropTags:   make(map[string]struct{}, len(configRule.DropTags)),
+		renameTags: configRule.RenameTags,
+	}
+	for _, key := range configRule.DropTags {
+		rule.dropTags[key] = struct{}{}
+	}
+	for key, value := range configRule.StaticTags {
+		rule.staticTags = append(rule.staticTags, key+":"+value)
+	}
+	sort.Strings(rule.staticTags)
+	if len(configRule.MaxTagValues) > 0 {
+		for key, max := range configRule.MaxTagValues {
+			if max <= 0 {
+				return nil, fmt.Errorf("profile: %s, rule num %d: max_tag_values of `%s` must be positive", profile, index, key)
+			}
+		}
+		rule.limiter = newTagValuesLimiter(configRule.MaxTagValues)
+	}
+	return rule, nil
+}
+
+// processTags applies the rule to the tags of a metric named metricName.
+// The tags are modified in place.
+func (r *MetricRule) processTags(metricName string, tags []string) []string {
+	if len(r.dropTags) == 0 && len(r.renameTags) == 0 && r.limiter == nil {
+		return append(tags, r.staticTags...)
+	}
+
+	n := 0
+	for _, tag := range tags {
+		key, value, hasValue := splitTag(tag)
+		if _, found := r.dropTags[key]; found {
+			continue
+		}
+		if newKey, found := r.renameTags[key]; found {
+			key = newKey
+			tag = key
+			if hasValue {
+				tag += ":" + value
+			}
+		}
+		if r.limiter != nil && !r.limiter.allow(metricName, key, value) {
+			tlmOverflowTags.Inc(r.profile)
+			tag = key + ":" + OverflowTagValue
+		}
+		tags[n] = tag
+		n++
+	}
+	return append(tags[:n], r.staticTags...)
+}
+
+// splitTag returns the key and the value of a `key:value` tag.
+func splitTag(tag string) (string, string, bool) {
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		return tag[:i], tag[i+1:], true
+	}
+	return tag, "", false
+}
+
+// tagValuesLimiter tracks the distinct values of some tag keys per metric name.
+// The values which were not seen for `tagValuesExpiration` are forgotten.
+type tagValuesLimiter struct {
+	maxValues map[string]int
+	now       func() time.Time
+
+	mutex     sync.Mutex
+	lastPurge time.Time
+	// metric name -> tag key -> tag value -> last time the value was seen
+	values map[string]map[string]map[string]time.Time
+}
+
+func newTagValuesLimiter(maxValues map[string]int) *tagValuesLimiter {
+	return &tagValuesLimiter{
+		maxValues: maxValues,
+		now:       time.Now,
+		values:    make(map[string]map[string]map[string]time.Time),
+	}
+}
+
+// allow returns false when the value is a new value of the tag key and the
+// maximum number of values for this key and this metric name is reached, or
+// when the metric name can't be tracked.
+func (l *tagValuesLimiter) allow(metricName, key, value string) bool {
+	max, found := l.maxValues[key]
+	if !found {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastPurge) >= tagValuesPurgeInterval {
+		l.purge(now)
+	}
+
+	valuesByKey, found := l.values[metricName]
+	if !found {
+		if len(l.values) >= maxTrackedMetricNames {
+			return false
+		}
+		valuesByKey = make(map[string]map[string]time.Time)
+		l.values[metricName] = valuesByKey
+	}
+	values, found := valuesByKey[key]
+	if !found {
+		values = make(map[string]time.Time)
+		valuesByKey[key] = values
+	}
+
+	if _, found := values[value]; !found && len(values) >= max {
+		return false
+	}
+	values[value] = now
+	return true
+}
+
+// purge forgets the tag values which were not seen for `tagValuesExpiration`
+// and the metric names left without values.
+func (l *tagValuesLimiter) purge(now time.Time) {
+	l.lastPurge = now
+	for metricName, valuesByKey := range l.values {
+		for key, values := range valuesByKey {
+			for value, lastSeen := range values {
+				if now.Sub(lastSeen) >= tagValuesExpiration {
+					delete(values, value)
+				}
+			}
+			if len(values) == 0 {
+				delete(valuesByKey, key)
+			}
+		}
+		if len(valuesByKey) == 0 {
+			delete(l.values, metricName)
+		}
+	}
+}
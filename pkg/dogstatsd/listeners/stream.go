@@ -0,0 +1,337 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// streamFramingNewline separates the messages with '\n'.
+	streamFramingNewline = "newline"
+	// streamFramingLengthPrefixed prefixes each payload with its length as a 32 bits little endian integer.
+	streamFramingLengthPrefixed = "length_prefixed"
+
+	lengthPrefixSize = 4
+)
+
+// streamOriginFunc returns the origin of the packets received on a connection.
+type streamOriginFunc func(conn net.Conn) (string, error)
+
+// streamListener implements the accept loop and the framing shared by the
+// listeners of stream oriented protocols (TCP and stream mode UDS). Each
+// connection has its own packets.Assembler so that origin detection can be
+// done once per connection.
+type streamListener struct {
+	name                    string
+	listener                net.Listener
+	packetsBuffer           *packets.Buffer
+	sharedPacketPoolManager *packets.PoolManager
+	packetSourceType        packets.SourceType
+	bufferSize              int
+	flushTimeout            time.Duration
+	framing                 string
+	maxConnections          int
+	optionalOriginFunc      streamOriginFunc
+	expvars                 *expvar.Map
+
+	// readBuffers and readers are pools of the buffers used to read the connections.
+	readBuffers sync.Pool
+	readers     sync.Pool
+
+	connections map[net.Conn]struct{}
+	stopped     bool
+	mutex       sync.Mutex
+	wg          sync.WaitGroup
+}
+
+func newStreamListener(
+	name string,
+	listener net.Listener,
+	packetOut chan packets.Packets,
+	sharedPacketPoolManager *packets.PoolManager,
+	packetSourceType packets.SourceType,
+	optionalOriginFunc streamOriginFunc,
+	expvars *expvar.Map) (*streamListener, error) {
+
+	framing := config.Datadog.GetString("dogstatsd_stream_framing")
+	if framing != streamFramingNewline && framing != streamFramingLengthPrefixed {
+		return nil, fmt.Errorf("invalid dogstatsd_stream_framing %q: must be %q or %q", framing, streamFramingNewline, streamFramingLengthPrefixed)
+	}
+
+	flushTimeout := config.Datadog.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+	bufferSize := config.Datadog.GetInt("dogstatsd_buffer_size")
+	return &streamListener{
+		name:                    name,
+		listener:                listener,
+		packetsBuffer:           packets.NewBuffer(uint(config.Datadog.GetInt("dogstatsd_packet_buffer_size")), flushTimeout, packetOut),
+		sharedPacketPoolManager: sharedPacketPoolManager,
+		packetSourceType:        packetSourceType,
+		bufferSize:              bufferSize,
+		flushTimeout:            flushTimeout,
+		framing:                 framing,
+		maxConnections:          config.Datadog.GetInt("dogstatsd_stream_max_connections"),
+		optionalOriginFunc:      optionalOriginFunc,
+		expvars:                 expvars,
+		readBuffers: sync.Pool{
+			New: func() interface{} {
+				b := make([]byte, bufferSize)
+				return &b
+			},
+		},
+		readers: sync.Pool{
+			New: func() interface{} {
+				return bufio.NewReaderSize(nil, bufferSize)
+			},
+		},
+		connections: make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// Listen runs the accept loop. Should be called in its own goroutine
+func (l *streamListener) Listen() {
+	log.Infof("dogstatsd-%s: starting to listen on %s", l.name, l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			// listener has been closed
+			if strings.HasSuffix(err.Error(), " use of closed network connection") {
+				return
+			}
+			log.Errorf("dogstatsd-%s: error accepting a connection: %v", l.name, err)
+			continue
+		}
+
+		if !l.addConnection(conn) {
+			continue
+		}
+		go l.listenConnection(conn)
+	}
+}
+
+func (l *streamListener) addConnection(conn net.Conn) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.stopped {
+		conn.Close()
+		return false
+	}
+	if l.maxConnections > 0 && len(l.connections) >= l.maxConnections {
+		log.Warnf("dogstatsd-%s: rejecting a connection from %s: the maximum number of connections (%d) is reached", l.name, conn.RemoteAddr(), l.maxConnections)
+		tlmStreamRejectedConnections.Inc(l.name)
+		conn.Close()
+		return false
+	}
+	l.connections[conn] = struct{}{}
+	l.wg.Add(1)
+	tlmStreamConnections.Set(float64(len(l.connections)), l.name)
+	return true
+}
+
+func (l *streamListener) removeConnection(conn net.Conn) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	conn.Close()
+	delete(l.connections, conn)
+	tlmStreamConnections.Set(float64(len(l.connections)), l.name)
+	l.wg.Done()
+}
+
+func (l *streamListener) listenConnection(conn net.Conn) {
+	defer l.removeConnection(conn)
+	log.Debugf("dogstatsd-%s: new connection from %s", l.name, conn.RemoteAddr())
+
+	assembler := packets.NewAssembler(l.flushTimeout, l.packetsBuffer, l.sharedPacketPoolManager, l.packetSourceType)
+	defer func() {
+		assembler.Flush()
+		assembler.Close()
+	}()
+
+	if l.optionalOriginFunc != nil {
+		origin, err := l.optionalOriginFunc(conn)
+		if err != nil {
+			log.Warnf("dogstatsd-%s: error processing origin, data will not be tagged : %v", l.name, err)
+			tlmStreamOriginDetectionError.Inc(l.name)
+		} else {
+			assembler.SetOrigin(origin)
+		}
+	}
+
+	var err error
+	if l.framing == streamFramingLengthPrefixed {
+		err = l.readLengthPrefixed(conn, assembler)
+	} else {
+		err = l.readNewlineDelimited(conn, assembler)
+	}
+
+	if err != nil && err != io.EOF && !strings.HasSuffix(err.Error(), " use of closed network connection") {
+		log.Errorf("dogstatsd-%s: error reading from %s: %v", l.name, conn.RemoteAddr(), err)
+		l.onReadError()
+	}
+	log.Debugf("dogstatsd-%s: connection from %s closed", l.name, conn.RemoteAddr())
+}
+
+// readNewlineDelimited reads messages separated by '\n' until the connection is closed.
+func (l *streamListener) readNewlineDelimited(conn net.Conn, assembler *packets.Assembler) error {
+	bufferPtr := l.readBuffers.Get().(*[]byte)
+	defer l.readBuffers.Put(bufferPtr)
+	buffer := *bufferPtr
+
+	startWriteIndex := 0
+	// discarding is true while the end of a message bigger than the buffer is read.
+	discarding := false
+	t1 := time.Now()
+	var t2 time.Time
+	for {
+		t2 = time.Now()
+		tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), l.name)
+
+		bytesRead, err := conn.Read(buffer[startWriteIndex:])
+		t1 = time.Now()
+		if err != nil {
+			// A last message without '\n' is complete when the client closes the connection.
+			if err == io.EOF && startWriteIndex > 0 {
+				l.onReadSuccess(startWriteIndex)
+				assembler.AddMessage(buffer[:startWriteIndex])
+			}
+			return err
+		}
+
+		endIndex := startWriteIndex + bytesRead
+		startIndex := 0
+
+		// The end of a dropped message is discarded up to the next '\n'.
+		if discarding {
+			newlineIndex := bytes.IndexByte(buffer[:endIndex], '\n')
+			if newlineIndex < 0 {
+				startWriteIndex = 0
+				continue
+			}
+			discarding = false
+			startIndex = newlineIndex + 1
+		}
+
+		// When there is no '\n', the message is partial. LastIndexByte returns -1 and messageSize is 0.
+		// If there is a '\n', at least one message is completed and '\n' is part of this message.
+		messageSize := bytes.LastIndexByte(buffer[startIndex:endIndex], '\n') + 1
+		if messageSize > 0 {
+			l.onReadSuccess(messageSize)
+			assembler.AddMessage(buffer[startIndex : startIndex+messageSize-1])
+		}
+
+		readIndex := startIndex + messageSize
+		startWriteIndex = endIndex - readIndex
+
+		// If the message is bigger than the buffer size, drop it and its end, up to the next '\n'.
+		if startWriteIndex >= len(buffer) {
+			log.Warnf("dogstatsd-%s: dropping a message bigger than dogstatsd_buffer_size (%d bytes)", l.name, len(buffer))
+			l.onReadError()
+			startWriteIndex = 0
+			discarding = true
+		} else {
+			copy(buffer, buffer[readIndex:endIndex])
+		}
+	}
+}
+
+// readLengthPrefixed reads payloads prefixed by their length until the connection is closed.
+func (l *streamListener) readLengthPrefixed(conn net.Conn, assembler *packets.Assembler) error {
+	reader := l.readers.Get().(*bufio.Reader)
+	reader.Reset(conn)
+	defer func() {
+		reader.Reset(nil)
+		l.readers.Put(reader)
+	}()
+	bufferPtr := l.readBuffers.Get().(*[]byte)
+	defer l.readBuffers.Put(bufferPtr)
+	buffer := *bufferPtr
+
+	prefix := make([]byte, lengthPrefixSize)
+	t1 := time.Now()
+	var t2 time.Time
+	for {
+		t2 = time.Now()
+		tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), l.name)
+
+		if _, err := io.ReadFull(reader, prefix); err != nil {
+			return err
+		}
+		t1 = time.Now()
+
+		size := int(binary.LittleEndian.Uint32(prefix))
+		if size > len(buffer) {
+			log.Warnf("dogstatsd-%s: dropping a payload of %d bytes bigger than dogstatsd_buffer_size (%d bytes)", l.name, size, len(buffer))
+			l.onReadError()
+			if _, err := io.CopyN(io.Discard, reader, int64(size)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := io.ReadFull(reader, buffer[:size]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("truncated payload: %v", err)
+			}
+			return err
+		}
+		if size > 0 {
+			l.onReadSuccess(size + lengthPrefixSize)
+			assembler.AddMessage(bytes.TrimSuffix(buffer[:size], []byte{'\n'}))
+		}
+	}
+}
+
+func (l *streamListener) onReadSuccess(n int) {
+	l.expvars.Add("Packets", 1)
+	l.expvars.Add("Bytes", int64(n))
+	tlmStreamPackets.Inc(l.name, "ok")
+	tlmStreamPacketsBytes.Add(float64(n), l.name)
+}
+
+func (l *streamListener) onReadError() {
+	l.expvars.Add("Packets", 1)
+	l.expvars.Add("PacketReadingErrors", 1)
+	tlmStreamPackets.Inc(l.name, "error")
+}
+
+// getActiveConnectionsCount returns the number of active connections.
+func (l *streamListener) getActiveConnectionsCount() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.connections)
+}
+
+// Stop closes the listener and the connections and waits for the connections
+// to be processed.
+func (l *streamListener) Stop() {
+	l.listener.Close()
+
+	l.mutex.Lock()
+	l.stopped = true
+	for conn := range l.connections {
+		// Stop the current execution of net.Conn.Read()
+		conn.Close()
+	}
+	l.mutex.Unlock()
+
+	l.wg.Wait()
+	l.packetsBuffer.Close()
+}
@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+// +build !windows
+
+package listeners
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+)
+
+func newTestTCPListener(t *testing.T, packetChannel chan packets.Packets) *TCPListener {
+	s, err := NewTCPListener(packetChannel, packetPoolManagerUDP, nil)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	go s.Listen()
+	return s
+}
+
+func TestTCPReceiveNewlineDelimited(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("dogstatsd_tcp_port", 0)
+	mockConfig.Set("dogstatsd_packet_buffer_flush_timeout", 10*time.Millisecond)
+
+	packetChannel := make(chan packets.Packets)
+	s := newTestTCPListener(t, packetChannel)
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	// The second message is split over two writes
+	_, err = conn.Write([]byte("daemon:666|g\ndaemon:"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("777|g\n"))
+	require.NoError(t, err)
+	// The last message is complete when the connection is closed
+	_, err = conn.Write([]byte("daemon:888|g"))
+	require.NoError(t, err)
+	conn.Close()
+
+	assert.Equal(t, "daemon:666|g\ndaemon:777|g\ndaemon:888|g", string(receiveTestPackets(t, packetChannel, packets.TCP)))
+}
+
+func TestTCPReceiveNewlineDelimitedBigMessage(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("dogstatsd_tcp_port", 0)
+	mockConfig.Set("dogstatsd_buffer_size", 16)
+	mockConfig.Set("dogstatsd_packet_buffer_flush_timeout", 10*time.Millisecond)
+
+	packetChannel := make(chan packets.Packets)
+	s := newTestTCPListener(t, packetChannel)
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	// The message bigger than the buffer is dropped up to its '\n'
+	_, err = conn.Write([]byte("daemon:666|g\nbig:" + strings.Repeat("1", 40) + "|g\ndaemon:777|g\n"))
+	require.NoError(t, err)
+	conn.Close()
+
+	assert.Equal(t, "daemon:666|g\ndaemon:777|g", string(receiveTestPackets(t, packetChannel, packets.TCP)))
+}
+
+func TestTCPReceiveLengthPrefixed(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("dogstatsd_tcp_port", 0)
+	mockConfig.Set("dogstatsd_stream_framing", "length_prefixed")
+	mockConfig.Set("dogstatsd_packet_buffer_flush_timeout", 10*time.Millisecond)
+
+	packetChannel := make(chan packets.Packets)
+	s := newTestTCPListener(t, packetChannel)
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write(lengthPrefixed("daemon:666|g\ndaemon:777|g"))
+	require.NoError(t, err)
+	// Payloads bigger than the buffer are dropped
+	_, err = conn.Write(lengthPrefixed(string(make([]byte, config.Datadog.GetInt("dogstatsd_buffer_size")+1))))
+	require.NoError(t, err)
+	_, err = conn.Write(lengthPrefixed("daemon:888|g\n"))
+	require.NoError(t, err)
+	conn.Close()
+
+	assert.Equal(t, "daemon:666|g\ndaemon:777|g\ndaemon:888|g", string(receiveTestPackets(t, packetChannel, packets.TCP)))
+}
+
+func TestTCPInvalidFraming(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("dogstatsd_tcp_port", 0)
+	mockConfig.Set("dogstatsd_stream_framing", "unknown")
+
+	s, err := NewTCPListener(nil, packetPoolManagerUDP, nil)
+	assert.Nil(t, s)
+	assert.Error(t, err)
+}
+
+func TestTCPMaxConnections(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("dogstatsd_tcp_port", 0)
+	mockConfig.Set("dogstatsd_stream_max_connections", 1)
+
+	s := newTestTCPListener(t, make(chan packets.Packets))
+	defer s.Stop()
+
+	conn1, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn1.Close()
+	require.Eventually(t, func() bool { return s.getActiveConnectionsCount() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// The second connection is closed by the listener
+	conn2, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn2.Close()
+	require.NoError(t, conn2.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = conn2.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.Equal(t, 1, s.getActiveConnectionsCount())
+
+	conn1.Close()
+	require.Eventually(t, func() bool { return s.getActiveConnectionsCount() == 0 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestTCPStopClosesConnections(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("dogstatsd_tcp_port", 0)
+
+	s := newTestTCPListener(t, make(chan packets.Packets))
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool { return s.getActiveConnectionsCount() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	s.Stop()
+	assert.Equal(t, 0, s.getActiveConnectionsCount())
+}
+
+func lengthPrefixed(payload string) []byte {
+	buffer := make([]byte, lengthPrefixSize+len(payload))
+	binary.LittleEndian.PutUint32(buffer, uint32(len(payload)))
+	copy(buffer[lengthPrefixSize:], payload)
+	return buffer
+}
+
+// receiveTestPackets returns the contents of the packets received until the channel is idle.
+func receiveTestPackets(t *testing.T, packetChannel chan packets.Packets, source packets.SourceType) []byte {
+	var contents []byte
+	for {
+		select {
+		case pkts := <-packetChannel:
+			for _, packet := range pkts {
+				assert.Equal(t, source, packet.Source)
+				if len(contents) > 0 {
+					contents = append(contents, '\n')
+				}
+				contents = append(contents, packet.Contents...)
+			}
+		case <-time.After(500 * time.Millisecond):
+			require.NotEmpty(t, contents, "Timeout on receive channel")
+			return contents
+		}
+	}
+}
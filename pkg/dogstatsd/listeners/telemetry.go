@@ -24,6 +24,18 @@ var (
 	tlmUDSPacketsBytes = telemetry.NewCounter("dogstatsd", "uds_packets_bytes",
 		nil, "Dogstatsd UDS packets bytes")
 
+	// TCP and stream UDS
+	tlmStreamPackets = telemetry.NewCounter("dogstatsd", "stream_packets",
+		[]string{"listener_type", "state"}, "Dogstatsd stream packets count")
+	tlmStreamPacketsBytes = telemetry.NewCounter("dogstatsd", "stream_packets_bytes",
+		[]string{"listener_type"}, "Dogstatsd stream packets bytes count")
+	tlmStreamConnections = telemetry.NewGauge("dogstatsd", "stream_connections",
+		[]string{"listener_type"}, "Dogstatsd active stream connections")
+	tlmStreamRejectedConnections = telemetry.NewCounter("dogstatsd", "stream_rejected_connections",
+		[]string{"listener_type"}, "Dogstatsd stream connections rejected because the connection limit is reached")
+	tlmStreamOriginDetectionError = telemetry.NewCounter("dogstatsd", "stream_origin_detection_error",
+		[]string{"listener_type"}, "Dogstatsd stream origin detection error count")
+
 	tlmListener            = telemetry.NewHistogramNoOp()
 	defaultListenerBuckets = []float64{300, 500, 1000, 1500, 2000, 2500, 3000, 10000, 20000, 50000}
 )
@@ -67,6 +67,34 @@ func processUDSOrigin(ancillary []byte) (int, string, error) {
 		return 0, packets.NoOrigin, err
 	}
 
+	return processUcredOrigin(cred)
+}
+
+// processUDSPeerOrigin determines the origin of a stream mode UDS connection
+// from the credentials of the peer process, retrieved with SO_PEERCRED.
+func processUDSPeerOrigin(conn *net.UnixConn) (int, string, error) {
+	rawconn, err := conn.SyscallConn()
+	if err != nil {
+		return 0, packets.NoOrigin, err
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	err = rawconn.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, packets.NoOrigin, err
+	}
+	if credErr != nil {
+		return 0, packets.NoOrigin, credErr
+	}
+
+	return processUcredOrigin(cred)
+}
+
+// processUcredOrigin returns the PID and the origin of the process of `cred`.
+func processUcredOrigin(cred *unix.Ucred) (int, string, error) {
 	if cred.Pid == 0 {
 		return 0, packets.NoOrigin, fmt.Errorf("matched PID for the process is 0, it belongs " +
 			"probably to another namespace. Is the agent in host PID mode?")
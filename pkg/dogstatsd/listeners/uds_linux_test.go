@@ -14,6 +14,8 @@
 package listeners
 
 import (
+	"net"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -50,3 +52,20 @@ func TestUDSPassCred(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, enabled, 1)
 }
+
+func TestUDSPeerOriginPID(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "dsd-stream.socket")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	client, err := net.Dial("unix", socketPath)
+	require.NoError(t, err)
+	defer client.Close()
+	conn, err := listener.AcceptUnix()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	pid, _, _ := processUDSPeerOrigin(conn)
+	assert.Equal(t, os.Getpid(), pid)
+}
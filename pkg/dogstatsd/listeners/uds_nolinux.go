@@ -32,3 +32,8 @@ func enableUDSPassCred(conn *net.UnixConn) error {
 func processUDSOrigin(oob []byte) (int, string, error) {
 	return 0, packets.NoOrigin, ErrLinuxOnly
 }
+
+// processUDSPeerOrigin returns a "not implemented" error on non-linux hosts
+func processUDSPeerOrigin(conn *net.UnixConn) (int, string, error) {
+	return 0, packets.NoOrigin, ErrLinuxOnly
+}
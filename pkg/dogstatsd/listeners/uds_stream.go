@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	udsStreamExpvars             = expvar.NewMap("dogstatsd-uds_stream")
+	udsStreamPacketReadingErrors = expvar.Int{}
+	udsStreamPackets             = expvar.Int{}
+	udsStreamBytes               = expvar.Int{}
+)
+
+func init() {
+	udsStreamExpvars.Set("PacketReadingErrors", &udsStreamPacketReadingErrors)
+	udsStreamExpvars.Set("Packets", &udsStreamPackets)
+	udsStreamExpvars.Set("Bytes", &udsStreamBytes)
+}
+
+// UDSStreamListener implements the StatsdListener interface for Unix Domain
+// Socket stream protocol (SOCK_STREAM). It listens to a given socket path and
+// sends back packets ready to be processed. The messages are framed according
+// to `dogstatsd_stream_framing`.
+// Origin detection is done once per connection from the peer credentials.
+type UDSStreamListener struct {
+	*streamListener
+	socketPath      string
+	trafficCapture  *replay.TrafficCapture // Currently ignored
+	OriginDetection bool
+}
+
+// NewUDSStreamListener returns an idle stream mode UDS Statsd listener
+func NewUDSStreamListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, capture *replay.TrafficCapture) (*UDSStreamListener, error) {
+	socketPath := config.Datadog.GetString("dogstatsd_stream_socket")
+	originDetection := config.Datadog.GetBool("dogstatsd_origin_detection")
+
+	address, addrErr := net.ResolveUnixAddr("unix", socketPath)
+	if addrErr != nil {
+		return nil, fmt.Errorf("dogstatsd-uds_stream: can't ResolveUnixAddr: %v", addrErr)
+	}
+	fileInfo, err := os.Stat(socketPath)
+	// Socket file already exists
+	if err == nil {
+		// Make sure it's a UNIX socket
+		if fileInfo.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("dogstatsd-uds_stream: cannot reuse %s socket path: path already exists and is not a UNIX socket", socketPath)
+		}
+		err = os.Remove(socketPath)
+		if err != nil {
+			return nil, fmt.Errorf("dogstatsd-uds_stream: cannot remove stale UNIX socket: %v", err)
+		}
+	}
+
+	listener, err := net.ListenUnix("unix", address)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	// The socket file is removed by Stop
+	listener.SetUnlinkOnClose(false)
+	err = os.Chmod(socketPath, 0722)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("can't set the socket at write only: %s", err)
+	}
+
+	var originFunc streamOriginFunc
+	if originDetection {
+		log.Debugf("dogstatsd-uds_stream: enabling origin detection on %s", listener.Addr())
+		originFunc = func(conn net.Conn) (string, error) {
+			_, origin, err := processUDSPeerOrigin(conn.(*net.UnixConn))
+			return origin, err
+		}
+	}
+
+	stream, err := newStreamListener("uds_stream", listener, packetOut, sharedPacketPoolManager, packets.UDSStream, originFunc, udsStreamExpvars)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	log.Debugf("dogstatsd-uds_stream: %s successfully initialized", listener.Addr())
+	return &UDSStreamListener{
+		streamListener:  stream,
+		socketPath:      socketPath,
+		trafficCapture:  capture,
+		OriginDetection: originDetection,
+	}, nil
+}
+
+// Stop closes the UDS connections and stops listening
+func (l *UDSStreamListener) Stop() {
+	l.streamListener.Stop()
+
+	// Socket cleanup on exit
+	err := os.Remove(l.socketPath)
+	if err != nil {
+		log.Infof("dogstatsd-uds_stream: error removing socket file: %s", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+// +build !windows
+
+package listeners
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+)
+
+func TestUDSStreamReceive(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "dsd-stream.socket")
+	mockConfig := config.Mock(t)
+	mockConfig.Set("dogstatsd_stream_socket", socketPath)
+	mockConfig.Set("dogstatsd_packet_buffer_flush_timeout", 10*time.Millisecond)
+
+	packetChannel := make(chan packets.Packets)
+	s, err := NewUDSStreamListener(packetChannel, packetPoolManagerUDP, nil)
+	require.NoError(t, err)
+	go s.Listen()
+
+	conn, err := net.Dial("unix", socketPath)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("daemon:666|g|#sometag1:somevalue1\n"))
+	require.NoError(t, err)
+	conn.Close()
+
+	assert.Equal(t, "daemon:666|g|#sometag1:somevalue1", string(receiveTestPackets(t, packetChannel, packets.UDSStream)))
+
+	s.Stop()
+	_, err = os.Stat(socketPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUDSStreamReuseStaleSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "dsd-stream.socket")
+	mockConfig := config.Mock(t)
+	mockConfig.Set("dogstatsd_stream_socket", socketPath)
+
+	s, err := NewUDSStreamListener(nil, packetPoolManagerUDP, nil)
+	require.NoError(t, err)
+	s.listener.Close()
+
+	s, err = NewUDSStreamListener(nil, packetPoolManagerUDP, nil)
+	require.NoError(t, err)
+	s.Stop()
+
+	require.NoError(t, os.WriteFile(socketPath, []byte{}, 0600))
+	_, err = NewUDSStreamListener(nil, packetPoolManagerUDP, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,491 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/base64"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// openMetricsPushPath is the path of the push endpoint. Like the Prometheus
+	// Pushgateway, it can be followed by grouping labels:
+	// /metrics/job/<job>{/<label>/<value>}
+	openMetricsPushPath = "/metrics"
+
+	// openMetricsCounterExpiry is the duration after which the last value of a
+	// cumulative series that is no longer pushed is forgotten.
+	openMetricsCounterExpiry = time.Hour
+	// openMetricsCounterPurgeInterval is the interval at which the expired
+	// cumulative series are forgotten.
+	openMetricsCounterPurgeInterval = time.Minute
+)
+
+var (
+	dogstatsdOpenMetricsPushes        = expvar.Int{}
+	dogstatsdOpenMetricsParseErrors   = expvar.Int{}
+	dogstatsdOpenMetricsSamples       = expvar.Int{}
+	dogstatsdOpenMetricsSeriesDropped = expvar.Int{}
+)
+
+func init() {
+	dogstatsdExpvars.Set("OpenMetricsPushes", &dogstatsdOpenMetricsPushes)
+	dogstatsdExpvars.Set("OpenMetricsParseErrors", &dogstatsdOpenMetricsParseErrors)
+	dogstatsdExpvars.Set("OpenMetricsSamples", &dogstatsdOpenMetricsSamples)
+	dogstatsdExpvars.Set("OpenMetricsSeriesDropped", &dogstatsdOpenMetricsSeriesDropped)
+}
+
+// cumulativeValue is the last value pushed for a cumulative series.
+type cumulativeValue struct {
+	value    float64
+	lastSeen time.Time
+}
+
+// openMetricsPushServer receives metrics pushed over HTTP in the Prometheus
+// text exposition format or in the OpenMetrics text format, and feeds them to
+// the aggregator with the same mapping and enrichment as DogStatsD messages.
+//
+// Prometheus counters, and the buckets, counts and sums of the histograms and
+// summaries are cumulative: they are submitted as counts of the difference
+// with the previous push of the same series. At most maxSeries cumulative
+// series are tracked, the new series are dropped once the limit is reached.
+type openMetricsPushServer struct {
+	server         *Server
+	listener       net.Listener
+	httpServer     *http.Server
+	maxPayloadSize int64
+	maxSeries      int
+
+	// mutex protects the batcher and the cumulative values
+	mutex       sync.Mutex
+	batcher     *batcher
+	cumulatives map[string]cumulativeValue
+	lastPurge   time.Time
+	// droppedSeries is the number of series dropped by the current push
+	droppedSeries int
+}
+
+func newOpenMetricsPushServer(s *Server) (*openMetricsPushServer, error) {
+	var address string
+	if config.Datadog.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		address = fmt.Sprintf(":%d", config.Datadog.GetInt("dogstatsd_openmetrics_port"))
+	} else {
+		address = net.JoinHostPort(config.GetBindHost(), config.Datadog.GetString("dogstatsd_openmetrics_port"))
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	var b *batcher
+	if s.ServerlessMode {
+		b = newServerlessBatcher(s.demultiplexer)
+	} else {
+		b = newBatcher(s.demultiplexer.(aggregator.DemultiplexerWithAggregator))
+	}
+
+	o := &openMetricsPushServer{
+		server:         s,
+		listener:       listener,
+		maxPayloadSize: int64(config.Datadog.GetInt("dogstatsd_openmetrics_max_payload_size")),
+		maxSeries:      config.Datadog.GetInt("dogstatsd_openmetrics_max_series"),
+		batcher:        b,
+		cumulatives:    make(map[string]cumulativeValue),
+		lastPurge:      time.Now(),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(openMetricsPushPath, o)
+	mux.Handle(openMetricsPushPath+"/", o)
+	o.httpServer = &http.Server{
+		Handler:     mux,
+		ReadTimeout: 30 * time.Second,
+	}
+
+	log.Debugf("dogstatsd-openmetrics: %s successfully initialized", listener.Addr())
+	return o, nil
+}
+
+// listen serves the push endpoint. Should be called in its own goroutine
+func (o *openMetricsPushServer) listen() {
+	log.Infof("dogstatsd-openmetrics: starting to listen on %s", o.listener.Addr())
+	if err := o.httpServer.Serve(o.listener); err != nil && err != http.ErrServerClosed {
+		log.Errorf("dogstatsd-openmetrics: error serving the push endpoint: %v", err)
+	}
+}
+
+// stop closes the listener and the active connections
+func (o *openMetricsPushServer) stop() {
+	o.httpServer.Close() //nolint:errcheck
+}
+
+// ServeHTTP implements http.Handler
+func (o *openMetricsPushServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupingTags, err := parseGroupingLabels(strings.TrimPrefix(r.URL.Path, openMetricsPushPath))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, o.maxPayloadSize)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid gzip payload: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer gzipReader.Close()
+		body = gzipReader
+	}
+
+	samples, err := o.parsePush(body, r.Header.Get("Content-Type"), groupingTags, time.Now())
+	if err != nil {
+		dogstatsdOpenMetricsParseErrors.Add(1)
+		tlmProcessedError.Inc()
+		o.server.errLog("Dogstatsd: error parsing OpenMetrics payload from %s: %s", r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dogstatsdOpenMetricsPushes.Add(1)
+
+	o.submit(samples)
+	w.WriteHeader(http.StatusOK)
+}
+
+// submit sends the samples to the aggregator.
+func (o *openMetricsPushServer) submit(samples []metrics.MetricSample) {
+	debugEnabled := o.server.Debug.Enabled.Load()
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for idx := range samples {
+		if debugEnabled {
+			o.server.storeMetricStats(samples[idx])
+		}
+		o.batcher.appendSample(samples[idx])
+	}
+	o.batcher.flush()
+}
+
+// parsePush parses a pushed payload and returns the enriched metric samples.
+func (o *openMetricsPushServer) parsePush(body io.Reader, contentType string, groupingTags []string, now time.Time) ([]metrics.MetricSample, error) {
+	if strings.HasPrefix(contentType, "application/openmetrics-text") {
+		normalized, err := normalizeOpenMetrics(body)
+		if err != nil {
+			return nil, err
+		}
+		body = strings.NewReader(normalized)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(body)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.purgeCumulatives(now)
+	o.droppedSeries = 0
+
+	var metricSamples []metrics.MetricSample
+	for _, family := range families {
+		for _, sample := range o.convertFamily(family, groupingTags, now) {
+			metricSamples = o.server.mapAndEnrichMetricSample(metricSamples, sample, "")
+		}
+	}
+	if o.droppedSeries > 0 {
+		dogstatsdOpenMetricsSeriesDropped.Add(int64(o.droppedSeries))
+		o.server.errLog("Dogstatsd: dropping %d new OpenMetrics series: the maximum number of series (%d) is reached", o.droppedSeries, o.maxSeries)
+	}
+	dogstatsdOpenMetricsSamples.Add(int64(len(metricSamples)))
+	dogstatsdMetricPackets.Add(int64(len(metricSamples)))
+	tlmProcessedOk.Add(float64(len(metricSamples)))
+	return metricSamples, nil
+}
+
+// convertFamily converts a Prometheus metric family to DogStatsD metric samples.
+// Histograms and summaries are split in `.count`, `.sum` and `.bucket` or
+// `.quantile` metrics.
+func (o *openMetricsPushServer) convertFamily(family *dto.MetricFamily, groupingTags []string, now time.Time) []dogstatsdMetricSample {
+	var samples []dogstatsdMetricSample
+	name := family.GetName()
+
+	gauge := func(name string, value float64, tags []string) {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return
+		}
+		samples = append(samples, dogstatsdMetricSample{name: name, value: value, metricType: gaugeType, sampleRate: 1, tags: tags})
+	}
+	cumulative := func(name string, value float64, tags []string) {
+		if delta, ok := o.delta(name, value, tags, now); ok {
+			samples = append(samples, dogstatsdMetricSample{name: name, value: delta, metricType: countType, sampleRate: 1, tags: tags})
+		}
+	}
+
+	for _, metric := range family.GetMetric() {
+		tags := labelsToTags(groupingTags, metric.GetLabel())
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			cumulative(name, metric.GetCounter().GetValue(), tags)
+		case dto.MetricType_GAUGE:
+			gauge(name, metric.GetGauge().GetValue(), tags)
+		case dto.MetricType_UNTYPED:
+			gauge(name, metric.GetUntyped().GetValue(), tags)
+		case dto.MetricType_SUMMARY:
+			summary := metric.GetSummary()
+			cumulative(name+".count", float64(summary.GetSampleCount()), tags)
+			cumulative(name+".sum", summary.GetSampleSum(), tags)
+			for _, quantile := range summary.GetQuantile() {
+				gauge(name+".quantile", quantile.GetValue(), appendTag(tags, "quantile", formatBound(quantile.GetQuantile())))
+			}
+		case dto.MetricType_HISTOGRAM:
+			histogram := metric.GetHistogram()
+			cumulative(name+".count", float64(histogram.GetSampleCount()), tags)
+			cumulative(name+".sum", histogram.GetSampleSum(), tags)
+			for _, bucket := range histogram.GetBucket() {
+				cumulative(name+".bucket", float64(bucket.GetCumulativeCount()), appendTag(tags, "upper_bound", formatBound(bucket.GetUpperBound())))
+			}
+		}
+	}
+	return samples
+}
+
+// delta returns the difference between value and the previous value of the
+// cumulative series. The first value of a series is its increase since the
+// series started, so that the series pushed only once, like the ones of the
+// batch jobs, are not lost.
+// A value lower than the previous one is a reset of the series: the value is
+// then the increase since the reset.
+// It returns false when the value can't be submitted: it is NaN, or the series
+// is new and the maximum number of series is reached.
+func (o *openMetricsPushServer) delta(name string, value float64, tags []string, now time.Time) (float64, bool) {
+	if math.IsNaN(value) {
+		return 0, false
+	}
+
+	sortedTags := make([]string, len(tags))
+	copy(sortedTags, tags)
+	sort.Strings(sortedTags)
+	key := name + "|" + strings.Join(sortedTags, ",")
+
+	previous, found := o.cumulatives[key]
+	if !found && o.maxSeries > 0 && len(o.cumulatives) >= o.maxSeries {
+		// the value can't be submitted without its previous value on the next push
+		o.droppedSeries++
+		return 0, false
+	}
+	o.cumulatives[key] = cumulativeValue{value: value, lastSeen: now}
+	if !found || value < previous.value {
+		return value, true
+	}
+	return value - previous.value, true
+}
+
+// purgeCumulatives forgets the series that haven't been pushed for openMetricsCounterExpiry.
+func (o *openMetricsPushServer) purgeCumulatives(now time.Time) {
+	if now.Sub(o.lastPurge) < openMetricsCounterPurgeInterval {
+		return
+	}
+	for key, cumulative := range o.cumulatives {
+		if now.Sub(cumulative.lastSeen) > openMetricsCounterExpiry {
+			delete(o.cumulatives, key)
+		}
+	}
+	o.lastPurge = now
+}
+
+// labelsToTags returns a new tags slice with the grouping tags and the labels.
+// Each sample needs its own slice as the enrichment modifies it in place.
+func labelsToTags(groupingTags []string, labels []*dto.LabelPair) []string {
+	tags := make([]string, 0, len(groupingTags)+len(labels)+1)
+	tags = append(tags, groupingTags...)
+	for _, label := range labels {
+		// an empty label value is equivalent to a missing label
+		if label.GetValue() == "" {
+			continue
+		}
+		tags = append(tags, label.GetName()+":"+label.GetValue())
+	}
+	return tags
+}
+
+// appendTag returns a copy of tags with an extra tag.
+func appendTag(tags []string, key, value string) []string {
+	newTags := make([]string, len(tags), len(tags)+1)
+	copy(newTags, tags)
+	return append(newTags, key+":"+value)
+}
+
+func formatBound(bound float64) string {
+	return strconv.FormatFloat(bound, 'g', -1, 64)
+}
+
+// parseGroupingLabels parses Pushgateway style grouping labels from the path
+// following /metrics: /job/<job>{/<label>/<value>}. A label name suffixed with
+// `@base64` has a base64url encoded value.
+func parseGroupingLabels(path string) ([]string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("invalid grouping labels %q: expected /<label>/<value> pairs", path)
+	}
+
+	tags := make([]string, 0, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		name, value := parts[i], parts[i+1]
+		if strings.HasSuffix(name, "@base64") {
+			name = strings.TrimSuffix(name, "@base64")
+			decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 value for grouping label %q: %v", name, err)
+			}
+			value = string(decoded)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("invalid grouping labels %q: empty label name", path)
+		}
+		if value == "" {
+			continue
+		}
+		tags = append(tags, name+":"+value)
+	}
+	return tags, nil
+}
+
+// normalizeOpenMetrics rewrites an OpenMetrics text payload into the
+// Prometheus text format: the `# EOF` and `# UNIT` lines, the exemplars, the
+// timestamps and the `_created` series are dropped, the `_total` suffix of the
+// counters is removed and the types unknown to the Prometheus format are
+// converted to untyped.
+func normalizeOpenMetrics(body io.Reader) (string, error) {
+	types := map[string]string{}
+	var builder strings.Builder
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			switch fields[1] {
+			case "HELP":
+				builder.WriteString(line)
+			case "TYPE":
+				if len(fields) != 4 {
+					return "", fmt.Errorf("invalid TYPE line %q", line)
+				}
+				metricType := fields[3]
+				switch metricType {
+				case "counter", "gauge", "histogram", "summary":
+				default:
+					// unknown, info, stateset and gaugehistogram
+					metricType = "untyped"
+				}
+				types[fields[2]] = metricType
+				builder.WriteString("# TYPE " + fields[2] + " " + metricType)
+			default:
+				// # EOF, # UNIT and comments
+				continue
+			}
+			builder.WriteByte('\n')
+			continue
+		}
+
+		name, labels, rest := splitOpenMetricsSample(line)
+		fields := strings.Fields(rest)
+		if name == "" || len(fields) == 0 {
+			return "", fmt.Errorf("invalid sample line %q", line)
+		}
+
+		if family := strings.TrimSuffix(name, "_created"); family != name {
+			if t := types[family]; t == "counter" || t == "histogram" || t == "summary" {
+				continue
+			}
+		}
+		if family := strings.TrimSuffix(name, "_total"); family != name && types[family] == "counter" {
+			name = family
+		}
+
+		builder.WriteString(name)
+		builder.WriteString(labels)
+		builder.WriteByte(' ')
+		builder.WriteString(fields[0])
+		builder.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// splitOpenMetricsSample splits a sample line in its name, its label set
+// (braces included) and the rest of the line.
+func splitOpenMetricsSample(line string) (string, string, string) {
+	nameEnd := strings.IndexAny(line, "{ ")
+	if nameEnd < 0 {
+		return line, "", ""
+	}
+	if line[nameEnd] == ' ' {
+		return line[:nameEnd], "", line[nameEnd:]
+	}
+
+	inQuotes := false
+	for i := nameEnd + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			inQuotes = !inQuotes
+		case '}':
+			if !inQuotes {
+				return line[:nameEnd], line[nameEnd : i+1], line[i+1:]
+			}
+		}
+	}
+	return line[:nameEnd], line[nameEnd:], ""
+}
@@ -0,0 +1,273 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// getAvailableTCPPort requests a random port number and makes sure it is available
+func getAvailableTCPPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return -1, fmt.Errorf("can't find an available tcp port: %s", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func newOpenMetricsTestServer(t *testing.T, demux aggregator.Demultiplexer, datadogYaml string) (*Server, int) {
+	mockConfig := config.Mock(t)
+	mockConfig.SetConfigType("yaml")
+	require.NoError(t, mockConfig.ReadConfig(strings.NewReader(datadogYaml)))
+
+	udpPort, err := getAvailableUDPPort()
+	require.NoError(t, err)
+	mockConfig.Set("dogstatsd_port", udpPort)
+	port, err := getAvailableTCPPort()
+	require.NoError(t, err)
+	mockConfig.Set("dogstatsd_openmetrics_port", port)
+
+	s, err := NewServer(demux, false)
+	require.NoError(t, err, "cannot start DSD")
+	require.NotNil(t, s.openMetrics)
+	return s, port
+}
+
+func TestOpenMetricsPush(t *testing.T) {
+	demux := aggregator.InitTestAgentDemultiplexerWithFlushInterval(10 * time.Millisecond)
+	defer demux.Stop(false)
+	s, port := newOpenMetricsTestServer(t, demux, "")
+	defer s.Stop()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/metrics/job/backup/instance/db1", port)
+	push := func(payload string) {
+		resp, err := http.Post(url, "text/plain; version=0.0.4", strings.NewReader(payload))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// the first push of the counter submits its value
+	push("# TYPE backup_size_bytes gauge\nbackup_size_bytes{tier=\"cold\"} 1024\n# TYPE backup_files counter\nbackup_files 10\n")
+	samples := demux.WaitForSamples(time.Second * 2)
+	require.Len(t, samples, 2)
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Name < samples[j].Name })
+	assert.Equal(t, "backup_files", samples[0].Name)
+	assert.Equal(t, metrics.CounterType, samples[0].Mtype)
+	assert.EqualValues(t, 10, samples[0].Value)
+	assert.Equal(t, "backup_size_bytes", samples[1].Name)
+	assert.Equal(t, metrics.GaugeType, samples[1].Mtype)
+	assert.EqualValues(t, 1024, samples[1].Value)
+	assert.ElementsMatch(t, []string{"job:backup", "instance:db1", "tier:cold"}, samples[1].Tags)
+	demux.Reset()
+
+	// the second push of the counter submits the increase
+	push("# TYPE backup_files counter\nbackup_files 15\n")
+	samples = demux.WaitForSamples(time.Second * 2)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "backup_files", samples[0].Name)
+	assert.Equal(t, metrics.CounterType, samples[0].Mtype)
+	assert.EqualValues(t, 5, samples[0].Value)
+	assert.ElementsMatch(t, []string{"job:backup", "instance:db1"}, samples[0].Tags)
+}
+
+func TestOpenMetricsPushErrors(t *testing.T) {
+	demux := mockDemultiplexer()
+	defer demux.Stop(false)
+	s, port := newOpenMetricsTestServer(t, demux, "")
+	defer s.Stop()
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Post(fmt.Sprintf("http://127.0.0.1:%d/metrics/job", port), "text/plain", strings.NewReader("metric 1\n"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(fmt.Sprintf("http://127.0.0.1:%d/metrics", port), "text/plain", strings.NewReader("metric{ 1\n"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestOpenMetricsHistogramAndSummary(t *testing.T) {
+	demux := mockDemultiplexer()
+	defer demux.Stop(false)
+	s, _ := newOpenMetricsTestServer(t, demux, "")
+	defer s.Stop()
+
+	payload := func(factor int) string {
+		return fmt.Sprintf(`# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.5"} %d
+request_duration_seconds_bucket{le="+Inf"} %d
+request_duration_seconds_sum %d
+request_duration_seconds_count %d
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.99"} 0.2
+rpc_duration_seconds_sum %d
+rpc_duration_seconds_count %d
+`, 2*factor, 3*factor, 4*factor, 3*factor, 5*factor, 6*factor)
+	}
+
+	now := time.Now()
+	samples, err := s.openMetrics.parsePush(strings.NewReader(payload(1)), "text/plain", nil, now)
+	require.NoError(t, err)
+	// the values are submitted for the first push
+	assert.ElementsMatch(t, []MetricSample{
+		{Name: "request_duration_seconds.count", Value: 3, Mtype: metrics.CounterType, Tags: []string{}},
+		{Name: "request_duration_seconds.sum", Value: 4, Mtype: metrics.CounterType, Tags: []string{}},
+		{Name: "request_duration_seconds.bucket", Value: 2, Mtype: metrics.CounterType, Tags: []string{"upper_bound:0.5"}},
+		{Name: "request_duration_seconds.bucket", Value: 3, Mtype: metrics.CounterType, Tags: []string{"upper_bound:+Inf"}},
+		{Name: "rpc_duration_seconds.count", Value: 6, Mtype: metrics.CounterType, Tags: []string{}},
+		{Name: "rpc_duration_seconds.sum", Value: 5, Mtype: metrics.CounterType, Tags: []string{}},
+		{Name: "rpc_duration_seconds.quantile", Value: 0.2, Mtype: metrics.GaugeType, Tags: []string{"quantile:0.99"}},
+	}, toMetricSamples(samples))
+
+	samples, err = s.openMetrics.parsePush(strings.NewReader(payload(2)), "text/plain", nil, now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []MetricSample{
+		{Name: "request_duration_seconds.count", Value: 3, Mtype: metrics.CounterType, Tags: []string{}},
+		{Name: "request_duration_seconds.sum", Value: 4, Mtype: metrics.CounterType, Tags: []string{}},
+		{Name: "request_duration_seconds.bucket", Value: 2, Mtype: metrics.CounterType, Tags: []string{"upper_bound:0.5"}},
+		{Name: "request_duration_seconds.bucket", Value: 3, Mtype: metrics.CounterType, Tags: []string{"upper_bound:+Inf"}},
+		{Name: "rpc_duration_seconds.count", Value: 6, Mtype: metrics.CounterType, Tags: []string{}},
+		{Name: "rpc_duration_seconds.sum", Value: 5, Mtype: metrics.CounterType, Tags: []string{}},
+		{Name: "rpc_duration_seconds.quantile", Value: 0.2, Mtype: metrics.GaugeType, Tags: []string{"quantile:0.99"}},
+	}, toMetricSamples(samples))
+
+	// a lower value is a reset of the counter
+	samples, err = s.openMetrics.parsePush(strings.NewReader("# TYPE rpc_duration_seconds summary\nrpc_duration_seconds_sum 1\nrpc_duration_seconds_count 2\n"), "text/plain", nil, now.Add(20*time.Second))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []MetricSample{
+		{Name: "rpc_duration_seconds.count", Value: 2, Mtype: metrics.CounterType, Tags: []string{}},
+		{Name: "rpc_duration_seconds.sum", Value: 1, Mtype: metrics.CounterType, Tags: []string{}},
+	}, toMetricSamples(samples))
+}
+
+func TestOpenMetricsMaxSeries(t *testing.T) {
+	demux := mockDemultiplexer()
+	defer demux.Stop(false)
+	s, _ := newOpenMetricsTestServer(t, demux, "dogstatsd_openmetrics_max_series: 1")
+	defer s.Stop()
+
+	now := time.Now()
+	dropped := dogstatsdOpenMetricsSeriesDropped.Value()
+	samples, err := s.openMetrics.parsePush(strings.NewReader("# TYPE jobs counter\njobs 1\n# TYPE errors counter\nerrors 1\n"), "text/plain", nil, now)
+	require.NoError(t, err)
+	// the new series are dropped once the limit is reached
+	require.Len(t, samples, 1)
+	assert.Len(t, s.openMetrics.cumulatives, 1)
+	assert.Equal(t, dropped+1, dogstatsdOpenMetricsSeriesDropped.Value())
+
+	// the expired series make room for the new ones
+	samples, err = s.openMetrics.parsePush(strings.NewReader("# TYPE other counter\nother 1\n"), "text/plain", nil, now.Add(openMetricsCounterExpiry+time.Minute))
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "other", samples[0].Name)
+	assert.Len(t, s.openMetrics.cumulatives, 1)
+}
+
+func TestOpenMetricsMapperAndTags(t *testing.T) {
+	demux := mockDemultiplexer()
+	defer demux.Stop(false)
+	s, _ := newOpenMetricsTestServer(t, demux, `
+dogstatsd_tags:
+  - env:prod
+dogstatsd_mapper_profiles:
+  - name: jobs
+    prefix: 'job_'
+    mappings:
+      - match: 'job_(\w+)_duration_seconds'
+        match_type: regex
+        name: 'job.duration'
+        tags:
+          job_name: '$1'
+`)
+	defer s.Stop()
+
+	samples, err := s.openMetrics.parsePush(strings.NewReader("job_backup_duration_seconds{host=\"db1\"} 12\n"), "text/plain", []string{"team:storage"}, time.Now())
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "job.duration", samples[0].Name)
+	assert.Equal(t, "db1", samples[0].Host)
+	assert.ElementsMatch(t, []string{"team:storage", "job_name:backup", "env:prod"}, samples[0].Tags)
+}
+
+func TestOpenMetricsFormat(t *testing.T) {
+	demux := mockDemultiplexer()
+	defer demux.Stop(false)
+	s, _ := newOpenMetricsTestServer(t, demux, "")
+	defer s.Stop()
+
+	payload := `# TYPE jobs counter
+# HELP jobs Number of jobs.
+jobs_total{queue="a # b"} 1 1656928800.5 # {trace_id="abc"} 1
+jobs_created{queue="a # b"} 1656928000
+# TYPE temperature gauge
+# UNIT temperature celsius
+temperature 21.5
+# TYPE build info
+build_info{version="1.2"} 1
+# EOF
+`
+	now := time.Now()
+	_, err := s.openMetrics.parsePush(strings.NewReader(payload), "application/openmetrics-text; version=1.0.0; charset=utf-8", nil, now)
+	require.NoError(t, err)
+	samples, err := s.openMetrics.parsePush(strings.NewReader(strings.Replace(payload, "} 1 1656928800.5", "} 3 1656928810.5", 1)), "application/openmetrics-text", nil, now.Add(time.Second))
+	require.NoError(t, err)
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Name < samples[j].Name })
+	assert.Equal(t, []MetricSample{
+		{Name: "build_info", Value: 1, Mtype: metrics.GaugeType, Tags: []string{"version:1.2"}},
+		{Name: "jobs", Value: 2, Mtype: metrics.CounterType, Tags: []string{"queue:a # b"}},
+		{Name: "temperature", Value: 21.5, Mtype: metrics.GaugeType, Tags: []string{}},
+	}, toMetricSamples(samples))
+}
+
+func TestParseGroupingLabels(t *testing.T) {
+	tags, err := parseGroupingLabels("")
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+
+	tags, err = parseGroupingLabels("/job/backup/instance/db1/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"job:backup", "instance:db1"}, tags)
+
+	// "/var/tmp" encoded in base64url
+	tags, err = parseGroupingLabels("/job/backup/path@base64/L3Zhci90bXA=")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"job:backup", "path:/var/tmp"}, tags)
+
+	_, err = parseGroupingLabels("/job")
+	assert.Error(t, err)
+	_, err = parseGroupingLabels("/job/backup/path@base64/!!!")
+	assert.Error(t, err)
+}
+
+func toMetricSamples(samples []metrics.MetricSample) []MetricSample {
+	result := make([]MetricSample, 0, len(samples))
+	for _, sample := range samples {
+		result = append(result, MetricSample{Name: sample.Name, Value: sample.Value, Tags: sample.Tags, Mtype: sample.Mtype})
+	}
+	return result
+}
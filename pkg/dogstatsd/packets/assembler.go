@@ -23,6 +23,7 @@ type Assembler struct {
 	flushTimer              *time.Ticker
 	closeChannel            chan struct{}
 	packetSourceType        SourceType
+	origin                  string
 	sync.Mutex
 }
 
@@ -77,6 +78,7 @@ func (p *Assembler) flush() {
 	}
 	p.packet.Contents = p.packet.Buffer[:p.packetLength]
 	p.packet.Source = p.packetSourceType
+	p.packet.Origin = p.origin
 	p.packetsBuffer.Append(p.packet)
 	// retrieve an available packet from the packet pool,
 	// which will be pushed back by the server when processed.
@@ -84,6 +86,21 @@ func (p *Assembler) flush() {
 	p.packetLength = 0
 }
 
+// SetOrigin sets the origin of the assembled packets. It is used by the listeners
+// creating an assembler per connection.
+func (p *Assembler) SetOrigin(origin string) {
+	p.Lock()
+	p.origin = origin
+	p.Unlock()
+}
+
+// Flush sends the pending messages to the packets buffer.
+func (p *Assembler) Flush() {
+	p.Lock()
+	p.flush()
+	p.Unlock()
+}
+
 // Close closes the packet assembler
 func (p *Assembler) Close() {
 	p.Lock()
@@ -149,6 +149,7 @@ type Server struct {
 	debugTagsAccumulator      *tagset.HashingTagsAccumulator
 	TCapture                  *replay.TrafficCapture
 	mapper                    *mapper.MetricMapper
+	openMetrics               *openMetricsPushServer
 	eolTerminationUDP         bool
 	eolTerminationUDS         bool
 	eolTerminationNamedPipe   bool
@@ -277,6 +278,24 @@ func NewServer(demultiplexer aggregator.Demultiplexer, serverless bool) (*Server
 			tmpListeners = append(tmpListeners, udpListener)
 		}
 	}
+	if config.Datadog.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPoolManager, capture)
+		if err != nil {
+			log.Errorf(err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+
+	streamSocketPath := config.Datadog.GetString("dogstatsd_stream_socket")
+	if len(streamSocketPath) > 0 {
+		unixStreamListener, err := listeners.NewUDSStreamListener(packetsChannel, sharedPacketPoolManager, capture)
+		if err != nil {
+			log.Errorf(err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, unixStreamListener)
+		}
+	}
 
 	pipeName := config.Datadog.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
@@ -412,6 +431,19 @@ func NewServer(demultiplexer aggregator.Demultiplexer, serverless bool) (*Server
 			s.mapper = mapperInstance
 		}
 	}
+
+	// receive the metrics pushed in the Prometheus or OpenMetrics format
+	// ----------------------
+
+	if config.Datadog.GetInt("dogstatsd_openmetrics_port") > 0 {
+		openMetrics, err := newOpenMetricsPushServer(s)
+		if err != nil {
+			log.Errorf("dogstatsd-openmetrics: can't start the push endpoint: %v", err)
+		} else {
+			s.openMetrics = openMetrics
+			go openMetrics.listen()
+		}
+	}
 	return s, nil
 }
 
@@ -660,32 +692,48 @@ func (s *Server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 		return metricSamples, err
 	}
 
+	metricSamples = s.mapAndEnrichMetricSample(metricSamples, sample, origin)
+
+	if len(sample.values) > 0 {
+		s.sharedFloat64List.put(sample.values)
+	}
+
+	for range metricSamples {
+		dogstatsdMetricPackets.Add(1)
+		okCnt.Inc()
+	}
+	return metricSamples, nil
+}
+
+// mapAndEnrichMetricSample applies the mapper profiles and the enrichment to
+// sample and appends the resulting metric samples to metricSamples.
+func (s *Server) mapAndEnrichMetricSample(metricSamples []metrics.MetricSample, sample dogstatsdMetricSample, origin string) []metrics.MetricSample {
 	if s.mapper != nil {
 		mapResult := s.mapper.Map(sample.name)
 		if mapResult != nil {
+			if mapResult.Drop {
+				log.Tracef("Dogstatsd mapper: metric %q dropped", sample.name)
+				return metricSamples
+			}
 			log.Tracef("Dogstatsd mapper: metric mapped from %q to %q with tags %v", sample.name, mapResult.Name, mapResult.Tags)
 			sample.name = mapResult.Name
-			sample.tags = append(sample.tags, mapResult.Tags...)
+			sample.tags = mapResult.ProcessTags(sample.name, append(sample.tags, mapResult.Tags...))
 		}
 	}
-	metricSamples = enrichMetricSample(metricSamples, sample, s.metricPrefix, s.metricPrefixBlacklist, s.metricBlocklist, s.defaultHostname, origin, s.entityIDPrecedenceEnabled, s.ServerlessMode)
 
-	if len(sample.values) > 0 {
-		s.sharedFloat64List.put(sample.values)
-	}
+	first := len(metricSamples)
+	metricSamples = enrichMetricSample(metricSamples, sample, s.metricPrefix, s.metricPrefixBlacklist, s.metricBlocklist, s.defaultHostname, origin, s.entityIDPrecedenceEnabled, s.ServerlessMode)
 
-	for idx := range metricSamples {
-		// All metricSamples already share the same Tags slice. We can
+	for idx := first; idx < len(metricSamples); idx++ {
+		// All the new metricSamples share the same Tags slice. We can
 		// extends the first one and reuse it for the rest.
-		if idx == 0 {
+		if idx == first {
 			metricSamples[idx].Tags = append(metricSamples[idx].Tags, s.extraTags...)
 		} else {
-			metricSamples[idx].Tags = metricSamples[0].Tags
+			metricSamples[idx].Tags = metricSamples[first].Tags
 		}
-		dogstatsdMetricPackets.Add(1)
-		okCnt.Inc()
 	}
-	return metricSamples, nil
+	return metricSamples
 }
 
 func (s *Server) parseEventMessage(parser *parser, message []byte, origin string) (*metrics.Event, error) {
@@ -718,6 +766,9 @@ func (s *Server) parseServiceCheckMessage(parser *parser, message []byte, origin
 
 // Stop stops a running Dogstatsd server
 func (s *Server) Stop() {
+	if s.openMetrics != nil {
+		s.openMetrics.stop()
+	}
 	close(s.stopChan)
 	for _, l := range s.listeners {
 		l.Stop()
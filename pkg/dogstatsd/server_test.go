@@ -802,6 +802,36 @@ dogstatsd_mapper_profiles:
 			expectedSamples:   nil,
 			expectedCacheSize: 999,
 		},
+		{
+			name: "Rules",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration.*"
+        name: "test.job.duration"
+        tags:
+          job_type: "$1"
+    rules:
+      - match: "test.debug.*"
+        drop: true
+      - match: "test.job.*.*"
+        drop_tags: ["pod_name"]
+        rename_tags:
+          user_id: user
+        static_tags:
+          team: jobs
+`,
+			packets: []string{
+				"test.debug.foo:666|g|#pod_name:pod1",
+				"test.job.duration.my_job_type:666|g|#pod_name:pod1,user_id:a",
+			},
+			expectedSamples: []MetricSample{
+				{Name: "test.job.duration", Tags: []string{"job_type:my_job_type", "user:a", "team:jobs"}, Mtype: metrics.GaugeType, Value: 666.0},
+			},
+			expectedCacheSize: 1000,
+		},
 	}
 
 	samples := []metrics.MetricSample{}
@@ -224,7 +224,7 @@ func newHTTPPassthroughPipeline(desc passthroughPipelineDesc, destinationsContex
 
 	encoder := sender.IdentityContentType
 	if endpoints.Main.UseCompression {
-		encoder = sender.NewGzipContentEncoding(endpoints.Main.CompressionLevel)
+		encoder = sender.NewContentEncoding(endpoints.Main.CompressionKind, endpoints.Main.CompressionLevel)
 	}
 
 	strategy := sender.NewBatchStrategy(inputChan,
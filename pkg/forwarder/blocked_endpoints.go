@@ -9,9 +9,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/backoff"
-	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 type block struct {
@@ -20,45 +18,35 @@ type block struct {
 }
 
 type blockedEndpoints struct {
-	errorPerEndpoint map[string]*block
-	backoffPolicy    backoff.Policy
-	m                sync.RWMutex
+	errorPerEndpoint        map[string]*block
+	backoffPolicy           backoff.Policy
+	endpointBackoffPolicies map[string]backoff.Policy
+	m                       sync.RWMutex
 }
 
 func newBlockedEndpoints() *blockedEndpoints {
-	backoffFactor := config.Datadog.GetFloat64("forwarder_backoff_factor")
-	if backoffFactor < 2 {
-		log.Warnf("Configured forwarder_backoff_factor (%v) is less than 2; 2 will be used", backoffFactor)
-		backoffFactor = 2
-	}
-
-	backoffBase := config.Datadog.GetFloat64("forwarder_backoff_base")
-	if backoffBase <= 0 {
-		log.Warnf("Configured forwarder_backoff_base (%v) is not positive; 2 will be used", backoffBase)
-		backoffBase = 2
-	}
-
-	backoffMax := config.Datadog.GetFloat64("forwarder_backoff_max")
-	if backoffMax <= 0 {
-		log.Warnf("Configured forwarder_backoff_max (%v) is not positive; 64 seconds will be used", backoffMax)
-		backoffMax = 64
-	}
+	return newBlockedEndpointsWithRetryPolicy(buildDomainRetryPolicy("", 0))
+}
 
-	recInterval := config.Datadog.GetInt("forwarder_recovery_interval")
-	if recInterval <= 0 {
-		log.Warnf("Configured forwarder_recovery_interval (%v) is not positive; %v will be used", recInterval, config.DefaultForwarderRecoveryInterval)
-		recInterval = config.DefaultForwarderRecoveryInterval
+func newBlockedEndpointsWithRetryPolicy(policy *domainRetryPolicy) *blockedEndpoints {
+	endpointBackoffPolicies := make(map[string]backoff.Policy, len(policy.endpoints))
+	for name, endpointPolicy := range policy.endpoints {
+		endpointBackoffPolicies[name] = endpointPolicy.backoffPolicy
 	}
 
-	recoveryReset := config.Datadog.GetBool("forwarder_recovery_reset")
-
 	return &blockedEndpoints{
-		errorPerEndpoint: make(map[string]*block),
-		backoffPolicy:    backoff.NewPolicy(backoffFactor, backoffBase, backoffMax, recInterval, recoveryReset),
+		errorPerEndpoint:        make(map[string]*block),
+		backoffPolicy:           policy.backoffPolicy,
+		endpointBackoffPolicies: endpointBackoffPolicies,
 	}
 }
 
 func (e *blockedEndpoints) close(endpoint string) {
+	e.closeWithEndpointName(endpoint, "")
+}
+
+// closeWithEndpointName is like close but uses the backoff policy of the endpoint `endpointName`.
+func (e *blockedEndpoints) closeWithEndpointName(endpoint string, endpointName string) {
 	e.m.Lock()
 	defer e.m.Unlock()
 
@@ -69,13 +57,19 @@ func (e *blockedEndpoints) close(endpoint string) {
 		b = &block{}
 	}
 
-	b.nbError = e.backoffPolicy.IncError(b.nbError)
-	b.until = time.Now().Add(e.getBackoffDuration(b.nbError))
+	backoffPolicy := e.getBackoffPolicy(endpointName)
+	b.nbError = backoffPolicy.IncError(b.nbError)
+	b.until = time.Now().Add(backoffPolicy.GetBackoffDuration(b.nbError))
 
 	e.errorPerEndpoint[endpoint] = b
 }
 
 func (e *blockedEndpoints) recover(endpoint string) {
+	e.recoverWithEndpointName(endpoint, "")
+}
+
+// recoverWithEndpointName is like recover but uses the backoff policy of the endpoint `endpointName`.
+func (e *blockedEndpoints) recoverWithEndpointName(endpoint string, endpointName string) {
 	e.m.Lock()
 	defer e.m.Unlock()
 
@@ -86,8 +80,9 @@ func (e *blockedEndpoints) recover(endpoint string) {
 		b = &block{}
 	}
 
-	b.nbError = e.backoffPolicy.DecError(b.nbError)
-	b.until = time.Now().Add(e.getBackoffDuration(b.nbError))
+	backoffPolicy := e.getBackoffPolicy(endpointName)
+	b.nbError = backoffPolicy.DecError(b.nbError)
+	b.until = time.Now().Add(backoffPolicy.GetBackoffDuration(b.nbError))
 
 	e.errorPerEndpoint[endpoint] = b
 }
@@ -105,3 +100,10 @@ func (e *blockedEndpoints) isBlock(endpoint string) bool {
 func (e *blockedEndpoints) getBackoffDuration(numErrors int) time.Duration {
 	return e.backoffPolicy.GetBackoffDuration(numErrors)
 }
+
+func (e *blockedEndpoints) getBackoffPolicy(endpointName string) *backoff.Policy {
+	if policy, ok := e.endpointBackoffPolicies[endpointName]; ok {
+		return &policy
+	}
+	return &e.backoffPolicy
+}
@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// agentVersionPrefixRegexp matches the Agent version added to the domains by `config.AddAgentVersionToDomain`.
+var agentVersionPrefixRegexp = regexp.MustCompile(`^(https?://)\d+-\d+-\d+-`)
+
+// ReplayOptions contains the options of `DefaultForwarder.ReplayDeadLetters`.
+type ReplayOptions struct {
+	// DryRun reports the transactions which would be replayed without sending them.
+	DryRun bool
+	// Endpoints restricts the replay to the transactions of these endpoint names. All
+	// the transactions are replayed when it is empty.
+	Endpoints []string
+}
+
+// ReplayReport summarizes a replay of the dead-letter directory.
+type ReplayReport struct {
+	Archives               int
+	SkippedArchives        int
+	Transactions           int
+	Sent                   int
+	Failed                 int
+	TransactionsByEndpoint map[string]int
+}
+
+// newDeadLetterQueue creates the dead-letter directory from the configuration. It returns nil
+// when `forwarder_dead_letter_path` is not set. The archives are encrypted with
+// `forwarder_storage_encryption_key`, whether the retry queue is stored on disk or not.
+func newDeadLetterQueue() *retry.DeadLetterQueue {
+	deadLetterPath := config.Datadog.GetString("forwarder_dead_letter_path")
+	if deadLetterPath == "" {
+		return nil
+	}
+
+	var optionalEncryption *retry.FileEncryption
+	if encryptionKey := config.Datadog.GetString("forwarder_storage_encryption_key"); encryptionKey != "" {
+		var err error
+		if optionalEncryption, err = retry.NewFileEncryption(encryptionKey); err != nil {
+			// Do not fall back to unencrypted archives when the user asked for encryption.
+			log.Errorf("Dropped transactions will not be archived. Invalid `forwarder_storage_encryption_key`: %v", err)
+			return nil
+		}
+	}
+
+	maxSize := config.Datadog.GetInt64("forwarder_dead_letter_max_size_in_bytes")
+	queue, err := retry.NewDeadLetterQueue(deadLetterPath, maxSize, optionalEncryption)
+	if err != nil {
+		log.Errorf("Dropped transactions will not be archived. Cannot initialize the dead-letter directory %s: %v", deadLetterPath, err)
+		return nil
+	}
+	log.Infof("Dropped transactions are archived in the dead-letter directory %s", deadLetterPath)
+	return queue
+}
+
+func getVersionedDomains(domainResolvers map[string]resolver.DomainResolver) []string {
+	domains := make([]string, 0, len(domainResolvers))
+	for configDomain := range domainResolvers {
+		domain, _ := config.AddAgentVersionToDomain(configDomain, "app")
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
+// ReplayDeadLetters sends again the transactions archived in the dead-letter directory `path`.
+// The transactions successfully sent are removed from the archives, the other ones are kept
+// for a later replay. The transactions are sent synchronously, bypassing the workers and the
+// retry queue of the forwarder, which does not need to be started.
+func (f *DefaultForwarder) ReplayDeadLetters(path string, options ReplayOptions) (ReplayReport, error) {
+	report := ReplayReport{TransactionsByEndpoint: make(map[string]int)}
+
+	archives, err := retry.ReadDeadLetterArchives(path)
+	if err != nil {
+		return report, err
+	}
+
+	var optionalEncryption *retry.FileEncryption
+	if encryptionKey := config.Datadog.GetString("forwarder_storage_encryption_key"); encryptionKey != "" {
+		if optionalEncryption, err = retry.NewFileEncryption(encryptionKey); err != nil {
+			return report, fmt.Errorf("invalid `forwarder_storage_encryption_key`: %v", err)
+		}
+	}
+
+	client := NewHTTPClient()
+	endpoints := make(map[string]struct{}, len(options.Endpoints))
+	for _, endpoint := range options.Endpoints {
+		endpoints[endpoint] = struct{}{}
+	}
+
+	for _, archive := range archives {
+		report.Archives++
+		domainResolver := f.getDeadLetterResolver(archive.Metadata.Domain)
+		if domainResolver == nil {
+			log.Warnf("Skipping the dead-letter archive %s: the domain %s is not configured", archive.Path, archive.Metadata.Domain)
+			report.SkippedArchives++
+			continue
+		}
+
+		transactions, err := archive.ReadTransactions(domainResolver, optionalEncryption)
+		if err != nil {
+			log.Errorf("Skipping the dead-letter archive %s: %v", archive.Path, err)
+			report.SkippedArchives++
+			continue
+		}
+
+		var toReplay []*transaction.HTTPTransaction
+		var toKeep []*transaction.HTTPTransaction
+		for _, t := range transactions {
+			if _, found := endpoints[t.GetEndpointName()]; len(endpoints) > 0 && !found {
+				toKeep = append(toKeep, t)
+				continue
+			}
+			toReplay = append(toReplay, t)
+			report.Transactions++
+			report.TransactionsByEndpoint[t.GetEndpointName()]++
+		}
+
+		if options.DryRun || len(toReplay) == 0 {
+			continue
+		}
+
+		failed := replayTransactions(client, toReplay)
+		report.Sent += len(toReplay) - len(failed)
+		report.Failed += len(failed)
+
+		if err := archive.Rewrite(append(toKeep, failed...), domainResolver, optionalEncryption); err != nil {
+			log.Errorf("Cannot update the dead-letter archive %s, its transactions may be replayed again: %v", archive.Path, err)
+		}
+	}
+	return report, nil
+}
+
+// replayTransactions sends `transactions` once, waiting for each of them to complete, and returns
+// the transactions which were not sent successfully.
+func replayTransactions(client *http.Client, transactions []*transaction.HTTPTransaction) []*transaction.HTTPTransaction {
+	var failed []*transaction.HTTPTransaction
+	for _, t := range transactions {
+		sent := false
+		// The failed transactions stay in the dead-letter directory instead of the retry queue.
+		t.Retryable = false
+		t.CompletionHandler = func(t *transaction.HTTPTransaction, statusCode int, body []byte, err error) {
+			sent = err == nil && statusCode > 0 && statusCode < 400
+		}
+		// The transaction is not retryable, so `Process` always calls its completion handler.
+		_ = t.Process(context.Background(), client)
+		if !sent {
+			failed = append(failed, t)
+		}
+	}
+	return failed
+}
+
+func (f *DefaultForwarder) getDeadLetterResolver(domain string) resolver.DomainResolver {
+	if domainResolver, found := f.domainResolvers[domain]; found {
+		return domainResolver
+	}
+	// The archive may have been created by another version of the Agent.
+	unversionedDomain := agentVersionPrefixRegexp.ReplaceAllString(domain, "$1")
+	for d, domainResolver := range f.domainResolvers {
+		if agentVersionPrefixRegexp.ReplaceAllString(d, "$1") == unversionedDomain {
+			return domainResolver
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/endpoints"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+)
+
+func TestReplayDeadLetters(t *testing.T) {
+	var mutex sync.Mutex
+	requests := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests[r.URL.Path]++
+		mutex.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	config.Mock(t)
+
+	deadLetterPath := t.TempDir()
+	f := NewDefaultForwarder(NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{
+		ts.URL: {"api_key1"},
+	})))
+	archiveTestTransactions(t, f, deadLetterPath, endpoints.SeriesEndpoint, endpoints.SketchSeriesEndpoint)
+
+	// Dry run
+	report, err := f.ReplayDeadLetters(deadLetterPath, ReplayOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Archives)
+	assert.Equal(t, 2, report.Transactions)
+	assert.Equal(t, map[string]int{"series_v2": 1, "sketches_v2": 1}, report.TransactionsByEndpoint)
+	assert.Equal(t, 0, report.Sent)
+
+	// Only replay the series
+	report, err = f.ReplayDeadLetters(deadLetterPath, ReplayOptions{Endpoints: []string{"series_v2"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Transactions)
+	assert.Equal(t, 1, report.Sent)
+	assert.Equal(t, 0, report.Failed)
+	mutex.Lock()
+	assert.Equal(t, 1, requests["/api/v2/series"])
+	assert.Equal(t, 0, requests["/api/beta/sketches"])
+	mutex.Unlock()
+
+	archives, err := retry.ReadDeadLetterArchives(deadLetterPath)
+	require.NoError(t, err)
+	require.Len(t, archives, 1)
+	assert.Equal(t, []string{"sketches_v2"}, archives[0].Metadata.Endpoints)
+
+	// Replay the remaining transactions
+	report, err = f.ReplayDeadLetters(deadLetterPath, ReplayOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Sent)
+	archives, err = retry.ReadDeadLetterArchives(deadLetterPath)
+	require.NoError(t, err)
+	assert.Len(t, archives, 0)
+}
+
+func TestReplayDeadLettersUnknownDomain(t *testing.T) {
+	config.Mock(t)
+	deadLetterPath := t.TempDir()
+	f := NewDefaultForwarder(NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{
+		"https://app.datadoghq.eu": {"api_key1"},
+	})))
+	archiveTestTransactions(t, f, deadLetterPath, endpoints.SeriesEndpoint)
+
+	other := NewDefaultForwarder(NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{
+		"https://app.datadoghq.com": {"api_key1"},
+	})))
+	report, err := other.ReplayDeadLetters(deadLetterPath, ReplayOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Archives)
+	assert.Equal(t, 1, report.SkippedArchives)
+	assert.Equal(t, 0, report.Transactions)
+}
+
+func TestGetDeadLetterResolverOtherAgentVersion(t *testing.T) {
+	config.Mock(t)
+	f := NewDefaultForwarder(NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{
+		"https://app.datadoghq.com": {"api_key1"},
+	})))
+	assert.NotNil(t, f.getDeadLetterResolver("https://7-12-0-app.agent.datadoghq.com"))
+	assert.Nil(t, f.getDeadLetterResolver("https://7-12-0-app.agent.datadoghq.eu"))
+}
+
+// archiveTestTransactions archives in `deadLetterPath` a transaction for each endpoint, like the retry queue does.
+func archiveTestTransactions(t *testing.T, f *DefaultForwarder, deadLetterPath string, endpoints ...transaction.Endpoint) {
+	queue, err := retry.NewDeadLetterQueue(deadLetterPath, 0, nil)
+	require.NoError(t, err)
+
+	var transactions []transaction.Transaction
+	for _, endpoint := range endpoints {
+		data := []byte("data payload")
+		for _, t := range f.createHTTPTransactions(endpoint, Payloads{&data}, false, http.Header{}) {
+			transactions = append(transactions, t)
+		}
+	}
+	require.Len(t, f.domainResolvers, 1)
+	for _, domainResolver := range f.domainResolvers {
+		sorter := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
+		retry.BuildTransactionRetryQueue(0, 0, "", nil, nil, queue, sorter, domainResolver).
+			ArchiveDroppedTransactions(transactions, retry.DeadLetterReasonRetryQueueFull)
+	}
+}
+
+func TestReplayDeadLettersKeepsFailedTransactions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+	config.Mock(t)
+
+	deadLetterPath := t.TempDir()
+	f := NewDefaultForwarder(NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{
+		ts.URL: {"api_key1"},
+	})))
+	archiveTestTransactions(t, f, deadLetterPath, endpoints.SeriesEndpoint)
+
+	report, err := f.ReplayDeadLetters(deadLetterPath, ReplayOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Sent)
+	assert.Equal(t, 1, report.Failed)
+	archives, err := retry.ReadDeadLetterArchives(deadLetterPath)
+	require.NoError(t, err)
+	require.Len(t, archives, 1)
+	assert.Equal(t, 1, archives[0].Metadata.TransactionCount)
+}
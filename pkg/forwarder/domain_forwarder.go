@@ -40,6 +40,7 @@ type domainForwarder struct {
 	m                         sync.Mutex // To control Start/Stop races
 	transactionPrioritySorter retry.TransactionPrioritySorter
 	blockedList               *blockedEndpoints
+	retryPolicy               *domainRetryPolicy
 }
 
 func newDomainForwarder(
@@ -47,7 +48,8 @@ func newDomainForwarder(
 	retryQueue *retry.TransactionRetryQueue,
 	numberOfWorkers int,
 	connectionResetInterval time.Duration,
-	transactionPrioritySorter retry.TransactionPrioritySorter) *domainForwarder {
+	transactionPrioritySorter retry.TransactionPrioritySorter,
+	retryPolicy *domainRetryPolicy) *domainForwarder {
 	return &domainForwarder{
 		isRetrying:                atomic.NewBool(false),
 		domain:                    domain,
@@ -55,8 +57,9 @@ func newDomainForwarder(
 		retryQueue:                retryQueue,
 		connectionResetInterval:   connectionResetInterval,
 		internalState:             Stopped,
-		blockedList:               newBlockedEndpoints(),
+		blockedList:               newBlockedEndpointsWithRetryPolicy(retryPolicy),
 		transactionPrioritySorter: transactionPrioritySorter,
+		retryPolicy:               retryPolicy,
 	}
 }
 
@@ -71,6 +74,7 @@ func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 
 	droppedRetryQueueFull := 0
 	droppedWorkerBusy := 0
+	var expiredTransactions []transaction.Transaction
 
 	var transactions []transaction.Transaction
 	var err error
@@ -84,7 +88,14 @@ func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 
 	for _, t := range transactions {
 		transactionEndpointName := t.GetEndpointName()
-		if !f.blockedList.isBlock(t.GetTarget()) {
+		if f.isExpired(t, retryBefore) {
+			transactionsExpired.Add(1)
+			tlmTxExpired.Inc(f.domain, transactionEndpointName)
+			transaction.TransactionsDroppedByEndpoint.Add(transactionEndpointName, 1)
+			transaction.TransactionsDropped.Add(1)
+			transaction.TlmTxDropped.Inc(f.domain, transactionEndpointName)
+			expiredTransactions = append(expiredTransactions, t)
+		} else if !f.blockedList.isBlock(t.GetTarget()) {
 			select {
 			case f.lowPrio <- t:
 				transactionsRetriedByEndpoint.Add(transactionEndpointName, 1)
@@ -107,12 +118,23 @@ func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 	transactionsRetryQueueSize.Set(int64(transactionCount))
 	tlmTxRetryQueueSize.Set(float64(transactionCount), f.domain)
 
+	if len(expiredTransactions) > 0 {
+		log.Warnf("Dropped %d transactions in this retry attempt for exceeding the maximum retry age of their endpoint", len(expiredTransactions))
+		f.retryQueue.ArchiveDroppedTransactions(expiredTransactions, retry.DeadLetterReasonMaxRetryAgeExceeded)
+	}
+
 	if droppedRetryQueueFull+droppedWorkerBusy > 0 {
 		log.Errorf("Dropped %d transactions in this retry attempt:%d for exceeding the retry queue payloads size limit of %d, %d because the workers are too busy",
 			droppedRetryQueueFull+droppedWorkerBusy, droppedRetryQueueFull, f.retryQueue.GetMaxMemSizeInBytes(), droppedWorkerBusy)
 	}
 }
 
+// isExpired returns true if the transaction is older than the maximum retry age of its endpoint.
+func (f *domainForwarder) isExpired(t transaction.Transaction, now time.Time) bool {
+	maxRetryAge := f.retryPolicy.forEndpoint(t.GetEndpointName()).maxRetryAge
+	return maxRetryAge > 0 && now.Sub(t.GetCreatedAt()) > maxRetryAge
+}
+
 func (f *domainForwarder) addToTransactionRetryQueue(t transaction.Transaction) int {
 	dropCount, err := f.retryQueue.Add(t)
 	if err != nil {
@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package endpoints
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// CompressionKind returns the compression kind configured for the payloads sent to the given endpoint:
+// the `serializer_compressor_kind_per_endpoint` entry for the endpoint name if any, `serializer_compressor_kind` otherwise.
+func CompressionKind(endpoint transaction.Endpoint) string {
+	if kind, found := config.Datadog.GetStringMapString("serializer_compressor_kind_per_endpoint")[endpoint.Name]; found {
+		return kind
+	}
+	return config.Datadog.GetString("serializer_compressor_kind")
+}
+
+// CompressorFor returns the compression strategy to use for the payloads sent to the given endpoint.
+// It falls back to the compression the agent was built with when the configured kind is invalid.
+func CompressorFor(endpoint transaction.Endpoint) compression.Compressor {
+	kind := CompressionKind(endpoint)
+	c, err := compression.NewCompressor(kind, config.Datadog.GetInt("serializer_compressor_level"))
+	if err != nil {
+		log.Errorf("Invalid compression for endpoint %s, using %s instead: %s", endpoint.Name, compression.DefaultKind, err)
+		return compression.DefaultCompressor()
+	}
+	return c
+}
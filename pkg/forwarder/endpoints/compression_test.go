@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package endpoints
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+func TestCompressorFor(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("serializer_compressor_kind", "gzip")
+	mockConfig.Set("serializer_compressor_kind_per_endpoint", map[string]string{
+		SeriesEndpoint.Name:       "zstd",
+		SketchSeriesEndpoint.Name: "lz4",
+	})
+
+	assert.Equal(t, "zstd", CompressorFor(SeriesEndpoint).ContentEncoding())
+	assert.Equal(t, "gzip", CompressorFor(V1SeriesEndpoint).ContentEncoding())
+	// invalid kinds fall back to the build default
+	assert.Equal(t, compression.DefaultCompressor().ContentEncoding(), CompressorFor(SketchSeriesEndpoint).ContentEncoding())
+}
@@ -0,0 +1,283 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/agent-payload/v5/gogen"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/endpoints"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const filePayloadExtension = ".json"
+
+// FilePayload is a payload written by the FileForwarder.
+type FilePayload struct {
+	Endpoint  string    `json:"endpoint"`
+	Timestamp time.Time `json:"timestamp"`
+	// Payload is the decompressed payload, as JSON. The protobuf payloads are decoded.
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// RawPayload is the decompressed payload when it can't be decoded
+	RawPayload []byte `json:"raw_payload,omitempty"`
+	// Path is the file of the payload, it is set by ReadFilePayloads
+	Path string `json:"-"`
+}
+
+// FileForwarder is a Forwarder writing the payloads to files instead of sending
+// them, one file per payload. Only the last maxFiles files are kept in the directory.
+type FileForwarder struct {
+	path     string
+	maxFiles int
+
+	mu    sync.Mutex
+	files []string
+	// lastTimestamp is the timestamp in the name of the last file written
+	lastTimestamp int64
+}
+
+// Compile-time check to ensure that FileForwarder implements the Forwarder interface
+var _ Forwarder = &FileForwarder{}
+
+// NewFileForwarder returns a new FileForwarder writing the payloads in the directory path.
+func NewFileForwarder(path string, maxFiles int) *FileForwarder {
+	return &FileForwarder{
+		path:     path,
+		maxFiles: maxFiles,
+	}
+}
+
+// Start creates the directory and lists the files written by a previous FileForwarder.
+func (f *FileForwarder) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(f.path, 0700); err != nil {
+		return err
+	}
+	files, err := listFilePayloads(f.path)
+	if err != nil {
+		return err
+	}
+	f.files = files
+	log.Infof("The payloads are written to the directory %s instead of being sent", f.path)
+	return nil
+}
+
+// Stop does nothing.
+func (f *FileForwarder) Stop() {}
+
+func (f *FileForwarder) writePayloads(endpoint transaction.Endpoint, payloads Payloads, extra http.Header) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, payload := range payloads {
+		if err := f.writePayload(endpoint, *payload, extra); err != nil {
+			log.Errorf("Can't write a payload of the endpoint %s to %s: %s", endpoint.Name, f.path, err)
+			return err
+		}
+	}
+	f.rotate()
+	return nil
+}
+
+func (f *FileForwarder) writePayload(endpoint transaction.Endpoint, payload []byte, extra http.Header) error {
+	now := time.Now()
+	filePayload := FilePayload{
+		Endpoint:  endpoint.Name,
+		Timestamp: now,
+	}
+
+	decompressor, err := compression.NewCompressorForContentEncoding(extra.Get("Content-Encoding"))
+	if err != nil {
+		return err
+	}
+	payload, err = decompressor.Decompress(payload)
+	if err != nil {
+		return err
+	}
+	if decoded, err := decodePayload(endpoint, payload); err != nil {
+		log.Debugf("Can't decode a payload of the endpoint %s, writing it raw: %s", endpoint.Name, err)
+		filePayload.RawPayload = payload
+	} else {
+		filePayload.Payload = decoded
+	}
+
+	data, err := json.MarshalIndent(filePayload, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// the names start with the timestamp, they must be unique and ordered even if the clock doesn't move
+	timestamp := now.UnixNano()
+	if timestamp <= f.lastTimestamp {
+		timestamp = f.lastTimestamp + 1
+	}
+	f.lastTimestamp = timestamp
+	name := fmt.Sprintf("%020d-%s%s", timestamp, endpoint.Name, filePayloadExtension)
+	if err := os.WriteFile(filepath.Join(f.path, name), data, 0600); err != nil {
+		return err
+	}
+	f.files = append(f.files, name)
+	return nil
+}
+
+// rotate removes the oldest files when there are more than maxFiles files.
+func (f *FileForwarder) rotate() {
+	if f.maxFiles <= 0 || len(f.files) <= f.maxFiles {
+		return
+	}
+	removed := len(f.files) - f.maxFiles
+	for _, name := range f.files[:removed] {
+		if err := os.Remove(filepath.Join(f.path, name)); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Can't remove the payload file %s: %s", name, err)
+		}
+	}
+	f.files = append(f.files[:0], f.files[removed:]...)
+}
+
+// decodePayload returns the decompressed payload as JSON.
+func decodePayload(endpoint transaction.Endpoint, payload []byte) (json.RawMessage, error) {
+	var message interface{ Unmarshal([]byte) error }
+	switch endpoint.Name {
+	case endpoints.SeriesEndpoint.Name:
+		message = &gogen.MetricPayload{}
+	case endpoints.SketchSeriesEndpoint.Name:
+		message = &gogen.SketchPayload{}
+	default:
+		if !json.Valid(payload) {
+			return nil, fmt.Errorf("not a JSON payload")
+		}
+		return payload, nil
+	}
+
+	if err := message.Unmarshal(payload); err != nil {
+		return nil, err
+	}
+	return json.Marshal(message)
+}
+
+// listFilePayloads returns the names of the payload files of the directory, from the oldest to the newest.
+func listFilePayloads(path string) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), filePayloadExtension) {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (f *FileForwarder) submitProcessLikePayload(endpoint transaction.Endpoint, payloads Payloads, extra http.Header) (chan Response, error) {
+	if err := f.writePayloads(endpoint, payloads, extra); err != nil {
+		return nil, err
+	}
+	// no response is expected from the files
+	results := make(chan Response)
+	close(results)
+	return results, nil
+}
+
+// SubmitV1Series writes the payloads to files.
+func (f *FileForwarder) SubmitV1Series(payload Payloads, extra http.Header) error {
+	return f.writePayloads(endpoints.V1SeriesEndpoint, payload, extra)
+}
+
+// SubmitV1Intake writes the payloads to files.
+func (f *FileForwarder) SubmitV1Intake(payload Payloads, extra http.Header) error {
+	return f.writePayloads(endpoints.V1IntakeEndpoint, payload, extra)
+}
+
+// SubmitV1CheckRuns writes the payloads to files.
+func (f *FileForwarder) SubmitV1CheckRuns(payload Payloads, extra http.Header) error {
+	return f.writePayloads(endpoints.V1CheckRunsEndpoint, payload, extra)
+}
+
+// SubmitSeries writes the payloads to files.
+func (f *FileForwarder) SubmitSeries(payload Payloads, extra http.Header) error {
+	return f.writePayloads(endpoints.SeriesEndpoint, payload, extra)
+}
+
+// SubmitSketchSeries writes the payloads to files.
+func (f *FileForwarder) SubmitSketchSeries(payload Payloads, extra http.Header) error {
+	return f.writePayloads(endpoints.SketchSeriesEndpoint, payload, extra)
+}
+
+// SubmitHostMetadata writes the payloads to files.
+func (f *FileForwarder) SubmitHostMetadata(payload Payloads, extra http.Header) error {
+	return f.writePayloads(endpoints.V1IntakeEndpoint, payload, extra)
+}
+
+// SubmitAgentChecksMetadata writes the payloads to files.
+func (f *FileForwarder) SubmitAgentChecksMetadata(payload Payloads, extra http.Header) error {
+	return f.writePayloads(endpoints.V1IntakeEndpoint, payload, extra)
+}
+
+// SubmitMetadata writes the payloads to files.
+func (f *FileForwarder) SubmitMetadata(payload Payloads, extra http.Header) error {
+	return f.writePayloads(endpoints.V1MetadataEndpoint, payload, extra)
+}
+
+// SubmitProcessChecks writes the payloads to files.
+func (f *FileForwarder) SubmitProcessChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ProcessesEndpoint, payload, extra)
+}
+
+// SubmitProcessDiscoveryChecks writes the payloads to files.
+func (f *FileForwarder) SubmitProcessDiscoveryChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ProcessDiscoveryEndpoint, payload, extra)
+}
+
+// SubmitProcessEventChecks writes the payloads to files.
+func (f *FileForwarder) SubmitProcessEventChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ProcessLifecycleEndpoint, payload, extra)
+}
+
+// SubmitRTProcessChecks writes the payloads to files.
+func (f *FileForwarder) SubmitRTProcessChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.RtProcessesEndpoint, payload, extra)
+}
+
+// SubmitContainerChecks writes the payloads to files.
+func (f *FileForwarder) SubmitContainerChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ContainerEndpoint, payload, extra)
+}
+
+// SubmitRTContainerChecks writes the payloads to files.
+func (f *FileForwarder) SubmitRTContainerChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.RtContainerEndpoint, payload, extra)
+}
+
+// SubmitConnectionChecks writes the payloads to files.
+func (f *FileForwarder) SubmitConnectionChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ConnectionsEndpoint, payload, extra)
+}
+
+// SubmitOrchestratorChecks writes the payloads to files.
+func (f *FileForwarder) SubmitOrchestratorChecks(payload Payloads, extra http.Header, payloadType int) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.OrchestratorEndpoint, payload, extra)
+}
+
+// SubmitContainerLifecycleEvents writes the payloads to files.
+func (f *FileForwarder) SubmitContainerLifecycleEvents(payload Payloads, extra http.Header) error {
+	return f.writePayloads(endpoints.ContainerLifecycleEndpoint, payload, extra)
+}
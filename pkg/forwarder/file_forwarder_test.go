@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/agent-payload/v5/gogen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+func compressedPayloads(t *testing.T, encoding string, payloads ...[]byte) (Payloads, http.Header) {
+	compressor, err := compression.NewCompressorForContentEncoding(encoding)
+	require.NoError(t, err)
+
+	result := make(Payloads, 0, len(payloads))
+	for _, payload := range payloads {
+		compressed, err := compressor.Compress(payload)
+		require.NoError(t, err)
+		result = append(result, &compressed)
+	}
+	headers := make(http.Header)
+	if encoding != "" {
+		headers.Set("Content-Encoding", encoding)
+	}
+	return result, headers
+}
+
+func seriesPayload(t *testing.T, series ...*gogen.MetricPayload_MetricSeries) []byte {
+	payload, err := (&gogen.MetricPayload{Series: series}).Marshal()
+	require.NoError(t, err)
+	return payload
+}
+
+func TestFileForwarder(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFileForwarder(dir, 0)
+	require.NoError(t, f.Start())
+
+	series := seriesPayload(t, &gogen.MetricPayload_MetricSeries{
+		Metric: "my.metric",
+		Tags:   []string{"env:prod"},
+		Points: []*gogen.MetricPayload_MetricPoint{{Value: 12, Timestamp: 1656928800}},
+	})
+	payloads, headers := compressedPayloads(t, "deflate", series)
+	require.NoError(t, f.SubmitSeries(payloads, headers))
+	payloads, headers = compressedPayloads(t, "gzip", []byte(`[{"check":"my.check","status":0}]`))
+	require.NoError(t, f.SubmitV1CheckRuns(payloads, headers))
+	payloads, headers = compressedPayloads(t, "", []byte{0xff, 0x01})
+	responses, err := f.SubmitProcessChecks(payloads, headers)
+	require.NoError(t, err)
+	_, open := <-responses
+	assert.False(t, open)
+
+	written, err := ReadFilePayloads(dir)
+	require.NoError(t, err)
+	require.Len(t, written, 3)
+
+	assert.Equal(t, "series_v2", written[0].Endpoint)
+	assert.JSONEq(t, `{"series":[{"metric":"my.metric","tags":["env:prod"],"points":[{"value":12,"timestamp":1656928800}]}]}`, string(written[0].Payload))
+	assert.Equal(t, "check_run_v1", written[1].Endpoint)
+	assert.JSONEq(t, `[{"check":"my.check","status":0}]`, string(written[1].Payload))
+	assert.Equal(t, "process", written[2].Endpoint)
+	assert.Empty(t, written[2].Payload)
+	assert.Equal(t, []byte{0xff, 0x01}, written[2].RawPayload)
+
+	// a single file can be read
+	single, err := ReadFilePayloads(written[1].Path)
+	require.NoError(t, err)
+	require.Len(t, single, 1)
+	assert.Equal(t, written[1].Payload, single[0].Payload)
+}
+
+func TestFileForwarderRotation(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFileForwarder(dir, 3)
+	require.NoError(t, f.Start())
+
+	for _, payload := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`} {
+		payloads, headers := compressedPayloads(t, "", []byte(payload))
+		require.NoError(t, f.SubmitV1Intake(payloads, headers))
+	}
+
+	// the files of a previous forwarder are rotated too
+	f = NewFileForwarder(dir, 3)
+	require.NoError(t, f.Start())
+	payloads, headers := compressedPayloads(t, "", []byte(`{"n":5}`))
+	require.NoError(t, f.SubmitV1Intake(payloads, headers))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+	written, err := ReadFilePayloads(dir)
+	require.NoError(t, err)
+	require.Len(t, written, 3)
+	for i, expected := range []string{`{"n":3}`, `{"n":4}`, `{"n":5}`} {
+		assert.JSONEq(t, expected, string(written[i].Payload))
+	}
+}
+
+func TestDiffFilePayloads(t *testing.T) {
+	expected := []FilePayload{
+		{Endpoint: "series_v2", Payload: []byte(`{"series":[{"metric":"a","points":[{"value":1,"timestamp":10}]},{"metric":"b","points":[{"value":2,"timestamp":10}]}]}`)},
+		{Endpoint: "check_run_v1", Payload: []byte(`[{"check":"my.check","status":0}]`)},
+	}
+	// same series in another order and another payload, with other timestamps
+	actual := []FilePayload{
+		{Endpoint: "check_run_v1", Payload: []byte(`[{"status":0,"check":"my.check"}]`)},
+		{Endpoint: "series_v2", Payload: []byte(`{"series":[{"metric":"b","points":[{"value":2,"timestamp":20}]}]}`)},
+		{Endpoint: "series_v2", Payload: []byte(`{"series":[{"metric":"a","points":[{"value":1,"timestamp":20}]}]}`)},
+	}
+
+	diff, err := DiffFilePayloads(expected, actual, []string{"timestamp"})
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	diff, err = DiffFilePayloads(expected, actual, nil)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "--- expected/series_v2")
+	assert.Contains(t, diff, `-          "timestamp": 10,`)
+	assert.Contains(t, diff, `+          "timestamp": 20,`)
+	assert.NotContains(t, diff, "check_run_v1")
+
+	diff, err = DiffFilePayloads(expected, expected[:1], nil)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "--- expected/check_run_v1")
+}
+
+func TestReadFilePayloadsErrors(t *testing.T) {
+	_, err := ReadFilePayloads(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "invalid.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+	_, err = ReadFilePayloads(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,251 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// ReadFilePayloads reads the payloads written by a FileForwarder. path is either
+// a payload file or a directory, whose payloads are returned from the oldest to the newest.
+func ReadFilePayloads(path string) ([]FilePayload, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{path}
+	if info.IsDir() {
+		names, err := listFilePayloads(path)
+		if err != nil {
+			return nil, err
+		}
+		paths = paths[:0]
+		for _, name := range names {
+			paths = append(paths, filepath.Join(path, name))
+		}
+	}
+
+	payloads := make([]FilePayload, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var payload FilePayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, fmt.Errorf("invalid payload file %s: %s", path, err)
+		}
+		payload.Path = path
+		payloads = append(payloads, payload)
+	}
+	return payloads, nil
+}
+
+// NormalizeFilePayloads returns the payloads of each endpoint as indented JSON,
+// in a form which doesn't depend on the split of the data in several payloads nor
+// on the order of the elements of the arrays, so that it can be compared:
+//   - the array payloads are concatenated,
+//   - the array fields of the object payloads are concatenated, the distinct values
+//     of the other fields are listed in an array when there are more than one.
+//
+// The object fields named like one of ignoredFields (for instance timestamps) are removed.
+func NormalizeFilePayloads(payloads []FilePayload, ignoredFields []string) (map[string]string, error) {
+	ignored := make(map[string]struct{}, len(ignoredFields))
+	for _, field := range ignoredFields {
+		ignored[field] = struct{}{}
+	}
+
+	payloadsByEndpoint := make(map[string][]interface{})
+	for _, payload := range payloads {
+		var value interface{}
+		if len(payload.Payload) > 0 {
+			if err := json.Unmarshal(payload.Payload, &value); err != nil {
+				return nil, fmt.Errorf("invalid payload in %s: %s", payload.Path, err)
+			}
+		} else {
+			value = payload.RawPayload
+		}
+		payloadsByEndpoint[payload.Endpoint] = append(payloadsByEndpoint[payload.Endpoint], value)
+	}
+
+	normalized := make(map[string]string, len(payloadsByEndpoint))
+	for endpoint, values := range payloadsByEndpoint {
+		value, err := normalizeJSON(mergePayloads(values), ignored)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		normalized[endpoint] = string(data) + "\n"
+	}
+	return normalized, nil
+}
+
+// mergePayloads merges the decoded payloads of an endpoint.
+func mergePayloads(values []interface{}) interface{} {
+	var arrays []interface{}
+	arrayPayloads := 0
+	objects := make([]map[string]interface{}, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case []interface{}:
+			arrays = append(arrays, v...)
+			arrayPayloads++
+		case map[string]interface{}:
+			objects = append(objects, v)
+		}
+	}
+
+	switch len(values) {
+	case len(objects):
+		return mergeObjects(objects)
+	case arrayPayloads:
+		return arrays
+	default:
+		return values
+	}
+}
+
+// mergeObjects concatenates the array fields of the objects and lists the distinct
+// values of the other fields.
+func mergeObjects(objects []map[string]interface{}) map[string]interface{} {
+	fields := make(map[string][]interface{})
+	for _, object := range objects {
+		for key, value := range object {
+			fields[key] = append(fields[key], value)
+		}
+	}
+
+	merged := make(map[string]interface{}, len(fields))
+	for key, values := range fields {
+		var concatenated []interface{}
+		distinct := make(map[string]interface{})
+		for _, value := range values {
+			if array, ok := value.([]interface{}); ok {
+				concatenated = append(concatenated, array...)
+				continue
+			}
+			encoded, _ := json.Marshal(value)
+			distinct[string(encoded)] = value
+		}
+
+		switch {
+		case len(distinct) == 0:
+			merged[key] = concatenated
+		case len(distinct) == 1 && concatenated == nil:
+			for _, value := range distinct {
+				merged[key] = value
+			}
+		default:
+			for _, value := range distinct {
+				concatenated = append(concatenated, value)
+			}
+			merged[key] = concatenated
+		}
+	}
+	return merged
+}
+
+// normalizeJSON removes the ignored fields and sorts the arrays of a decoded JSON value.
+func normalizeJSON(value interface{}, ignored map[string]struct{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if _, found := ignored[key]; found {
+				delete(v, key)
+				continue
+			}
+			normalizedField, err := normalizeJSON(field, ignored)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = normalizedField
+		}
+		return v, nil
+	case []interface{}:
+		// the elements are sorted by their JSON encoding, which sorts the object keys
+		keys := make([]string, len(v))
+		for i, element := range v {
+			normalizedElement, err := normalizeJSON(element, ignored)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = normalizedElement
+			key, err := json.Marshal(normalizedElement)
+			if err != nil {
+				return nil, err
+			}
+			keys[i] = string(key)
+		}
+		sort.Sort(byKey{values: v, keys: keys})
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+type byKey struct {
+	values []interface{}
+	keys   []string
+}
+
+func (b byKey) Len() int           { return len(b.values) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// DiffFilePayloads compares the normalized payloads (see NormalizeFilePayloads)
+// of each endpoint and returns a unified diff, which is empty when they are equal.
+func DiffFilePayloads(expected, actual []FilePayload, ignoredFields []string) (string, error) {
+	expectedByEndpoint, err := NormalizeFilePayloads(expected, ignoredFields)
+	if err != nil {
+		return "", err
+	}
+	actualByEndpoint, err := NormalizeFilePayloads(actual, ignoredFields)
+	if err != nil {
+		return "", err
+	}
+
+	endpoints := make(map[string]struct{})
+	for endpoint := range expectedByEndpoint {
+		endpoints[endpoint] = struct{}{}
+	}
+	for endpoint := range actualByEndpoint {
+		endpoints[endpoint] = struct{}{}
+	}
+	sortedEndpoints := make([]string, 0, len(endpoints))
+	for endpoint := range endpoints {
+		sortedEndpoints = append(sortedEndpoints, endpoint)
+	}
+	sort.Strings(sortedEndpoints)
+
+	var result string
+	for _, endpoint := range sortedEndpoints {
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(expectedByEndpoint[endpoint]),
+			B:        difflib.SplitLines(actualByEndpoint[endpoint]),
+			FromFile: "expected/" + endpoint,
+			ToFile:   "actual/" + endpoint,
+			Context:  3,
+		})
+		if err != nil {
+			return "", err
+		}
+		result += diff
+	}
+	return result, nil
+}
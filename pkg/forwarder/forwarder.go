@@ -6,6 +6,7 @@
 package forwarder
 
 import (
+	"expvar"
 	"fmt"
 	"net/http"
 	"path"
@@ -229,6 +230,14 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 	var optionalRemovalPolicy *retry.FileRemovalPolicy
 	storageMaxSize := config.Datadog.GetInt64("forwarder_storage_max_size_in_bytes")
 	var diskUsageLimit *retry.DiskUsageLimit
+	var optionalEncryption *retry.FileEncryption
+	var optionalDeadLetterQueue *retry.DeadLetterQueue
+
+	// The dead-letter directory is a core-only feature, like the disk persistence, but it also
+	// archives the transactions dropped from the retry queue in memory.
+	if agentName != "" {
+		optionalDeadLetterQueue = newDeadLetterQueue()
+	}
 
 	// Disk Persistence is a core-only feature for now.
 	if storageMaxSize == 0 {
@@ -245,7 +254,28 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 		optionalRemovalPolicy, err = retry.NewFileRemovalPolicy(storagePath, outdatedFileInDays, retry.FileRemovalPolicyTelemetry{})
 		if err != nil {
 			log.Errorf("Error when initializing the removal policy: %v", err)
-		} else {
+		}
+
+		diskRatio := config.Datadog.GetFloat64("forwarder_storage_max_disk_ratio")
+		diskUsageLimit = retry.NewDiskUsageLimit(storagePath, filesystem.NewDisk(), storageMaxSize, diskRatio)
+
+		if encryptionKey := config.Datadog.GetString("forwarder_storage_encryption_key"); encryptionKey != "" {
+			optionalEncryption, err = retry.NewFileEncryption(encryptionKey)
+			if err != nil {
+				// Do not fall back to unencrypted files when the user asked for encryption.
+				log.Errorf("Retry queue storage on disk is disabled. Invalid `forwarder_storage_encryption_key`: %v", err)
+				optionalRemovalPolicy = nil
+				diskUsageLimit = nil
+			}
+		}
+
+		if optionalRemovalPolicy != nil {
+			if optionalDeadLetterQueue != nil {
+				if err := optionalRemovalPolicy.SetDeadLetterQueue(optionalDeadLetterQueue, getVersionedDomains(options.DomainResolvers)); err != nil {
+					log.Errorf("Outdated files are not moved to the dead-letter directory: %v", err)
+				}
+			}
+
 			filesRemoved, err := optionalRemovalPolicy.RemoveOutdatedFiles()
 			if err != nil {
 				log.Errorf("Error when removing outdated files: %v", err)
@@ -253,20 +283,16 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 			log.Debugf("Outdated files removed: %v", strings.Join(filesRemoved, ", "))
 		}
 
-		diskRatio := config.Datadog.GetFloat64("forwarder_storage_max_disk_ratio")
-		diskUsageLimit = retry.NewDiskUsageLimit(storagePath, filesystem.NewDisk(), storageMaxSize, diskRatio)
-
 	} else {
 		log.Infof("Retry queue storage on disk is disabled because the feature is unavailable for this process.")
 	}
 
-	flushToDiskMemRatio := config.Datadog.GetFloat64("forwarder_flush_to_disk_mem_ratio")
 	domainForwarderSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}
 	transactionContainerSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
 	var queueDiskSpaceUsedList []retry.QueueDiskSpaceUsed
 
-	for domain, resolver := range options.DomainResolvers {
-		domain, _ := config.AddAgentVersionToDomain(domain, "app")
+	for configDomain, resolver := range options.DomainResolvers {
+		domain, _ := config.AddAgentVersionToDomain(configDomain, "app")
 		resolver.SetBaseDomain(domain)
 		if resolver.GetAPIKeys() == nil || len(resolver.GetAPIKeys()) == 0 {
 			log.Errorf("No API keys for domain '%s', dropping domain ", domain)
@@ -280,13 +306,21 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 				}
 			}
 
+			retryPolicy := buildDomainRetryPolicy(configDomain, options.RetryQueuePayloadsTotalMaxSize)
+			retryPoliciesExpvar.Set(configDomain, expvar.Func(func() interface{} { return retryPolicy.status() }))
+
 			transactionContainer := retry.BuildTransactionRetryQueue(
-				options.RetryQueuePayloadsTotalMaxSize,
-				flushToDiskMemRatio,
+				retryPolicy.retryQueuePayloadsMaxSize,
+				retryPolicy.flushToDiskMemRatio,
 				domainFolderPath,
 				diskUsageLimit,
+				optionalEncryption,
+				optionalDeadLetterQueue,
 				transactionContainerSort,
 				resolver)
+			for endpointName, limit := range retryPolicy.endpointQueueLimits {
+				transactionContainer.SetEndpointLimit(endpointName, limit.retryQueuePayloadsMaxSize, limit.flushToDiskMemRatio)
+			}
 			f.domainResolvers[domain] = resolver
 			queueDiskSpaceUsedList = append(queueDiskSpaceUsedList, transactionContainer)
 			fwd := newDomainForwarder(
@@ -294,7 +328,8 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 				transactionContainer,
 				options.NumberOfWorkers,
 				options.ConnectionResetInterval,
-				domainForwarderSort)
+				domainForwarderSort,
+				retryPolicy)
 			f.domainForwarders[domain] = fwd
 			// Register all alternate domains for each forwarder
 			for _, v := range resolver.GetAlternateDomains() {
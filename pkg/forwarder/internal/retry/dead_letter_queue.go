@@ -0,0 +1,408 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const deadLetterMetadataExtension = ".json"
+
+// Reasons for which transactions are archived in the dead-letter directory.
+const (
+	// DeadLetterReasonRetryQueueFull is used when the retry queue in memory is full.
+	DeadLetterReasonRetryQueueFull = "retry_queue_full"
+	// DeadLetterReasonDiskLimitReached is used when a retry file is removed because the disk limit is reached.
+	DeadLetterReasonDiskLimitReached = "disk_limit_reached"
+	// DeadLetterReasonOutdated is used when a retry file is older than `forwarder_outdated_file_in_days`.
+	DeadLetterReasonOutdated = "outdated"
+	// DeadLetterReasonMaxRetryAgeExceeded is used when a transaction exceeds the maximum retry age of its endpoint.
+	DeadLetterReasonMaxRetryAgeExceeded = "max_retry_age_exceeded"
+)
+
+var errDeadLetterQueueFull = errors.New("the maximum size of the dead-letter directory is reached")
+
+// DeadLetterMetadata describes an archive of the dead-letter directory. It is stored
+// next to the archive in a JSON file.
+type DeadLetterMetadata struct {
+	Domain    string    `json:"domain"`
+	Reason    string    `json:"reason"`
+	DroppedAt time.Time `json:"dropped_at"`
+	// TransactionCount and Endpoints are not set when a retry file is archived as a whole.
+	TransactionCount int      `json:"transaction_count,omitempty"`
+	Endpoints        []string `json:"endpoints,omitempty"`
+}
+
+// DeadLetterQueue archives the transactions dropped by the retry queue so that
+// they can be replayed later with `agent forwarder replay`.
+// The archives use the format of the retry files. As the replay removes the archives from
+// another process, the size of the directory is read again from the disk once the maximum
+// size seems reached.
+type DeadLetterQueue struct {
+	path               string
+	maxSizeInBytes     int64
+	currentSizeInBytes int64
+	optionalEncryption *FileEncryption
+	telemetry          deadLetterQueueTelemetry
+	mutex              sync.Mutex
+}
+
+// NewDeadLetterQueue creates a new instance of DeadLetterQueue storing at most
+// `maxSizeInBytes` bytes in `path`.
+func NewDeadLetterQueue(path string, maxSizeInBytes int64, optionalEncryption *FileEncryption) (*DeadLetterQueue, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+
+	q := &DeadLetterQueue{
+		path:               path,
+		maxSizeInBytes:     maxSizeInBytes,
+		optionalEncryption: optionalEncryption,
+		telemetry:          deadLetterQueueTelemetry{},
+	}
+	if err := q.refreshCurrentSize(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// refreshCurrentSize reads the size of the archives from the disk.
+func (q *DeadLetterQueue) refreshCurrentSize() error {
+	archives, err := ReadDeadLetterArchives(q.path)
+	if err != nil {
+		return err
+	}
+	currentSizeInBytes := int64(0)
+	for _, archive := range archives {
+		if size, err := util.GetFileSize(archive.Path); err == nil {
+			currentSizeInBytes += size
+		}
+	}
+	q.currentSizeInBytes = currentSizeInBytes
+	return nil
+}
+
+// archiveFile moves the retry file `filename` to the dead-letter directory.
+func (q *DeadLetterQueue) archiveFile(filename string, domain string, reason string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	size, err := util.GetFileSize(filename)
+	if err != nil {
+		return err
+	}
+	if err := q.makeRoomFor(size, domain, reason); err != nil {
+		return err
+	}
+
+	archivePath, err := q.createArchive(reason)
+	if err != nil {
+		return err
+	}
+	if err := moveFile(filename, archivePath); err != nil {
+		_ = os.Remove(archivePath)
+		return err
+	}
+
+	metadata := DeadLetterMetadata{Domain: domain, Reason: reason, DroppedAt: time.Now().UTC()}
+	if err := writeDeadLetterMetadata(archivePath, metadata); err != nil {
+		log.Errorf("Cannot write the metadata of the dead-letter archive %s: %v", archivePath, err)
+	}
+	q.currentSizeInBytes += size
+	q.telemetry.addArchivedFilesCount(domain, reason)
+	return nil
+}
+
+// archiveContent writes `content`, the serialized transactions, to the dead-letter directory.
+func (q *DeadLetterQueue) archiveContent(content []byte, metadata DeadLetterMetadata) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.optionalEncryption != nil {
+		var err error
+		if content, err = q.optionalEncryption.encrypt(content); err != nil {
+			return err
+		}
+	}
+
+	size := int64(len(content))
+	if err := q.makeRoomFor(size, metadata.Domain, metadata.Reason); err != nil {
+		return err
+	}
+
+	archivePath, err := q.createArchive(metadata.Reason)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(archivePath, content, 0600); err != nil {
+		_ = os.Remove(archivePath)
+		return err
+	}
+	if err := writeDeadLetterMetadata(archivePath, metadata); err != nil {
+		log.Errorf("Cannot write the metadata of the dead-letter archive %s: %v", archivePath, err)
+	}
+	q.currentSizeInBytes += size
+	q.telemetry.addArchivedFilesCount(metadata.Domain, metadata.Reason)
+	q.telemetry.addArchivedTransactionsCount(metadata.TransactionCount, metadata.Domain, metadata.Reason)
+	return nil
+}
+
+func (q *DeadLetterQueue) makeRoomFor(size int64, domain string, reason string) error {
+	if q.maxSizeInBytes <= 0 || q.currentSizeInBytes+size <= q.maxSizeInBytes {
+		return nil
+	}
+	// Some archives may have been replayed since the size was computed.
+	if err := q.refreshCurrentSize(); err != nil {
+		log.Errorf("Cannot read the size of the dead-letter directory %s: %v", q.path, err)
+	}
+	if q.currentSizeInBytes+size > q.maxSizeInBytes {
+		q.telemetry.addDroppedFilesCount(domain, reason)
+		return errDeadLetterQueueFull
+	}
+	return nil
+}
+
+func (q *DeadLetterQueue) createArchive(reason string) (string, error) {
+	prefix := time.Now().UTC().Format(retryFileFormat) + reason + "_"
+	file, err := ioutil.TempFile(q.path, prefix+"*"+retryTransactionsExtension)
+	if err != nil {
+		return "", err
+	}
+	return file.Name(), file.Close()
+}
+
+// deadLetterArchiver archives the transactions of a domain in a DeadLetterQueue.
+type deadLetterArchiver struct {
+	queue      *DeadLetterQueue
+	serializer *HTTPTransactionsSerializer
+	domain     string
+	mutex      sync.Mutex
+}
+
+func newDeadLetterArchiver(queue *DeadLetterQueue, resolver resolver.DomainResolver) *deadLetterArchiver {
+	return &deadLetterArchiver{
+		queue:      queue,
+		serializer: NewHTTPTransactionsSerializer(resolver),
+		domain:     resolver.GetBaseDomain(),
+	}
+}
+
+func (a *deadLetterArchiver) archiveTransactions(transactions []transaction.Transaction, reason string) {
+	if len(transactions) == 0 {
+		return
+	}
+	if err := a.serializeAndArchive(transactions, reason); err != nil {
+		log.Errorf("Cannot archive %d dropped transactions in the dead-letter directory: %v", len(transactions), err)
+	}
+}
+
+func (a *deadLetterArchiver) serializeAndArchive(transactions []transaction.Transaction, reason string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Reset the serializer in case some transactions were serialized
+	// but `GetBytesAndReset` was not called because of an error.
+	_, _ = a.serializer.GetBytesAndReset()
+
+	endpoints := make(map[string]struct{})
+	for _, t := range transactions {
+		if err := t.SerializeTo(a.serializer); err != nil {
+			return err
+		}
+		endpoints[t.GetEndpointName()] = struct{}{}
+	}
+	content, err := a.serializer.GetBytesAndReset()
+	if err != nil {
+		return err
+	}
+
+	metadata := DeadLetterMetadata{
+		Domain:           a.domain,
+		Reason:           reason,
+		DroppedAt:        time.Now().UTC(),
+		TransactionCount: len(transactions),
+	}
+	for endpoint := range endpoints {
+		metadata.Endpoints = append(metadata.Endpoints, endpoint)
+	}
+	sort.Strings(metadata.Endpoints)
+	return a.queue.archiveContent(content, metadata)
+}
+
+func (a *deadLetterArchiver) archiveFile(filename string, reason string) error {
+	return a.queue.archiveFile(filename, a.domain, reason)
+}
+
+// DeadLetterArchive is an archive of the dead-letter directory.
+type DeadLetterArchive struct {
+	Path     string
+	Metadata DeadLetterMetadata
+}
+
+// ReadDeadLetterArchives returns the archives of the dead-letter directory `path`
+// sorted from the oldest to the newest.
+func ReadDeadLetterArchives(path string) ([]DeadLetterArchive, error) {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []DeadLetterArchive
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || filepath.Ext(entry.Name()) != retryTransactionsExtension {
+			continue
+		}
+		archivePath := filepath.Join(path, entry.Name())
+		content, err := ioutil.ReadFile(getDeadLetterMetadataPath(archivePath))
+		if err != nil {
+			log.Warnf("Ignoring the dead-letter archive %s as its metadata cannot be read: %v", archivePath, err)
+			continue
+		}
+		var metadata DeadLetterMetadata
+		if err := json.Unmarshal(content, &metadata); err != nil {
+			log.Warnf("Ignoring the dead-letter archive %s as its metadata is invalid: %v", archivePath, err)
+			continue
+		}
+		archives = append(archives, DeadLetterArchive{Path: archivePath, Metadata: metadata})
+	}
+
+	sort.SliceStable(archives, func(i, j int) bool {
+		return archives[i].Metadata.DroppedAt.Before(archives[j].Metadata.DroppedAt)
+	})
+	return archives, nil
+}
+
+// ReadTransactions reads the transactions of the archive. `resolver` must be a resolver for the domain of the archive.
+func (a DeadLetterArchive) ReadTransactions(resolver resolver.DomainResolver, optionalEncryption *FileEncryption) ([]*transaction.HTTPTransaction, error) {
+	content, err := ioutil.ReadFile(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	if isEncryptedFileContent(content) {
+		if content, err = optionalEncryption.decrypt(content); err != nil {
+			return nil, fmt.Errorf("cannot decrypt the dead-letter archive %s: %v", a.Path, err)
+		}
+	}
+
+	transactions, errorCount, err := NewHTTPTransactionsSerializer(resolver).Deserialize(content)
+	if err != nil {
+		return nil, err
+	}
+	if errorCount > 0 {
+		log.Warnf("%d transactions of the dead-letter archive %s cannot be read", errorCount, a.Path)
+	}
+
+	httpTransactions := make([]*transaction.HTTPTransaction, 0, len(transactions))
+	for _, t := range transactions {
+		httpTransactions = append(httpTransactions, t.(*transaction.HTTPTransaction))
+	}
+	return httpTransactions, nil
+}
+
+// Rewrite replaces the transactions of the archive by `transactions`. The archive is
+// removed when `transactions` is empty.
+func (a DeadLetterArchive) Rewrite(transactions []*transaction.HTTPTransaction, resolver resolver.DomainResolver, optionalEncryption *FileEncryption) error {
+	if len(transactions) == 0 {
+		return a.Remove()
+	}
+
+	serializer := NewHTTPTransactionsSerializer(resolver)
+	endpoints := make(map[string]struct{})
+	for _, t := range transactions {
+		if err := t.SerializeTo(serializer); err != nil {
+			return err
+		}
+		endpoints[t.GetEndpointName()] = struct{}{}
+	}
+	content, err := serializer.GetBytesAndReset()
+	if err != nil {
+		return err
+	}
+	if optionalEncryption != nil {
+		if content, err = optionalEncryption.encrypt(content); err != nil {
+			return err
+		}
+	}
+
+	metadata := a.Metadata
+	metadata.TransactionCount = len(transactions)
+	metadata.Endpoints = nil
+	for endpoint := range endpoints {
+		metadata.Endpoints = append(metadata.Endpoints, endpoint)
+	}
+	sort.Strings(metadata.Endpoints)
+
+	tmpPath := a.Path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, content, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, a.Path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return writeDeadLetterMetadata(a.Path, metadata)
+}
+
+// Remove removes the archive and its metadata.
+func (a DeadLetterArchive) Remove() error {
+	if err := os.Remove(a.Path); err != nil {
+		return err
+	}
+	return os.Remove(getDeadLetterMetadataPath(a.Path))
+}
+
+func getDeadLetterMetadataPath(archivePath string) string {
+	return strings.TrimSuffix(archivePath, retryTransactionsExtension) + deadLetterMetadataExtension
+}
+
+func writeDeadLetterMetadata(archivePath string, metadata DeadLetterMetadata) error {
+	content, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getDeadLetterMetadataPath(archivePath), content, 0600)
+}
+
+// moveFile moves `src` to `dst`, copying the file when they are not on the same file system.
+func moveFile(src string, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,220 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterQueueArchiveTransactions(t *testing.T) {
+	a := assert.New(t)
+	queue := newTestDeadLetterQueue(a, t.TempDir(), 0, nil)
+	archiver := newDeadLetterArchiver(queue, resolver.NewSingleDomainResolver(domainName, nil))
+
+	archiver.archiveTransactions(createHTTPTransactionCollectionTests("endpoint2", "endpoint1"), DeadLetterReasonRetryQueueFull)
+	archiver.archiveTransactions(nil, DeadLetterReasonRetryQueueFull)
+
+	archives, err := ReadDeadLetterArchives(queue.path)
+	a.NoError(err)
+	a.Len(archives, 1)
+	metadata := archives[0].Metadata
+	a.Equal(domainName, metadata.Domain)
+	a.Equal(DeadLetterReasonRetryQueueFull, metadata.Reason)
+	a.Equal(2, metadata.TransactionCount)
+	a.Equal([]string{"endpoint1", "endpoint2"}, metadata.Endpoints)
+
+	transactions := readTestDeadLetterArchive(a, archives[0], nil)
+	a.Equal([]string{"endpoint2", "endpoint1"}, getEndpointsFromHTTPTransactions(transactions))
+}
+
+func TestDeadLetterQueueMaxSize(t *testing.T) {
+	a := assert.New(t)
+	queue := newTestDeadLetterQueue(a, t.TempDir(), 1, nil)
+	archiver := newDeadLetterArchiver(queue, resolver.NewSingleDomainResolver(domainName, nil))
+
+	archiver.archiveTransactions(createHTTPTransactionCollectionTests("endpoint1"), DeadLetterReasonRetryQueueFull)
+
+	archives, err := ReadDeadLetterArchives(queue.path)
+	a.NoError(err)
+	a.Len(archives, 0)
+}
+
+func TestDeadLetterQueueMaxSizeAfterReplay(t *testing.T) {
+	a := assert.New(t)
+	queue := newTestDeadLetterQueue(a, t.TempDir(), 0, nil)
+	archiver := newDeadLetterArchiver(queue, resolver.NewSingleDomainResolver(domainName, nil))
+	archiver.archiveTransactions(createHTTPTransactionCollectionTests("endpoint1"), DeadLetterReasonRetryQueueFull)
+	queue.maxSizeInBytes = queue.currentSizeInBytes
+
+	// the directory is full until its archives are replayed
+	archiver.archiveTransactions(createHTTPTransactionCollectionTests("endpoint1"), DeadLetterReasonRetryQueueFull)
+	archives, err := ReadDeadLetterArchives(queue.path)
+	a.NoError(err)
+	a.Len(archives, 1)
+
+	a.NoError(archives[0].Remove())
+	archiver.archiveTransactions(createHTTPTransactionCollectionTests("endpoint1"), DeadLetterReasonRetryQueueFull)
+	archives, err = ReadDeadLetterArchives(queue.path)
+	a.NoError(err)
+	a.Len(archives, 1)
+	a.Equal(queue.maxSizeInBytes, queue.currentSizeInBytes)
+}
+
+func TestDeadLetterQueueEncryption(t *testing.T) {
+	a := assert.New(t)
+	encryption := newTestFileEncryption(a, "0123456789abcdef")
+	queue := newTestDeadLetterQueue(a, t.TempDir(), 0, encryption)
+	archiver := newDeadLetterArchiver(queue, resolver.NewSingleDomainResolver(domainName, nil))
+
+	archiver.archiveTransactions(createHTTPTransactionCollectionTests("endpoint1"), DeadLetterReasonMaxRetryAgeExceeded)
+
+	contents := readRetryFiles(a, queue.path)
+	a.Len(contents, 1)
+	a.True(isEncryptedFileContent(contents[0]))
+
+	archives, err := ReadDeadLetterArchives(queue.path)
+	a.NoError(err)
+	a.Len(archives, 1)
+	_, err = archives[0].ReadTransactions(resolver.NewSingleDomainResolver(domainName, nil), nil)
+	a.Error(err)
+	transactions := readTestDeadLetterArchive(a, archives[0], encryption)
+	a.Equal([]string{"endpoint1"}, getEndpointsFromHTTPTransactions(transactions))
+}
+
+func TestDeadLetterArchiveRewrite(t *testing.T) {
+	a := assert.New(t)
+	queue := newTestDeadLetterQueue(a, t.TempDir(), 0, nil)
+	domainResolver := resolver.NewSingleDomainResolver(domainName, nil)
+	archiver := newDeadLetterArchiver(queue, domainResolver)
+	archiver.archiveTransactions(createHTTPTransactionCollectionTests("endpoint1", "endpoint2", "endpoint3"), DeadLetterReasonRetryQueueFull)
+
+	archives, err := ReadDeadLetterArchives(queue.path)
+	a.NoError(err)
+	a.Len(archives, 1)
+	transactions := readTestDeadLetterArchive(a, archives[0], nil)
+
+	a.NoError(archives[0].Rewrite(transactions[1:2], domainResolver, nil))
+	archives, err = ReadDeadLetterArchives(queue.path)
+	a.NoError(err)
+	a.Len(archives, 1)
+	a.Equal(1, archives[0].Metadata.TransactionCount)
+	a.Equal([]string{"endpoint2"}, archives[0].Metadata.Endpoints)
+	a.Equal([]string{"endpoint2"}, getEndpointsFromHTTPTransactions(readTestDeadLetterArchive(a, archives[0], nil)))
+
+	a.NoError(archives[0].Rewrite(nil, domainResolver, nil))
+	a.Empty(getRemainingFiles(a, queue.path))
+}
+
+func TestTransactionRetryQueueDeadLetter(t *testing.T) {
+	a := assert.New(t)
+	queue := newTestDeadLetterQueue(a, t.TempDir(), 0, nil)
+	container := BuildTransactionRetryQueue(20, 0.5, "", nil, nil, queue, createDropPrioritySorter(), resolver.NewSingleDomainResolver("", nil))
+
+	for _, payloadSize := range []int{10, 15} {
+		_, err := container.Add(createTransactionWithPayloadSize(payloadSize))
+		a.NoError(err)
+	}
+
+	archives, err := ReadDeadLetterArchives(queue.path)
+	a.NoError(err)
+	a.Len(archives, 1)
+	a.Equal(DeadLetterReasonRetryQueueFull, archives[0].Metadata.Reason)
+	a.Equal(1, archives[0].Metadata.TransactionCount)
+}
+
+func TestOnDiskRetryQueueDeadLetter(t *testing.T) {
+	a := assert.New(t)
+	queue := newTestDeadLetterQueue(a, t.TempDir(), 0, nil)
+	disk := diskUsageRetrieverMock{
+		diskUsage: &filesystem.DiskUsage{
+			Available: 10000,
+			Total:     10000,
+		}}
+	archiver := newDeadLetterArchiver(queue, resolver.NewSingleDomainResolver(domainName, nil))
+	q, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil)), t.TempDir(), NewDiskUsageLimit("", disk, 100, 1), newOnDiskRetryQueueTelemetry("domain"), nil, archiver)
+	a.NoError(err)
+
+	for i := 0; i < 10; i++ {
+		a.NoError(q.Serialize(createHTTPTransactionCollectionTests(strconv.Itoa(i))))
+	}
+	a.LessOrEqual(q.GetDiskSpaceUsed(), int64(100))
+
+	archives, err := ReadDeadLetterArchives(queue.path)
+	a.NoError(err)
+	a.Equal(10-q.getFilesCount(), len(archives))
+	a.Equal(DeadLetterReasonDiskLimitReached, archives[0].Metadata.Reason)
+	a.Equal([]string{"0"}, getEndpointsFromHTTPTransactions(readTestDeadLetterArchive(a, archives[0], nil)))
+}
+
+func TestFileRemovalPolicyDeadLetter(t *testing.T) {
+	a := assert.New(t)
+	root := t.TempDir()
+	queue := newTestDeadLetterQueue(a, t.TempDir(), 0, nil)
+	p, err := NewFileRemovalPolicy(root, 2, FileRemovalPolicyTelemetry{})
+	a.NoError(err)
+	a.NoError(p.SetDeadLetterQueue(queue, []string{"domain1"}))
+
+	domain1, err := p.getFolderPathForDomain("domain1")
+	a.NoError(err)
+	domain2, err := p.getFolderPathForDomain("domain2")
+	a.NoError(err)
+	file1 := createRetryFile(a, domain1, "file1")
+	file2 := createRetryFile(a, domain2, "file2")
+	modTime := time.Now().Add(time.Duration(-3*24) * time.Hour)
+	a.NoError(os.Chtimes(file1, modTime, modTime))
+	a.NoError(os.Chtimes(file2, modTime, modTime))
+
+	pathsRemoved, err := p.RemoveOutdatedFiles()
+	a.NoError(err)
+	assertFilenamesEqual(a, []string{file1, file2}, pathsRemoved)
+	a.Empty(getRemainingFiles(a, root))
+
+	archives, err := ReadDeadLetterArchives(queue.path)
+	a.NoError(err)
+	a.Len(archives, 1)
+	a.Equal("domain1", archives[0].Metadata.Domain)
+	a.Equal(DeadLetterReasonOutdated, archives[0].Metadata.Reason)
+}
+
+func TestNewDeadLetterQueueExistingArchives(t *testing.T) {
+	a := assert.New(t)
+	deadLetterPath := path.Join(t.TempDir(), "dead_letter")
+	queue := newTestDeadLetterQueue(a, deadLetterPath, 0, nil)
+	archiver := newDeadLetterArchiver(queue, resolver.NewSingleDomainResolver(domainName, nil))
+	archiver.archiveTransactions(createHTTPTransactionCollectionTests("endpoint1"), DeadLetterReasonRetryQueueFull)
+
+	a.Equal(queue.currentSizeInBytes, newTestDeadLetterQueue(a, deadLetterPath, 0, nil).currentSizeInBytes)
+	a.Greater(queue.currentSizeInBytes, int64(0))
+}
+
+func newTestDeadLetterQueue(a *assert.Assertions, path string, maxSizeInBytes int64, encryption *FileEncryption) *DeadLetterQueue {
+	queue, err := NewDeadLetterQueue(path, maxSizeInBytes, encryption)
+	a.NoError(err)
+	return queue
+}
+
+func readTestDeadLetterArchive(a *assert.Assertions, archive DeadLetterArchive, encryption *FileEncryption) []*transaction.HTTPTransaction {
+	transactions, err := archive.ReadTransactions(resolver.NewSingleDomainResolver(domainName, nil), encryption)
+	a.NoError(err)
+	return transactions
+}
+
+func getEndpointsFromHTTPTransactions(transactions []*transaction.HTTPTransaction) []string {
+	var endpoints []string
+	for _, t := range transactions {
+		endpoints = append(endpoints, t.Endpoint.Name)
+	}
+	return endpoints
+}
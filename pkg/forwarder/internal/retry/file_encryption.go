@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encryptedFileMagic prefixes the retry files encrypted by FileEncryption. Unencrypted retry files
+// are protobuf messages which can never start with this sequence (field 8 with the group end wire type).
+var encryptedFileMagic = []byte("DDRQAES1")
+
+var errNoEncryptionKey = errors.New("the retry file is encrypted but no encryption key is configured")
+
+// FileEncryption encrypts and decrypts the content of the retry files with AES-GCM.
+// An encrypted file is made of `encryptedFileMagic`, a random nonce and the sealed payload.
+type FileEncryption struct {
+	aead cipher.AEAD
+}
+
+// NewFileEncryption creates a new instance of FileEncryption from a base64 encoded AES key
+// of 16, 24 or 32 bytes.
+func NewFileEncryption(base64Key string) (*FileEncryption, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(base64Key))
+	if err != nil {
+		return nil, fmt.Errorf("the encryption key is not valid base64: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("the encryption key is not a valid AES key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &FileEncryption{aead: aead}, nil
+}
+
+// encrypt returns the encrypted representation of payload.
+func (e *FileEncryption) encrypt(payload []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+	header := make([]byte, len(encryptedFileMagic)+nonceSize, len(encryptedFileMagic)+nonceSize+len(payload)+e.aead.Overhead())
+	copy(header, encryptedFileMagic)
+	nonce := header[len(encryptedFileMagic):]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(header, nonce, payload, encryptedFileMagic), nil
+}
+
+// decrypt returns the payload from its encrypted representation.
+// The receiver can be nil in which case an error is returned.
+func (e *FileEncryption) decrypt(data []byte) ([]byte, error) {
+	if e == nil {
+		return nil, errNoEncryptionKey
+	}
+	nonceSize := e.aead.NonceSize()
+	if len(data) < len(encryptedFileMagic)+nonceSize {
+		return nil, errors.New("the retry file is truncated")
+	}
+	nonce := data[len(encryptedFileMagic) : len(encryptedFileMagic)+nonceSize]
+	ciphertext := data[len(encryptedFileMagic)+nonceSize:]
+	return e.aead.Open(nil, nonce, ciphertext, encryptedFileMagic)
+}
+
+func isEncryptedFileContent(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedFileMagic)
+}
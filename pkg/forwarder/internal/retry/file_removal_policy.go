@@ -17,6 +17,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/util"
 	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/hashicorp/go-multierror"
 )
 
@@ -26,6 +27,9 @@ type FileRemovalPolicy struct {
 	knownDomainFolders map[string]struct{}
 	outdatedFileTime   time.Time
 	telemetry          FileRemovalPolicyTelemetry
+
+	optionalDeadLetterQueue *DeadLetterQueue
+	deadLetterDomains       map[string]string
 }
 
 // NewFileRemovalPolicy creates a new instance of FileRemovalPolicy
@@ -67,6 +71,22 @@ func (p *FileRemovalPolicy) RegisterDomain(domainName string) (string, error) {
 	return folder, nil
 }
 
+// SetDeadLetterQueue archives the outdated files of `domainNames` in `queue` instead of removing them.
+// The outdated files of other domains are still removed as they cannot be replayed.
+func (p *FileRemovalPolicy) SetDeadLetterQueue(queue *DeadLetterQueue, domainNames []string) error {
+	domains := make(map[string]string, len(domainNames))
+	for _, domainName := range domainNames {
+		folder, err := p.getFolderPathForDomain(domainName)
+		if err != nil {
+			return err
+		}
+		domains[folder] = domainName
+	}
+	p.optionalDeadLetterQueue = queue
+	p.deadLetterDomains = domains
+	return nil
+}
+
 // RemoveOutdatedFiles removes the outdated files when a file is
 // older than outDatedFileDayCount days.
 func (p *FileRemovalPolicy) RemoveOutdatedFiles() ([]string, error) {
@@ -128,16 +148,31 @@ func (p *FileRemovalPolicy) removeUnknownDomain(folderPath string) ([]string, er
 }
 
 func (p *FileRemovalPolicy) removeOutdatedRetryFiles(folderPath string) ([]string, error) {
-	return p.removeRetryFiles(folderPath, func(filename string) bool {
+	isOutdated := func(filename string) bool {
 		modTime, err := util.GetFileModTime(filename)
 		if err != nil {
 			return false
 		}
 		return modTime.Before(p.outdatedFileTime)
-	})
+	}
+
+	if domainName, found := p.deadLetterDomains[folderPath]; found && p.optionalDeadLetterQueue != nil {
+		return p.removeRetryFilesWith(folderPath, isOutdated, func(filename string) error {
+			if err := p.optionalDeadLetterQueue.archiveFile(filename, domainName, DeadLetterReasonOutdated); err != nil {
+				log.Errorf("Cannot move %s to the dead-letter directory, removing it: %v", filename, err)
+				return os.Remove(filename)
+			}
+			return nil
+		})
+	}
+	return p.removeRetryFiles(folderPath, isOutdated)
 }
 
 func (p *FileRemovalPolicy) removeRetryFiles(folderPath string, shouldRemove func(string) bool) ([]string, error) {
+	return p.removeRetryFilesWith(folderPath, shouldRemove, os.Remove)
+}
+
+func (p *FileRemovalPolicy) removeRetryFilesWith(folderPath string, shouldRemove func(string) bool, remove func(string) error) ([]string, error) {
 	files, err := p.getRetryFiles(folderPath)
 	if err != nil {
 		return nil, err
@@ -147,7 +182,7 @@ func (p *FileRemovalPolicy) removeRetryFiles(folderPath string, shouldRemove fun
 	var errs error
 	for _, f := range files {
 		if shouldRemove(f) {
-			if err = os.Remove(f); err != nil {
+			if err = remove(f); err != nil {
 				errs = multierror.Append(errs, err)
 			} else {
 				filesRemoved = append(filesRemoved, f)
@@ -22,6 +22,11 @@ import (
 const retryTransactionsExtension = ".retry"
 const retryFileFormat = "2006_01_02__15_04_05_"
 
+// retryQuarantineDirectory is the directory of the storage path where the retry files which
+// can't be decrypted are moved. They can be moved back to the storage path to be retried once
+// the Agent uses their encryption key.
+const retryQuarantineDirectory = "quarantine"
+
 type onDiskRetryQueue struct {
 	serializer         *HTTPTransactionsSerializer
 	storagePath        string
@@ -29,23 +34,29 @@ type onDiskRetryQueue struct {
 	filenames          []string
 	currentSizeInBytes int64
 	telemetry          onDiskRetryQueueTelemetry
+	optionalEncryption *FileEncryption
+	optionalDeadLetter *deadLetterArchiver
 }
 
 func newOnDiskRetryQueue(
 	serializer *HTTPTransactionsSerializer,
 	storagePath string,
 	diskUsageLimit *DiskUsageLimit,
-	telemetry onDiskRetryQueueTelemetry) (*onDiskRetryQueue, error) {
+	telemetry onDiskRetryQueueTelemetry,
+	optionalEncryption *FileEncryption,
+	optionalDeadLetter *deadLetterArchiver) (*onDiskRetryQueue, error) {
 
 	if err := os.MkdirAll(storagePath, 0700); err != nil {
 		return nil, err
 	}
 
 	storage := &onDiskRetryQueue{
-		serializer:     serializer,
-		storagePath:    storagePath,
-		diskUsageLimit: diskUsageLimit,
-		telemetry:      telemetry,
+		serializer:         serializer,
+		storagePath:        storagePath,
+		diskUsageLimit:     diskUsageLimit,
+		telemetry:          telemetry,
+		optionalEncryption: optionalEncryption,
+		optionalDeadLetter: optionalDeadLetter,
 	}
 
 	if err := storage.reloadExistingRetryFiles(); err != nil {
@@ -77,6 +88,11 @@ func (s *onDiskRetryQueue) Serialize(transactions []transaction.Transaction) err
 	if err != nil {
 		return err
 	}
+	if s.optionalEncryption != nil {
+		if bytes, err = s.optionalEncryption.encrypt(bytes); err != nil {
+			return err
+		}
+	}
 	bufferSize := int64(len(bytes))
 
 	if err := s.makeRoomFor(bufferSize); err != nil {
@@ -113,6 +129,18 @@ func (s *onDiskRetryQueue) Deserialize() ([]transaction.Transaction, error) {
 	path := s.filenames[index]
 	bytes, err := ioutil.ReadFile(path)
 
+	if err == nil && isEncryptedFileContent(bytes) {
+		if bytes, err = s.optionalEncryption.decrypt(bytes); err != nil {
+			// The file is kept, it may have been encrypted with another key.
+			s.telemetry.addDecryptionErrorsCount()
+			quarantinePath, errQuarantine := s.quarantineFileAt(index)
+			if errQuarantine != nil {
+				return nil, fmt.Errorf("cannot decrypt the retry file %s: %v. The file is left in place: %v", path, err, errQuarantine)
+			}
+			return nil, fmt.Errorf("cannot decrypt the retry file %s: %v. The file is moved to %s", path, err, quarantinePath)
+		}
+	}
+
 	// Remove the file even in case of a read failure.
 	if errRemoveFile := s.removeFileAt(index); errRemoveFile != nil {
 		return nil, errRemoveFile
@@ -156,9 +184,13 @@ func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
 	for len(s.filenames) > 0 && s.currentSizeInBytes+bufferSize > maxStorageInBytes {
 		index := 0
 		filename := s.filenames[index]
-		log.Errorf("Maximum disk space for retry transactions is reached. Removing %s", filename)
-		if err := s.removeFileAt(index); err != nil {
-			return err
+		if s.optionalDeadLetter != nil && s.archiveFileAt(index) {
+			log.Errorf("Maximum disk space for retry transactions is reached. Moving %s to the dead-letter directory", filename)
+		} else {
+			log.Errorf("Maximum disk space for retry transactions is reached. Removing %s", filename)
+			if err := s.removeFileAt(index); err != nil {
+				return err
+			}
 		}
 		s.telemetry.addFilesRemovedCount()
 	}
@@ -186,11 +218,52 @@ func (s *onDiskRetryQueue) removeFileAt(index int) error {
 	return nil
 }
 
+// archiveFileAt moves the file at `index` to the dead-letter directory. It returns false
+// if the file is still in the retry queue.
+func (s *onDiskRetryQueue) archiveFileAt(index int) bool {
+	filename := s.filenames[index]
+	size, err := util.GetFileSize(filename)
+	if err != nil {
+		return false
+	}
+	if err := s.optionalDeadLetter.archiveFile(filename, DeadLetterReasonDiskLimitReached); err != nil {
+		log.Errorf("Cannot move %s to the dead-letter directory: %v", filename, err)
+		return false
+	}
+	s.filenames = append(s.filenames[:index], s.filenames[index+1:]...)
+	s.currentSizeInBytes -= size
+	return true
+}
+
+// quarantineFileAt moves the file at `index` to the quarantine directory and returns its new
+// path. The file is no longer in the retry queue, even when it can't be moved.
+func (s *onDiskRetryQueue) quarantineFileAt(index int) (string, error) {
+	filename := s.filenames[index]
+	s.filenames = append(s.filenames[:index], s.filenames[index+1:]...)
+	if size, err := util.GetFileSize(filename); err == nil {
+		s.currentSizeInBytes -= size
+	}
+
+	quarantinePath := filepath.Join(s.storagePath, retryQuarantineDirectory)
+	if err := os.MkdirAll(quarantinePath, 0700); err != nil {
+		return "", err
+	}
+	quarantineFilename := filepath.Join(quarantinePath, filepath.Base(filename))
+	if err := os.Rename(filename, quarantineFilename); err != nil {
+		return "", err
+	}
+	return quarantineFilename, nil
+}
+
 func (s *onDiskRetryQueue) reloadExistingRetryFiles() error {
 	files, sizeInBytes, err := s.getExistingRetryFiles()
 	if err != nil {
 		return err
 	}
+
+	if s.optionalEncryption != nil {
+		files, sizeInBytes = s.encryptCleartextRetryFiles(files)
+	}
 	s.currentSizeInBytes = sizeInBytes
 
 	sort.Slice(files, func(i, j int) bool {
@@ -221,3 +294,59 @@ func (s *onDiskRetryQueue) getExistingRetryFiles() ([]os.FileInfo, int64, error)
 	}
 	return files, currentSizeInBytes, nil
 }
+
+// encryptCleartextRetryFiles encrypts the retry files written by an Agent running without
+// encryption. The modification time of the files is preserved as it defines the order in which
+// the files are retried and when they become outdated.
+func (s *onDiskRetryQueue) encryptCleartextRetryFiles(files []os.FileInfo) ([]os.FileInfo, int64) {
+	var migratedFiles []os.FileInfo
+	currentSizeInBytes := int64(0)
+	for _, file := range files {
+		fullPath := path.Join(s.storagePath, file.Name())
+		migratedFile, err := s.encryptCleartextRetryFile(fullPath, file)
+		if err != nil {
+			log.Errorf("Cannot encrypt the retry file %s, keeping it unencrypted: %v", fullPath, err)
+			migratedFile = file
+		}
+		currentSizeInBytes += migratedFile.Size()
+		migratedFiles = append(migratedFiles, migratedFile)
+	}
+	return migratedFiles, currentSizeInBytes
+}
+
+func (s *onDiskRetryQueue) encryptCleartextRetryFile(fullPath string, file os.FileInfo) (os.FileInfo, error) {
+	content, err := ioutil.ReadFile(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	if isEncryptedFileContent(content) {
+		return file, nil
+	}
+
+	encrypted, err := s.optionalEncryption.encrypt(content)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpFile, err := ioutil.TempFile(s.storagePath, file.Name()+"*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	_, err = tmpFile.Write(encrypted)
+	if errClose := tmpFile.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Chtimes(tmpFile.Name(), file.ModTime(), file.ModTime())
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), fullPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return nil, err
+	}
+
+	s.telemetry.addMigratedFilesCount()
+	return os.Stat(fullPath)
+}
@@ -6,8 +6,14 @@
 package retry
 
 import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config/resolver"
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
@@ -86,6 +92,100 @@ func TestOnDiskRetryQueueReloadExistingRetryFiles(t *testing.T) {
 	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
 }
 
+func TestOnDiskRetryQueueEncryption(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+
+	q := newTestEncryptedOnDiskRetryQueue(a, path, newTestFileEncryption(a, "0123456789abcdef0123456789abcdef"))
+	err := q.Serialize(createHTTPTransactionCollectionTests("endpoint1", "endpoint2"))
+	a.NoError(err)
+
+	content := readRetryFiles(a, path)
+	a.Len(content, 1)
+	a.True(isEncryptedFileContent(content[0]))
+	a.NotContains(string(content[0]), "endpoint1")
+
+	transactions, err := q.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
+}
+
+func TestOnDiskRetryQueueEncryptionMigration(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+
+	q := newTestOnDiskRetryQueue(a, path, 1000)
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint1")))
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint2")))
+	a.NoError(os.Chtimes(q.filenames[0], time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)))
+
+	q = newTestEncryptedOnDiskRetryQueue(a, path, newTestFileEncryption(a, "0123456789abcdef0123456789abcdef"))
+	a.Equal(2, q.getFilesCount())
+	for _, content := range readRetryFiles(a, path) {
+		a.True(isEncryptedFileContent(content))
+	}
+	a.Equal(int64(len(readRetryFiles(a, path)[0])+len(readRetryFiles(a, path)[1])), q.GetDiskSpaceUsed())
+
+	// The order of the files is preserved
+	transactions, err := q.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"endpoint2"}, getEndpointsFromTransactions(transactions))
+	transactions, err = q.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1"}, getEndpointsFromTransactions(transactions))
+}
+
+func TestOnDiskRetryQueueEncryptionWrongKey(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+
+	q := newTestEncryptedOnDiskRetryQueue(a, path, newTestFileEncryption(a, "0123456789abcdef0123456789abcdef"))
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint1")))
+
+	q = newTestEncryptedOnDiskRetryQueue(a, path, newTestFileEncryption(a, "fedcba9876543210fedcba9876543210"))
+	a.Equal(1, q.getFilesCount())
+	_, err := q.Deserialize()
+	a.Error(err)
+	a.Equal(0, q.getFilesCount())
+	a.Equal(int64(0), q.GetDiskSpaceUsed())
+
+	// The file is moved to the quarantine directory instead of being removed
+	quarantinePath := filepath.Join(path, retryQuarantineDirectory)
+	quarantined, err := ioutil.ReadDir(quarantinePath)
+	a.NoError(err)
+	a.Len(quarantined, 1)
+
+	// It can be retried once moved back with its key
+	a.NoError(os.Rename(filepath.Join(quarantinePath, quarantined[0].Name()), filepath.Join(path, quarantined[0].Name())))
+	q = newTestEncryptedOnDiskRetryQueue(a, path, newTestFileEncryption(a, "0123456789abcdef0123456789abcdef"))
+	transactions, err := q.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1"}, getEndpointsFromTransactions(transactions))
+
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint2")))
+
+	// An encrypted file cannot be read without a key
+	q = newTestOnDiskRetryQueue(a, path, 1000)
+	_, err = q.Deserialize()
+	a.Error(err)
+	quarantined, err = ioutil.ReadDir(quarantinePath)
+	a.NoError(err)
+	a.Len(quarantined, 1)
+}
+
+func TestNewFileEncryption(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := NewFileEncryption("not base64")
+	a.Error(err)
+	_, err = NewFileEncryption(base64.StdEncoding.EncodeToString([]byte("too short")))
+	a.Error(err)
+	for _, size := range []int{16, 24, 32} {
+		_, err = NewFileEncryption(base64.StdEncoding.EncodeToString(make([]byte, size)))
+		a.NoError(err)
+	}
+}
+
 func createHTTPTransactionCollectionTests(endpoints ...string) []transaction.Transaction {
 	var transactions []transaction.Transaction
 
@@ -115,7 +215,38 @@ func newTestOnDiskRetryQueue(a *assert.Assertions, path string, maxSizeInBytes i
 			Total:     10000,
 		}}
 	diskUsageLimit := NewDiskUsageLimit("", disk, maxSizeInBytes, 1)
-	storage, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil)), path, diskUsageLimit, telemetry)
+	storage, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil)), path, diskUsageLimit, telemetry, nil, nil)
+	a.NoError(err)
+	return storage
+}
+
+func newTestFileEncryption(a *assert.Assertions, key string) *FileEncryption {
+	encryption, err := NewFileEncryption(base64.StdEncoding.EncodeToString([]byte(key)))
+	a.NoError(err)
+	return encryption
+}
+
+func newTestEncryptedOnDiskRetryQueue(a *assert.Assertions, path string, encryption *FileEncryption) *onDiskRetryQueue {
+	disk := diskUsageRetrieverMock{
+		diskUsage: &filesystem.DiskUsage{
+			Available: 10000,
+			Total:     10000,
+		}}
+	diskUsageLimit := NewDiskUsageLimit("", disk, 1000, 1)
+	storage, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil)), path, diskUsageLimit, newOnDiskRetryQueueTelemetry("domain"), encryption, nil)
 	a.NoError(err)
 	return storage
 }
+
+func readRetryFiles(a *assert.Assertions, path string) [][]byte {
+	files, err := filepath.Glob(filepath.Join(path, "*"+retryTransactionsExtension))
+	a.NoError(err)
+	sort.Strings(files)
+	var contents [][]byte
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		a.NoError(err)
+		contents = append(contents, content)
+	}
+	return contents
+}
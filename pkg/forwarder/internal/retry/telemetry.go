@@ -74,6 +74,13 @@ var (
 	filesRemovedCountTelemetry              *counterExpvar
 	deserializeErrorsCountTelemetry         *counterExpvar
 	deserializeTransactionsCountTelemetry   *counterExpvar
+	decryptionErrorsCountTelemetry          *counterExpvar
+	migratedFilesCountTelemetry             *counterExpvar
+
+	deadLetterExpvar                             = expvar.Map{}
+	deadLetterArchivedFilesCountTelemetry        *counterExpvar
+	deadLetterArchivedTransactionsCountTelemetry *counterExpvar
+	deadLetterDroppedFilesCountTelemetry         *counterExpvar
 )
 
 func init() {
@@ -185,6 +192,39 @@ func init() {
 		domainTag,
 		"The number of transactions read from the disk",
 		&fileStorageExpvar)
+	decryptionErrorsCountTelemetry = newCounterExpvar(
+		"file_storage",
+		"decryption_errors_count",
+		domainTag,
+		"The number of retry files which cannot be decrypted",
+		&fileStorageExpvar)
+	migratedFilesCountTelemetry = newCounterExpvar(
+		"file_storage",
+		"migrated_files_count",
+		domainTag,
+		"The number of unencrypted retry files encrypted at startup",
+		&fileStorageExpvar)
+
+	transaction.ForwarderExpvars.Set("DeadLetter", &deadLetterExpvar)
+	domainAndReasonTags := []string{"domain", "reason"}
+	deadLetterArchivedFilesCountTelemetry = newCounterExpvar(
+		"dead_letter",
+		"archived_files_count",
+		domainAndReasonTags,
+		"The number of files written to the dead-letter directory",
+		&deadLetterExpvar)
+	deadLetterArchivedTransactionsCountTelemetry = newCounterExpvar(
+		"dead_letter",
+		"archived_transactions_count",
+		domainAndReasonTags,
+		"The number of dropped transactions written to the dead-letter directory",
+		&deadLetterExpvar)
+	deadLetterDroppedFilesCountTelemetry = newCounterExpvar(
+		"dead_letter",
+		"dropped_files_count",
+		domainAndReasonTags,
+		"The number of files not written to the dead-letter directory because its maximum size is reached",
+		&deadLetterExpvar)
 }
 
 // FileRemovalPolicyTelemetry handles the telemetry for FileRemovalPolicy.
@@ -279,6 +319,28 @@ func (t onDiskRetryQueueTelemetry) addDeserializeTransactionsCount(count int) {
 	deserializeTransactionsCountTelemetry.add(float64(count), t.domainName)
 }
 
+func (t onDiskRetryQueueTelemetry) addDecryptionErrorsCount() {
+	decryptionErrorsCountTelemetry.add(1, t.domainName)
+}
+
+func (t onDiskRetryQueueTelemetry) addMigratedFilesCount() {
+	migratedFilesCountTelemetry.add(1, t.domainName)
+}
+
+type deadLetterQueueTelemetry struct{}
+
+func (deadLetterQueueTelemetry) addArchivedFilesCount(domainName string, reason string) {
+	deadLetterArchivedFilesCountTelemetry.add(1, domainName, reason)
+}
+
+func (deadLetterQueueTelemetry) addArchivedTransactionsCount(count int, domainName string, reason string) {
+	deadLetterArchivedTransactionsCountTelemetry.add(float64(count), domainName, reason)
+}
+
+func (deadLetterQueueTelemetry) addDroppedFilesCount(domainName string, reason string) {
+	deadLetterDroppedFilesCountTelemetry.add(1, domainName, reason)
+}
+
 func toCamelCase(s string) string {
 	parts := strings.Split(s, "_")
 	var camelCase string
@@ -34,10 +34,15 @@ type TransactionRetryQueue struct {
 	currentMemSizeInBytes int
 	maxMemSizeInBytes     int
 	flushToStorageRatio   float64
-	dropPrioritySorter    TransactionPrioritySorter
-	optionalSerializer    DiskTransactionSerializer
-	telemetry             TransactionRetryQueueTelemetry
-	mutex                 sync.RWMutex
+	// endpointLimits are the limits of the transactions of some endpoints, which also count
+	// in the limit of the queue. endpointMemSizesInBytes is the memory used by their transactions.
+	endpointLimits          map[string]endpointLimit
+	endpointMemSizesInBytes map[string]int
+	dropPrioritySorter      TransactionPrioritySorter
+	optionalSerializer      DiskTransactionSerializer
+	optionalDeadLetter      *deadLetterArchiver
+	telemetry               TransactionRetryQueueTelemetry
+	mutex                   sync.RWMutex
 }
 
 // BuildTransactionRetryQueue builds a new instance of TransactionRetryQueue
@@ -46,14 +51,21 @@ func BuildTransactionRetryQueue(
 	flushToStorageRatio float64,
 	optionalDomainFolderPath string,
 	optionalDiskUsageLimit *DiskUsageLimit,
+	optionalEncryption *FileEncryption,
+	optionalDeadLetterQueue *DeadLetterQueue,
 	dropPrioritySorter TransactionPrioritySorter,
 	resolver resolver.DomainResolver) *TransactionRetryQueue {
 	var storage DiskTransactionSerializer
 	var err error
 
+	var deadLetter *deadLetterArchiver
+	if optionalDeadLetterQueue != nil {
+		deadLetter = newDeadLetterArchiver(optionalDeadLetterQueue, resolver)
+	}
+
 	if optionalDomainFolderPath != "" && optionalDiskUsageLimit != nil {
 		serializer := NewHTTPTransactionsSerializer(resolver)
-		storage, err = newOnDiskRetryQueue(serializer, optionalDomainFolderPath, optionalDiskUsageLimit, newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain()))
+		storage, err = newOnDiskRetryQueue(serializer, optionalDomainFolderPath, optionalDiskUsageLimit, newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain()), optionalEncryption, deadLetter)
 
 		// If the storage on disk cannot be used, log the error and continue.
 		// Returning `nil, err` would mean not using `TransactionRetryQueue` and so not using `forwarder_retry_queue_payloads_max_size` config.
@@ -62,12 +74,20 @@ func BuildTransactionRetryQueue(
 		}
 	}
 
-	return NewTransactionRetryQueue(
+	queue := NewTransactionRetryQueue(
 		dropPrioritySorter,
 		storage,
 		maxMemSizeInBytes,
 		flushToStorageRatio,
 		NewTransactionRetryQueueTelemetry(resolver.GetBaseDomain()))
+	queue.optionalDeadLetter = deadLetter
+	return queue
+}
+
+// endpointLimit is the memory limit of the transactions of an endpoint.
+type endpointLimit struct {
+	maxMemSizeInBytes   int
+	flushToStorageRatio float64
 }
 
 // NewTransactionRetryQueue creates a new instance of NewTransactionRetryQueue
@@ -83,9 +103,22 @@ func NewTransactionRetryQueue(
 		dropPrioritySorter:  dropPrioritySorter,
 		optionalSerializer:  optionalTransactionSerializer,
 		telemetry:           telemetry,
+
+		endpointLimits:          make(map[string]endpointLimit),
+		endpointMemSizesInBytes: make(map[string]int),
 	}
 }
 
+// SetEndpointLimit limits the memory used by the transactions of the endpoint `endpointName`. When
+// the limit is exceeded, the transactions of this endpoint are flushed to disk, or dropped, the same way
+// as the transactions of the queue when the limit of the queue is exceeded.
+func (tc *TransactionRetryQueue) SetEndpointLimit(endpointName string, maxMemSizeInBytes int, flushToStorageRatio float64) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	tc.endpointLimits[endpointName] = endpointLimit{maxMemSizeInBytes: maxMemSizeInBytes, flushToStorageRatio: flushToStorageRatio}
+}
+
 // Add adds a new transaction and flush transactions to disk if the memory limit is exceeded.
 // The amount of transactions flushed to disk is control by
 // `flushToStorageRatio` which is the ratio of the transactions to be flushed.
@@ -96,14 +129,54 @@ func NewTransactionRetryQueue(
 // The first 3 transactions are flushed to the disk as 10 + 20 + 30 >= 60
 // If disk serialization failed or is not enabled, remove old transactions such as
 // `currentMemSizeInBytes` <= `maxMemSizeInBytes`
+// When the endpoint of the transaction has its own limit, the transactions of this endpoint are
+// first flushed or removed the same way so that the endpoint does not exceed its limit.
 func (tc *TransactionRetryQueue) Add(t transaction.Transaction) (int, error) {
+	droppedTransactions, diskErr := tc.add(t)
+	if len(droppedTransactions) > 0 && tc.optionalDeadLetter != nil {
+		// The dropped transactions are archived without holding the lock as it writes to the disk.
+		tc.optionalDeadLetter.archiveTransactions(droppedTransactions, DeadLetterReasonRetryQueueFull)
+	}
+	return len(droppedTransactions), diskErr
+}
+
+// add adds `t` to the queue and returns the transactions dropped to make room for it.
+func (tc *TransactionRetryQueue) add(t transaction.Transaction) ([]transaction.Transaction, error) {
 	tc.mutex.Lock()
 	defer tc.mutex.Unlock()
 
-	var diskErr error
 	payloadSize := t.GetPayloadSize()
+	endpointName := t.GetEndpointName()
+
+	var droppedTransactions []transaction.Transaction
+	var diskErr error
+	if limit, found := tc.endpointLimits[endpointName]; found {
+		droppedTransactions, diskErr = tc.makeRoom(payloadSize, limit, endpointName)
+	}
+	dropped, err := tc.makeRoom(payloadSize, endpointLimit{tc.maxMemSizeInBytes, tc.flushToStorageRatio}, "")
+	droppedTransactions = append(droppedTransactions, dropped...)
+	if err != nil {
+		diskErr = multierror.Append(diskErr, err)
+	}
+
+	tc.transactions = append(tc.transactions, t)
+	tc.currentMemSizeInBytes += payloadSize
+	if _, found := tc.endpointLimits[endpointName]; found {
+		tc.endpointMemSizesInBytes[endpointName] += payloadSize
+	}
+	tc.telemetry.setCurrentMemSizeInBytes(tc.currentMemSizeInBytes)
+	tc.telemetry.setTransactionsCount(len(tc.transactions))
+
+	return droppedTransactions, diskErr
+}
+
+// makeRoom flushes to disk, or removes, the transactions of the endpoint `endpointName`, or all
+// the transactions when it is empty, so that a payload of `payloadSize` bytes fits in the limit.
+// It returns the transactions removed.
+func (tc *TransactionRetryQueue) makeRoom(payloadSize int, limit endpointLimit, endpointName string) ([]transaction.Transaction, error) {
+	var diskErr error
 	if tc.optionalSerializer != nil {
-		payloadsGroupToFlush := tc.extractTransactionsForDisk(payloadSize)
+		payloadsGroupToFlush := tc.extractTransactionsForDisk(payloadSize, limit, endpointName)
 		for _, payloads := range payloadsGroupToFlush {
 			if err := tc.optionalSerializer.Serialize(payloads); err != nil {
 				diskErr = multierror.Append(diskErr, err)
@@ -115,21 +188,23 @@ func (tc *TransactionRetryQueue) Add(t transaction.Transaction) (int, error) {
 		}
 	}
 
-	// If disk serialization failed or is not enabled, make sure `currentMemSizeInBytes` <= `maxMemSizeInBytes`
-	payloadSizeInBytesToDrop := (tc.currentMemSizeInBytes + payloadSize) - tc.maxMemSizeInBytes
-	inMemTransactionDroppedCount := 0
+	// If disk serialization failed or is not enabled, make sure the memory used is <= `limit.maxMemSizeInBytes`
+	payloadSizeInBytesToDrop := (tc.memSizeInBytes(endpointName) + payloadSize) - limit.maxMemSizeInBytes
+	var droppedTransactions []transaction.Transaction
 	if payloadSizeInBytesToDrop > 0 {
-		transactions := tc.extractTransactionsFromMemory(payloadSizeInBytesToDrop)
-		inMemTransactionDroppedCount = len(transactions)
-		tc.telemetry.addTransactionsDroppedCount(inMemTransactionDroppedCount)
+		droppedTransactions = tc.extractTransactionsFromMemory(payloadSizeInBytesToDrop, endpointName)
+		tc.telemetry.addTransactionsDroppedCount(len(droppedTransactions))
 	}
+	return droppedTransactions, diskErr
+}
 
-	tc.transactions = append(tc.transactions, t)
-	tc.currentMemSizeInBytes += payloadSize
-	tc.telemetry.setCurrentMemSizeInBytes(tc.currentMemSizeInBytes)
-	tc.telemetry.setTransactionsCount(len(tc.transactions))
-
-	return inMemTransactionDroppedCount, diskErr
+// memSizeInBytes returns the memory used by the transactions of the endpoint `endpointName`, or
+// by all the transactions when it is empty.
+func (tc *TransactionRetryQueue) memSizeInBytes(endpointName string) int {
+	if endpointName == "" {
+		return tc.currentMemSizeInBytes
+	}
+	return tc.endpointMemSizesInBytes[endpointName]
 }
 
 // ExtractTransactions extracts transactions from the container.
@@ -153,11 +228,20 @@ func (tc *TransactionRetryQueue) ExtractTransactions() ([]transaction.Transactio
 		}
 	}
 	tc.currentMemSizeInBytes = 0
+	tc.endpointMemSizesInBytes = make(map[string]int)
 	tc.telemetry.setCurrentMemSizeInBytes(tc.currentMemSizeInBytes)
 	tc.telemetry.setTransactionsCount(len(tc.transactions))
 	return transactions, nil
 }
 
+// ArchiveDroppedTransactions archives in the dead-letter directory the transactions dropped
+// for `reason`. It does nothing when the dead-letter directory is not configured.
+func (tc *TransactionRetryQueue) ArchiveDroppedTransactions(transactions []transaction.Transaction, reason string) {
+	if tc.optionalDeadLetter != nil {
+		tc.optionalDeadLetter.archiveTransactions(transactions, reason)
+	}
+}
+
 // GetCurrentMemSizeInBytes gets the current memory usage in bytes
 func (tc *TransactionRetryQueue) getCurrentMemSizeInBytes() int {
 	tc.mutex.RLock()
@@ -192,12 +276,12 @@ func (tc *TransactionRetryQueue) GetDiskSpaceUsed() int64 {
 	return 0
 }
 
-func (tc *TransactionRetryQueue) extractTransactionsForDisk(payloadSize int) [][]transaction.Transaction {
-	sizeInBytesToFlush := int(float64(tc.maxMemSizeInBytes) * tc.flushToStorageRatio)
+func (tc *TransactionRetryQueue) extractTransactionsForDisk(payloadSize int, limit endpointLimit, endpointName string) [][]transaction.Transaction {
+	sizeInBytesToFlush := int(float64(limit.maxMemSizeInBytes) * limit.flushToStorageRatio)
 	var payloadsGroupToFlush [][]transaction.Transaction
-	for tc.currentMemSizeInBytes+payloadSize > tc.maxMemSizeInBytes && len(tc.transactions) > 0 {
+	for tc.memSizeInBytes(endpointName)+payloadSize > limit.maxMemSizeInBytes && len(tc.transactions) > 0 {
 		// Flush the N first transactions whose payload size sum is greater than `sizeInBytesToFlush`
-		transactions := tc.extractTransactionsFromMemory(sizeInBytesToFlush)
+		transactions := tc.extractTransactionsFromMemory(sizeInBytesToFlush, endpointName)
 
 		if len(transactions) == 0 {
 			// Happens when `sizeInBytesToFlush == 0`
@@ -210,19 +294,31 @@ func (tc *TransactionRetryQueue) extractTransactionsForDisk(payloadSize int) [][
 	return payloadsGroupToFlush
 }
 
-func (tc *TransactionRetryQueue) extractTransactionsFromMemory(payloadSizeInBytesToExtract int) []transaction.Transaction {
-	i := 0
+// extractTransactionsFromMemory extracts the first transactions of the endpoint `endpointName`, or
+// of all the endpoints when it is empty, whose payload size sum is greater than `payloadSizeInBytesToExtract`.
+func (tc *TransactionRetryQueue) extractTransactionsFromMemory(payloadSizeInBytesToExtract int, endpointName string) []transaction.Transaction {
 	sizeInBytesExtracted := 0
 	var transactionsExtracted []transaction.Transaction
+	var transactionsKept []transaction.Transaction
 
 	tc.dropPrioritySorter.Sort(tc.transactions)
-	for ; i < len(tc.transactions) && sizeInBytesExtracted < payloadSizeInBytesToExtract; i++ {
-		transaction := tc.transactions[i]
-		sizeInBytesExtracted += transaction.GetPayloadSize()
-		transactionsExtracted = append(transactionsExtracted, transaction)
+	for i, t := range tc.transactions {
+		if sizeInBytesExtracted >= payloadSizeInBytesToExtract {
+			transactionsKept = append(transactionsKept, tc.transactions[i:]...)
+			break
+		}
+		if endpointName != "" && t.GetEndpointName() != endpointName {
+			transactionsKept = append(transactionsKept, t)
+			continue
+		}
+		sizeInBytesExtracted += t.GetPayloadSize()
+		transactionsExtracted = append(transactionsExtracted, t)
+		if _, found := tc.endpointMemSizesInBytes[t.GetEndpointName()]; found {
+			tc.endpointMemSizesInBytes[t.GetEndpointName()] -= t.GetPayloadSize()
+		}
 	}
 
-	tc.transactions = tc.transactions[i:]
+	tc.transactions = transactionsKept
 	tc.currentMemSizeInBytes -= sizeInBytesExtracted
 	return transactionsExtracted
 }
@@ -99,6 +99,68 @@ func TestTransactionRetryQueueZeroMaxMemSizeInBytes(t *testing.T) {
 	a.Equal(1, inMemTrDropped)
 }
 
+func TestTransactionRetryQueueEndpointLimit(t *testing.T) {
+	a := assert.New(t)
+	q := newOnDiskRetryQueueTest(t, a)
+
+	container := NewTransactionRetryQueue(createDropPrioritySorter(), q, 100, 0.6, NewTransactionRetryQueueTelemetry("domain"))
+	container.SetEndpointLimit("series_v2", 30, 0.5)
+
+	// When adding the last `series_v2` transaction, the transactions of `series_v2` exceed their limit
+	// and the first 2 are flushed to the disk as 10 + 15 >= 30 * 0.5
+	for _, tr := range []*transaction.HTTPTransaction{
+		createEndpointTransactionWithPayloadSize("series_v2", 10),
+		createEndpointTransactionWithPayloadSize("intake", 20),
+		createEndpointTransactionWithPayloadSize("series_v2", 15),
+		createEndpointTransactionWithPayloadSize("intake", 25),
+		createEndpointTransactionWithPayloadSize("series_v2", 10),
+	} {
+		_, err := container.Add(tr)
+		a.NoError(err)
+	}
+	a.Equal(20+25+10, container.getCurrentMemSizeInBytes())
+	a.Equal(1, q.getFilesCount())
+
+	assertPayloadSizeFromExtractTransactions(a, container, []int{20, 25, 10})
+	assertPayloadSizeFromExtractTransactions(a, container, []int{10, 15})
+}
+
+func TestTransactionRetryQueueEndpointLimitNoTransactionStorage(t *testing.T) {
+	a := assert.New(t)
+	container := NewTransactionRetryQueue(createDropPrioritySorter(), nil, 100, 0.1, NewTransactionRetryQueueTelemetry("domain"))
+	container.SetEndpointLimit("series_v2", 30, 0.1)
+
+	for _, tr := range []*transaction.HTTPTransaction{
+		createEndpointTransactionWithPayloadSize("series_v2", 10),
+		createEndpointTransactionWithPayloadSize("intake", 20),
+		createEndpointTransactionWithPayloadSize("series_v2", 15),
+		createEndpointTransactionWithPayloadSize("intake", 25),
+	} {
+		dropCount, err := container.Add(tr)
+		a.Equal(0, dropCount)
+		a.NoError(err)
+	}
+
+	// Only the first `series_v2` transaction is dropped when adding the last one
+	dropCount, err := container.Add(createEndpointTransactionWithPayloadSize("series_v2", 10))
+	a.Equal(1, dropCount)
+	a.NoError(err)
+
+	// Both limits apply: the queue drops the oldest transactions regardless of their endpoint
+	dropCount, err = container.Add(createEndpointTransactionWithPayloadSize("intake", 40))
+	a.Equal(1, dropCount)
+	a.NoError(err)
+	a.Equal(15+25+10+40, container.getCurrentMemSizeInBytes())
+
+	assertPayloadSizeFromExtractTransactions(a, container, []int{15, 25, 10, 40})
+}
+
+func createEndpointTransactionWithPayloadSize(endpointName string, payloadSize int) *transaction.HTTPTransaction {
+	tr := createTransactionWithPayloadSize(payloadSize)
+	tr.Endpoint.Name = endpointName
+	return tr
+}
+
 func createTransactionWithPayloadSize(payloadSize int) *transaction.HTTPTransaction {
 	tr := transaction.NewHTTPTransaction()
 	payload := make([]byte, payloadSize)
@@ -134,7 +196,7 @@ func newOnDiskRetryQueueTest(t *testing.T, a *assert.Assertions) *onDiskRetryQue
 			Total:     10000,
 		}}
 	diskUsageLimit := NewDiskUsageLimit("", disk, 1000, 1)
-	q, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver("", nil)), path, diskUsageLimit, newOnDiskRetryQueueTelemetry("domain"))
+	q, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver("", nil)), path, diskUsageLimit, newOnDiskRetryQueueTelemetry("domain"), nil, nil)
 	a.NoError(err)
 	return q
 }
@@ -0,0 +1,270 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/backoff"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// retryPolicyConfig is the user configuration of a retry policy. Unset fields
+// are inherited from the parent policy.
+type retryPolicyConfig struct {
+	BackoffFactor             *float64                     `mapstructure:"backoff_factor" json:"backoff_factor"`
+	BackoffBase               *float64                     `mapstructure:"backoff_base" json:"backoff_base"`
+	BackoffMax                *float64                     `mapstructure:"backoff_max" json:"backoff_max"`
+	MaxRetryAge               *int                         `mapstructure:"max_retry_age" json:"max_retry_age"`
+	RetryQueuePayloadsMaxSize *int                         `mapstructure:"retry_queue_payloads_max_size" json:"retry_queue_payloads_max_size"`
+	FlushToDiskMemRatio       *float64                     `mapstructure:"flush_to_disk_mem_ratio" json:"flush_to_disk_mem_ratio"`
+	Endpoints                 map[string]retryPolicyConfig `mapstructure:"endpoints" json:"endpoints"`
+}
+
+// retryPolicy defines how the transactions sent to an endpoint are retried.
+type retryPolicy struct {
+	backoffPolicy backoff.Policy
+	// maxRetryAge is the maximum age of a transaction to be retried. 0 means no limit.
+	maxRetryAge time.Duration
+}
+
+// retryQueueLimit defines how much of the retry queue the transactions of an endpoint can use.
+type retryQueueLimit struct {
+	retryQueuePayloadsMaxSize int
+	flushToDiskMemRatio       float64
+}
+
+// domainRetryPolicy defines how the transactions sent to a domain are retried.
+// Endpoints without a specific policy use the policy of the domain.
+type domainRetryPolicy struct {
+	retryPolicy
+	retryQueuePayloadsMaxSize int
+	flushToDiskMemRatio       float64
+	endpoints                 map[string]retryPolicy
+	// endpointQueueLimits are the limits of the endpoints whose policy sets `retry_queue_payloads_max_size`
+	// or `flush_to_disk_mem_ratio`. The other endpoints only share the limit of the domain retry queue.
+	endpointQueueLimits map[string]retryQueueLimit
+}
+
+// forEndpoint returns the retry policy of the endpoint `endpointName`.
+func (p *domainRetryPolicy) forEndpoint(endpointName string) retryPolicy {
+	if policy, ok := p.endpoints[endpointName]; ok {
+		return policy
+	}
+	return p.retryPolicy
+}
+
+// status returns a representation of the policy for the forwarder status.
+func (p *domainRetryPolicy) status() map[string]interface{} {
+	endpoints := make(map[string]interface{}, len(p.endpoints))
+	for name, policy := range p.endpoints {
+		endpointStatus := policy.status()
+		if limit, ok := p.endpointQueueLimits[name]; ok {
+			endpointStatus["RetryQueuePayloadsMaxSize"] = limit.retryQueuePayloadsMaxSize
+			endpointStatus["FlushToDiskMemRatio"] = limit.flushToDiskMemRatio
+		}
+		endpoints[name] = endpointStatus
+	}
+	status := p.retryPolicy.status()
+	status["RetryQueuePayloadsMaxSize"] = p.retryQueuePayloadsMaxSize
+	status["FlushToDiskMemRatio"] = p.flushToDiskMemRatio
+	status["Endpoints"] = endpoints
+	return status
+}
+
+func (p retryPolicy) status() map[string]interface{} {
+	maxRetryAge := "unlimited"
+	if p.maxRetryAge > 0 {
+		maxRetryAge = p.maxRetryAge.String()
+	}
+	return map[string]interface{}{
+		"BackoffFactor": p.backoffPolicy.MinBackoffFactor,
+		"BackoffBase":   p.backoffPolicy.BaseBackoffTime,
+		"BackoffMax":    p.backoffPolicy.MaxBackoffTime,
+		"MaxRetryAge":   maxRetryAge,
+	}
+}
+
+// newDefaultRetryPolicyConfig returns the retry policy configured with the global `forwarder_*` settings.
+func newDefaultRetryPolicyConfig(retryQueuePayloadsMaxSize int) retryPolicyConfig {
+	backoffFactor := config.Datadog.GetFloat64("forwarder_backoff_factor")
+	if backoffFactor < 2 {
+		log.Warnf("Configured forwarder_backoff_factor (%v) is less than 2; 2 will be used", backoffFactor)
+		backoffFactor = 2
+	}
+
+	backoffBase := config.Datadog.GetFloat64("forwarder_backoff_base")
+	if backoffBase <= 0 {
+		log.Warnf("Configured forwarder_backoff_base (%v) is not positive; 2 will be used", backoffBase)
+		backoffBase = 2
+	}
+
+	backoffMax := config.Datadog.GetFloat64("forwarder_backoff_max")
+	if backoffMax <= 0 {
+		log.Warnf("Configured forwarder_backoff_max (%v) is not positive; 64 seconds will be used", backoffMax)
+		backoffMax = 64
+	}
+
+	maxRetryAge := config.Datadog.GetInt("forwarder_retry_max_age")
+	if maxRetryAge < 0 {
+		log.Warnf("Configured forwarder_retry_max_age (%v) is negative; transactions will be retried regardless of their age", maxRetryAge)
+		maxRetryAge = 0
+	}
+
+	flushToDiskMemRatio := config.Datadog.GetFloat64("forwarder_flush_to_disk_mem_ratio")
+
+	return retryPolicyConfig{
+		BackoffFactor:             &backoffFactor,
+		BackoffBase:               &backoffBase,
+		BackoffMax:                &backoffMax,
+		MaxRetryAge:               &maxRetryAge,
+		RetryQueuePayloadsMaxSize: &retryQueuePayloadsMaxSize,
+		FlushToDiskMemRatio:       &flushToDiskMemRatio,
+	}
+}
+
+// buildDomainRetryPolicy builds the retry policy of `domain`. From the lowest to the highest precedence,
+// the settings are taken from the global `forwarder_*` settings, the domain settings in `forwarder_domain_retry_policies`,
+// the endpoint settings in `forwarder_endpoint_retry_policies` and the endpoint settings of the domain.
+func buildDomainRetryPolicy(domain string, retryQueuePayloadsMaxSize int) *domainRetryPolicy {
+	defaultConfig := newDefaultRetryPolicyConfig(retryQueuePayloadsMaxSize)
+
+	recInterval := config.Datadog.GetInt("forwarder_recovery_interval")
+	if recInterval <= 0 {
+		log.Warnf("Configured forwarder_recovery_interval (%v) is not positive; %v will be used", recInterval, config.DefaultForwarderRecoveryInterval)
+		recInterval = config.DefaultForwarderRecoveryInterval
+	}
+	recoveryReset := config.Datadog.GetBool("forwarder_recovery_reset")
+
+	endpointConfigs := map[string]retryPolicyConfig{}
+	if config.Datadog.IsSet("forwarder_endpoint_retry_policies") {
+		if err := config.Datadog.UnmarshalKey("forwarder_endpoint_retry_policies", &endpointConfigs); err != nil {
+			log.Errorf("Could not parse forwarder_endpoint_retry_policies: %v", err)
+		}
+	}
+	ignoreNestedEndpointConfigs(endpointConfigs, "forwarder_endpoint_retry_policies")
+
+	domainConfig := retryPolicyConfig{}
+	if config.Datadog.IsSet("forwarder_domain_retry_policies") {
+		domainConfigs := map[string]retryPolicyConfig{}
+		if err := config.Datadog.UnmarshalKey("forwarder_domain_retry_policies", &domainConfigs); err != nil {
+			log.Errorf("Could not parse forwarder_domain_retry_policies: %v", err)
+		}
+		for configDomain, c := range domainConfigs {
+			if normalizeRetryPolicyDomain(configDomain) == normalizeRetryPolicyDomain(domain) {
+				domainConfig = c
+				ignoreNestedEndpointConfigs(domainConfig.Endpoints, "forwarder_domain_retry_policies for "+configDomain)
+			}
+		}
+	}
+
+	domainPolicyConfig := domainConfig.inherit(defaultConfig, domain)
+	policy := &domainRetryPolicy{
+		retryPolicy:               domainPolicyConfig.toRetryPolicy(recInterval, recoveryReset),
+		retryQueuePayloadsMaxSize: *domainPolicyConfig.RetryQueuePayloadsMaxSize,
+		flushToDiskMemRatio:       *domainPolicyConfig.FlushToDiskMemRatio,
+		endpoints:                 map[string]retryPolicy{},
+		endpointQueueLimits:       map[string]retryQueueLimit{},
+	}
+
+	for name, c := range endpointConfigs {
+		policy.setEndpoint(name, c.inherit(domainPolicyConfig, name), c.setsRetryQueueLimit(), recInterval, recoveryReset)
+	}
+	for name, c := range domainConfig.Endpoints {
+		parent := domainPolicyConfig
+		endpointConfig, ok := endpointConfigs[name]
+		if ok {
+			parent = endpointConfig.inherit(domainPolicyConfig, name)
+		}
+		limited := c.setsRetryQueueLimit() || endpointConfig.setsRetryQueueLimit()
+		policy.setEndpoint(name, c.inherit(parent, domain+" "+name), limited, recInterval, recoveryReset)
+	}
+	return policy
+}
+
+// setEndpoint sets the policy of the endpoint `name`. When `limited` is true, the transactions
+// of the endpoint are limited by the retry queue settings of `c`.
+func (p *domainRetryPolicy) setEndpoint(name string, c retryPolicyConfig, limited bool, recInterval int, recoveryReset bool) {
+	p.endpoints[name] = c.toRetryPolicy(recInterval, recoveryReset)
+	delete(p.endpointQueueLimits, name)
+	if limited {
+		p.endpointQueueLimits[name] = retryQueueLimit{
+			retryQueuePayloadsMaxSize: *c.RetryQueuePayloadsMaxSize,
+			flushToDiskMemRatio:       *c.FlushToDiskMemRatio,
+		}
+	}
+}
+
+// setsRetryQueueLimit returns whether `c` sets one of the retry queue settings.
+func (c retryPolicyConfig) setsRetryQueueLimit() bool {
+	return c.RetryQueuePayloadsMaxSize != nil || c.FlushToDiskMemRatio != nil
+}
+
+// inherit returns a copy of `c` where the unset or invalid fields are taken from `parent`.
+func (c retryPolicyConfig) inherit(parent retryPolicyConfig, name string) retryPolicyConfig {
+	if c.BackoffFactor == nil || *c.BackoffFactor < 2 {
+		if c.BackoffFactor != nil {
+			log.Warnf("Configured backoff_factor (%v) for %s is less than 2; %v will be used", *c.BackoffFactor, name, *parent.BackoffFactor)
+		}
+		c.BackoffFactor = parent.BackoffFactor
+	}
+	if c.BackoffBase == nil || *c.BackoffBase <= 0 {
+		if c.BackoffBase != nil {
+			log.Warnf("Configured backoff_base (%v) for %s is not positive; %v will be used", *c.BackoffBase, name, *parent.BackoffBase)
+		}
+		c.BackoffBase = parent.BackoffBase
+	}
+	if c.BackoffMax == nil || *c.BackoffMax <= 0 {
+		if c.BackoffMax != nil {
+			log.Warnf("Configured backoff_max (%v) for %s is not positive; %v seconds will be used", *c.BackoffMax, name, *parent.BackoffMax)
+		}
+		c.BackoffMax = parent.BackoffMax
+	}
+	if c.MaxRetryAge == nil || *c.MaxRetryAge < 0 {
+		if c.MaxRetryAge != nil {
+			log.Warnf("Configured max_retry_age (%v) for %s is negative; %v will be used", *c.MaxRetryAge, name, *parent.MaxRetryAge)
+		}
+		c.MaxRetryAge = parent.MaxRetryAge
+	}
+	if c.RetryQueuePayloadsMaxSize == nil || *c.RetryQueuePayloadsMaxSize < 0 {
+		if c.RetryQueuePayloadsMaxSize != nil {
+			log.Warnf("Configured retry_queue_payloads_max_size (%v) for %s is negative; %v will be used", *c.RetryQueuePayloadsMaxSize, name, *parent.RetryQueuePayloadsMaxSize)
+		}
+		c.RetryQueuePayloadsMaxSize = parent.RetryQueuePayloadsMaxSize
+	}
+	if c.FlushToDiskMemRatio == nil || *c.FlushToDiskMemRatio < 0 || *c.FlushToDiskMemRatio > 1 {
+		if c.FlushToDiskMemRatio != nil {
+			log.Warnf("Configured flush_to_disk_mem_ratio (%v) for %s is not between 0 and 1; %v will be used", *c.FlushToDiskMemRatio, name, *parent.FlushToDiskMemRatio)
+		}
+		c.FlushToDiskMemRatio = parent.FlushToDiskMemRatio
+	}
+	return c
+}
+
+func (c retryPolicyConfig) toRetryPolicy(recInterval int, recoveryReset bool) retryPolicy {
+	return retryPolicy{
+		backoffPolicy: backoff.NewPolicy(*c.BackoffFactor, *c.BackoffBase, *c.BackoffMax, recInterval, recoveryReset),
+		maxRetryAge:   time.Duration(*c.MaxRetryAge) * time.Second,
+	}
+}
+
+// ignoreNestedEndpointConfigs removes the endpoints set in the endpoint policies of `endpointConfigs`,
+// as an endpoint policy cannot contain endpoints. The rest of these policies still applies.
+func ignoreNestedEndpointConfigs(endpointConfigs map[string]retryPolicyConfig, setting string) {
+	for name, c := range endpointConfigs {
+		if len(c.Endpoints) > 0 {
+			log.Warnf("Ignoring the endpoints set in the retry policy of the endpoint %s in %s: an endpoint policy cannot contain endpoints", name, setting)
+			c.Endpoints = nil
+			endpointConfigs[name] = c
+		}
+	}
+}
+
+func normalizeRetryPolicyDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), "/")
+}
@@ -0,0 +1,215 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package forwarder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+)
+
+func TestBuildDomainRetryPolicyDefault(t *testing.T) {
+	config.Mock(t)
+
+	policy := buildDomainRetryPolicy("https://app.datadoghq.com", 1000)
+	assert.Equal(t, float64(2), policy.backoffPolicy.MinBackoffFactor)
+	assert.Equal(t, float64(2), policy.backoffPolicy.BaseBackoffTime)
+	assert.Equal(t, float64(64), policy.backoffPolicy.MaxBackoffTime)
+	assert.Equal(t, time.Duration(0), policy.maxRetryAge)
+	assert.Equal(t, 1000, policy.retryQueuePayloadsMaxSize)
+	assert.Equal(t, 0.5, policy.flushToDiskMemRatio)
+	assert.Empty(t, policy.endpoints)
+	assert.Equal(t, policy.retryPolicy, policy.forEndpoint("series_v2"))
+}
+
+func TestBuildDomainRetryPolicy(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_backoff_max", 32)
+	mockConfig.Set("forwarder_retry_max_age", 3600)
+	mockConfig.Set("forwarder_endpoint_retry_policies", map[string]interface{}{
+		"series_v2": map[string]interface{}{
+			"backoff_base":  4,
+			"max_retry_age": 60,
+		},
+		"intake": map[string]interface{}{
+			"backoff_factor": 3,
+		},
+	})
+	mockConfig.Set("forwarder_domain_retry_policies", map[string]interface{}{
+		"https://app.datadoghq.eu/": map[string]interface{}{
+			"backoff_max":                   128,
+			"max_retry_age":                 86400,
+			"retry_queue_payloads_max_size": 2000,
+			"flush_to_disk_mem_ratio":       0.3,
+			"endpoints": map[string]interface{}{
+				"series_v2": map[string]interface{}{
+					"backoff_max": 256,
+				},
+				"sketches_v2": map[string]interface{}{
+					"max_retry_age": 600,
+				},
+			},
+		},
+	})
+
+	policy := buildDomainRetryPolicy("https://app.datadoghq.com", 1000)
+	assert.Equal(t, float64(32), policy.backoffPolicy.MaxBackoffTime)
+	assert.Equal(t, time.Hour, policy.maxRetryAge)
+	assert.Equal(t, 1000, policy.retryQueuePayloadsMaxSize)
+	assert.Equal(t, 0.5, policy.flushToDiskMemRatio)
+	series := policy.forEndpoint("series_v2")
+	assert.Equal(t, float64(4), series.backoffPolicy.BaseBackoffTime)
+	assert.Equal(t, float64(32), series.backoffPolicy.MaxBackoffTime)
+	assert.Equal(t, time.Minute, series.maxRetryAge)
+	assert.Equal(t, float64(3), policy.forEndpoint("intake").backoffPolicy.MinBackoffFactor)
+
+	policy = buildDomainRetryPolicy("https://app.datadoghq.eu", 1000)
+	assert.Equal(t, float64(128), policy.backoffPolicy.MaxBackoffTime)
+	assert.Equal(t, 24*time.Hour, policy.maxRetryAge)
+	assert.Equal(t, 2000, policy.retryQueuePayloadsMaxSize)
+	assert.Equal(t, 0.3, policy.flushToDiskMemRatio)
+	series = policy.forEndpoint("series_v2")
+	assert.Equal(t, float64(4), series.backoffPolicy.BaseBackoffTime)
+	assert.Equal(t, float64(256), series.backoffPolicy.MaxBackoffTime)
+	assert.Equal(t, time.Minute, series.maxRetryAge)
+	sketches := policy.forEndpoint("sketches_v2")
+	assert.Equal(t, float64(128), sketches.backoffPolicy.MaxBackoffTime)
+	assert.Equal(t, 10*time.Minute, sketches.maxRetryAge)
+	assert.Equal(t, policy.retryPolicy, policy.forEndpoint("check_run_v1"))
+}
+
+func TestBuildDomainRetryPolicyEndpointQueueLimits(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_endpoint_retry_policies", map[string]interface{}{
+		"series_v2": map[string]interface{}{
+			"backoff_base":                  4,
+			"retry_queue_payloads_max_size": 200,
+		},
+		"intake": map[string]interface{}{
+			"backoff_factor": 3,
+		},
+	})
+	mockConfig.Set("forwarder_domain_retry_policies", map[string]interface{}{
+		"https://app.datadoghq.com": map[string]interface{}{
+			"backoff_max": 128,
+			"endpoints": map[string]interface{}{
+				"sketches_v2": map[string]interface{}{
+					"max_retry_age":           600,
+					"flush_to_disk_mem_ratio": 0.3,
+				},
+				"series_v2": map[string]interface{}{
+					"flush_to_disk_mem_ratio": 0.2,
+				},
+			},
+		},
+	})
+
+	policy := buildDomainRetryPolicy("https://app.datadoghq.com", 1000)
+	assert.Equal(t, 1000, policy.retryQueuePayloadsMaxSize)
+	assert.Equal(t, 0.5, policy.flushToDiskMemRatio)
+	assert.Equal(t, float64(4), policy.forEndpoint("series_v2").backoffPolicy.BaseBackoffTime)
+	assert.Equal(t, 600*time.Second, policy.forEndpoint("sketches_v2").maxRetryAge)
+	assert.Equal(t, float64(3), policy.forEndpoint("intake").backoffPolicy.MinBackoffFactor)
+
+	// only the endpoints setting the retry queue settings have their own limit
+	assert.Equal(t, map[string]retryQueueLimit{
+		"series_v2":   {retryQueuePayloadsMaxSize: 200, flushToDiskMemRatio: 0.2},
+		"sketches_v2": {retryQueuePayloadsMaxSize: 1000, flushToDiskMemRatio: 0.3},
+	}, policy.endpointQueueLimits)
+
+	endpoints := policy.status()["Endpoints"].(map[string]interface{})
+	assert.Equal(t, 200, endpoints["series_v2"].(map[string]interface{})["RetryQueuePayloadsMaxSize"])
+	assert.Equal(t, 0.2, endpoints["series_v2"].(map[string]interface{})["FlushToDiskMemRatio"])
+	assert.NotContains(t, endpoints["intake"], "RetryQueuePayloadsMaxSize")
+}
+
+func TestBuildDomainRetryPolicyInvalidValues(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_domain_retry_policies", map[string]interface{}{
+		"https://app.datadoghq.com": map[string]interface{}{
+			"backoff_factor":          1,
+			"backoff_base":            0,
+			"backoff_max":             -1,
+			"max_retry_age":           -1,
+			"flush_to_disk_mem_ratio": 2,
+		},
+	})
+
+	policy := buildDomainRetryPolicy("https://app.datadoghq.com", 1000)
+	assert.Equal(t, float64(2), policy.backoffPolicy.MinBackoffFactor)
+	assert.Equal(t, float64(2), policy.backoffPolicy.BaseBackoffTime)
+	assert.Equal(t, float64(64), policy.backoffPolicy.MaxBackoffTime)
+	assert.Equal(t, time.Duration(0), policy.maxRetryAge)
+	assert.Equal(t, 0.5, policy.flushToDiskMemRatio)
+}
+
+func TestBlockedEndpointsEndpointBackoffPolicy(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_endpoint_retry_policies", map[string]interface{}{
+		"series_v2": map[string]interface{}{
+			"backoff_base": 1,
+			"backoff_max":  1,
+		},
+	})
+
+	e := newBlockedEndpointsWithRetryPolicy(buildDomainRetryPolicy("test", 0))
+	e.closeWithEndpointName("series", "series_v2")
+	e.closeWithEndpointName("series", "series_v2")
+	e.closeWithEndpointName("series", "series_v2")
+	assert.LessOrEqual(t, time.Until(e.errorPerEndpoint["series"].until), time.Second)
+
+	e.closeWithEndpointName("sketches", "sketches_v2")
+	e.closeWithEndpointName("sketches", "sketches_v2")
+	e.closeWithEndpointName("sketches", "sketches_v2")
+	assert.Greater(t, time.Until(e.errorPerEndpoint["sketches"].until), time.Second)
+}
+
+func TestForwarderRetryMaxAge(t *testing.T) {
+	mockConfig := config.Mock(t)
+	mockConfig.Set("forwarder_endpoint_retry_policies", map[string]interface{}{
+		"series_v2": map[string]interface{}{
+			"max_retry_age": 60,
+		},
+	})
+	forwarder := newDomainForwarderForTest(0)
+	forwarder.init()
+	transaction.TransactionsDropped.Set(0)
+	transactionsExpired.Set(0)
+
+	payload := []byte{1}
+	expired := transaction.NewHTTPTransaction()
+	expired.Endpoint.Name = "series_v2"
+	expired.Payload = &payload
+	expired.CreatedAt = time.Now().Add(-2 * time.Minute)
+	recent := transaction.NewHTTPTransaction()
+	recent.Endpoint.Name = "series_v2"
+	recent.Payload = &payload
+	oldWithoutMaxAge := transaction.NewHTTPTransaction()
+	oldWithoutMaxAge.Endpoint.Name = "sketches_v2"
+	oldWithoutMaxAge.Payload = &payload
+	oldWithoutMaxAge.CreatedAt = time.Now().Add(-2 * time.Minute)
+
+	forwarder.retryQueue.Add(expired)
+	forwarder.retryQueue.Add(recent)
+	forwarder.retryTransactions(time.Now())
+
+	assert.Len(t, forwarder.lowPrio, 1)
+	assert.Equal(t, recent, <-forwarder.lowPrio)
+	assert.Equal(t, int64(1), transactionsExpired.Value())
+	assert.Equal(t, int64(1), transaction.TransactionsDropped.Value())
+
+	forwarder.retryQueue.Add(oldWithoutMaxAge)
+	forwarder.retryTransactions(time.Now())
+	assert.Len(t, forwarder.lowPrio, 1)
+	assert.Equal(t, int64(1), transactionsExpired.Value())
+}
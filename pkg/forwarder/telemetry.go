@@ -26,6 +26,8 @@ var (
 	transactionsRetried              = expvar.Int{}
 	transactionsRetriedByEndpoint    = expvar.Map{}
 	transactionsRetryQueueSize       = expvar.Int{}
+	transactionsExpired              = expvar.Int{}
+	retryPoliciesExpvar              = expvar.Map{}
 
 	tlmTxInputBytes = telemetry.NewCounter("transactions", "input_bytes",
 		[]string{"domain", "endpoint"}, "Incoming transaction sizes in bytes")
@@ -39,6 +41,8 @@ var (
 		[]string{"domain", "endpoint"}, "Transaction retry count")
 	tlmTxRetryQueueSize = telemetry.NewGauge("transactions", "retry_queue_size",
 		[]string{"domain"}, "Retry queue size")
+	tlmTxExpired = telemetry.NewCounter("transactions", "expired",
+		[]string{"domain", "endpoint"}, "Count of transactions dropped because they exceed the maximum retry age")
 )
 
 func init() {
@@ -102,4 +106,6 @@ func initTransactionsExpvars() {
 	transaction.TransactionsExpvars.Set("Retried", &transactionsRetried)
 	transaction.TransactionsExpvars.Set("RetriedByEndpoint", &transactionsRetriedByEndpoint)
 	transaction.TransactionsExpvars.Set("RetryQueueSize", &transactionsRetryQueueSize)
+	transaction.TransactionsExpvars.Set("Expired", &transactionsExpired)
+	transaction.ForwarderExpvars.Set("RetryPolicies", &retryPoliciesExpvar)
 }
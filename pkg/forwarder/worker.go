@@ -179,11 +179,11 @@ func (w *Worker) process(ctx context.Context, t transaction.Transaction) {
 		requeue()
 		log.Errorf("Too many errors for endpoint '%s': retrying later", target)
 	} else if err := t.Process(ctx, w.Client); err != nil {
-		w.blockedList.close(target)
+		w.blockedList.closeWithEndpointName(target, t.GetEndpointName())
 		requeue()
 		log.Errorf("Error while processing transaction: %v", err)
 	} else {
-		w.blockedList.recover(target)
+		w.blockedList.recoverWithEndpointName(target, t.GetEndpointName())
 	}
 }
 
@@ -78,7 +78,7 @@ func createAgent(endpoints *config.Endpoints) (*Agent, *sources.LogSources, *ser
 	services := service.NewServices()
 
 	// setup and start the agent
-	agent = NewAgent(sources, services, nil, endpoints)
+	agent = NewAgent(sources, services, nil, nil, endpoints)
 	return agent, sources, services
 }
 
@@ -11,6 +11,9 @@ import (
 
 // v2: In the third version of the auditor, we dropped Timestamp and used a generic Offset instead to reinforce the separation of concerns
 // between the auditor and log sources.
+// v3: In the fourth version of the auditor, we added the Fingerprint of the files to identify them by their content
+// and not only by their path. The field is optional and the format is otherwise unchanged, so both versions are read
+// the same way, and the registries without fingerprints are still written with the version 2.
 
 func unmarshalRegistryV2(b []byte) (map[string]*RegistryEntry, error) {
 	var r JSONRegistry
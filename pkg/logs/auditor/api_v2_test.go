@@ -34,3 +34,32 @@ func TestAuditorUnmarshalRegistryV2(t *testing.T) {
 	assert.Equal(t, "2006-01-12T01:01:03.000000001Z", r["path2.log"].Offset)
 	assert.Equal(t, 2, r["path2.log"].LastUpdated.Second())
 }
+
+func TestAuditorUnmarshalRegistryV3(t *testing.T) {
+	input := `{
+	    "Registry": {
+	        "file:/var/log/app.log": {
+	            "Offset": "1",
+	            "LastUpdated": "2006-01-12T01:01:01.000000001Z",
+	            "TailingMode": "end",
+	            "Fingerprint": "1024:e3b0c44298fc1c14"
+	        },
+	        "journald:": {
+	            "Offset": "cursor",
+	            "LastUpdated": "2006-01-12T01:01:02.000000001Z"
+	        }
+	    },
+	    "Version": 3
+	}`
+	r, err := unmarshalRegistryV2([]byte(input))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "1", r["file:/var/log/app.log"].Offset)
+	assert.Equal(t, "end", r["file:/var/log/app.log"].TailingMode)
+	assert.Equal(t, "1024:e3b0c44298fc1c14", r["file:/var/log/app.log"].Fingerprint)
+	assert.Equal(t, 1, r["file:/var/log/app.log"].LastUpdated.Second())
+
+	assert.Equal(t, "cursor", r["journald:"].Offset)
+	assert.Equal(t, "", r["journald:"].Fingerprint)
+	assert.Equal(t, 2, r["journald:"].LastUpdated.Second())
+}
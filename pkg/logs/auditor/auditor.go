@@ -28,12 +28,18 @@ const defaultFlushPeriod = 1 * time.Second
 const defaultCleanupPeriod = 300 * time.Second
 
 // latest version of the API used by the auditor to retrieve the registry from disk.
-const registryAPIVersion = 2
+const registryAPIVersion = 3
+
+// version of the API used to write the registries without fingerprints, so that they can still be
+// read by the agents not supporting them.
+const registryAPIVersionWithoutFingerprints = 2
 
 // Registry holds a list of offsets.
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	GetFingerprint(identifier string) string
+	GetIdentifierForFingerprint(fingerprint string) string
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -43,6 +49,7 @@ type RegistryEntry struct {
 	Offset             string
 	TailingMode        string
 	IngestionTimestamp int64
+	Fingerprint        string `json:",omitempty"`
 }
 
 // JSONRegistry represents the registry that will be written on disk
@@ -62,10 +69,12 @@ type Auditor interface {
 
 // A RegistryAuditor is storing the Auditor information using a registry.
 type RegistryAuditor struct {
-	health        *health.Handle
-	chansMutex    sync.Mutex
-	inputChan     chan *message.Payload
-	registry      map[string]*RegistryEntry
+	health     *health.Handle
+	chansMutex sync.Mutex
+	inputChan  chan *message.Payload
+	registry   map[string]*RegistryEntry
+	// fingerprints indexes the identifier of the most recently updated entry by fingerprint.
+	fingerprints  map[string]string
 	registryPath  string
 	registryMutex sync.Mutex
 	entryTTL      time.Duration
@@ -78,6 +87,7 @@ func New(runPath string, filename string, ttl time.Duration, health *health.Hand
 		health:       health,
 		registryPath: filepath.Join(runPath, filename),
 		entryTTL:     ttl,
+		fingerprints: make(map[string]string),
 	}
 }
 
@@ -85,6 +95,7 @@ func New(runPath string, filename string, ttl time.Duration, health *health.Hand
 func (a *RegistryAuditor) Start() {
 	a.createChannels()
 	a.registry = a.recoverRegistry()
+	a.fingerprints = indexFingerprints(a.registry)
 	a.cleanupRegistry()
 	go a.run()
 }
@@ -149,6 +160,28 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetFingerprint returns the fingerprint of the file registered for a given identifier,
+// returns an empty string if it does not exist.
+func (a *RegistryAuditor) GetFingerprint(identifier string) string {
+	r := a.readOnlyRegistryCopy()
+	entry, exists := r[identifier]
+	if !exists {
+		return ""
+	}
+	return entry.Fingerprint
+}
+
+// GetIdentifierForFingerprint returns the identifier of the most recently updated entry
+// registered with a given fingerprint, returns an empty string if it does not exist.
+func (a *RegistryAuditor) GetIdentifierForFingerprint(fingerprint string) string {
+	if fingerprint == "" {
+		return ""
+	}
+	a.registryMutex.Lock()
+	defer a.registryMutex.Unlock()
+	return a.fingerprints[fingerprint]
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
@@ -171,7 +204,7 @@ func (a *RegistryAuditor) run() {
 			}
 			// update the registry with new entry
 			for _, msg := range payload.Messages {
-				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.IngestionTimestamp)
+				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.Origin.Fingerprint, msg.IngestionTimestamp)
 			}
 		case <-cleanUpTicker.C:
 			// remove expired offsets from registry
@@ -216,15 +249,20 @@ func (a *RegistryAuditor) cleanupRegistry() {
 	a.registryMutex.Lock()
 	defer a.registryMutex.Unlock()
 	expireBefore := time.Now().UTC().Add(-a.entryTTL)
+	var expired bool
 	for path, entry := range a.registry {
 		if entry.LastUpdated.Before(expireBefore) {
 			delete(a.registry, path)
+			expired = true
 		}
 	}
+	if expired {
+		a.fingerprints = indexFingerprints(a.registry)
+	}
 }
 
-// updateRegistry updates the registry entry matching identifier with new the offset and timestamp
-func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, ingestionTimestamp int64) {
+// updateRegistry updates the registry entry matching identifier with new the offset, fingerprint and timestamp
+func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, fingerprint string, ingestionTimestamp int64) {
 	a.registryMutex.Lock()
 	defer a.registryMutex.Unlock()
 	if identifier == "" {
@@ -240,6 +278,12 @@ func (a *RegistryAuditor) updateRegistry(identifier string, offset string, taili
 		if v.IngestionTimestamp > ingestionTimestamp {
 			return
 		}
+		if v.Fingerprint != fingerprint {
+			a.unindexFingerprint(identifier, v.Fingerprint)
+		}
+	}
+	if fingerprint != "" {
+		a.fingerprints[fingerprint] = identifier
 	}
 
 	a.registry[identifier] = &RegistryEntry{
@@ -247,9 +291,41 @@ func (a *RegistryAuditor) updateRegistry(identifier string, offset string, taili
 		Offset:             offset,
 		TailingMode:        tailingMode,
 		IngestionTimestamp: ingestionTimestamp,
+		Fingerprint:        fingerprint,
+	}
+}
+
+// unindexFingerprint points fingerprint to the most recently updated entry other than identifier,
+// when the entry identifier no longer has this fingerprint. a.registryMutex must be held.
+func (a *RegistryAuditor) unindexFingerprint(identifier string, fingerprint string) {
+	if fingerprint == "" || a.fingerprints[fingerprint] != identifier {
+		return
+	}
+	delete(a.fingerprints, fingerprint)
+	var lastUpdated time.Time
+	for id, entry := range a.registry {
+		if id != identifier && entry.Fingerprint == fingerprint && (a.fingerprints[fingerprint] == "" || entry.LastUpdated.After(lastUpdated)) {
+			a.fingerprints[fingerprint] = id
+			lastUpdated = entry.LastUpdated
+		}
 	}
 }
 
+// indexFingerprints returns the identifier of the most recently updated entry of registry by fingerprint.
+func indexFingerprints(registry map[string]*RegistryEntry) map[string]string {
+	fingerprints := make(map[string]string)
+	for identifier, entry := range registry {
+		if entry.Fingerprint == "" {
+			continue
+		}
+		if other, ok := fingerprints[entry.Fingerprint]; ok && !entry.LastUpdated.After(registry[other].LastUpdated) {
+			continue
+		}
+		fingerprints[entry.Fingerprint] = identifier
+	}
+	return fingerprints
+}
+
 // readOnlyRegistryCopy returns a read only copy of the registry
 func (a *RegistryAuditor) readOnlyRegistryCopy() map[string]RegistryEntry {
 	a.registryMutex.Lock()
@@ -274,9 +350,15 @@ func (a *RegistryAuditor) flushRegistry() error {
 // marshalRegistry marshals a registry
 func (a *RegistryAuditor) marshalRegistry(registry map[string]RegistryEntry) ([]byte, error) {
 	r := JSONRegistry{
-		Version:  registryAPIVersion,
+		Version:  registryAPIVersionWithoutFingerprints,
 		Registry: registry,
 	}
+	for _, entry := range registry {
+		if entry.Fingerprint != "" {
+			r.Version = registryAPIVersion
+			break
+		}
+	}
 	return json.Marshal(r)
 }
 
@@ -293,7 +375,7 @@ func (a *RegistryAuditor) unmarshalRegistry(b []byte) (map[string]*RegistryEntry
 	}
 	// ensure backward compatibility
 	switch int(version) {
-	case 2:
+	case 2, 3:
 		return unmarshalRegistryV2(b)
 	case 1:
 		return unmarshalRegistryV1(b)
@@ -59,14 +59,15 @@ func (suite *AuditorTestSuite) TestAuditorStartStop() {
 func (suite *AuditorTestSuite) TestAuditorUpdatesRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.Equal(0, len(suite.a.registry))
-	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", 0)
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", "", 0)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("end", suite.a.registry[suite.source.Config.Path].TailingMode)
-	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", 1)
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", "4:f00d", 1)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("43", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("beginning", suite.a.registry[suite.source.Config.Path].TailingMode)
+	suite.Equal("4:f00d", suite.a.registry[suite.source.Config.Path].Fingerprint)
 }
 
 func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
@@ -86,6 +87,23 @@ func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
 }
 
+func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistryWithFingerprints() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+		TailingMode: "end",
+		Fingerprint: "4:f00d",
+	}
+	suite.a.flushRegistry()
+	r, err := ioutil.ReadFile(suite.testPath)
+	suite.Nil(err)
+	suite.Equal("{\"Version\":3,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\",\"IngestionTimestamp\":0,\"Fingerprint\":\"4:f00d\"}}}", string(r))
+
+	suite.a.registry = suite.a.recoverRegistry()
+	suite.Equal("4:f00d", suite.a.registry[suite.source.Config.Path].Fingerprint)
+}
+
 func (suite *AuditorTestSuite) TestAuditorRecoversRegistryForOffset() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
@@ -100,6 +118,44 @@ func (suite *AuditorTestSuite) TestAuditorRecoversRegistryForOffset() {
 	suite.Equal("", offset)
 }
 
+func (suite *AuditorTestSuite) TestAuditorRecoversRegistryForFingerprint() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry["file:/var/log/app.log"] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+		Fingerprint: "4:f00d",
+	}
+	suite.a.registry["file:/var/log/archive/app.log"] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 2, 1, time.UTC),
+		Offset:      "43",
+		Fingerprint: "4:f00d",
+	}
+	suite.a.registry["file:/var/log/other.log"] = &RegistryEntry{
+		Offset: "44",
+	}
+	suite.a.fingerprints = indexFingerprints(suite.a.registry)
+
+	suite.Equal("4:f00d", suite.a.GetFingerprint("file:/var/log/app.log"))
+	suite.Equal("", suite.a.GetFingerprint("file:/var/log/other.log"))
+	suite.Equal("", suite.a.GetFingerprint("file:/var/log/missing.log"))
+
+	// the most recently updated entry wins
+	suite.Equal("file:/var/log/archive/app.log", suite.a.GetIdentifierForFingerprint("4:f00d"))
+	suite.Equal("", suite.a.GetIdentifierForFingerprint("4:beef"))
+	suite.Equal("", suite.a.GetIdentifierForFingerprint(""))
+
+	// the index follows the updates of the registry
+	suite.a.updateRegistry("file:/var/log/app.log", "45", "", "4:f00d", 0)
+	suite.Equal("file:/var/log/app.log", suite.a.GetIdentifierForFingerprint("4:f00d"))
+	suite.a.updateRegistry("file:/var/log/app.log", "0", "", "4:beef", 0)
+	suite.Equal("file:/var/log/archive/app.log", suite.a.GetIdentifierForFingerprint("4:f00d"))
+	suite.Equal("file:/var/log/app.log", suite.a.GetIdentifierForFingerprint("4:beef"))
+	suite.a.entryTTL = 0
+	suite.a.cleanupRegistry()
+	suite.Equal("", suite.a.GetIdentifierForFingerprint("4:f00d"))
+	suite.Equal("", suite.a.GetIdentifierForFingerprint("4:beef"))
+}
+
 func (suite *AuditorTestSuite) TestAuditorCleansupRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
@@ -32,6 +32,12 @@ func (a *NullAuditor) GetOffset(identifier string) string { return "" }
 // GetTailingMode returns an empty string.
 func (a *NullAuditor) GetTailingMode(identifier string) string { return "" }
 
+// GetFingerprint returns an empty string.
+func (a *NullAuditor) GetFingerprint(identifier string) string { return "" }
+
+// GetIdentifierForFingerprint returns an empty string.
+func (a *NullAuditor) GetIdentifierForFingerprint(fingerprint string) string { return "" }
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
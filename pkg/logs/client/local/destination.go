@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package local implements the destinations writing the logs to a local file or to stdout.
+package local
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// retryInterval is the interval between two attempts to write a payload for the destinations retrying.
+const retryInterval = time.Second
+
+// Destination writes the encoded messages of the payloads, one per line, to a rotated
+// file or to stdout. The messages are written as they would be sent to the intake, as
+// JSON objects with the HTTP transport.
+type Destination struct {
+	name                string
+	writer              io.WriteCloser
+	destinationsContext *client.DestinationsContext
+	shouldRetry         bool
+	retryLock           sync.Mutex
+	lastRetryError      error
+}
+
+// NewDestination returns a new destination writing to the file or to stdout of the local endpoint.
+func NewDestination(endpoint config.Endpoint, destinationsContext *client.DestinationsContext, shouldRetry bool) *Destination {
+	var name string
+	var writer io.WriteCloser
+	if endpoint.Type == config.FileEndpointType {
+		name = endpoint.Path
+		writer = newRotatingFile(endpoint.Path, endpoint.MaxSize, endpoint.MaxFiles)
+	} else {
+		name = "stdout"
+		writer = stdout
+	}
+	return &Destination{
+		name:                name,
+		writer:              writer,
+		destinationsContext: destinationsContext,
+		shouldRetry:         shouldRetry,
+	}
+}
+
+// Start reads the payloads from the input and writes them until the input is closed.
+func (d *Destination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
+	stop := make(chan struct{})
+	go func() {
+		for payload := range input {
+			d.writeAndRetry(payload, output, isRetrying)
+		}
+		d.updateRetryState(nil, isRetrying)
+		if err := d.writer.Close(); err != nil {
+			log.Warnf("Could not close %s: %v", d.name, err)
+		}
+		stop <- struct{}{}
+	}()
+	return stop
+}
+
+func (d *Destination) writeAndRetry(payload *message.Payload, output chan *message.Payload, isRetrying chan bool) {
+	var lines bytes.Buffer
+	for _, msg := range payload.Messages {
+		lines.Write(msg.Content)
+		lines.WriteByte('\n')
+	}
+
+	for {
+		_, err := d.writer.Write(lines.Bytes())
+		if err == nil {
+			break
+		}
+		log.Warnf("Could not write logs to %s: %v", d.name, err)
+		metrics.DestinationErrors.Add(1)
+		metrics.TlmDestinationErrors.Inc()
+		if !d.shouldRetry {
+			metrics.DestinationLogsDropped.Add(d.name, int64(len(payload.Messages)))
+			metrics.TlmLogsDropped.Add(float64(len(payload.Messages)), d.name)
+			output <- payload
+			return
+		}
+		d.updateRetryState(err, isRetrying)
+		var stopped <-chan struct{}
+		if ctx := d.destinationsContext.Context(); ctx != nil {
+			stopped = ctx.Done()
+		}
+		select {
+		case <-time.After(retryInterval):
+		case <-stopped:
+			d.updateRetryState(nil, isRetrying)
+			return
+		}
+	}
+	d.updateRetryState(nil, isRetrying)
+
+	metrics.LogsSent.Add(int64(len(payload.Messages)))
+	metrics.TlmLogsSent.Add(float64(len(payload.Messages)))
+	metrics.BytesSent.Add(int64(payload.UnencodedSize))
+	metrics.TlmBytesSent.Add(float64(payload.UnencodedSize))
+	metrics.EncodedBytesSent.Add(int64(lines.Len()))
+	metrics.TlmEncodedBytesSent.Add(float64(lines.Len()))
+	output <- payload
+}
+
+func (d *Destination) updateRetryState(err error, isRetrying chan bool) {
+	d.retryLock.Lock()
+	defer d.retryLock.Unlock()
+
+	if err != nil {
+		if isRetrying != nil && d.lastRetryError == nil {
+			isRetrying <- true
+		}
+	} else {
+		if isRetrying != nil && d.lastRetryError != nil {
+			isRetrying <- false
+		}
+	}
+	d.lastRetryError = err
+}
+
+// stdout is shared by the destinations of all the pipelines, which write whole payloads at once.
+var stdout = &lockedStdout{}
+
+// lockedStdout serializes the writes to stdout and is never closed.
+type lockedStdout struct {
+	mu sync.Mutex
+}
+
+func (w *lockedStdout) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return os.Stdout.Write(b)
+}
+
+func (w *lockedStdout) Close() error {
+	return nil
+}
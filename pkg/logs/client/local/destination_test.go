@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package local
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newPayload(contents ...string) *message.Payload {
+	payload := &message.Payload{Encoded: []byte("compressed")}
+	for _, content := range contents {
+		payload.Messages = append(payload.Messages, &message.Message{Content: []byte(content)})
+	}
+	return payload
+}
+
+func TestFileDestination(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.json")
+	destination := NewDestination(config.Endpoint{Type: config.FileEndpointType, Path: path, MaxSize: 1000, MaxFiles: 1}, client.NewDestinationsContext(), true)
+
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload, 2)
+	stop := destination.Start(input, output, nil)
+
+	// the messages are written one per line, as they were encoded by the processor
+	first := newPayload(`{"message":"hello"}`, `{"message":"world"}`)
+	second := newPayload(`{"message":"bye"}`)
+	input <- first
+	input <- second
+	close(input)
+	<-stop
+
+	assert.Equal(t, first, <-output)
+	assert.Equal(t, second, <-output)
+	assert.Equal(t, "{\"message\":\"hello\"}\n{\"message\":\"world\"}\n{\"message\":\"bye\"}\n", readFile(t, path))
+}
+
+func TestUnreliableFileDestinationDropsPayloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "logs.json")
+	destination := NewDestination(config.Endpoint{Type: config.FileEndpointType, Path: path, MaxSize: 1000, MaxFiles: 1}, client.NewDestinationsContext(), false)
+
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload, 1)
+	stop := destination.Start(input, output, nil)
+
+	payload := newPayload("hello")
+	input <- payload
+	assert.Equal(t, payload, <-output)
+	close(input)
+	<-stop
+	assert.NoFileExists(t, path)
+}
+
+func TestReliableFileDestinationRetries(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "missing", "logs.json")
+	destinationsContext := client.NewDestinationsContext()
+	destinationsContext.Start()
+	destination := NewDestination(config.Endpoint{Type: config.FileEndpointType, Path: path, MaxSize: 1000, MaxFiles: 1}, destinationsContext, true)
+
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload, 1)
+	isRetrying := make(chan bool, 2)
+	stop := destination.Start(input, output, isRetrying)
+
+	input <- newPayload("hello")
+	assert.True(t, <-isRetrying)
+	assert.Empty(t, output)
+
+	// the destination gives up once stopped
+	destinationsContext.Stop()
+	assert.False(t, <-isRetrying)
+	close(input)
+	<-stop
+	assert.Empty(t, output)
+}
@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFile(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.json")
+	f := newRotatingFile(path, 10, 2)
+	defer f.Close()
+
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	// the oldest file is removed
+	assert.Equal(t, "line 4\n", readFile(t, path))
+	assert.Equal(t, "line 3\n", readFile(t, path+".1"))
+	assert.Equal(t, "line 2\n", readFile(t, path+".2"))
+	assert.NoFileExists(t, path+".3")
+
+	// a write larger than the maximum size is not split
+	_, err := f.Write([]byte("a very long line\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "a very long line\n", readFile(t, path))
+	assert.Equal(t, "line 4\n", readFile(t, path+".1"))
+}
+
+func TestRotatingFileAppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.json")
+	require.NoError(t, os.WriteFile(path, []byte("line 1\n"), 0640))
+
+	f := newRotatingFile(path, 10, 2)
+	defer f.Close()
+	_, err := f.Write([]byte("2\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "line 1\n2\n", readFile(t, path))
+
+	// the size of the existing content is accounted for
+	_, err = f.Write([]byte("line 3\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "line 3\n", readFile(t, path))
+	assert.Equal(t, "line 1\n2\n", readFile(t, path+".1"))
+}
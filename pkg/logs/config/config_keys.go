@@ -7,6 +7,8 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"path/filepath"
 	"time"
 
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
@@ -110,12 +112,23 @@ func (l *LogsConfigKeys) compressionLevel() int {
 	return l.getConfig().GetInt(l.getConfigKey("compression_level"))
 }
 
+func (l *LogsConfigKeys) compressionKind() string {
+	return l.getConfig().GetString(l.getConfigKey("compression_kind"))
+}
+
 func (l *LogsConfigKeys) useCompression() bool {
 	return l.getConfig().GetBool(l.getConfigKey("use_compression"))
 }
 
+// hasAdditionalEndpoints returns true if logs are also sent to additional intakes, the local
+// endpoints don't need a specific transport.
 func (l *LogsConfigKeys) hasAdditionalEndpoints() bool {
-	return len(l.getAdditionalEndpoints()) > 0
+	for _, endpoint := range l.getAdditionalEndpoints() {
+		if !endpoint.IsLocal() {
+			return true
+		}
+	}
+	return false
 }
 
 // getLogsAPIKey provides the dd api key used by the main logs agent sender.
@@ -147,7 +160,35 @@ func (l *LogsConfigKeys) getAdditionalEndpoints() []Endpoint {
 	if err != nil {
 		log.Warnf("Could not parse additional_endpoints for logs: %v", err)
 	}
-	return endpoints
+	valid := endpoints[:0]
+	for _, endpoint := range endpoints {
+		if endpoint.IsLocal() {
+			if err := endpoint.validateLocal(); err != nil {
+				log.Warnf("Invalid additional endpoint for logs: %v", err)
+				continue
+			}
+		}
+		valid = append(valid, endpoint)
+	}
+	return valid
+}
+
+// localDestination returns the local endpoint replacing the intake as main endpoint,
+// or nil when the logs are sent to the intake.
+func (l *LogsConfigKeys) localDestination() (*Endpoint, error) {
+	endpoint := &Endpoint{
+		Type:     l.getConfig().GetString(l.getConfigKey("local_destination.type")),
+		Path:     l.getConfig().GetString(l.getConfigKey("local_destination.path")),
+		MaxSize:  l.getConfig().GetInt64(l.getConfigKey("local_destination.max_size_in_bytes")),
+		MaxFiles: l.getConfig().GetInt(l.getConfigKey("local_destination.max_files")),
+	}
+	if !endpoint.IsLocal() {
+		return nil, nil
+	}
+	if err := endpoint.validateLocal(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", l.getConfigKey("local_destination"), err)
+	}
+	return endpoint, nil
 }
 
 func (l *LogsConfigKeys) expectedTagsDuration() time.Duration {
@@ -249,6 +290,28 @@ func (l *LogsConfigKeys) aggregationTimeout() time.Duration {
 	return l.getConfig().GetDuration(l.getConfigKey("aggregation_timeout")) * time.Millisecond
 }
 
+// diskSpool returns the settings of the on-disk spool, or nil when it is disabled.
+func (l *LogsConfigKeys) diskSpool() *DiskSpoolConfig {
+	if !l.getConfig().GetBool(l.getConfigKey("disk_spool.enabled")) {
+		return nil
+	}
+	path := l.getConfig().GetString(l.getConfigKey("disk_spool.path"))
+	if path == "" {
+		path = filepath.Join(l.getConfig().GetString(l.getConfigKey("run_path")), "spool")
+	}
+	key := l.getConfigKey("disk_spool.max_size_in_bytes")
+	maxSize := l.getConfig().GetInt64(key)
+	if maxSize <= 0 {
+		log.Warnf("Invalid %s: %v should be > 0, the logs are not spooled on disk", key, maxSize)
+		return nil
+	}
+	return &DiskSpoolConfig{
+		Path:    path,
+		MaxSize: maxSize,
+		MaxAge:  l.getConfig().GetDuration(l.getConfigKey("disk_spool.max_age")) * time.Second,
+	}
+}
+
 func (l *LogsConfigKeys) useV2API() bool {
 	return l.getConfig().GetBool(l.getConfigKey("use_v2_api"))
 }
@@ -7,6 +7,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
@@ -160,6 +161,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    3,
 		BackoffBase:      1.0,
 		BackoffMax:       2.0,
@@ -174,6 +176,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    3,
 		BackoffBase:      1.0,
 		BackoffMax:       2.0,
@@ -188,6 +191,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    3,
 		BackoffBase:      1.0,
 		BackoffMax:       2.0,
@@ -272,6 +276,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
 		BackoffMax:       coreConfig.DefaultLogsSenderBackoffMax,
@@ -285,6 +290,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
 		BackoffMax:       coreConfig.DefaultLogsSenderBackoffMax,
@@ -298,6 +304,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
 		BackoffMax:       coreConfig.DefaultLogsSenderBackoffMax,
@@ -345,6 +352,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig2() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
 		BackoffMax:       coreConfig.DefaultLogsSenderBackoffMax,
@@ -361,6 +369,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig2() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
 		BackoffMax:       coreConfig.DefaultLogsSenderBackoffMax,
@@ -374,6 +383,7 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig2() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
 		BackoffMax:       coreConfig.DefaultLogsSenderBackoffMax,
@@ -433,6 +443,112 @@ func (suite *ConfigTestSuite) TestMultipleTCPEndpointsInConf() {
 	suite.Equal(expectedEndpoints, endpoints)
 }
 
+func (suite *ConfigTestSuite) TestEndpointsCompressionKind() {
+	suite.config.Set("api_key", "123")
+	suite.config.Set("logs_config.compression_kind", "zstd")
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{
+		{
+			"api_key": "456",
+			"host":    "additional.endpoint",
+			"port":    1234},
+	})
+
+	endpoints, err := BuildHTTPEndpoints("test-track", "test-proto", "test-source")
+
+	suite.Nil(err)
+	suite.Equal("zstd", endpoints.Main.CompressionKind)
+	suite.Len(endpoints.Endpoints, 2)
+	suite.Equal("zstd", endpoints.Endpoints[1].CompressionKind)
+}
+
+func (suite *ConfigTestSuite) TestEndpointsDiskSpool() {
+	suite.config.Set("api_key", "123")
+
+	endpoints, err := BuildHTTPEndpoints("test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Nil(endpoints.DiskSpool)
+
+	suite.config.Set("logs_config.run_path", "/opt/datadog-agent/run")
+	suite.config.Set("logs_config.disk_spool.enabled", true)
+	suite.config.Set("logs_config.disk_spool.max_size_in_bytes", 1000)
+	suite.config.Set("logs_config.disk_spool.max_age", 60)
+	endpoints, err = BuildHTTPEndpoints("test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Equal(&DiskSpoolConfig{Path: filepath.Join("/opt/datadog-agent/run", "spool"), MaxSize: 1000, MaxAge: time.Minute}, endpoints.DiskSpool)
+
+	suite.config.Set("logs_config.disk_spool.path", "/var/spool/datadog")
+	endpoints, err = BuildHTTPEndpoints("test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Equal("/var/spool/datadog", endpoints.DiskSpool.Path)
+
+	suite.config.Set("logs_config.disk_spool.max_size_in_bytes", 0)
+	endpoints, err = BuildHTTPEndpoints("test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Nil(endpoints.DiskSpool)
+}
+
+func (suite *ConfigTestSuite) TestEndpointsLocalDestination() {
+	suite.config.Set("api_key", "123")
+	suite.config.Set("logs_config.local_destination.type", "file")
+	suite.config.Set("logs_config.local_destination.path", "/var/log/datadog/logs.json")
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{
+		{
+			"api_key": "456",
+			"host":    "additional.endpoint",
+			"port":    1234},
+		{
+			"type":        "stdout",
+			"is_reliable": false},
+	})
+
+	endpoints, err := BuildEndpoints(HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.True(endpoints.UseHTTP)
+	suite.Equal(Endpoint{Type: FileEndpointType, Path: "/var/log/datadog/logs.json", MaxSize: 100 * 1024 * 1024, MaxFiles: 5}, endpoints.Main)
+	suite.Len(endpoints.Endpoints, 3)
+	suite.Equal(endpoints.Main, endpoints.Endpoints[0])
+	suite.Equal("additional.endpoint", endpoints.Endpoints[1].Host)
+	suite.Equal("456", endpoints.Endpoints[1].APIKey)
+	suite.True(endpoints.Endpoints[1].UseCompression)
+	suite.Equal(StdoutEndpointType, endpoints.Endpoints[2].Type)
+	suite.False(endpoints.Endpoints[2].GetIsReliable())
+	suite.Equal([]string{
+		"Reliable: Writing logs to /var/log/datadog/logs.json",
+		"Reliable: Sending compressed logs in HTTPS to additional.endpoint on port 1234",
+		"Unreliable: Writing logs to stdout",
+	}, endpoints.GetStatus())
+
+	suite.config.Set("logs_config.local_destination.path", "")
+	_, err = BuildEndpoints(HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Error(err)
+
+	suite.config.Set("logs_config.local_destination.type", "syslog")
+	_, err = BuildEndpoints(HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Error(err)
+}
+
+func (suite *ConfigTestSuite) TestEndpointsAdditionalLocalDestination() {
+	suite.config.Set("api_key", "123")
+	suite.config.Set("logs_config.additional_endpoints", []map[string]interface{}{
+		{
+			"type":              "file",
+			"path":              "/var/log/datadog/logs.json",
+			"max_size_in_bytes": 1000},
+		{
+			"type": "unknown"},
+	})
+
+	// the local endpoints don't force the use of TCP
+	endpoints, err := BuildEndpoints(HTTPConnectivitySuccess, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.True(endpoints.UseHTTP)
+	suite.False(endpoints.Main.IsLocal())
+	suite.Len(endpoints.Endpoints, 2)
+	suite.Equal(FileEndpointType, endpoints.Endpoints[1].Type)
+	suite.Equal(int64(1000), endpoints.Endpoints[1].MaxSize)
+	suite.Equal(5, endpoints.Endpoints[1].MaxFiles)
+}
+
 func (suite *ConfigTestSuite) TestEndpointsSetLogsDDUrl() {
 	suite.config.Set("api_key", "123")
 	suite.config.Set("compliance_config.endpoints.logs_dd_url", "my-proxy:443")
@@ -449,6 +565,7 @@ func (suite *ConfigTestSuite) TestEndpointsSetLogsDDUrl() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
 		BackoffMax:       coreConfig.DefaultLogsSenderBackoffMax,
@@ -494,6 +611,7 @@ func (suite *ConfigTestSuite) TestEndpointsSetDDSite() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
 		BackoffMax:       coreConfig.DefaultLogsSenderBackoffMax,
@@ -529,6 +647,7 @@ func (suite *ConfigTestSuite) TestBuildServerlessEndpoints() {
 		UseSSL:           true,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
 		BackoffMax:       coreConfig.DefaultLogsSenderBackoffMax,
@@ -563,6 +682,7 @@ func getTestEndpoint(host string, port int, ssl bool) Endpoint {
 		UseSSL:           ssl,
 		UseCompression:   true,
 		CompressionLevel: 6,
+		CompressionKind:  "gzip",
 		BackoffFactor:    coreConfig.DefaultLogsSenderBackoffFactor,
 		BackoffBase:      coreConfig.DefaultLogsSenderBackoffBase,
 		BackoffMax:       coreConfig.DefaultLogsSenderBackoffMax,
@@ -32,14 +32,27 @@ const (
 	EPIntakeVersion2
 )
 
+// Types of the local endpoints, the logs are sent to the intake when the type is empty.
+const (
+	FileEndpointType   = "file"
+	StdoutEndpointType = "stdout"
+)
+
+// Default rotation settings of the file endpoints
+const (
+	DefaultFileEndpointMaxSize  = 100 * 1024 * 1024
+	DefaultFileEndpointMaxFiles = 5
+)
+
 // Endpoint holds all the organization and network parameters to send logs to Datadog.
 type Endpoint struct {
 	APIKey                  string `mapstructure:"api_key" json:"api_key"`
 	Host                    string
 	Port                    int
 	UseSSL                  bool
-	UseCompression          bool `mapstructure:"use_compression" json:"use_compression"`
-	CompressionLevel        int  `mapstructure:"compression_level" json:"compression_level"`
+	UseCompression          bool   `mapstructure:"use_compression" json:"use_compression"`
+	CompressionLevel        int    `mapstructure:"compression_level" json:"compression_level"`
+	CompressionKind         string `mapstructure:"compression_kind" json:"compression_kind"`
 	ProxyAddress            string
 	IsReliable              *bool `mapstructure:"is_reliable" json:"is_reliable"`
 	ConnectionResetInterval time.Duration
@@ -54,10 +67,51 @@ type Endpoint struct {
 	TrackType IntakeTrackType
 	Protocol  IntakeProtocol
 	Origin    IntakeOrigin
+
+	// The local endpoints write the encoded logs, one per line, to a file or to
+	// stdout instead of sending them. The files are rotated once they reach
+	// MaxSize bytes and MaxFiles rotated files are kept.
+	Type     string `mapstructure:"type" json:"type"`
+	Path     string `mapstructure:"path" json:"path"`
+	MaxSize  int64  `mapstructure:"max_size_in_bytes" json:"max_size_in_bytes"`
+	MaxFiles int    `mapstructure:"max_files" json:"max_files"`
+}
+
+// IsLocal returns true if the endpoint is a file or stdout.
+func (e *Endpoint) IsLocal() bool {
+	return e.Type != ""
+}
+
+// validateLocal validates the settings of a local endpoint and sets the default rotation settings.
+func (e *Endpoint) validateLocal() error {
+	switch e.Type {
+	case StdoutEndpointType:
+		return nil
+	case FileEndpointType:
+		if e.Path == "" {
+			return fmt.Errorf("no path provided for the file endpoint")
+		}
+		if e.MaxSize <= 0 {
+			e.MaxSize = DefaultFileEndpointMaxSize
+		}
+		if e.MaxFiles <= 0 {
+			e.MaxFiles = DefaultFileEndpointMaxFiles
+		}
+		return nil
+	default:
+		return fmt.Errorf("endpoint type %s is not supported, it must be %s or %s", e.Type, FileEndpointType, StdoutEndpointType)
+	}
 }
 
 // GetStatus returns the endpoint status
 func (e *Endpoint) GetStatus(prefix string, useHTTP bool) string {
+	switch e.Type {
+	case FileEndpointType:
+		return fmt.Sprintf("%sWriting logs to %s", prefix, e.Path)
+	case StdoutEndpointType:
+		return fmt.Sprintf("%sWriting logs to stdout", prefix)
+	}
+
 	compression := "uncompressed"
 	if e.UseCompression {
 		compression = "compressed"
@@ -108,6 +162,15 @@ type Endpoints struct {
 	BatchMaxConcurrentSend int
 	BatchMaxSize           int
 	BatchMaxContentSize    int
+	// DiskSpool is nil when the payloads are not spooled on disk during outages
+	DiskSpool *DiskSpoolConfig
+}
+
+// DiskSpoolConfig holds the settings of the on-disk spool of the reliable destinations.
+type DiskSpoolConfig struct {
+	Path    string
+	MaxSize int64
+	MaxAge  time.Duration
 }
 
 // GetStatus returns the endpoints status, one line per endpoint
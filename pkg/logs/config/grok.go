@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// grokPatterns are the patterns which can be referenced by the parse_grok rules,
+// with %{NAME} to match a pattern or %{NAME:field} to capture it in a field.
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"BASE16NUM":         `(?:0[xX])?[0-9A-Fa-f]+`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:\d{1,3}\.){3}\d{1,3}`,
+	"IPV6":              `[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`,
+	"IP":                `(?:(?:\d{1,3}\.){3}\d{1,3}|[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"PATH":              `(?:/[^\s]*)+`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"LOGLEVEL":          `(?i:emerg(?:ency)?|alert|crit(?:ical)?|fatal|severe|err(?:or)?|warn(?:ing)?|notice|info(?:rmation)?|debug|trace)`,
+	"TIMESTAMP_ISO8601": `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:[.,]\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`,
+	"HTTPDATE":          `\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+}
+
+// grokReferenceRegex matches the %{NAME} and %{NAME:field} references of a grok pattern.
+var grokReferenceRegex = regexp.MustCompile(`%\{(\w+)(?::(\w+))?\}`)
+
+// compileGrokPattern expands the references of a grok pattern and compiles it,
+// the captured fields are named groups of the regular expression.
+func compileGrokPattern(pattern string) (*regexp.Regexp, error) {
+	var err error
+	expanded := grokReferenceRegex.ReplaceAllStringFunc(pattern, func(reference string) string {
+		submatches := grokReferenceRegex.FindStringSubmatch(reference)
+		name, field := submatches[1], submatches[2]
+		grokPattern, found := grokPatterns[name]
+		if !found {
+			if err == nil {
+				err = fmt.Errorf("unknown grok pattern %s", name)
+			}
+			return reference
+		}
+		if field == "" {
+			return "(?:" + grokPattern + ")"
+		}
+		return "(?P<" + field + ">" + grokPattern + ")"
+	})
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile(expanded)
+}
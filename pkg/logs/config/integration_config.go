@@ -15,14 +15,16 @@ import (
 
 // Logs source types
 const (
-	TCPType           = "tcp"
-	UDPType           = "udp"
-	FileType          = "file"
-	DockerType        = "docker"
-	ContainerdType    = "containerd"
-	JournaldType      = "journald"
-	WindowsEventType  = "windows_event"
-	StringChannelType = "string_channel"
+	TCPType             = "tcp"
+	UDPType             = "udp"
+	SyslogType          = "syslog"
+	FileType            = "file"
+	DockerType          = "docker"
+	ContainerdType      = "containerd"
+	JournaldType        = "journald"
+	WindowsEventType    = "windows_event"
+	StringChannelType   = "string_channel"
+	KubernetesAuditType = "kubernetes_audit"
 
 	// UTF16BE for UTF-16 Big endian encoding
 	UTF16BE string = "utf-16-be"
@@ -30,6 +32,12 @@ const (
 	UTF16LE string = "utf-16-le"
 	// SHIFTJIS for Shift JIS (Japanese) encoding
 	SHIFTJIS string = "shift-jis"
+
+	// CompressedFilesReadOnce reads the decompressed content of a compressed file once,
+	// until the end of its compressed stream, then stops tailing it.
+	CompressedFilesReadOnce string = "read_once"
+	// CompressedFilesIgnore does not read the compressed files.
+	CompressedFilesIgnore string = "ignore"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -37,13 +45,24 @@ const (
 type LogsConfig struct {
 	Type string
 
-	Port        int    // Network
+	Port        int    // Network, Kubernetes audit
 	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout"` // Network
-	Path        string // File, Journald
+	Path        string // File, Journald, Kubernetes audit
+
+	Protocol    string `mapstructure:"protocol" json:"protocol"`           // Syslog
+	TLSCertFile string `mapstructure:"tls_cert_file" json:"tls_cert_file"` // Syslog, Kubernetes audit
+	TLSKeyFile  string `mapstructure:"tls_key_file" json:"tls_key_file"`   // Syslog, Kubernetes audit
+
+	TLSClientCAFile   string `mapstructure:"tls_client_ca_file" json:"tls_client_ca_file"`   // Kubernetes audit
+	BearerTokenFile   string `mapstructure:"bearer_token_file" json:"bearer_token_file"`     // Kubernetes audit
+	AllowInsecureHTTP bool   `mapstructure:"allow_insecure_http" json:"allow_insecure_http"` // Kubernetes audit
 
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
+	// CompressedFiles is how the gzip and zstd compressed files matched by Path are handled,
+	// CompressedFilesReadOnce when empty.
+	CompressedFiles string `mapstructure:"compressed_files" json:"compressed_files"` // File
 
 	IncludeSystemUnits []string `mapstructure:"include_units" json:"include_units"`           // Journald
 	ExcludeSystemUnits []string `mapstructure:"exclude_units" json:"exclude_units"`           // Journald
@@ -107,12 +126,27 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+	case SyslogType:
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
+		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("Protocol: %#v,"), c.Protocol)
+		fmt.Fprintf(&b, ws("TLSCertFile: %#v,"), c.TLSCertFile)
+		fmt.Fprintf(&b, ws("TLSKeyFile: %#v,"), c.TLSKeyFile)
+	case KubernetesAuditType:
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
+		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
+		fmt.Fprintf(&b, ws("TLSCertFile: %#v,"), c.TLSCertFile)
+		fmt.Fprintf(&b, ws("TLSKeyFile: %#v,"), c.TLSKeyFile)
+		fmt.Fprintf(&b, ws("TLSClientCAFile: %#v,"), c.TLSClientCAFile)
+		fmt.Fprintf(&b, ws("BearerTokenFile: %#v,"), c.BearerTokenFile)
+		fmt.Fprintf(&b, ws("AllowInsecureHTTP: %t,"), c.AllowInsecureHTTP)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
 		fmt.Fprintf(&b, ws("Identifier: %#v,"), c.Identifier)
 		fmt.Fprintf(&b, ws("ExcludePaths: %#v,"), c.ExcludePaths)
 		fmt.Fprintf(&b, ws("TailingMode: %#v,"), c.TailingMode)
+		fmt.Fprintf(&b, ws("CompressedFiles: %#v,"), c.CompressedFiles)
 	case DockerType, ContainerdType:
 		fmt.Fprintf(&b, ws("Image: %#v,"), c.Image)
 		fmt.Fprintf(&b, ws("Label: %#v,"), c.Label)
@@ -206,10 +240,24 @@ func (c *LogsConfig) Validate() error {
 		if err != nil {
 			return err
 		}
+		err = c.validateCompressedFiles()
+		if err != nil {
+			return err
+		}
 	case c.Type == TCPType && c.Port == 0:
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType:
+		err := c.validateSyslog()
+		if err != nil {
+			return err
+		}
+	case c.Type == KubernetesAuditType:
+		err := c.validateKubernetesAudit()
+		if err != nil {
+			return err
+		}
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -218,6 +266,44 @@ func (c *LogsConfig) Validate() error {
 	return CompileProcessingRules(c.ProcessingRules)
 }
 
+// validateKubernetesAudit checks that a Kubernetes audit source either tails the audit log
+// of the API server or receives the events of its webhook backend. The webhook backend must
+// be authenticated, by its client certificate or a bearer token, over TLS unless plain HTTP
+// is explicitly allowed.
+func (c *LogsConfig) validateKubernetesAudit() error {
+	switch {
+	case (c.Path == "") == (c.Port == 0):
+		return fmt.Errorf("kubernetes_audit source must have either a path or a port")
+	case c.Path != "":
+		return c.validateTailingMode()
+	case (c.TLSCertFile == "") != (c.TLSKeyFile == ""):
+		return fmt.Errorf("kubernetes_audit source must have both a tls_cert_file and a tls_key_file to use TLS")
+	case c.TLSClientCAFile != "" && c.TLSCertFile == "":
+		return fmt.Errorf("kubernetes_audit source must have a tls_cert_file to verify the client certificates")
+	case c.AllowInsecureHTTP:
+		return nil
+	case c.TLSCertFile == "":
+		return fmt.Errorf("kubernetes_audit source must have a tls_cert_file and a tls_key_file, or allow_insecure_http set to true")
+	case c.TLSClientCAFile == "" && c.BearerTokenFile == "":
+		return fmt.Errorf("kubernetes_audit source must have a tls_client_ca_file or a bearer_token_file to authenticate the requests, or allow_insecure_http set to true")
+	}
+	return nil
+}
+
+func (c *LogsConfig) validateSyslog() error {
+	switch {
+	case c.Port == 0:
+		return fmt.Errorf("syslog source must have a port")
+	case c.Protocol != "" && c.Protocol != TCPType && c.Protocol != UDPType:
+		return fmt.Errorf("invalid protocol '%v' for syslog source, must be tcp or udp", c.Protocol)
+	case (c.TLSCertFile == "") != (c.TLSKeyFile == ""):
+		return fmt.Errorf("syslog source must have both a tls_cert_file and a tls_key_file to use TLS")
+	case c.TLSCertFile != "" && c.Protocol == UDPType:
+		return fmt.Errorf("TLS is only supported by syslog sources using the tcp protocol")
+	}
+	return nil
+}
+
 func (c *LogsConfig) validateTailingMode() error {
 	mode, found := TailingModeFromString(c.TailingMode)
 	if !found && c.TailingMode != "" {
@@ -229,6 +315,15 @@ func (c *LogsConfig) validateTailingMode() error {
 	return nil
 }
 
+func (c *LogsConfig) validateCompressedFiles() error {
+	switch c.CompressedFiles {
+	case "", CompressedFilesReadOnce, CompressedFilesIgnore:
+		return nil
+	default:
+		return fmt.Errorf("invalid compressed_files '%v' for %v, must be %v or %v", c.CompressedFiles, c.Path, CompressedFilesReadOnce, CompressedFilesIgnore)
+	}
+}
+
 // AutoMultiLineEnabled determines whether auto multi line detection is enabled for this config,
 // considering both the agent-wide logs_config.auto_multi_line_detection and any config for this
 // particular log source.
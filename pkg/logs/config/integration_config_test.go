@@ -19,8 +19,17 @@ import (
 func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 	validConfigs := []*LogsConfig{
 		{Type: FileType, Path: "/var/log/foo.log"},
+		{Type: FileType, Path: "/var/log/foo.log.*.gz", CompressedFiles: CompressedFilesReadOnce},
+		{Type: FileType, Path: "/var/log/foo.log*", CompressedFiles: CompressedFilesIgnore},
 		{Type: TCPType, Port: 1234},
 		{Type: UDPType, Port: 5678},
+		{Type: SyslogType, Port: 514},
+		{Type: SyslogType, Port: 514, Protocol: UDPType},
+		{Type: SyslogType, Port: 6514, Protocol: TCPType, TLSCertFile: "/etc/cert.pem", TLSKeyFile: "/etc/key.pem"},
+		{Type: KubernetesAuditType, Path: "/var/log/kubernetes/audit.log"},
+		{Type: KubernetesAuditType, Port: 8444, TLSCertFile: "/etc/cert.pem", TLSKeyFile: "/etc/key.pem", TLSClientCAFile: "/etc/ca.pem"},
+		{Type: KubernetesAuditType, Port: 8444, TLSCertFile: "/etc/cert.pem", TLSKeyFile: "/etc/key.pem", BearerTokenFile: "/etc/token"},
+		{Type: KubernetesAuditType, Port: 8444, AllowInsecureHTTP: true},
 		{Type: DockerType},
 		{Type: JournaldType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
 	}
@@ -35,8 +44,21 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 	invalidConfigs := []*LogsConfig{
 		{},
 		{Type: FileType},
+		{Type: FileType, Path: "/var/log/foo.log", CompressedFiles: "always"},
 		{Type: TCPType},
 		{Type: UDPType},
+		{Type: SyslogType},
+		{Type: SyslogType, Port: 514, Protocol: "http"},
+		{Type: SyslogType, Port: 6514, TLSCertFile: "/etc/cert.pem"},
+		{Type: SyslogType, Port: 6514, Protocol: UDPType, TLSCertFile: "/etc/cert.pem", TLSKeyFile: "/etc/key.pem"},
+		{Type: KubernetesAuditType},
+		{Type: KubernetesAuditType, Path: "/var/log/kubernetes/audit.log", Port: 8444},
+		{Type: KubernetesAuditType, Port: 8444, TLSKeyFile: "/etc/key.pem", AllowInsecureHTTP: true},
+		{Type: KubernetesAuditType, Port: 8444},
+		{Type: KubernetesAuditType, Port: 8444, BearerTokenFile: "/etc/token"},
+		{Type: KubernetesAuditType, Port: 8444, TLSCertFile: "/etc/cert.pem", TLSKeyFile: "/etc/key.pem"},
+		{Type: KubernetesAuditType, Port: 8444, TLSClientCAFile: "/etc/ca.pem", AllowInsecureHTTP: true},
+		{Type: KubernetesAuditType, Path: "/var/log/kubernetes/*.log", TailingMode: "beginning"},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: "bar"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch}}},
@@ -8,6 +8,7 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"time"
 )
 
 // Processing rule types
@@ -16,25 +17,89 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	GenerateMetric = "generate_metric"
+	Sample         = "sample"
+	Dedupe         = "dedupe"
 )
 
-// ProcessingRule defines an exclusion or a masking rule to
+// defaultDedupeWindow is the window of the dedupe rules without one.
+const defaultDedupeWindow = 10 * time.Second
+
+// Metric types of the rules generating metrics
+const (
+	MetricTypeCount        = "count"
+	MetricTypeDistribution = "distribution"
+)
+
+// Parsing rule types
+const (
+	JSONParsing     = "parse_json"
+	KeyValueParsing = "parse_key_value"
+	GrokParsing     = "parse_grok"
+)
+
+// Timestamp formats of the parsing rules, other values are Go time layouts
+const (
+	TimestampFormatUnix   = "unix"
+	TimestampFormatUnixMs = "unix_ms"
+)
+
+// ProcessingRule defines an exclusion, a masking or a parsing rule to
 // be applied on log lines
 type ProcessingRule struct {
 	Type               string
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// The parsing rules promote the parsed fields named by these settings to the
+	// status, the service, the timestamp and the tags of the log. The fields of
+	// nested JSON objects are named with dots.
+	StatusField    string   `mapstructure:"status_field" json:"status_field"`
+	ServiceField   string   `mapstructure:"service_field" json:"service_field"`
+	TimestampField string   `mapstructure:"timestamp_field" json:"timestamp_field"`
+	TagFields      []string `mapstructure:"tag_fields" json:"tag_fields"`
+	// TimestampFormat is unix, unix_ms or a Go time layout, RFC3339 by default
+	TimestampFormat string `mapstructure:"timestamp_format" json:"timestamp_format"`
+	// The rules generating metrics submit a metric for each log line matching the
+	// pattern, its value is the named capturing group ValueGroup, or 1 without one.
+	MetricName string `mapstructure:"metric_name" json:"metric_name"`
+	// MetricType is count or distribution, count by default
+	MetricType string `mapstructure:"metric_type" json:"metric_type"`
+	ValueGroup string `mapstructure:"value_group" json:"value_group"`
+	// DropLog drops the matching log lines once their metric is submitted
+	DropLog bool `mapstructure:"drop_log" json:"drop_log"`
+	// The sample rules keep one in KeepOneIn or at most MaxPerSecond of the log
+	// lines matching the pattern, or of all of them without a pattern.
+	KeepOneIn    int `mapstructure:"keep_one_in" json:"keep_one_in"`
+	MaxPerSecond int `mapstructure:"max_per_second" json:"max_per_second"`
+	// The dedupe rules collapse the identical log lines matching the pattern, or all of
+	// them without a pattern, received within the window into the first of them.
+	Window string `mapstructure:"window" json:"window"`
 	// TODO: should be moved out
-	Regex       *regexp.Regexp
-	Placeholder []byte
+	Regex        *regexp.Regexp
+	Placeholder  []byte
+	DedupeWindow time.Duration
+}
+
+// IsParsingRule returns true for the rules parsing the content of the logs.
+func (r *ProcessingRule) IsParsingRule() bool {
+	switch r.Type {
+	case JSONParsing, KeyValueParsing, GrokParsing:
+		return true
+	}
+	return false
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, except for the JSON and key/value parsing rules
+// Each parsing rule must promote at least one field.
+// Each rule generating metrics must have a metric name, a supported metric type and
+// a value group, mandatory for distributions, which is a named group of its pattern.
+// Each sample rule must keep either one in N or N per second of the log lines, and
+// each dedupe rule must have a valid window. Their pattern is optional.
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -44,12 +109,45 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		switch rule.Type {
 		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
 			break
+		case JSONParsing, KeyValueParsing, GrokParsing:
+			if rule.StatusField == "" && rule.ServiceField == "" && rule.TimestampField == "" && len(rule.TagFields) == 0 {
+				return fmt.Errorf("no field to promote for processing rule: %s", rule.Name)
+			}
+		case GenerateMetric:
+			if err := validateMetricRule(rule); err != nil {
+				return err
+			}
+		case Sample:
+			if (rule.KeepOneIn > 0) == (rule.MaxPerSecond > 0) || rule.KeepOneIn < 0 || rule.MaxPerSecond < 0 {
+				return fmt.Errorf("either keep_one_in or max_per_second must be a positive number for processing rule: %s", rule.Name)
+			}
+		case Dedupe:
+			if _, err := parseDedupeWindow(rule.Window); err != nil {
+				return fmt.Errorf("invalid window %s for processing rule: %s: %s", rule.Window, rule.Name, err)
+			}
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
 			return fmt.Errorf("type %s is not supported for processing rule `%s`", rule.Type, rule.Name)
 		}
 
+		switch rule.Type {
+		case JSONParsing, KeyValueParsing:
+			continue
+		case Sample, Dedupe:
+			if rule.Pattern == "" {
+				continue
+			}
+		case GrokParsing:
+			if rule.Pattern == "" {
+				return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
+			}
+			if _, err := compileGrokPattern(rule.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern %s for processing rule: %s: %s", rule.Pattern, rule.Name, err)
+			}
+			continue
+		}
+
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
@@ -64,12 +162,37 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		switch rule.Type {
+		case JSONParsing, KeyValueParsing:
+			continue
+		case GrokParsing:
+			re, err := compileGrokPattern(rule.Pattern)
+			if err != nil {
+				return err
+			}
+			rule.Regex = re
+			continue
+		case Dedupe:
+			window, err := parseDedupeWindow(rule.Window)
+			if err != nil {
+				return err
+			}
+			rule.DedupeWindow = window
+			if rule.Pattern == "" {
+				continue
+			}
+		case Sample:
+			if rule.Pattern == "" {
+				continue
+			}
+		}
+
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, GenerateMetric, Sample, Dedupe:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -83,3 +206,45 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 	}
 	return nil
 }
+
+// validateMetricRule validates the settings of a rule generating metrics.
+func validateMetricRule(rule *ProcessingRule) error {
+	if rule.MetricName == "" {
+		return fmt.Errorf("no metric name provided for processing rule: %s", rule.Name)
+	}
+	switch rule.MetricType {
+	case "", MetricTypeCount:
+	case MetricTypeDistribution:
+		if rule.ValueGroup == "" {
+			return fmt.Errorf("no value group provided for the distribution of processing rule: %s", rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric type %s is not supported for processing rule `%s`", rule.MetricType, rule.Name)
+	}
+	if rule.ValueGroup == "" {
+		return nil
+	}
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+	}
+	if re.SubexpIndex(rule.ValueGroup) < 0 {
+		return fmt.Errorf("value group %s is not a named group of the pattern of processing rule: %s", rule.ValueGroup, rule.Name)
+	}
+	return nil
+}
+
+// parseDedupeWindow parses the window of a dedupe rule, 10s by default.
+func parseDedupeWindow(window string) (time.Duration, error) {
+	if window == "" {
+		return defaultDedupeWindow, nil
+	}
+	duration, err := time.ParseDuration(window)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("the window must be positive")
+	}
+	return duration, nil
+}
@@ -10,6 +10,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -34,3 +35,82 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateParsingRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "json", Type: JSONParsing, StatusField: "level"},
+		{Name: "kv", Type: KeyValueParsing, TagFields: []string{"user"}},
+		{Name: "grok", Type: GrokParsing, Pattern: "%{LOGLEVEL:level} %{GREEDYDATA}", StatusField: "level"},
+	}
+	assert.NoError(t, ValidateProcessingRules(validRules))
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_field", Type: JSONParsing},
+		{Name: "no_pattern", Type: GrokParsing, StatusField: "level"},
+		{Name: "unknown_grok_pattern", Type: GrokParsing, Pattern: "%{UNKNOWN:level}", StatusField: "level"},
+		{Name: "invalid_pattern", Type: GrokParsing, Pattern: "%{WORD:level} (", StatusField: "level"},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestCompileGrokRule(t *testing.T) {
+	rules := []*ProcessingRule{{Name: "grok", Type: GrokParsing, Pattern: `^%{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} \[%{WORD}\] %{GREEDYDATA:msg}$`, StatusField: "level"}}
+	assert.NoError(t, CompileProcessingRules(rules))
+
+	submatches := rules[0].Regex.FindStringSubmatch("2022-07-04T10:00:00.123Z WARN [main] disk is full")
+	assert.Equal(t, []string{"", "ts", "level", "msg"}, rules[0].Regex.SubexpNames())
+	assert.Equal(t, []string{"2022-07-04T10:00:00.123Z", "WARN", "disk is full"}, submatches[1:])
+}
+
+func TestValidateMetricRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "count", Type: GenerateMetric, Pattern: "error", MetricName: "app.errors"},
+		{Name: "sum", Type: GenerateMetric, Pattern: `bytes=(?P<bytes>\d+)`, MetricName: "app.bytes", MetricType: MetricTypeCount, ValueGroup: "bytes"},
+		{Name: "distribution", Type: GenerateMetric, Pattern: `duration=(?P<duration>\d+)`, MetricName: "app.duration", MetricType: MetricTypeDistribution, ValueGroup: "duration", DropLog: true},
+	}
+	assert.NoError(t, ValidateProcessingRules(validRules))
+	assert.NoError(t, CompileProcessingRules(validRules))
+	for _, rule := range validRules {
+		assert.NotNil(t, rule.Regex, rule.Name)
+	}
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_metric_name", Type: GenerateMetric, Pattern: "error"},
+		{Name: "no_pattern", Type: GenerateMetric, MetricName: "app.errors"},
+		{Name: "unknown_metric_type", Type: GenerateMetric, Pattern: "error", MetricName: "app.errors", MetricType: "gauge"},
+		{Name: "distribution_without_value", Type: GenerateMetric, Pattern: "error", MetricName: "app.errors", MetricType: MetricTypeDistribution},
+		{Name: "unknown_value_group", Type: GenerateMetric, Pattern: `duration=(\d+)`, MetricName: "app.duration", ValueGroup: "duration"},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateSampleAndDedupeRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "one_in", Type: Sample, Pattern: "debug", KeepOneIn: 10},
+		{Name: "per_second", Type: Sample, MaxPerSecond: 100},
+		{Name: "dedupe", Type: Dedupe, Pattern: "timeout", Window: "1m"},
+		{Name: "dedupe_all", Type: Dedupe},
+	}
+	assert.NoError(t, ValidateProcessingRules(validRules))
+	assert.NoError(t, CompileProcessingRules(validRules))
+	assert.NotNil(t, validRules[0].Regex)
+	assert.Nil(t, validRules[1].Regex)
+	assert.Equal(t, time.Minute, validRules[2].DedupeWindow)
+	assert.Equal(t, 10*time.Second, validRules[3].DedupeWindow)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_rate", Type: Sample},
+		{Name: "both_rates", Type: Sample, KeepOneIn: 10, MaxPerSecond: 100},
+		{Name: "negative_rate", Type: Sample, KeepOneIn: -1},
+		{Name: "invalid_pattern", Type: Sample, Pattern: "(", KeepOneIn: 10},
+		{Name: "invalid_window", Type: Dedupe, Window: "10"},
+		{Name: "negative_window", Type: Dedupe, Window: "-1s"},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
@@ -40,6 +40,16 @@ func (r *fakeRegistry) GetTailingMode(identifier string) string {
 	panic("unused")
 }
 
+// GetFingerprint implements auditor.Registry#GetFingerprint.
+func (r *fakeRegistry) GetFingerprint(identifier string) string {
+	panic("unused")
+}
+
+// GetIdentifierForFingerprint implements auditor.Registry#GetIdentifierForFingerprint.
+func (r *fakeRegistry) GetIdentifierForFingerprint(fingerprint string) string {
+	panic("unused")
+}
+
 func TestUseFile(t *testing.T) {
 	ctrs := containersorpods.LogContainers
 	pods := containersorpods.LogPods
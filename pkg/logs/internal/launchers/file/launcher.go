@@ -19,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/launchers"
 	tailer "github.com/DataDog/datadog-agent/pkg/logs/internal/tailers/file"
+	"github.com/DataDog/datadog-agent/pkg/logs/kubeaudit"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
@@ -45,6 +46,8 @@ type Launcher struct {
 	pipelineProvider    pipeline.Provider
 	addedSources        chan *sources.LogSource
 	removedSources      chan *sources.LogSource
+	addedAuditSources   chan *sources.LogSource
+	removedAuditSources chan *sources.LogSource
 	activeSources       []*sources.LogSource
 	tailingLimit        int
 	fileProvider        *fileProvider
@@ -57,6 +60,9 @@ type Launcher struct {
 	// Feature flag defaulting to false, use `logs_config.validate_pod_container_id`.
 	validatePodContainerID bool
 	scanPeriod             time.Duration
+	// compressedFilesRead holds the compressed files whose content has been read entirely,
+	// by scan key, so that they are not read again while they are not replaced.
+	compressedFilesRead map[string]os.FileInfo
 }
 
 // NewLauncher returns a new launcher.
@@ -69,6 +75,7 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		stop:                   make(chan struct{}),
 		validatePodContainerID: validatePodContainerID,
 		scanPeriod:             scanPeriod,
+		compressedFilesRead:    make(map[string]os.FileInfo),
 	}
 }
 
@@ -76,6 +83,7 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 func (s *Launcher) Start(sourceProvider launchers.SourceProvider, pipelineProvider pipeline.Provider, registry auditor.Registry) {
 	s.pipelineProvider = pipelineProvider
 	s.addedSources, s.removedSources = sourceProvider.SubscribeForType(config.FileType)
+	s.addedAuditSources, s.removedAuditSources = sourceProvider.SubscribeForType(config.KubernetesAuditType)
 	s.registry = registry
 	go s.run()
 }
@@ -97,6 +105,13 @@ func (s *Launcher) run() {
 			s.addSource(source)
 		case source := <-s.removedSources:
 			s.removeSource(source)
+		case source := <-s.addedAuditSources:
+			// the Kubernetes audit sources with a port receive the events of the webhook backend
+			if source.Config.Path != "" {
+				s.addSource(source)
+			}
+		case source := <-s.removedAuditSources:
+			s.removeSource(source)
 		case <-scanTicker.C:
 			// check if there are new files to tail, tailers to stop and tailer to restart because of file rotation
 			s.scan()
@@ -124,6 +139,7 @@ func (s *Launcher) cleanup() {
 func (s *Launcher) scan() {
 	files := s.fileProvider.filesToTail(s.activeSources)
 	filesTailed := make(map[string]bool)
+	filesScanned := make(map[string]bool)
 	tailersLen := len(s.tailers)
 
 	for _, file := range files {
@@ -137,11 +153,25 @@ func (s *Launcher) scan() {
 		// when a tailer for a dead container is still tailing the file, and another
 		// tailer is tailing the file for the new container).
 		tailerKey := file.GetScanKey()
+		filesScanned[tailerKey] = true
 		tailer, isTailed := s.tailers[tailerKey]
 		if isTailed && tailer.IsFinished() {
+			if tailer.IsCompressed() {
+				s.setCompressedFileRead(tailerKey, file)
+			}
 			// skip this tailer as it must be stopped
 			continue
 		}
+		var mode config.TailingMode = config.Beginning
+		if !isTailed {
+			if read, replaced := s.isCompressedFileRead(tailerKey, file); read {
+				// the whole content of this compressed file has already been sent
+				continue
+			} else if replaced {
+				// another compressed file has the same path, the offset of the previous one must be ignored
+				mode = config.ForceBeginning
+			}
+		}
 		if !isTailed && tailersLen >= s.tailingLimit {
 			// can't create new tailer because tailingLimit is reached
 			continue
@@ -149,7 +179,7 @@ func (s *Launcher) scan() {
 
 		if !isTailed && tailersLen < s.tailingLimit {
 			// create a new tailer tailing from the beginning of the file if no offset has been recorded
-			succeeded := s.startNewTailer(file, config.Beginning)
+			succeeded := s.startNewTailer(file, mode)
 			if !succeeded {
 				// the setup failed, let's try to tail this file in the next scan
 				continue
@@ -182,6 +212,37 @@ func (s *Launcher) scan() {
 			s.stopTailer(scanKey, tailer)
 		}
 	}
+
+	for scanKey := range s.compressedFilesRead {
+		if !filesScanned[scanKey] {
+			delete(s.compressedFilesRead, scanKey)
+		}
+	}
+}
+
+// setCompressedFileRead records that the whole content of a compressed file has been read.
+func (s *Launcher) setCompressedFileRead(scanKey string, file *tailer.File) {
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		log.Debugf("Could not stat the compressed file %s: %v", file.Path, err)
+		return
+	}
+	s.compressedFilesRead[scanKey] = info
+}
+
+// isCompressedFileRead returns true if the whole content of the compressed file has
+// already been read, and whether the file has been replaced by another one since then.
+func (s *Launcher) isCompressedFileRead(scanKey string, file *tailer.File) (read bool, replaced bool) {
+	previous, found := s.compressedFilesRead[scanKey]
+	if !found {
+		return false, false
+	}
+	info, err := os.Stat(file.Path)
+	if err != nil || os.SameFile(previous, info) {
+		return true, false
+	}
+	delete(s.compressedFilesRead, scanKey)
+	return false, true
 }
 
 // addSource keeps track of the new source and launch new tailers for this source.
@@ -263,7 +324,12 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 	var whence int
 	mode := s.handleTailingModeChange(tailer.Identifier(), m)
 
-	offset, whence, err := Position(s.registry, tailer.Identifier(), mode)
+	var err error
+	if tailer.IsFingerprintEnabled() {
+		offset, whence, err = PositionWithFingerprint(s.registry, tailer.Identifier(), tailer.Fingerprint(), mode)
+	} else {
+		offset, whence, err = Position(s.registry, tailer.Identifier(), mode)
+	}
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
 	}
@@ -388,7 +454,11 @@ func (s *Launcher) restartTailerAfterFileRotation(tailer *tailer.Tailer, file *t
 
 // createTailer returns a new initialized tailer
 func (s *Launcher) createTailer(file *tailer.File, outputChan chan *message.Message) *tailer.Tailer {
-	return tailer.NewTailer(outputChan, file, s.tailerSleepDuration, decoder.NewDecoderFromSource(file.Source))
+	t := tailer.NewTailer(outputChan, file, s.tailerSleepDuration, decoder.NewDecoderFromSource(file.Source))
+	if file.Source.Config().Type == config.KubernetesAuditType {
+		t.SetMessageProcessor(kubeaudit.NewProcessor())
+	}
+	return t
 }
 
 func (s *Launcher) createRotatedTailer(t *tailer.Tailer, file *tailer.File, pattern *regexp.Regexp) *tailer.Tailer {
@@ -9,6 +9,8 @@
 package file
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"os"
 	"testing"
@@ -477,3 +479,83 @@ func TestLauncherUpdatesSourceForExistingTailer(t *testing.T) {
 func getScanKey(path string, source *sources.LogSource) string {
 	return filetailer.NewFile(path, source, false).GetScanKey()
 }
+
+func TestLauncherScanCompressedFile(t *testing.T) {
+	testDir := t.TempDir()
+	launcher := NewLauncher(2, 20*time.Millisecond, false, 10*time.Second)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = auditor.NewRegistry()
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/*.gz", testDir)})
+	launcher.activeSources = append(launcher.activeSources, source)
+	status.Clear()
+	status.InitStatus(util.CreateSources([]*sources.LogSource{source}))
+	defer status.Clear()
+
+	path := fmt.Sprintf("%s/test.log.1.gz", testDir)
+	writeGzipFile := func(content string) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write([]byte(content))
+		assert.Nil(t, err)
+		assert.Nil(t, w.Close())
+		tmpPath := path + ".tmp"
+		assert.Nil(t, os.WriteFile(tmpPath, buf.Bytes(), 0600))
+		assert.Nil(t, os.Rename(tmpPath, path))
+	}
+	writeGzipFile("hello\nworld\n")
+
+	launcher.scan()
+	assert.Equal(t, 1, len(launcher.tailers))
+	assert.Equal(t, "hello", string((<-outputChan).Content))
+	msg := <-outputChan
+	assert.Equal(t, "world", string(msg.Content))
+	assert.Equal(t, "12"+filetailer.ArchiveReadSuffix, msg.Origin.Offset)
+	tailer := launcher.tailers[path]
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+
+	// the finished tailer is stopped and the file is not read again
+	launcher.scan()
+	assert.Equal(t, 0, len(launcher.tailers))
+	launcher.scan()
+	assert.Equal(t, 0, len(launcher.tailers))
+	assert.Contains(t, launcher.compressedFilesRead, path)
+
+	// a new compressed file with the same path is read from the beginning
+	writeGzipFile("bye\n")
+	launcher.scan()
+	assert.Equal(t, 1, len(launcher.tailers))
+	assert.Equal(t, "bye", string((<-outputChan).Content))
+
+	// the files which are not matched anymore are forgotten
+	assert.Nil(t, os.Remove(path))
+	launcher.scan()
+	assert.Equal(t, 0, len(launcher.tailers))
+	assert.Empty(t, launcher.compressedFilesRead)
+}
+
+func TestLauncherKubernetesAuditEvents(t *testing.T) {
+	path := fmt.Sprintf("%s/audit.log", t.TempDir())
+	lines := `{"kind":"Event","apiVersion":"audit.k8s.io/v1","auditID":"a1","stage":"RequestReceived","verb":"delete","user":{"username":"admin"},"objectRef":{"resource":"pods","namespace":"default"}}` + "\n"
+	lines += `{"kind":"Event","apiVersion":"audit.k8s.io/v1","auditID":"a1","stage":"ResponseComplete","verb":"delete","user":{"username":"admin"},"objectRef":{"resource":"pods","namespace":"default"},"responseStatus":{"code":403},"stageTimestamp":"2022-03-01T10:00:00.123456Z"}` + "\n"
+	lines += "not an audit event\n"
+	assert.Nil(t, os.WriteFile(path, []byte(lines), 0600))
+
+	launcher := NewLauncher(1, 10*time.Millisecond, false, 10*time.Second)
+	outputChan := make(chan *message.Message, 10)
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.KubernetesAuditType, Path: path})
+	tailer := launcher.createTailer(filetailer.NewFile(path, source, false), outputChan)
+	assert.Nil(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+
+	// the RequestReceived stage is dropped and the audit events are parsed
+	msg := <-outputChan
+	assert.Contains(t, string(msg.Content), `"stage":"ResponseComplete"`)
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, time.Date(2022, 3, 1, 10, 0, 0, 123456000, time.UTC), msg.Timestamp)
+	assert.Subset(t, msg.Origin.Tags(), []string{"kube_audit_verb:delete", "kube_audit_user:admin", "kube_audit_resource:pods", "kube_namespace:default", "kube_audit_response_code:403"})
+
+	msg = <-outputChan
+	assert.Equal(t, "not an audit event", string(msg.Content))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+}
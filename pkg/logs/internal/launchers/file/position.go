@@ -8,9 +8,12 @@ package file
 import (
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/internal/tailers/file"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Position returns the position from where logs should be collected.
@@ -26,6 +29,9 @@ func Position(registry auditor.Registry, identifier string, mode config.TailingM
 		offset, whence = 0, io.SeekStart
 	case mode == config.ForceEnd:
 		offset, whence = 0, io.SeekEnd
+	case strings.HasSuffix(value, tailer.ArchiveReadSuffix):
+		// the whole content of this compressed file has already been read
+		offset, whence = 0, io.SeekEnd
 	case value != "":
 		// an offset was registered, tailing mode is not forced, tail from the offset
 		whence = io.SeekStart
@@ -47,3 +53,34 @@ func Position(registry auditor.Registry, identifier string, mode config.TailingM
 	}
 	return offset, whence, err
 }
+
+// PositionWithFingerprint returns the position from where logs should be collected for a file
+// identified by its fingerprint in addition to its path:
+//   - a file moved or renamed since it was last tailed resumes from the offset registered with
+//     its fingerprint for its previous path,
+//   - a file whose content changed since it was last tailed (copytruncate rotation, another file
+//     reusing the path) is tailed from its beginning, the registered offset was another file's.
+//
+// Otherwise, or when fingerprint is empty, the position is the one of Position.
+func PositionWithFingerprint(registry auditor.Registry, identifier string, fingerprint string, mode config.TailingMode) (int64, int, error) {
+	if mode == config.ForceBeginning || mode == config.ForceEnd {
+		return Position(registry, identifier, mode)
+	}
+
+	registeredFingerprint := registry.GetFingerprint(identifier)
+	switch {
+	case fingerprint != "" && registeredFingerprint == fingerprint:
+		return Position(registry, identifier, mode)
+	case fingerprint != "":
+		if previousIdentifier := registry.GetIdentifierForFingerprint(fingerprint); previousIdentifier != "" {
+			log.Infof("%s was previously tailed as %s, resuming from its offset", identifier, previousIdentifier)
+			return Position(registry, previousIdentifier, mode)
+		}
+	}
+	if registeredFingerprint != "" && registeredFingerprint != fingerprint {
+		// an empty fingerprint means the file is shorter than the registered one, it has been truncated
+		log.Infof("The content of %s changed since its offset was registered, tailing it from the beginning", identifier)
+		return 0, io.SeekStart, nil
+	}
+	return Position(registry, identifier, mode)
+}
@@ -56,6 +56,12 @@ func TestPosition(t *testing.T) {
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
+	registry.SetOffset("123456789:eof")
+	offset, whence, err = Position(registry, "", config.Beginning)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekEnd, whence)
+
 	registry.SetOffset("123456789")
 	offset, whence, err = Position(registry, "", config.ForceBeginning)
 	assert.Nil(t, err)
@@ -68,3 +74,63 @@ func TestPosition(t *testing.T) {
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
 }
+
+// fingerprintRegistry implements auditor.Registry with one entry per identifier.
+type fingerprintRegistry struct {
+	offsets      map[string]string
+	fingerprints map[string]string
+}
+
+func (r *fingerprintRegistry) GetOffset(identifier string) string      { return r.offsets[identifier] }
+func (r *fingerprintRegistry) GetTailingMode(identifier string) string { return "" }
+func (r *fingerprintRegistry) GetFingerprint(identifier string) string {
+	return r.fingerprints[identifier]
+}
+func (r *fingerprintRegistry) GetIdentifierForFingerprint(fingerprint string) string {
+	for identifier, f := range r.fingerprints {
+		if f == fingerprint {
+			return identifier
+		}
+	}
+	return ""
+}
+
+func TestPositionWithFingerprint(t *testing.T) {
+	registry := &fingerprintRegistry{
+		offsets: map[string]string{
+			"file:/var/log/app.log":     "42",
+			"file:/var/log/old.log":     "43",
+			"file:/var/log/unknown.log": "44",
+		},
+		fingerprints: map[string]string{
+			"file:/var/log/app.log": "4:aaaa",
+			"file:/var/log/old.log": "4:bbbb",
+		},
+	}
+
+	for name, test := range map[string]struct {
+		identifier  string
+		fingerprint string
+		mode        config.TailingMode
+		offset      int64
+		whence      int
+	}{
+		"same file":                       {"file:/var/log/app.log", "4:aaaa", config.End, 42, io.SeekStart},
+		"moved file":                      {"file:/var/log/archive/old.log", "4:bbbb", config.End, 43, io.SeekStart},
+		"new file":                        {"file:/var/log/new.log", "4:cccc", config.End, 0, io.SeekEnd},
+		"new file from beginning":         {"file:/var/log/new.log", "4:cccc", config.Beginning, 0, io.SeekStart},
+		"replaced file":                   {"file:/var/log/app.log", "4:cccc", config.End, 0, io.SeekStart},
+		"truncated file":                  {"file:/var/log/app.log", "", config.End, 0, io.SeekStart},
+		"forced end":                      {"file:/var/log/app.log", "4:cccc", config.ForceEnd, 0, io.SeekEnd},
+		"registered without fingerprint":  {"file:/var/log/unknown.log", "4:dddd", config.End, 44, io.SeekStart},
+		"not fingerprinted yet":           {"file:/var/log/unknown.log", "", config.End, 44, io.SeekStart},
+		"not fingerprinted yet, new file": {"file:/var/log/new.log", "", config.End, 0, io.SeekEnd},
+	} {
+		t.Run(name, func(t *testing.T) {
+			offset, whence, err := PositionWithFingerprint(registry, test.identifier, test.fingerprint, test.mode)
+			assert.Nil(t, err)
+			assert.Equal(t, test.offset, offset)
+			assert.Equal(t, test.whence, whence)
+		})
+	}
+}
@@ -0,0 +1,241 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/internal/tailers/syslog"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/startstop"
+)
+
+// A SyslogListener receives syslog messages over TCP, optionally with TLS, or over UDP,
+// and delegates their framing and parsing to syslog tailers: one per TCP connection,
+// a single one for UDP where each datagram is a message.
+type SyslogListener struct {
+	pipelineProvider pipeline.Provider
+	source           *sources.LogSource
+	idleTimeout      time.Duration
+	frameSize        int
+	listener         net.Listener
+	tailers          []*tailer.Tailer
+	mu               sync.Mutex
+	stop             chan struct{}
+}
+
+// NewSyslogListener returns an initialized SyslogListener
+func NewSyslogListener(pipelineProvider pipeline.Provider, source *sources.LogSource, frameSize int) *SyslogListener {
+	var idleTimeout time.Duration
+	if source.Config.IdleTimeout != "" {
+		var err error
+		idleTimeout, err = time.ParseDuration(source.Config.IdleTimeout)
+		if err != nil {
+			log.Errorf("Error parsing log's idle_timeout as a duration: %s", err)
+			idleTimeout = 0
+		}
+	}
+
+	return &SyslogListener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+		idleTimeout:      idleTimeout,
+		frameSize:        frameSize,
+		tailers:          []*tailer.Tailer{},
+		stop:             make(chan struct{}, 1),
+	}
+}
+
+// Start starts to receive syslog messages.
+func (l *SyslogListener) Start() {
+	log.Infof("Starting syslog forwarder on port %d (%s), with read buffer size: %d", l.source.Config.Port, l.protocol(), l.frameSize)
+	var err error
+	if l.protocol() == config.UDPType {
+		err = l.startUDPTailer()
+	} else {
+		err = l.startListener()
+	}
+	if err != nil {
+		log.Errorf("Can't start syslog forwarder on port %d: %v", l.source.Config.Port, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.source.Status.Success()
+	if l.listener != nil {
+		go l.run()
+	}
+}
+
+// Stop stops the listener from accepting new connections and all the active tailers.
+func (l *SyslogListener) Stop() {
+	log.Infof("Stopping syslog forwarder on port %d", l.source.Config.Port)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.listener != nil {
+		l.stop <- struct{}{}
+		l.listener.Close()
+	}
+	stopper := startstop.NewParallelStopper()
+	for _, tailer := range l.tailers {
+		stopper.Add(tailer)
+	}
+	stopper.Stop()
+	l.tailers = []*tailer.Tailer{}
+}
+
+// protocol returns the protocol of the source, TCP by default.
+func (l *SyslogListener) protocol() string {
+	if l.source.Config.Protocol == "" {
+		return config.TCPType
+	}
+	return l.source.Config.Protocol
+}
+
+// run accepts new TCP connections and create a dedicated tailer for each.
+func (l *SyslogListener) run() {
+	defer l.listener.Close()
+	for {
+		select {
+		case <-l.stop:
+			// stop accepting new connections.
+			return
+		default:
+			conn, err := l.listener.Accept()
+			switch {
+			case err != nil && isClosedConnError(err):
+				return
+			case err != nil:
+				// an error occurred, restart the listener.
+				log.Warnf("Can't listen on port %d, restarting a listener: %v", l.source.Config.Port, err)
+				l.listener.Close()
+				err := l.startListener()
+				if err != nil {
+					log.Errorf("Can't restart listener on port %d: %v", l.source.Config.Port, err)
+					l.source.Status.Error(err)
+					return
+				}
+				l.source.Status.Success()
+				continue
+			default:
+				l.startTCPTailer(conn)
+				l.source.Status.Success()
+			}
+		}
+	}
+}
+
+// startListener starts a new TCP listener, using TLS when a certificate is configured,
+// returns an error if it failed.
+func (l *SyslogListener) startListener() error {
+	address := fmt.Sprintf(":%d", l.source.Config.Port)
+	if l.source.Config.TLSCertFile == "" {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+		l.listener = listener
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.source.Config.TLSCertFile, l.source.Config.TLSKeyFile)
+	if err != nil {
+		return fmt.Errorf("can't load the TLS certificate: %v", err)
+	}
+	listener, err := tls.Listen("tcp", address, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		return err
+	}
+	l.listener = listener
+	return nil
+}
+
+// startTCPTailer creates and starts a new tailer that reads the frames of the connection.
+func (l *SyslogListener) startTCPTailer(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	frames := tailer.NewFrameReader(conn, l.frameSize)
+	var t *tailer.Tailer
+	t = tailer.NewTailer(l.source, conn, l.pipelineProvider.NextPipelineChan(), func() ([]byte, error) {
+		if l.idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(l.idleTimeout)) //nolint:errcheck
+		}
+		frame, err := frames.ReadFrame()
+		if err != nil {
+			if err != io.EOF {
+				l.source.Status.Error(err)
+			}
+			go l.stopTailer(t)
+		}
+		return frame, err
+	})
+	l.tailers = append(l.tailers, t)
+	t.Start()
+}
+
+// stopTailer stops the tailer.
+func (l *SyslogListener) stopTailer(tailer *tailer.Tailer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, t := range l.tailers {
+		if t == tailer {
+			// Only stop the tailer if it has not already been stopped
+			tailer.Stop()
+			l.tailers = append(l.tailers[:i], l.tailers[i+1:]...)
+			break
+		}
+	}
+}
+
+// startUDPTailer opens a new UDP connection and starts a tailer reading one message per datagram.
+func (l *SyslogListener) startUDPTailer() error {
+	udpAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf(":%d", l.source.Config.Port))
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := tailer.NewTailer(l.source, conn, l.pipelineProvider.NextPipelineChan(), func() ([]byte, error) {
+		// a datagram longer than the frame is truncated
+		frame := make([]byte, l.frameSize)
+		n, err := conn.Read(frame)
+		if err != nil && !isClosedConnError(err) {
+			go l.resetUDPTailer()
+		}
+		return frame[:n], err
+	})
+	l.tailers = append(l.tailers, t)
+	t.Start()
+	return nil
+}
+
+// resetUDPTailer creates a new UDP tailer.
+func (l *SyslogListener) resetUDPTailer() {
+	log.Infof("Resetting the syslog UDP connection on port: %d", l.source.Config.Port)
+	l.Stop()
+	err := l.startUDPTailer()
+	if err != nil {
+		log.Errorf("Could not reset the syslog UDP connection on port %d: %v", l.source.Config.Port, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.source.Status.Success()
+}
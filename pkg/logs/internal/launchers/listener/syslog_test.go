@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestSyslogTCPShouldReceiveFramedMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewSyslogListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Port: tcpTestPort}), 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rfc5424 := `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3"] An application event`
+	fmt.Fprintf(conn, "%d %s", len(rfc5424), rfc5424)
+	fmt.Fprintf(conn, "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8\n")
+
+	var msg *message.Message
+	msg = <-msgChan
+	assert.Equal(t, "An application event", string(msg.Content))
+	assert.Equal(t, message.StatusNotice, msg.GetStatus())
+	assert.Equal(t, "mymachine.example.com", msg.Hostname)
+	assert.Equal(t, "evntslog", msg.Origin.Service())
+	assert.Contains(t, msg.Origin.Tags(), "exampleSDID@32473.iut:3")
+
+	msg = <-msgChan
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(msg.Content))
+	assert.Equal(t, message.StatusCritical, msg.GetStatus())
+	assert.Equal(t, "mymachine", msg.Hostname)
+	assert.Equal(t, "su", msg.Origin.Service())
+	assert.Equal(t, 1, len(listener.tailers))
+}
+
+func TestSyslogTLSShouldReceiveMessages(t *testing.T) {
+	certFile, keyFile := generateTestCertificate(t)
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewSyslogListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Port: tcpTestPort, TLSCertFile: certFile, TLSKeyFile: keyFile}), 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := tls.Dial("tcp", listener.listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "<13>1 - - app - - - hello world\n")
+	msg := <-msgChan
+	assert.Equal(t, "hello world", string(msg.Content))
+	assert.Equal(t, message.StatusNotice, msg.GetStatus())
+}
+
+func TestSyslogTLSShouldFailWithoutCertificate(t *testing.T) {
+	pp := mock.NewMockProvider()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Port: tcpTestPort, TLSCertFile: "/missing/cert.pem", TLSKeyFile: "/missing/key.pem"})
+	listener := NewSyslogListener(pp, source, 9000)
+	listener.Start()
+	defer listener.Stop()
+	assert.True(t, source.Status.IsError())
+}
+
+func TestSyslogUDPShouldReceiveMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewSyslogListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Port: udpTestPort, Protocol: config.UDPType}), 9000)
+	listener.Start()
+	defer listener.Stop()
+	require.Len(t, listener.tailers, 1)
+
+	conn, err := net.Dial("udp", listener.tailers[0].Conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "<11>Oct 11 22:14:15 myhost app[42]: something failed")
+	msg := <-msgChan
+	assert.Equal(t, "something failed", string(msg.Content))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Contains(t, msg.Origin.Tags(), "syslog_procid:42")
+}
+
+// generateTestCertificate writes a self-signed certificate and its key, and returns their paths.
+func generateTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600))
+	return certFile, keyFile
+}
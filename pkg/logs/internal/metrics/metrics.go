@@ -24,6 +24,21 @@ var (
 	// TlmLogsProcessed is the total number of processed logs.
 	TlmLogsProcessed = telemetry.NewCounter("logs", "processed",
 		nil, "Total number of processed logs")
+	// LogsParsingFailures is the number of logs which couldn't be parsed, per parsing rule
+	LogsParsingFailures = expvar.Map{}
+	// TlmLogsParsingFailures is the number of logs which couldn't be parsed, per parsing rule
+	TlmLogsParsingFailures = telemetry.NewCounter("logs", "parsing_failures",
+		[]string{"rule"}, "Number of logs which couldn't be parsed per parsing rule")
+	// LogsMetricFailures is the number of logs whose metric couldn't be generated, per processing rule
+	LogsMetricFailures = expvar.Map{}
+	// TlmLogsMetricFailures is the number of logs whose metric couldn't be generated, per processing rule
+	TlmLogsMetricFailures = telemetry.NewCounter("logs", "metric_failures",
+		[]string{"rule"}, "Number of logs whose metric couldn't be generated per processing rule")
+	// LogsDroppedByRule is the number of logs dropped by the sample and dedupe rules, per processing rule
+	LogsDroppedByRule = expvar.Map{}
+	// TlmLogsDroppedByRule is the number of logs dropped by the sample and dedupe rules, per processing rule
+	TlmLogsDroppedByRule = telemetry.NewCounter("logs", "dropped_by_rule",
+		[]string{"rule"}, "Number of logs dropped by the sample and dedupe rules per processing rule")
 
 	// LogsSent is the total number of sent logs.
 	LogsSent = expvar.Int{}
@@ -66,6 +81,9 @@ func init() {
 	LogsExpvars = expvar.NewMap("logs-agent")
 	LogsExpvars.Set("LogsDecoded", &LogsDecoded)
 	LogsExpvars.Set("LogsProcessed", &LogsProcessed)
+	LogsExpvars.Set("LogsParsingFailures", &LogsParsingFailures)
+	LogsExpvars.Set("LogsMetricFailures", &LogsMetricFailures)
+	LogsExpvars.Set("LogsDroppedByRule", &LogsDroppedByRule)
 	LogsExpvars.Set("LogsSent", &LogsSent)
 	LogsExpvars.Set("DestinationErrors", &DestinationErrors)
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
@@ -6,6 +6,7 @@
 package processor
 
 import (
+	"strconv"
 	"unicode"
 	"unicode/utf8"
 
@@ -33,3 +34,9 @@ func toValidUtf8(msg []byte) string {
 	}
 	return string(str)
 }
+
+// repeatCount returns the number of identical log lines collapsed by a dedupe rule
+// into the message, as a string, for the formats without a dedicated field.
+func repeatCount(msg *message.Message) string {
+	return strconv.Itoa(msg.RepeatCount)
+}
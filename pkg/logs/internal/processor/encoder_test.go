@@ -57,6 +57,19 @@ func TestRawEncoder(t *testing.T) {
 
 }
 
+func TestRawEncoderRepeatCount(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{Source: "Source"})
+	msg := newMessage([]byte("message"), source, message.StatusError)
+	msg.RepeatCount = 3
+
+	raw, err := RawEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+
+	content := string(raw)
+	extra := content[strings.Index(content, "[") : strings.LastIndex(content, "]")+1]
+	assert.Equal(t, "[dd ddsource=\"Source\"][dd repeat_count=\"3\"]", extra)
+}
+
 func TestRawEncoderDefaults(t *testing.T) {
 
 	logsConfig := &config.LogsConfig{}
@@ -148,6 +161,20 @@ func TestProtoEncoder(t *testing.T) {
 
 }
 
+func TestProtoEncoderRepeatCount(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("message"), source, message.StatusError)
+	msg.Origin.SetTags([]string{"a"})
+	msg.RepeatCount = 3
+
+	proto, err := ProtoEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+
+	log := &pb.Log{}
+	assert.Nil(t, log.Unmarshal(proto))
+	assert.Equal(t, []string{"a", "repeat_count:3"}, log.Tags)
+}
+
 func TestProtoEncoderEmpty(t *testing.T) {
 
 	logsConfig := &config.LogsConfig{}
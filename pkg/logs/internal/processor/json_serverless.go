@@ -22,13 +22,14 @@ type jsonServerlessEncoder struct{}
 
 // JSON representation of a message.
 type jsonServerlessPayload struct {
-	Message   jsonServerlessMessage `json:"message"`
-	Status    string                `json:"status"`
-	Timestamp int64                 `json:"timestamp"`
-	Hostname  string                `json:"hostname"`
-	Service   string                `json:"service,omitempty"`
-	Source    string                `json:"ddsource"`
-	Tags      string                `json:"ddtags"`
+	Message     jsonServerlessMessage `json:"message"`
+	Status      string                `json:"status"`
+	Timestamp   int64                 `json:"timestamp"`
+	Hostname    string                `json:"hostname"`
+	Service     string                `json:"service,omitempty"`
+	Source      string                `json:"ddsource"`
+	Tags        string                `json:"ddtags"`
+	RepeatCount int                   `json:"repeat_count,omitempty"`
 }
 
 type jsonServerlessMessage struct {
@@ -62,11 +63,12 @@ func (j *jsonServerlessEncoder) Encode(msg *message.Message, redactedMsg []byte)
 			Message: toValidUtf8(redactedMsg),
 			Lambda:  lambdaPart,
 		},
-		Status:    msg.GetStatus(),
-		Timestamp: ts.UnixNano() / nanoToMillis,
-		Hostname:  msg.GetHostname(),
-		Service:   msg.Origin.Service(),
-		Source:    msg.Origin.Source(),
-		Tags:      msg.Origin.TagsToString(),
+		Status:      msg.GetStatus(),
+		Timestamp:   ts.UnixNano() / nanoToMillis,
+		Hostname:    msg.GetHostname(),
+		Service:     msg.Origin.Service(),
+		Source:      msg.Origin.Source(),
+		Tags:        msg.Origin.TagsToString(),
+		RepeatCount: msg.RepeatCount,
 	})
 }
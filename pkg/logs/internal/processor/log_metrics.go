@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	pkgmetrics "github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// metricSampleBatchSize is the number of metric samples buffered by a processor
+// before they are submitted.
+const metricSampleBatchSize = 32
+
+var errNoMetricSink = errors.New("the metrics can't be submitted by this agent")
+
+// metricBatcher buffers the metrics generated from the logs to submit them in batches
+// to the sink, which aggregates them as DogStatsD metrics.
+type metricBatcher struct {
+	mu      sync.Mutex
+	sink    pkgmetrics.MetricSampleSink
+	samples []pkgmetrics.MetricSample
+}
+
+func newMetricBatcher(sink pkgmetrics.MetricSampleSink) *metricBatcher {
+	return &metricBatcher{
+		sink:    sink,
+		samples: make([]pkgmetrics.MetricSample, 0, metricSampleBatchSize),
+	}
+}
+
+// add buffers the sample, the batch is submitted once full.
+func (b *metricBatcher) add(sample pkgmetrics.MetricSample) error {
+	if b == nil || b.sink == nil {
+		return errNoMetricSink
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.samples = append(b.samples, sample)
+	if len(b.samples) >= metricSampleBatchSize {
+		b.flushLocked()
+	}
+	return nil
+}
+
+// flush submits the buffered samples.
+func (b *metricBatcher) flush() {
+	if b == nil || b.sink == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+func (b *metricBatcher) flushLocked() {
+	if len(b.samples) == 0 {
+		return
+	}
+	b.sink.AddMetricSamples(b.samples)
+	b.samples = b.samples[:0]
+}
+
+// applyMetricRule buffers the metric of the rule if the content matches its pattern,
+// it returns false when the log line must be dropped afterwards. The lines whose
+// metric can't be generated are never dropped.
+func (p *Processor) applyMetricRule(msg *message.Message, rule *config.ProcessingRule, content []byte) bool {
+	match := rule.Regex.FindSubmatch(content)
+	if match == nil {
+		return true
+	}
+	sample, err := newMetricSample(msg, rule, match)
+	if err == nil {
+		err = p.metricSamples.add(sample)
+	}
+	if err != nil {
+		log.Debugf("Can't generate a metric with the processing rule %s: %s", rule.Name, err)
+		metrics.LogsMetricFailures.Add(rule.Name, 1)
+		metrics.TlmLogsMetricFailures.Inc(rule.Name)
+		return true
+	}
+	return !rule.DropLog
+}
+
+// newMetricSample returns the metric sample of the rule for a log line matched by its pattern,
+// its value is the named group of the rule, or 1 without one.
+func newMetricSample(msg *message.Message, rule *config.ProcessingRule, match [][]byte) (pkgmetrics.MetricSample, error) {
+	value := 1.0
+	if rule.ValueGroup != "" {
+		group := match[rule.Regex.SubexpIndex(rule.ValueGroup)]
+		var err error
+		if value, err = strconv.ParseFloat(string(group), 64); err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return pkgmetrics.MetricSample{}, fmt.Errorf("invalid metric value %q", group)
+		}
+	}
+	mtype := pkgmetrics.CountType
+	if rule.MetricType == config.MetricTypeDistribution {
+		mtype = pkgmetrics.DistributionType
+	}
+	return pkgmetrics.MetricSample{
+		Name:       rule.MetricName,
+		Value:      value,
+		Mtype:      mtype,
+		Tags:       metricTags(msg.Origin),
+		SampleRate: 1,
+	}, nil
+}
+
+// metricTags returns the tags of the metrics generated from the logs of the origin:
+// its tags, its source and its service. The name of the tailed file is not a tag of
+// the metrics, it would create a context per file.
+func metricTags(origin *message.Origin) []string {
+	originTags := origin.Tags()
+	tags := make([]string, 0, len(originTags)+2)
+	for _, tag := range originTags {
+		if !strings.HasPrefix(tag, "filename:") {
+			tags = append(tags, tag)
+		}
+	}
+	if source := origin.Source(); source != "" {
+		tags = append(tags, "source:"+source)
+	}
+	if service := origin.Service(); service != "" {
+		tags = append(tags, "service:"+service)
+	}
+	return tags
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	pkgmetrics "github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// testMetricSink sends the batches it receives to a channel.
+type testMetricSink chan []pkgmetrics.MetricSample
+
+func (s testMetricSink) AddMetricSamples(samples []pkgmetrics.MetricSample) {
+	s <- append([]pkgmetrics.MetricSample(nil), samples...)
+}
+
+func newTestMetricProcessor() (*Processor, testMetricSink) {
+	sink := make(testMetricSink, 10)
+	return &Processor{metricSamples: newMetricBatcher(sink)}, sink
+}
+
+func TestGenerateCountMetric(t *testing.T) {
+	p, sink := newTestMetricProcessor()
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:       config.GenerateMetric,
+		Pattern:    `status=5\d\d`,
+		MetricName: "app.requests.errors",
+	})
+	source.Config.Service = "web"
+	source.Config.Source = "nginx"
+
+	shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("GET / status=200"), source, ""))
+	assert.True(t, shouldProcess)
+	p.metricSamples.flush()
+	assert.Empty(t, sink)
+
+	// the name of the tailed file is not a tag of the metric
+	msg := newMessage([]byte("GET / status=503"), source, "")
+	msg.Origin.SetTags([]string{"filename:access.log", "dirname:/var/log/nginx"})
+	shouldProcess, _ = p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	p.metricSamples.flush()
+	assert.Equal(t, []pkgmetrics.MetricSample{{
+		Name:       "app.requests.errors",
+		Value:      1,
+		Mtype:      pkgmetrics.CountType,
+		Tags:       []string{"dirname:/var/log/nginx", "env:prod", "source:nginx", "service:web"},
+		SampleRate: 1,
+	}}, <-sink)
+}
+
+func TestGenerateDistributionMetric(t *testing.T) {
+	p, sink := newTestMetricProcessor()
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:       config.GenerateMetric,
+		Pattern:    `duration=(?P<duration>\S+)ms`,
+		MetricName: "app.requests.duration",
+		MetricType: config.MetricTypeDistribution,
+		ValueGroup: "duration",
+		DropLog:    true,
+	})
+
+	// the matching lines are dropped
+	shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("GET / duration=12.5ms"), source, ""))
+	assert.False(t, shouldProcess)
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("GET / done"), source, ""))
+	assert.True(t, shouldProcess)
+
+	// a line whose value can't be parsed is kept
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("GET / duration=NaNms"), source, ""))
+	assert.True(t, shouldProcess)
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("GET / duration=abcms"), source, ""))
+	assert.True(t, shouldProcess)
+
+	p.metricSamples.flush()
+	samples := <-sink
+	assert.Len(t, samples, 1)
+	assert.Equal(t, "app.requests.duration", samples[0].Name)
+	assert.Equal(t, 12.5, samples[0].Value)
+	assert.Equal(t, pkgmetrics.DistributionType, samples[0].Mtype)
+	assert.Equal(t, []string{"env:prod"}, samples[0].Tags)
+}
+
+func TestGenerateMetricBatches(t *testing.T) {
+	p, sink := newTestMetricProcessor()
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:       config.GenerateMetric,
+		Pattern:    `error`,
+		MetricName: "app.errors",
+	})
+
+	// the samples are submitted once the batch is full
+	for i := 0; i < metricSampleBatchSize+1; i++ {
+		p.applyRedactingRules(newMessage([]byte("an error"), source, ""))
+	}
+	assert.Len(t, sink, 1)
+	assert.Len(t, <-sink, metricSampleBatchSize)
+	p.metricSamples.flush()
+	assert.Len(t, <-sink, 1)
+	p.metricSamples.flush()
+	assert.Empty(t, sink)
+}
+
+func TestGenerateMetricWithoutSink(t *testing.T) {
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:       config.GenerateMetric,
+		Pattern:    `error`,
+		MetricName: "app.errors",
+		DropLog:    true,
+	})
+	p := &Processor{metricSamples: newMetricBatcher(nil)}
+
+	// the line is kept when its metric can't be submitted
+	shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("an error"), source, ""))
+	assert.True(t, shouldProcess)
+}
+
+func TestProcessorSubmitsMetrics(t *testing.T) {
+	sink := make(testMetricSink, 10)
+	inputChan := make(chan *message.Message, 10)
+	outputChan := make(chan *message.Message, 10)
+	p := New(inputChan, outputChan, nil, RawEncoder, diagnostic.NewBufferedMessageReceiver(), sink)
+	p.Start()
+	defer p.Stop()
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:       config.GenerateMetric,
+		Pattern:    `error`,
+		MetricName: "app.errors",
+	})
+
+	// the samples are submitted once there is no more line to process
+	inputChan <- newMessage([]byte("an error"), source, "")
+	<-outputChan
+	samples := <-sink
+	assert.Len(t, samples, 1)
+	assert.Equal(t, "app.errors", samples[0].Name)
+}
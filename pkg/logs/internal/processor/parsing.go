@@ -0,0 +1,204 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// applyParsingRule parses the content of the message with the rule and promotes
+// the parsed fields to the status, the service, the timestamp and the tags of the
+// message. The message is not modified when an error is returned.
+func applyParsingRule(msg *message.Message, rule *config.ProcessingRule, content []byte) error {
+	var fields map[string]string
+	var err error
+	switch rule.Type {
+	case config.JSONParsing:
+		fields, err = parseJSONFields(content)
+	case config.KeyValueParsing:
+		fields, err = parseKeyValueFields(content)
+	case config.GrokParsing:
+		fields, err = parseGrokFields(rule, content)
+	default:
+		err = fmt.Errorf("unsupported parsing rule type %s", rule.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	var timestamp time.Time
+	if value, found := fields[rule.TimestampField]; found && rule.TimestampField != "" {
+		if timestamp, err = parseTimestamp(value, rule.TimestampFormat); err != nil {
+			return err
+		}
+	}
+
+	if !timestamp.IsZero() {
+		msg.Timestamp = timestamp.UTC()
+	}
+	if value, found := fields[rule.StatusField]; found && rule.StatusField != "" {
+		if status, found := message.StatusFromName(value); found {
+			msg.SetStatus(status)
+		}
+	}
+
+	// the origin can be shared by several messages
+	origin := *msg.Origin
+	msg.Origin = &origin
+	if value, found := fields[rule.ServiceField]; found && rule.ServiceField != "" {
+		origin.SetService(value)
+	}
+	var tags []string
+	for _, field := range rule.TagFields {
+		if value, found := fields[field]; found {
+			tags = append(tags, field+":"+value)
+		}
+	}
+	if len(tags) > 0 {
+		origin.AddTags(tags...)
+	}
+	return nil
+}
+
+// parseJSONFields returns the fields of a JSON object, the fields of the nested
+// objects are named with dots.
+func parseJSONFields(content []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string)
+	flattenJSONFields("", object, fields)
+	return fields, nil
+}
+
+func flattenJSONFields(prefix string, object map[string]interface{}, fields map[string]string) {
+	for key, value := range object {
+		name := prefix + key
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenJSONFields(name+".", v, fields)
+		case string:
+			fields[name] = v
+		case json.Number:
+			fields[name] = v.String()
+		case bool:
+			fields[name] = strconv.FormatBool(v)
+		case nil:
+			continue
+		default:
+			if data, err := json.Marshal(v); err == nil {
+				fields[name] = string(data)
+			}
+		}
+	}
+}
+
+// parseKeyValueFields returns the key=value pairs of a logfmt content. The values
+// can be double quoted, the words which are not followed by = are ignored.
+func parseKeyValueFields(content []byte) (map[string]string, error) {
+	fields := make(map[string]string)
+	for i := 0; i < len(content); {
+		if isSpace(content[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(content) && content[i] != '=' && !isSpace(content[i]) {
+			i++
+		}
+		key := string(content[start:i])
+		if i == len(content) || content[i] != '=' {
+			continue
+		}
+		i++
+
+		var value string
+		if i < len(content) && content[i] == '"' {
+			end := i + 1
+			for end < len(content) && content[end] != '"' {
+				if content[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(content) {
+				return nil, fmt.Errorf("unterminated quoted value of the key %s", key)
+			}
+			unquoted, err := strconv.Unquote(string(content[i : end+1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted value of the key %s: %s", key, err)
+			}
+			value = unquoted
+			i = end + 1
+		} else {
+			start = i
+			for i < len(content) && !isSpace(content[i]) {
+				i++
+			}
+			value = string(content[start:i])
+		}
+		if key != "" {
+			fields[key] = value
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no key/value pair found")
+	}
+	return fields, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// parseGrokFields returns the fields captured by the grok pattern of the rule.
+func parseGrokFields(rule *config.ProcessingRule, content []byte) (map[string]string, error) {
+	submatches := rule.Regex.FindSubmatch(content)
+	if submatches == nil {
+		return nil, fmt.Errorf("the content doesn't match the pattern")
+	}
+	fields := make(map[string]string)
+	for i, name := range rule.Regex.SubexpNames() {
+		if name != "" && submatches[i] != nil {
+			fields[name] = string(submatches[i])
+		}
+	}
+	return fields, nil
+}
+
+// parseTimestamp parses a timestamp with one of the formats of the parsing rules.
+func parseTimestamp(value, format string) (time.Time, error) {
+	switch format {
+	case config.TimestampFormatUnix, config.TimestampFormatUnixMs:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		unit := time.Second
+		if format == config.TimestampFormatUnixMs {
+			unit = time.Millisecond
+		}
+		// the integer part is exact, only the fraction is rounded
+		integer, fraction := math.Modf(number)
+		return time.Unix(0, int64(integer)*int64(unit)+int64(math.Round(fraction*float64(unit)))), nil
+	case "":
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		return time.Parse(format, value)
+	}
+}
@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newParsingSource(t *testing.T, rule *config.ProcessingRule) *sources.LogSource {
+	rule.Name = "my_" + rule.Type
+	require.NoError(t, config.ValidateProcessingRules([]*config.ProcessingRule{rule}))
+	require.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	return sources.NewLogSource("", &config.LogsConfig{Tags: []string{"env:prod"}, ProcessingRules: []*config.ProcessingRule{rule}})
+}
+
+func parsingFailures(rule string) int64 {
+	if failures, ok := metrics.LogsParsingFailures.Get(rule).(*expvar.Int); ok {
+		return failures.Value()
+	}
+	return 0
+}
+
+func TestJSONParsing(t *testing.T) {
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:            config.JSONParsing,
+		StatusField:     "level",
+		ServiceField:    "app.name",
+		TimestampField:  "ts",
+		TimestampFormat: config.TimestampFormatUnixMs,
+		TagFields:       []string{"user", "http.status", "missing"},
+	})
+	p := &Processor{}
+
+	msg := newMessage([]byte(`{"level":"ERROR","app":{"name":"billing"},"ts":1656928800123,"user":"bob","http":{"status":500},"msg":"failed"}`), source, "")
+	shouldProcess, _ := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "billing", msg.Origin.Service())
+	assert.Equal(t, time.Unix(1656928800, 123000000).UTC(), msg.Timestamp)
+	assert.Equal(t, []string{"user:bob", "http.status:500", "env:prod"}, msg.Origin.Tags())
+
+	// the messages which can't be parsed are sent unchanged
+	failures := parsingFailures("my_parse_json")
+	msg = newMessage([]byte(`level=error`), source, "")
+	shouldProcess, _ = p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Equal(t, failures+1, parsingFailures("my_parse_json"))
+}
+
+func TestKeyValueParsing(t *testing.T) {
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:           config.KeyValueParsing,
+		StatusField:    "level",
+		TimestampField: "time",
+		TagFields:      []string{"msg", "path"},
+	})
+	p := &Processor{}
+
+	msg := newMessage([]byte(`time=2022-07-04T10:00:00Z level=warning msg="disk \"data\" is full" path=/var/lib standalone`), source, "")
+	p.applyRedactingRules(msg)
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, time.Date(2022, 7, 4, 10, 0, 0, 0, time.UTC), msg.Timestamp)
+	assert.Equal(t, []string{`msg:disk "data" is full`, "path:/var/lib", "env:prod"}, msg.Origin.Tags())
+
+	for _, content := range []string{`no pairs here`, `msg="unterminated`} {
+		_, err := parseKeyValueFields([]byte(content))
+		assert.Error(t, err, content)
+	}
+}
+
+func TestGrokParsing(t *testing.T) {
+	source := newParsingSource(t, &config.ProcessingRule{
+		Type:            config.GrokParsing,
+		Pattern:         `^%{HTTPDATE:date} %{LOGLEVEL:level} %{IP:client}`,
+		StatusField:     "level",
+		TimestampField:  "date",
+		TimestampFormat: "02/Jan/2006:15:04:05 -0700",
+		TagFields:       []string{"client"},
+	})
+	p := &Processor{}
+
+	msg := newMessage([]byte(`04/Jul/2022:12:00:00 +0200 crit 10.0.0.1 connection refused`), source, "")
+	p.applyRedactingRules(msg)
+	assert.Equal(t, message.StatusCritical, msg.GetStatus())
+	assert.Equal(t, time.Date(2022, 7, 4, 10, 0, 0, 0, time.UTC), msg.Timestamp)
+	assert.Equal(t, []string{"client:10.0.0.1", "env:prod"}, msg.Origin.Tags())
+
+	// an invalid timestamp is a parsing failure, nothing is promoted
+	msg = newMessage([]byte(`04/Jul/2022:12:00:00 +0200 crit 10.0.0.1 connection refused`), source, "")
+	source.Config.ProcessingRules[0].TimestampFormat = config.TimestampFormatUnix
+	p.applyRedactingRules(msg)
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.True(t, msg.Timestamp.IsZero())
+}
+
+func TestParsingAfterMasking(t *testing.T) {
+	mask := newProcessingRule(config.MaskSequences, "user=[redacted]", `user=\S+`)
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.KeyValueParsing, TagFields: []string{"user"}})
+	source.Config.ProcessingRules = append([]*config.ProcessingRule{mask}, source.Config.ProcessingRules...)
+	p := &Processor{}
+
+	msg := newMessage([]byte(`user=bob action=login`), source, "")
+	_, redacted := p.applyRedactingRules(msg)
+	assert.Equal(t, "user=[redacted] action=login", string(redacted))
+	assert.Equal(t, []string{"user:[redacted]", "env:prod"}, msg.Origin.Tags())
+}
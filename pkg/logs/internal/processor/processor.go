@@ -8,6 +8,9 @@ package processor
 import (
 	"context"
 	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -15,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	pkgmetrics "github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 // A Processor updates messages from an inputChan and pushes
@@ -27,10 +31,18 @@ type Processor struct {
 	done                      chan struct{}
 	diagnosticMessageReceiver diagnostic.MessageReceiver
 	mu                        sync.Mutex
+	ruleStates                ruleStates
+	metricSamples             *metricBatcher
+	clock                     clock.Clock
+}
+
+// New returns an initialized Processor. The metrics generated from the logs are
+// submitted to metricSink, the rules generating metrics fail when it is nil.
+func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, metricSink pkgmetrics.MetricSampleSink) *Processor {
+	return newWithClock(inputChan, outputChan, processingRules, encoder, diagnosticMessageReceiver, metricSink, clock.New())
 }
 
-// New returns an initialized Processor.
-func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver) *Processor {
+func newWithClock(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, metricSink pkgmetrics.MetricSampleSink, clock clock.Clock) *Processor {
 	return &Processor{
 		inputChan:                 inputChan,
 		outputChan:                outputChan,
@@ -38,6 +50,8 @@ func New(inputChan, outputChan chan *message.Message, processingRules []*config.
 		encoder:                   encoder,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
+		metricSamples:             newMetricBatcher(metricSink),
+		clock:                     clock,
 	}
 }
 
@@ -53,7 +67,9 @@ func (p *Processor) Stop() {
 	<-p.done
 }
 
-// Flush processes synchronously the messages that this processor has to process.
+// Flush processes synchronously the messages that this processor has to process,
+// the log lines held by the dedupe rules are forwarded and the buffered metrics
+// are submitted.
 func (p *Processor) Flush(ctx context.Context) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -63,6 +79,8 @@ func (p *Processor) Flush(ctx context.Context) {
 			return
 		default:
 			if len(p.inputChan) == 0 {
+				p.forwardDuplicates(true)
+				p.metricSamples.flush()
 				return
 			}
 			msg := <-p.inputChan
@@ -76,8 +94,34 @@ func (p *Processor) run() {
 	defer func() {
 		p.done <- struct{}{}
 	}()
-	for msg := range p.inputChan {
-		p.processMessage(msg)
+	// the ticker forwarding the held log lines starts once a dedupe rule holds one
+	var dedupeTicker *clock.Ticker
+	var dedupeTick <-chan time.Time
+	defer func() {
+		if dedupeTicker != nil {
+			dedupeTicker.Stop()
+		}
+	}()
+	for {
+		select {
+		case msg, ok := <-p.inputChan:
+			if !ok {
+				p.forwardDuplicates(true)
+				p.metricSamples.flush()
+				return
+			}
+			p.processMessage(msg)
+			if dedupeTicker == nil && p.ruleStates.holding.Load() {
+				dedupeTicker = p.clock.Ticker(dedupeFlushInterval)
+				dedupeTick = dedupeTicker.C
+			}
+		case <-dedupeTick:
+			p.forwardDuplicates(false)
+		}
+		// the metrics are submitted in batches while the log lines keep coming
+		if len(p.inputChan) == 0 {
+			p.metricSamples.flush()
+		}
 		p.mu.Lock() // block here if we're trying to flush synchronously
 		//nolint:staticcheck
 		p.mu.Unlock()
@@ -87,29 +131,45 @@ func (p *Processor) run() {
 func (p *Processor) processMessage(msg *message.Message) {
 	metrics.LogsDecoded.Add(1)
 	metrics.TlmLogsDecoded.Inc()
+	if p.ruleStates.holding.Load() {
+		p.ruleStates.received(msg)
+	}
 	if shouldProcess, redactedMsg := p.applyRedactingRules(msg); shouldProcess {
-		metrics.LogsProcessed.Add(1)
-		metrics.TlmLogsProcessed.Inc()
+		p.forward(msg, redactedMsg)
+	}
+}
 
-		p.diagnosticMessageReceiver.HandleMessage(*msg, redactedMsg)
+// forward encodes a processed message and sends it to the output channel.
+func (p *Processor) forward(msg *message.Message, redactedMsg []byte) {
+	metrics.LogsProcessed.Add(1)
+	metrics.TlmLogsProcessed.Inc()
 
-		// Encode the message to its final format
-		content, err := p.encoder.Encode(msg, redactedMsg)
-		if err != nil {
-			log.Error("unable to encode msg ", err)
-			return
-		}
-		msg.Content = content
-		p.outputChan <- msg
+	p.diagnosticMessageReceiver.HandleMessage(*msg, redactedMsg)
+
+	// Encode the message to its final format
+	content, err := p.encoder.Encode(msg, redactedMsg)
+	if err != nil {
+		log.Error("unable to encode msg ", err)
+		return
 	}
+	msg.Content = content
+	p.outputChan <- msg
 }
 
 // applyRedactingRules returns given a message if we should process it or not,
-// and a copy of the message with some fields redacted, depending on config
+// and a copy of the message with some fields redacted, depending on config.
+// The parsing rules promote the fields of the redacted content to the message and
+// the rules generating metrics submit a metric for the matching redacted content.
+// The log lines dropped by the sample rules or held by the dedupe rules are not processed.
 func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
-	content := msg.Content
+	return p.applyRules(msg, msg.Content, 0)
+}
+
+// applyRules applies the rules from the rule at index start on the content of the message.
+func (p *Processor) applyRules(msg *message.Message, content []byte, start int) (bool, []byte) {
 	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
-	for _, rule := range rules {
+	for i := start; i < len(rules); i++ {
+		rule := rules[i]
 		switch rule.Type {
 		case config.ExcludeAtMatch:
 			if rule.Regex.Match(content) {
@@ -121,6 +181,31 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+		case config.JSONParsing, config.KeyValueParsing, config.GrokParsing:
+			if err := applyParsingRule(msg, rule, content); err != nil {
+				log.Tracef("Can't parse a log with the processing rule %s: %s", rule.Name, err)
+				metrics.LogsParsingFailures.Add(rule.Name, 1)
+				metrics.TlmLogsParsingFailures.Inc(rule.Name)
+			}
+		case config.GenerateMetric:
+			if !p.applyMetricRule(msg, rule, content) {
+				return false, nil
+			}
+		case config.Sample:
+			if rule.Regex != nil && !rule.Regex.Match(content) {
+				continue
+			}
+			if !p.ruleStates.sample(rule, msg.Origin.LogSource, p.clock.Now()) {
+				recordDroppedByRule(rule)
+				return false, nil
+			}
+		case config.Dedupe:
+			if rule.Regex != nil && !rule.Regex.Match(content) {
+				continue
+			}
+			if p.ruleStates.dedupe(rule, i, msg, content, p.clock.Now()) {
+				return false, nil
+			}
 		}
 	}
 	return true, content
@@ -20,6 +20,10 @@ type protoEncoder struct{}
 
 // Encode encodes a message into a protobuf byte array.
 func (p *protoEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, error) {
+	tags := msg.Origin.Tags()
+	if msg.RepeatCount > 1 {
+		tags = append(tags, "repeat_count:"+repeatCount(msg))
+	}
 	return (&pb.Log{
 		Message:   toValidUtf8(redactedMsg),
 		Status:    msg.GetStatus(),
@@ -27,6 +31,6 @@ func (p *protoEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte,
 		Hostname:  msg.GetHostname(),
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
-		Tags:      msg.Origin.Tags(),
+		Tags:      tags,
 	}).Marshal()
 }
@@ -54,6 +54,9 @@ func (r *rawEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, e
 
 		// Tags
 		tagsPayload := msg.Origin.TagsPayload()
+		if msg.RepeatCount > 1 {
+			tagsPayload = append(tagsPayload, []byte("[dd repeat_count=\""+repeatCount(msg)+"\"]")...)
+		}
 		if len(tagsPayload) > 0 {
 			extraContent = append(extraContent, tagsPayload...)
 		} else {
@@ -0,0 +1,256 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// dedupeFlushInterval is the interval at which the processor forwards the log
+// lines held by the dedupe rules whose window is over.
+const dedupeFlushInterval = time.Second
+
+// maxPendingDuplicates bounds the number of distinct log lines held by the dedupe
+// rules of a processor, the lines received beyond are not deduplicated.
+const maxPendingDuplicates = 10000
+
+// maxSamplers bounds the number of sample rule states of a processor, the states
+// idle for samplerIdleTimeout are removed once it is reached.
+const maxSamplers = 10000
+
+// samplerIdleTimeout is the time after which the state of a sample rule for a
+// source which has not received any log line can be removed.
+const samplerIdleTimeout = time.Minute
+
+// ruleStates holds the state of the sample and dedupe rules of a processor. As
+// each processor has its own state, the rates are per logs pipeline.
+type ruleStates struct {
+	mu         sync.Mutex
+	samplers   map[samplerKey]*sampler
+	duplicates map[duplicateKey]*duplicate
+	// identifiers holds the registry identifiers of the held log lines.
+	identifiers map[string]*heldIdentifier
+	// holding is true once a log line has been held by a dedupe rule.
+	holding atomic.Bool
+}
+
+// heldIdentifier tracks the latest log line received for a registry identifier
+// which has held log lines, so that a held line never commits an offset older
+// than the one of a line forwarded since.
+type heldIdentifier struct {
+	held   int
+	latest *message.Origin
+}
+
+// samplerKey identifies the log lines of a source for a sample rule.
+type samplerKey struct {
+	rule   string
+	source *sources.LogSource
+}
+
+// sampler counts the log lines of a source matched by a sample rule, in the current
+// second for the rules keeping N lines per second.
+type sampler struct {
+	count       int
+	windowStart time.Time
+	lastSeen    time.Time
+}
+
+// duplicateKey identifies the identical log lines of a source for a dedupe rule.
+type duplicateKey struct {
+	rule    string
+	source  *sources.LogSource
+	content string
+}
+
+// duplicate is the first of identical log lines held by a dedupe rule until the end
+// of its window, the remaining rules are applied once it is over. It is forwarded
+// with the offset of the last identical line.
+type duplicate struct {
+	msg                *message.Message
+	content            []byte
+	ruleIndex          int
+	count              int
+	received           time.Time
+	deadline           time.Time
+	last               *message.Origin
+	ingestionTimestamp int64
+}
+
+// sample returns true if the log line of the source is kept by the sample rule.
+// Each source is sampled independently. The line is kept if no more rule state can
+// be tracked.
+func (s *ruleStates) sample(rule *config.ProcessingRule, source *sources.LogSource, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.samplers == nil {
+		s.samplers = make(map[samplerKey]*sampler)
+	}
+	key := samplerKey{rule: rule.Name, source: source}
+	state, found := s.samplers[key]
+	if !found {
+		if len(s.samplers) >= maxSamplers {
+			s.removeIdleSamplers(now)
+			if len(s.samplers) >= maxSamplers {
+				return true
+			}
+		}
+		state = &sampler{windowStart: now}
+		s.samplers[key] = state
+	}
+	state.lastSeen = now
+
+	if rule.KeepOneIn > 0 {
+		keep := state.count == 0
+		state.count = (state.count + 1) % rule.KeepOneIn
+		return keep
+	}
+	if now.Sub(state.windowStart) >= time.Second {
+		state.count = 0
+		state.windowStart = now
+	}
+	state.count++
+	return state.count <= rule.MaxPerSecond
+}
+
+// removeIdleSamplers removes the sample rule states which have not been used for
+// samplerIdleTimeout, such as the ones of the removed sources.
+func (s *ruleStates) removeIdleSamplers(now time.Time) {
+	for key, state := range s.samplers {
+		if now.Sub(state.lastSeen) >= samplerIdleTimeout {
+			delete(s.samplers, key)
+		}
+	}
+}
+
+// dedupe holds the log line until the end of the window of the dedupe rule, unless an
+// identical line is already held, in which case its repeat count is incremented instead.
+// It returns false if the line can't be held.
+func (s *ruleStates) dedupe(rule *config.ProcessingRule, ruleIndex int, msg *message.Message, content []byte, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.duplicates == nil {
+		s.duplicates = make(map[duplicateKey]*duplicate)
+		s.identifiers = make(map[string]*heldIdentifier)
+	}
+	key := duplicateKey{rule: rule.Name, source: msg.Origin.LogSource, content: string(content)}
+	if held, found := s.duplicates[key]; found {
+		held.count++
+		held.last = msg.Origin
+		held.ingestionTimestamp = msg.IngestionTimestamp
+		recordDroppedByRule(rule)
+		return true
+	}
+	if len(s.duplicates) >= maxPendingDuplicates {
+		return false
+	}
+	if msg.Timestamp.IsZero() {
+		// the line is sent with the time of its first occurrence
+		msg.Timestamp = now.UTC()
+	}
+	s.duplicates[key] = &duplicate{
+		msg:                msg,
+		content:            content,
+		ruleIndex:          ruleIndex,
+		count:              1,
+		received:           now,
+		deadline:           now.Add(rule.DedupeWindow),
+		last:               msg.Origin,
+		ingestionTimestamp: msg.IngestionTimestamp,
+	}
+	if identifier := msg.Origin.Identifier; identifier != "" {
+		state, found := s.identifiers[identifier]
+		if !found {
+			state = &heldIdentifier{latest: msg.Origin}
+			s.identifiers[identifier] = state
+		}
+		state.held++
+	}
+	s.holding.Store(true)
+	return true
+}
+
+// received records the log line as the latest one of its registry identifier if
+// this identifier has held log lines.
+func (s *ruleStates) received(msg *message.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state, found := s.identifiers[msg.Origin.Identifier]; found {
+		state.latest = msg.Origin
+	}
+}
+
+// takeDuplicates returns the held log lines whose window is over at now, or all
+// of them, in the order they were received. A held line takes the offset of its last
+// duplicate when it is the latest line received for its registry identifier, otherwise
+// it does not update the registry as a newer offset has already been forwarded.
+func (s *ruleStates) takeDuplicates(now time.Time, all bool) []*duplicate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []*duplicate
+	for key, held := range s.duplicates {
+		if !all && now.Before(held.deadline) {
+			continue
+		}
+		expired = append(expired, held)
+		delete(s.duplicates, key)
+
+		origin := held.msg.Origin
+		identifier := origin.Identifier
+		state, found := s.identifiers[identifier]
+		if !found {
+			continue
+		}
+		if state.latest == held.last {
+			origin.Offset = held.last.Offset
+			origin.Fingerprint = held.last.Fingerprint
+			held.msg.IngestionTimestamp = held.ingestionTimestamp
+		} else {
+			origin.Identifier = ""
+		}
+		state.held--
+		if state.held == 0 {
+			delete(s.identifiers, identifier)
+		}
+	}
+	if len(s.duplicates) == 0 {
+		s.holding.Store(false)
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].received.Before(expired[j].received)
+	})
+	return expired
+}
+
+// forwardDuplicates forwards the log lines held by the dedupe rules whose window is
+// over, or all of them, with the number of identical lines they collapse.
+func (p *Processor) forwardDuplicates(all bool) {
+	if !p.ruleStates.holding.Load() {
+		return
+	}
+	for _, held := range p.ruleStates.takeDuplicates(p.clock.Now(), all) {
+		if held.count > 1 {
+			held.msg.RepeatCount = held.count
+		}
+		if shouldProcess, redactedMsg := p.applyRules(held.msg, held.content, held.ruleIndex+1); shouldProcess {
+			p.forward(held.msg, redactedMsg)
+		}
+	}
+}
+
+func recordDroppedByRule(rule *config.ProcessingRule) {
+	metrics.LogsDroppedByRule.Add(rule.Name, 1)
+	metrics.TlmLogsDroppedByRule.Inc(rule.Name)
+}
@@ -0,0 +1,227 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newTestProcessor(clk clock.Clock, rules ...*config.ProcessingRule) (*Processor, chan *message.Message) {
+	outputChan := make(chan *message.Message, 100)
+	return newWithClock(make(chan *message.Message, 100), outputChan, rules, JSONEncoder, diagnostic.NewBufferedMessageReceiver(), nil, clk), outputChan
+}
+
+func TestSampleKeepOneIn(t *testing.T) {
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.Sample, Pattern: "debug", KeepOneIn: 3})
+	p := &Processor{clock: clock.NewMock()}
+
+	var kept int
+	for i := 0; i < 9; i++ {
+		if shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("debug line"), source, "")); shouldProcess {
+			kept++
+		}
+	}
+	assert.Equal(t, 3, kept)
+	assert.Equal(t, "6", metrics.LogsDroppedByRule.Get("my_sample").String())
+
+	// the lines not matching the pattern are not sampled
+	for i := 0; i < 3; i++ {
+		shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("error line"), source, ""))
+		assert.True(t, shouldProcess)
+	}
+}
+
+func TestSampleMaxPerSecond(t *testing.T) {
+	clk := clock.NewMock()
+	rule := &config.ProcessingRule{Type: config.Sample, MaxPerSecond: 2}
+	source := newParsingSource(t, rule)
+	p := &Processor{clock: clk}
+
+	keep := func() bool {
+		shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("line"), source, ""))
+		return shouldProcess
+	}
+	assert.True(t, keep())
+	assert.True(t, keep())
+	assert.False(t, keep())
+	clk.Add(500 * time.Millisecond)
+	assert.False(t, keep())
+	clk.Add(500 * time.Millisecond)
+	assert.True(t, keep())
+	assert.True(t, keep())
+	assert.False(t, keep())
+}
+
+func TestDedupe(t *testing.T) {
+	clk := clock.NewMock()
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.Dedupe, Pattern: "timeout", Window: "10s"})
+	p, outputChan := newTestProcessor(clk)
+
+	for i := 0; i < 3; i++ {
+		p.processMessage(newMessage([]byte("connection timeout"), source, ""))
+	}
+	p.processMessage(newMessage([]byte("other timeout"), source, ""))
+	// the lines not matching the pattern are not held
+	p.processMessage(newMessage([]byte("connection refused"), source, ""))
+	msg := <-outputChan
+	assert.Equal(t, 0, msg.RepeatCount)
+	assert.Contains(t, string(msg.Content), "connection refused")
+	assert.Empty(t, outputChan)
+
+	// the held lines are forwarded once the window is over
+	clk.Add(5 * time.Second)
+	p.forwardDuplicates(false)
+	assert.Empty(t, outputChan)
+	clk.Add(5 * time.Second)
+	p.forwardDuplicates(false)
+	require.Len(t, outputChan, 2)
+
+	msg = <-outputChan
+	assert.Equal(t, 3, msg.RepeatCount)
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(msg.Content, &payload))
+	assert.Equal(t, "connection timeout", payload["message"])
+	assert.Equal(t, float64(3), payload["repeat_count"])
+
+	msg = <-outputChan
+	assert.Equal(t, 0, msg.RepeatCount)
+	payload = nil
+	require.NoError(t, json.Unmarshal(msg.Content, &payload))
+	assert.Equal(t, "other timeout", payload["message"])
+	assert.NotContains(t, payload, "repeat_count")
+	assert.Equal(t, "2", metrics.LogsDroppedByRule.Get("my_dedupe").String())
+
+	// a new window starts
+	p.processMessage(newMessage([]byte("connection timeout"), source, ""))
+	assert.Empty(t, outputChan)
+}
+
+func TestDedupeAppliesNextRules(t *testing.T) {
+	clk := clock.NewMock()
+	dedupe := &config.ProcessingRule{Name: "dedupe", Type: config.Dedupe}
+	exclude := newProcessingRule(config.ExcludeAtMatch, "", "excluded")
+	mask := newProcessingRule(config.MaskSequences, "[masked]", `\d+`)
+	require.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{dedupe}))
+	p, outputChan := newTestProcessor(clk, dedupe, exclude, mask)
+	source := newSource("", "", "")
+	source.Config.ProcessingRules = nil
+
+	p.processMessage(newMessage([]byte("user 42 logged in"), &source, ""))
+	p.processMessage(newMessage([]byte("user 42 logged in"), &source, ""))
+	p.processMessage(newMessage([]byte("excluded line"), &source, ""))
+	assert.Empty(t, outputChan)
+
+	p.Flush(context.Background())
+	require.Len(t, outputChan, 1)
+	msg := <-outputChan
+	assert.Equal(t, 2, msg.RepeatCount)
+	assert.Contains(t, string(msg.Content), `"message":"user [masked] logged in"`)
+}
+
+func TestDedupeForwardedOnStop(t *testing.T) {
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.Dedupe})
+	p, outputChan := newTestProcessor(clock.New())
+	p.Start()
+	p.inputChan <- newMessage([]byte("line"), source, "")
+	p.inputChan <- newMessage([]byte("line"), source, "")
+	p.Stop()
+	require.Len(t, outputChan, 1)
+	assert.Equal(t, 2, (<-outputChan).RepeatCount)
+}
+
+func TestDedupeOffsets(t *testing.T) {
+	clk := clock.NewMock()
+	source := newParsingSource(t, &config.ProcessingRule{Type: config.Dedupe, Pattern: "timeout", Window: "10s"})
+	p, outputChan := newTestProcessor(clk)
+	process := func(content string, offset string) {
+		msg := newMessage([]byte(content), source, "")
+		msg.Origin.Identifier = "file:/var/log/app.log"
+		msg.Origin.Offset = offset
+		p.processMessage(msg)
+	}
+
+	// the held line takes the offset of its last duplicate
+	process("connection timeout", "10")
+	process("connection timeout", "20")
+	process("connection timeout", "30")
+	p.Flush(context.Background())
+	msg := <-outputChan
+	assert.Equal(t, "file:/var/log/app.log", msg.Origin.Identifier)
+	assert.Equal(t, "30", msg.Origin.Offset)
+
+	// the held line does not update the registry once a newer offset has been forwarded
+	process("connection timeout", "40")
+	process("connection timeout", "50")
+	process("connection refused", "60")
+	assert.Equal(t, "60", (<-outputChan).Origin.Offset)
+	p.Flush(context.Background())
+	msg = <-outputChan
+	assert.Equal(t, 2, msg.RepeatCount)
+	assert.Equal(t, "", msg.Origin.Identifier)
+	assert.Empty(t, p.ruleStates.identifiers)
+	assert.False(t, p.ruleStates.holding.Load())
+}
+
+func TestSampleRulesWithSameName(t *testing.T) {
+	p := &Processor{clock: clock.NewMock()}
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	first := &config.ProcessingRule{Name: "sample", Type: config.Sample, KeepOneIn: 2}
+	second := &config.ProcessingRule{Name: "sample", Type: config.Sample, KeepOneIn: 2}
+	assert.True(t, p.ruleStates.sample(first, source, p.clock.Now()))
+	// the state of a rule is kept when its config is reloaded
+	assert.False(t, p.ruleStates.sample(second, source, p.clock.Now()))
+}
+
+func TestSampleSourcesWithSameRuleName(t *testing.T) {
+	clk := clock.NewMock()
+	first := newParsingSource(t, &config.ProcessingRule{Type: config.Sample, MaxPerSecond: 1})
+	second := newParsingSource(t, &config.ProcessingRule{Type: config.Sample, MaxPerSecond: 1})
+	p := &Processor{clock: clk}
+
+	keep := func(source *sources.LogSource) bool {
+		shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("line"), source, ""))
+		return shouldProcess
+	}
+	// each source is sampled independently
+	assert.True(t, keep(first))
+	assert.True(t, keep(second))
+	assert.False(t, keep(first))
+	assert.False(t, keep(second))
+	assert.Len(t, p.ruleStates.samplers, 2)
+}
+
+func TestSampleIdleSamplersRemoved(t *testing.T) {
+	clk := clock.NewMock()
+	p := &Processor{clock: clk}
+	rule := &config.ProcessingRule{Name: "sample", Type: config.Sample, KeepOneIn: 2}
+	for i := 0; i < maxSamplers; i++ {
+		p.ruleStates.sample(rule, sources.NewLogSource("", &config.LogsConfig{}), clk.Now())
+	}
+
+	// the lines of a new source are kept while all the states are in use
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	assert.True(t, p.ruleStates.sample(rule, source, clk.Now()))
+	assert.True(t, p.ruleStates.sample(rule, source, clk.Now()))
+	assert.Len(t, p.ruleStates.samplers, maxSamplers)
+
+	clk.Add(samplerIdleTimeout)
+	assert.True(t, p.ruleStates.sample(rule, source, clk.Now()))
+	assert.False(t, p.ruleStates.sample(rule, source, clk.Now()))
+	assert.Len(t, p.ruleStates.samplers, 1)
+}
@@ -0,0 +1,209 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Compression kinds of the tailed files, detected from their magic bytes.
+const (
+	noCompression   = ""
+	gzipCompression = "gzip"
+	zstdCompression = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ArchiveReadSuffix is appended to the registry offset of the last message of a compressed
+// file, it records that the whole archive has been read so that it is not decompressed again
+// when the agent restarts.
+const ArchiveReadSuffix = ":eof"
+
+// errArchiveRead is returned by read when the whole content of a compressed file has been read.
+var errArchiveRead = errors.New("the whole archive has been read")
+
+// detectCompression returns the compression of the file from its first bytes.
+func detectCompression(f *os.File) (string, error) {
+	header := make([]byte, len(zstdMagic))
+	n, err := f.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return noCompression, err
+	}
+	header = header[:n]
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return gzipCompression, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		return zstdCompression, nil
+	default:
+		return noCompression, nil
+	}
+}
+
+func newDecompressor(compression string, r io.Reader) (io.ReadCloser, error) {
+	switch compression {
+	case gzipCompression:
+		return gzip.NewReader(r)
+	case zstdCompression:
+		return newZstdDecompressor(r)
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+}
+
+// IsCompressed returns true if the tailed file is compressed. A compressed file is
+// read once, until the end of its compressed stream, the tailer is then finished.
+func (t *Tailer) IsCompressed() bool {
+	return t.compression != noCompression
+}
+
+// setupCompressed opens the decompressed stream of the file at the decompressed offset.
+// Nothing is read from the end of the file, nor when the source ignores the compressed
+// files, the file is not decompressed at all.
+func (t *Tailer) setupCompressed(offset int64, whence int) error {
+	if whence == io.SeekEnd || t.file.Source.Config().CompressedFiles == config.CompressedFilesIgnore {
+		t.archiveSkipped = true
+		return nil
+	}
+	// the offset is an offset in the decompressed content, the stream has to be
+	// decompressed up to it as it can't be seeked.
+	skipped, err := t.openDecompressor(offset)
+	if err != nil {
+		return err
+	}
+	t.lastReadOffset.Store(skipped)
+	t.decodedOffset.Store(skipped)
+	return nil
+}
+
+// openDecompressor opens the file and skips the first skip decompressed bytes.
+// It returns the number of skipped bytes.
+func (t *Tailer) openDecompressor(skip int64) (int64, error) {
+	f, err := filesystem.OpenShared(t.fullpath)
+	if err != nil {
+		return 0, err
+	}
+	decompressor, err := newDecompressor(t.compression, f)
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+
+	skipped, err := io.CopyN(io.Discard, decompressor, skip)
+	switch err {
+	case nil:
+	case io.EOF:
+		log.Warnf("The decompressed content of %s is shorter than the offset %d", t.file.Path, skip)
+	case io.ErrUnexpectedEOF:
+		// the file is still being compressed, the next read resumes at the same offset
+		skipped = skip
+	default:
+		decompressor.Close()
+		f.Close()
+		return 0, err
+	}
+
+	t.osFile = f
+	t.decompressor = decompressor
+	t.decompressed = bufio.NewReader(decompressor)
+	return skipped, nil
+}
+
+func (t *Tailer) closeDecompressor() {
+	if t.decompressor != nil {
+		t.decompressor.Close()
+		t.decompressor = nil
+		t.decompressed = nil
+	}
+	if t.osFile != nil {
+		t.osFile.Close()
+		t.osFile = nil
+	}
+}
+
+// readCompressed reads the decompressed content of the file. It returns errArchiveRead
+// once the end of the compressed stream is reached. A truncated stream is most likely
+// a file still being compressed, as its decompression can't be resumed, it is opened
+// again at the same decompressed offset once the file stopped growing.
+func (t *Tailer) readCompressed() (int, error) {
+	if t.archiveSkipped {
+		return 0, errArchiveRead
+	}
+	if t.decompressor == nil {
+		if ready, err := t.truncatedFileReady(); err != nil || !ready {
+			return 0, err
+		}
+		if _, err := t.openDecompressor(t.lastReadOffset.Load()); err != nil {
+			t.file.Source.Status().Error(err)
+			return 0, log.Error("Unexpected error occurred while opening compressed file: ", err)
+		}
+		t.truncatedSize = 0
+	}
+
+	inBuf := make([]byte, 4096)
+	n, err := t.decompressed.Read(inBuf)
+	if n > 0 {
+		if _, peekErr := t.decompressed.Peek(1); peekErr == io.EOF {
+			// the length must be known before the last message is forwarded
+			t.archiveLength.Store(t.lastReadOffset.Load() + int64(n))
+		}
+		t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+		t.lastReadOffset.Add(int64(n))
+	}
+
+	switch {
+	case err == nil:
+		return n, nil
+	case err == io.EOF:
+		log.Infof("Finished reading the compressed file %s", t.file.Path)
+		return 0, errArchiveRead
+	case err == io.ErrUnexpectedEOF:
+		log.Debugf("The compressed file %s is truncated, waiting for more data", t.file.Path)
+		if info, statErr := t.osFile.Stat(); statErr == nil {
+			t.truncatedSize = info.Size()
+			t.compressedSize = t.truncatedSize
+		}
+		t.closeDecompressor()
+		return n, nil
+	default:
+		t.file.Source.Status().Error(err)
+		return n, log.Error("Unexpected error occurred while decompressing file: ", err)
+	}
+}
+
+// truncatedFileReady returns true when the truncated compressed file grew since it
+// was found truncated and stopped growing since the previous read.
+func (t *Tailer) truncatedFileReady() (bool, error) {
+	if t.truncatedSize == 0 {
+		return true, nil
+	}
+	info, err := os.Stat(t.fullpath)
+	if err != nil {
+		t.file.Source.Status().Error(err)
+		return false, log.Error("Unexpected error occurred while reading compressed file: ", err)
+	}
+	size := info.Size()
+	if size != t.compressedSize {
+		t.compressedSize = size
+		return false, nil
+	}
+	return size != t.truncatedSize, nil
+}
@@ -0,0 +1,230 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+// +build !windows
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+var compressedTestLines = []string{"hello world\n", "hello again\n", "good bye\n"}
+
+func gzipContent(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func newCompressedTestTailer(t *testing.T, path string) (*Tailer, chan *message.Message) {
+	return newCompressedFilesTestTailer(t, path, "")
+}
+
+func newCompressedFilesTestTailer(t *testing.T, path string, compressedFiles string) (*Tailer, chan *message.Message) {
+	outputChan := make(chan *message.Message, 10)
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, CompressedFiles: compressedFiles})
+	file := NewFile(path, source, false)
+	tailer := NewTailer(outputChan, file, 10*time.Millisecond, decoder.NewDecoderFromSource(file.Source))
+	return tailer, outputChan
+}
+
+func TestDetectCompression(t *testing.T) {
+	dir := t.TempDir()
+	for name, test := range map[string]struct {
+		content     []byte
+		compression string
+	}{
+		"plain":  {[]byte("hello world\n"), noCompression},
+		"empty":  {nil, noCompression},
+		"short":  {[]byte{0x1f}, noCompression},
+		"gzip":   {gzipContent(t, "hello"), gzipCompression},
+		"zstd":   {[]byte{0x28, 0xb5, 0x2f, 0xfd, 0x04}, zstdCompression},
+		"nozstd": {[]byte{0x28, 0xb5, 0x2f}, noCompression},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(path, test.content, 0600))
+			f, err := os.Open(path)
+			require.NoError(t, err)
+			defer f.Close()
+			compression, err := detectCompression(f)
+			assert.NoError(t, err)
+			assert.Equal(t, test.compression, compression)
+		})
+	}
+}
+
+func TestTailCompressedFile(t *testing.T) {
+	content := strings.Join(compressedTestLines, "")
+	for name, compressed := range map[string][]byte{
+		"app.log.1.gz":  gzipContent(t, content),
+		"app.log.1.zst": zstdContent(t, content),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if compressed == nil {
+				t.Skip("zstd requires cgo")
+			}
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, compressed, 0600))
+
+			tailer, outputChan := newCompressedTestTailer(t, path)
+			require.NoError(t, tailer.StartFromBeginning())
+			assert.True(t, tailer.IsCompressed())
+
+			// the offsets are offsets in the decompressed content
+			offset := 0
+			var msg *message.Message
+			for _, line := range compressedTestLines {
+				msg = <-outputChan
+				offset += len(line)
+				assert.Equal(t, strings.TrimSuffix(line, "\n"), string(msg.Content))
+				assert.Equal(t, offset, toInt(strings.TrimSuffix(msg.Origin.Offset, ArchiveReadSuffix)))
+			}
+			// the offset of the last message records that the archive has been read
+			assert.Equal(t, strconv.Itoa(offset)+ArchiveReadSuffix, msg.Origin.Offset)
+
+			// the tailer is finished once the whole content is read
+			assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+			didRotate, err := tailer.DidRotate()
+			assert.NoError(t, err)
+			assert.False(t, didRotate)
+			tailer.Stop()
+		})
+	}
+}
+
+func TestTailCompressedFileFromOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	require.NoError(t, os.WriteFile(path, gzipContent(t, strings.Join(compressedTestLines, "")), 0600))
+
+	tailer, outputChan := newCompressedTestTailer(t, path)
+	require.NoError(t, tailer.Start(int64(len(compressedTestLines[0])), io.SeekStart))
+	msg := <-outputChan
+	assert.Equal(t, "hello again", string(msg.Content))
+	assert.Equal(t, len(compressedTestLines[0])+len(compressedTestLines[1]), toInt(msg.Origin.Offset))
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	tailer.Stop()
+
+	// nothing is read from the end of a compressed file, which is not decompressed
+	tailer, outputChan = newCompressedTestTailer(t, path)
+	require.NoError(t, tailer.Start(0, io.SeekEnd))
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, outputChan)
+	assert.Nil(t, tailer.decompressor)
+	assert.Equal(t, int64(0), tailer.decodedOffset.Load())
+	tailer.Stop()
+}
+
+func TestTailCompressedFileReadOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	require.NoError(t, os.WriteFile(path, gzipContent(t, strings.Join(compressedTestLines, "")), 0600))
+
+	tailer, outputChan := newCompressedFilesTestTailer(t, path, config.CompressedFilesReadOnce)
+	require.NoError(t, tailer.StartFromBeginning())
+	for _, line := range compressedTestLines {
+		msg := <-outputChan
+		assert.Equal(t, strings.TrimSuffix(line, "\n"), string(msg.Content))
+	}
+
+	// the tailer stops at the end of the archive, even if content is appended to it
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.Write(gzipContent(t, "appended\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, outputChan)
+	tailer.Stop()
+}
+
+func TestTailCompressedFileIgnored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	require.NoError(t, os.WriteFile(path, gzipContent(t, strings.Join(compressedTestLines, "")), 0600))
+
+	tailer, outputChan := newCompressedFilesTestTailer(t, path, config.CompressedFilesIgnore)
+	require.NoError(t, tailer.StartFromBeginning())
+	assert.True(t, tailer.IsCompressed())
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, outputChan)
+	tailer.Stop()
+}
+
+func TestTailCompressedFileBeingWritten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	compressed := gzipContent(t, strings.Join(compressedTestLines, ""))
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.Write(compressed[:len(compressed)/2])
+	require.NoError(t, err)
+
+	tailer, outputChan := newCompressedTestTailer(t, path)
+	require.NoError(t, tailer.StartFromBeginning())
+
+	// the tailer waits for the end of the compressed stream
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, tailer.IsFinished())
+	_, err = f.Write(compressed[len(compressed)/2:])
+	require.NoError(t, err)
+
+	for _, line := range compressedTestLines {
+		msg := <-outputChan
+		assert.Equal(t, strings.TrimSuffix(line, "\n"), string(msg.Content))
+	}
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, outputChan)
+	tailer.Stop()
+}
+
+func TestTruncatedFileReady(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	require.NoError(t, os.WriteFile(path, []byte("truncated"), 0600))
+	tailer, _ := newCompressedTestTailer(t, path)
+	tailer.fullpath = path
+
+	// the file is not truncated
+	ready, err := tailer.truncatedFileReady()
+	assert.NoError(t, err)
+	assert.True(t, ready)
+
+	// the file did not grow since it was found truncated
+	tailer.truncatedSize, tailer.compressedSize = 9, 9
+	ready, err = tailer.truncatedFileReady()
+	assert.NoError(t, err)
+	assert.False(t, ready)
+
+	// the file is growing
+	require.NoError(t, os.WriteFile(path, []byte("truncated data"), 0600))
+	ready, err = tailer.truncatedFileReady()
+	assert.NoError(t, err)
+	assert.False(t, ready)
+
+	// the file stopped growing
+	ready, err = tailer.truncatedFileReady()
+	assert.NoError(t, err)
+	assert.True(t, ready)
+}
@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build cgo
+// +build cgo
+
+package file
+
+import (
+	"io"
+
+	"github.com/DataDog/zstd"
+)
+
+// newZstdDecompressor returns a reader of the zstd compressed content of r
+func newZstdDecompressor(r io.Reader) (io.ReadCloser, error) {
+	return zstd.NewReader(r), nil
+}
@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !cgo
+// +build !cgo
+
+package file
+
+import (
+	"errors"
+	"io"
+)
+
+// newZstdDecompressor returns an error as the zstd library requires cgo
+func newZstdDecompressor(r io.Reader) (io.ReadCloser, error) {
+	return nil, errors.New("zstd decompression is not available in agents built without cgo")
+}
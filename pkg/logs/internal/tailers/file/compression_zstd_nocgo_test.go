@@ -0,0 +1,16 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !cgo && !windows
+// +build !cgo,!windows
+
+package file
+
+import "testing"
+
+// zstdContent returns nil as the zstd library requires cgo, the zstd tests are skipped
+func zstdContent(t *testing.T, content string) []byte {
+	return nil
+}
@@ -0,0 +1,22 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build cgo && !windows
+// +build cgo,!windows
+
+package file
+
+import (
+	"testing"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func zstdContent(t *testing.T, content string) []byte {
+	compressed, err := zstd.Compress(nil, []byte(content))
+	require.NoError(t, err)
+	return compressed
+}
@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"fmt"
+	"hash/crc64"
+	"io"
+
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var fingerprintTable = crc64.MakeTable(crc64.ECMA)
+
+// computeFingerprint returns the fingerprint of the first size bytes of the file at path,
+// formatted as "<size>:<crc64>" so that fingerprints computed with different sizes never
+// match. It returns an empty fingerprint when the file is shorter than size.
+func computeFingerprint(path string, size int64) (string, error) {
+	f, err := filesystem.OpenShared(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := crc64.New(fingerprintTable)
+	n, err := io.CopyN(hash, f, size)
+	if err == io.EOF || n < size {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%016x", size, hash.Sum64()), nil
+}
+
+// IsFingerprintEnabled returns true if the tailed file is identified by its fingerprint
+// in addition to its path, see logs_config.fingerprint.
+func (t *Tailer) IsFingerprintEnabled() bool {
+	return t.fingerprintSize > 0
+}
+
+// Fingerprint returns the fingerprint of the tailed file. It is empty when fingerprinting
+// is disabled, or as long as the file is shorter than the fingerprint size.
+func (t *Tailer) Fingerprint() string {
+	if !t.IsFingerprintEnabled() {
+		return ""
+	}
+	if fingerprint := t.fingerprint.Load(); fingerprint != "" {
+		return fingerprint
+	}
+	fingerprint, err := computeFingerprint(t.file.Path, t.fingerprintSize)
+	if err != nil {
+		log.Debugf("Could not compute the fingerprint of %s: %v", t.file.Path, err)
+		return ""
+	}
+	t.fingerprint.Store(fingerprint)
+	return fingerprint
+}
+
+// fingerprintAt returns the fingerprint to attach to the message ending at offset. The
+// fingerprint of a file too short when the tailer started is computed once enough of its
+// content has been read.
+func (t *Tailer) fingerprintAt(offset int64) string {
+	if !t.IsFingerprintEnabled() {
+		return ""
+	}
+	if fingerprint := t.fingerprint.Load(); fingerprint != "" || offset < t.fingerprintSize {
+		return fingerprint
+	}
+	return t.Fingerprint()
+}
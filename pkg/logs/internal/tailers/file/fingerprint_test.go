@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestComputeFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("hello world\n"), 0600))
+
+	fingerprint, err := computeFingerprint(path, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, fingerprint, "the file is shorter than the fingerprint size")
+
+	fingerprint, err = computeFingerprint(path, 5)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(fingerprint, "5:"))
+
+	// only the first bytes matter
+	other := filepath.Join(dir, "other.log")
+	require.NoError(t, os.WriteFile(other, []byte("hello again\n"), 0600))
+	otherFingerprint, err := computeFingerprint(other, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, fingerprint, otherFingerprint)
+	otherFingerprint, err = computeFingerprint(other, 8)
+	assert.NoError(t, err)
+	assert.NotEqual(t, fingerprint, otherFingerprint)
+
+	_, err = computeFingerprint(filepath.Join(dir, "missing.log"), 5)
+	assert.Error(t, err)
+}
+
+func TestTailerFingerprint(t *testing.T) {
+	mockConfig := coreConfig.Mock(t)
+	mockConfig.Set("logs_config.fingerprint.enabled", true)
+	mockConfig.Set("logs_config.fingerprint.size", 16)
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString("hello world\n")
+	require.NoError(t, err)
+
+	outputChan := make(chan *message.Message, 10)
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	file := NewFile(path, source, false)
+	tailer := NewTailer(outputChan, file, 10*time.Millisecond, decoder.NewDecoderFromSource(file.Source))
+	assert.True(t, tailer.IsFingerprintEnabled())
+	assert.Empty(t, tailer.Fingerprint(), "the file is shorter than the fingerprint size")
+
+	require.NoError(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+	msg := <-outputChan
+	assert.Equal(t, "hello world", string(msg.Content))
+	assert.Empty(t, msg.Origin.Fingerprint)
+
+	// the fingerprint is computed once the file is long enough
+	_, err = f.WriteString("hello again\n")
+	require.NoError(t, err)
+	msg = <-outputChan
+	assert.Equal(t, "hello again", string(msg.Content))
+	expected, err := computeFingerprint(path, 16)
+	require.NoError(t, err)
+	assert.NotEmpty(t, expected)
+	assert.Equal(t, expected, msg.Origin.Fingerprint)
+	assert.Equal(t, expected, tailer.Fingerprint())
+}
+
+func TestTailerFingerprintDisabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("a", 2048)), 0600))
+
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	file := NewFile(path, source, false)
+	tailer := NewTailer(make(chan *message.Message, 10), file, 10*time.Millisecond, decoder.NewDecoderFromSource(file.Source))
+	assert.False(t, tailer.IsFingerprintEnabled())
+	assert.Empty(t, tailer.Fingerprint())
+}
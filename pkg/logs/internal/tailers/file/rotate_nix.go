@@ -20,7 +20,13 @@ import (
 // - renamed and recreated
 // - removed and recreated
 // - truncated
+//
+// The compressed files are read once and are never rotated.
 func (t *Tailer) DidRotate() (bool, error) {
+	if t.IsCompressed() {
+		return false, nil
+	}
+
 	f, err := filesystem.OpenShared(t.osFile.Name())
 	if err != nil {
 		return false, err
@@ -16,8 +16,13 @@ import (
 // DidRotate returns true if the file has been log-rotated.
 //
 // On Windows, log rotation is identified by the file size being smaller
-// than the last offset read.
+// than the last offset read. The compressed files are read once and
+// are never rotated.
 func (t *Tailer) DidRotate() (bool, error) {
+	if t.IsCompressed() {
+		return false, nil
+	}
+
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
 		return false, err
@@ -6,6 +6,7 @@
 package file
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -26,6 +27,12 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 )
 
+// MessageProcessor processes the messages of a tailer before they are forwarded.
+type MessageProcessor interface {
+	// Process updates the message, it returns false if the message must be dropped.
+	Process(msg *message.Message) bool
+}
+
 // Tailer tails a file, decodes the messages it contains, and passes them to a
 // supplied output channel for further processing.
 //
@@ -61,6 +68,38 @@ type Tailer struct {
 	// is platform-specific.
 	osFile *os.File
 
+	// compression is the compression of the file, detected when the tailer starts.
+	// The offsets of a compressed file are offsets in its decompressed content.
+	compression string
+
+	// decompressor reads the decompressed content of osFile when the file is compressed.
+	decompressor io.ReadCloser
+
+	// decompressed buffers the content of decompressor to detect the end of the stream
+	// before the last bytes are decoded.
+	decompressed *bufio.Reader
+
+	// archiveSkipped is true when the content of the compressed file must not be read.
+	archiveSkipped bool
+
+	// archiveLength is the length of the decompressed content of the file, 0 until the
+	// end of the compressed stream is reached.
+	archiveLength *atomic.Int64
+
+	// truncatedSize is the size of the compressed file when its stream was found
+	// truncated, 0 if it is not.
+	truncatedSize int64
+
+	// compressedSize is the size of the truncated compressed file at the previous read.
+	compressedSize int64
+
+	// fingerprintSize is the number of bytes from the beginning of the file used for
+	// its fingerprint, 0 when fingerprinting is disabled.
+	fingerprintSize int64
+
+	// fingerprint is the fingerprint of the file, empty until the file is long enough.
+	fingerprint *atomic.String
+
 	// tags are the tags to be attached to each log message, excluding tags provided
 	// by the tag provider.
 	tags []string
@@ -69,6 +108,10 @@ type Tailer struct {
 	// is called once for each log message.
 	tagProvider tag.Provider
 
+	// messageProcessor processes the messages before they are forwarded, it is nil
+	// unless the source needs one, for instance to parse the Kubernetes audit events.
+	messageProcessor MessageProcessor
+
 	// outputChan is the channel to which fully-decoded messages are written.
 	outputChan chan *message.Message
 
@@ -137,7 +180,10 @@ func NewTailer(outputChan chan *message.Message, file *File, sleepDuration time.
 	forwardContext, stopForward := context.WithCancel(context.Background())
 	closeTimeout := coreConfig.Datadog.GetDuration("logs_config.close_timeout") * time.Second
 	windowsOpenFileTimeout := coreConfig.Datadog.GetDuration("logs_config.windows_open_file_timeout") * time.Second
-
+	var fingerprintSize int64
+	if coreConfig.Datadog.GetBool("logs_config.fingerprint.enabled") {
+		fingerprintSize = coreConfig.Datadog.GetInt64("logs_config.fingerprint.size")
+	}
 	return &Tailer{
 		file:                   file,
 		outputChan:             outputChan,
@@ -145,9 +191,12 @@ func NewTailer(outputChan chan *message.Message, file *File, sleepDuration time.
 		tagProvider:            tagProvider,
 		lastReadOffset:         atomic.NewInt64(0),
 		decodedOffset:          atomic.NewInt64(0),
+		archiveLength:          atomic.NewInt64(0),
 		sleepDuration:          sleepDuration,
 		closeTimeout:           closeTimeout,
 		windowsOpenFileTimeout: windowsOpenFileTimeout,
+		fingerprintSize:        fingerprintSize,
+		fingerprint:            atomic.NewString(""),
 		stop:                   make(chan struct{}, 1),
 		done:                   make(chan struct{}, 1),
 		forwardContext:         forwardContext,
@@ -160,7 +209,16 @@ func NewTailer(outputChan chan *message.Message, file *File, sleepDuration time.
 // NewRotatedTailer creates a new tailer that replaces this one, writing
 // messages to the same channel but using an updated file and decoder.
 func (t *Tailer) NewRotatedTailer(file *File, decoder *decoder.Decoder) *Tailer {
-	return NewTailer(t.outputChan, file, t.sleepDuration, decoder)
+	tailer := NewTailer(t.outputChan, file, t.sleepDuration, decoder)
+	// the state of the processor is kept across the rotation
+	tailer.messageProcessor = t.messageProcessor
+	return tailer
+}
+
+// SetMessageProcessor sets the processor of the messages of the tailer, it must be
+// called before the tailer is started.
+func (t *Tailer) SetMessageProcessor(processor MessageProcessor) {
+	t.messageProcessor = processor
 }
 
 // Identifier returns a string that identifies this tailer in the registry.
@@ -225,7 +283,11 @@ func (t *Tailer) StopAfterFileRotation() {
 // until it is closed or the tailer is stopped.
 func (t *Tailer) readForever() {
 	defer func() {
-		t.osFile.Close()
+		if t.IsCompressed() {
+			t.closeDecompressor()
+		} else {
+			t.osFile.Close()
+		}
 		t.decoder.Stop()
 		log.Info("Closed", t.file.Path, "for tailer key", t.file.GetScanKey(), "read", t.bytesRead, "bytes and", t.decoder.GetLineCount(), "lines")
 	}()
@@ -287,17 +349,28 @@ func (t *Tailer) forwardMessages() {
 		origin := message.NewOrigin(t.file.Source.UnderlyingSource())
 		origin.Identifier = identifier
 		origin.Offset = strconv.FormatInt(offset, 10)
+		if archiveLength := t.archiveLength.Load(); archiveLength > 0 && offset >= archiveLength {
+			// records in the registry that the whole archive has been read
+			origin.Offset += ArchiveReadSuffix
+		}
+		if identifier != "" {
+			origin.Fingerprint = t.fingerprintAt(offset)
+		}
 		origin.SetTags(append(t.tags, t.tagProvider.GetTags()...))
 		// Ignore empty lines once the registry offset is updated
 		if len(output.Content) == 0 {
 			continue
 		}
+		msg := message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+		if t.messageProcessor != nil && !t.messageProcessor.Process(msg) {
+			continue
+		}
 		// Make the write to the output chan cancellable to be able to stop the tailer
 		// after a file rotation when it is stuck on it.
 		// We don't return directly to keep the same shutdown sequence that in the
 		// normal case.
 		select {
-		case t.outputChan <- message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp):
+		case t.outputChan <- msg:
 		case <-t.forwardContext.Done():
 		}
 	}
@@ -34,6 +34,17 @@ func (t *Tailer) setup(offset int64, whence int) error {
 		return err
 	}
 
+	t.compression, err = detectCompression(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if t.IsCompressed() {
+		f.Close()
+		log.Infof("%s is a %s compressed file, reading its decompressed content", t.file.Path, t.compression)
+		return t.setupCompressed(offset, whence)
+	}
+
 	t.osFile = f
 	ret, _ := f.Seek(offset, whence)
 	t.lastReadOffset.Store(ret)
@@ -45,6 +56,9 @@ func (t *Tailer) setup(offset int64, whence int) error {
 // read lets the tailer tail the content of a file
 // until it is closed or the tailer is stopped.
 func (t *Tailer) read() (int, error) {
+	if t.IsCompressed() {
+		return t.readCompressed()
+	}
 	// keep reading data from file
 	inBuf := make([]byte, 4096)
 	n, err := t.osFile.Read(inBuf)
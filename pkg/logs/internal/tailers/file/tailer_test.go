@@ -15,6 +15,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -328,6 +329,35 @@ func (suite *TailerTestSuite) TestMutliLineAutoDetect() {
 	suite.Equal(suite.tailer.GetDetectedPattern(), expectedRegex)
 }
 
+// prefixProcessor drops the messages without its prefix and removes the prefix of the others.
+type prefixProcessor string
+
+func (p prefixProcessor) Process(msg *message.Message) bool {
+	if !strings.HasPrefix(string(msg.Content), string(p)) {
+		return false
+	}
+	msg.Content = msg.Content[len(p):]
+	return true
+}
+
+func (suite *TailerTestSuite) TestMessageProcessor() {
+	suite.tailer.SetMessageProcessor(prefixProcessor("keep:"))
+
+	_, err := suite.testFile.WriteString("keep:hello\ndrop:hello\nkeep:world\n")
+	suite.Nil(err)
+
+	suite.Nil(suite.tailer.StartFromBeginning())
+	msg := <-suite.outputChan
+	suite.Equal("hello", string(msg.Content))
+	msg = <-suite.outputChan
+	suite.Equal("world", string(msg.Content))
+	suite.Equal("33", msg.Origin.Offset)
+
+	// the processor is kept by the tailer replacing this one after a rotation
+	rotatedTailer := suite.tailer.NewRotatedTailer(suite.tailer.file, decoder.NewDecoderFromSource(suite.source))
+	suite.Equal(suite.tailer.messageProcessor, rotatedTailer.messageProcessor)
+}
+
 func toInt(str string) int {
 	if value, err := strconv.ParseInt(str, 10, 64); err == nil {
 		return int(value)
@@ -35,6 +35,17 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	if err != nil {
 		return err
 	}
+	t.compression, err = detectCompression(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if t.IsCompressed() {
+		f.Close()
+		log.Infof("%s is a %s compressed file, reading its decompressed content", t.file.Path, t.compression)
+		return t.setupCompressed(offset, whence)
+	}
+
 	filePos, _ := f.Seek(offset, whence)
 	f.Close()
 
@@ -120,6 +131,9 @@ func (t *Tailer) readAvailable() (int, error) {
 // windows version open and close the file between each call to 'read'. This is
 // needed in order not to block the file and prevent the user from renaming it.
 func (t *Tailer) read() (int, error) {
+	if t.IsCompressed() {
+		return t.readCompressed()
+	}
 	n, err := t.readAvailable()
 	if err == io.EOF || os.IsNotExist(err) {
 		return n, nil
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxOctetCountLength is the maximum number of digits of the length of an octet-counted frame.
+const maxOctetCountLength = 10
+
+// FrameReader reads the syslog messages sent over a stream, as described in RFC 6587.
+// A frame is either octet-counted, "<length> <message>", or terminated by a new line.
+// The framing is detected for each frame: an octet-counted frame starts with a digit
+// while a syslog message starts with "<".
+type FrameReader struct {
+	reader  *bufio.Reader
+	maxSize int
+}
+
+// NewFrameReader returns a FrameReader reading from r. The frames longer than
+// maxSize are truncated.
+func NewFrameReader(r io.Reader, maxSize int) *FrameReader {
+	return &FrameReader{
+		reader:  bufio.NewReader(r),
+		maxSize: maxSize,
+	}
+}
+
+// ReadFrame returns the next frame, without its trailing new line.
+func (f *FrameReader) ReadFrame() ([]byte, error) {
+	first, err := f.reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		return f.readOctetCounted()
+	}
+	return f.readLine()
+}
+
+// readOctetCounted reads a frame prefixed by its length.
+func (f *FrameReader) readOctetCounted() ([]byte, error) {
+	var digits []byte
+	for {
+		c, err := f.reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if c == ' ' {
+			break
+		}
+		if c < '0' || c > '9' || len(digits) == maxOctetCountLength {
+			return nil, fmt.Errorf("invalid syslog frame length %q", append(digits, c))
+		}
+		digits = append(digits, c)
+	}
+	length, err := strconv.ParseInt(string(digits), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	size := length
+	if size > int64(f.maxSize) {
+		size = int64(f.maxSize)
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(f.reader, frame); err != nil {
+		return nil, err
+	}
+	if length > size {
+		// the trailing part of the message is dropped
+		if _, err := io.CopyN(io.Discard, f.reader, length-size); err != nil {
+			return nil, err
+		}
+	}
+	return frame, nil
+}
+
+// readLine reads a frame terminated by a new line, or by the end of the stream.
+func (f *FrameReader) readLine() ([]byte, error) {
+	var frame []byte
+	for {
+		line, err := f.reader.ReadSlice('\n')
+		if len(frame) < f.maxSize {
+			end := len(line)
+			if len(frame)+end > f.maxSize {
+				end = f.maxSize - len(frame)
+			}
+			frame = append(frame, line[:end]...)
+		}
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && len(frame) > 0:
+			// the last frame of the stream, the next read returns io.EOF
+			return bytes.TrimRight(frame, "\r\n"), nil
+		case err != nil:
+			return nil, err
+		default:
+			return bytes.TrimRight(frame, "\r\n"), nil
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFrames(t *testing.T, input string, maxSize int) ([]string, error) {
+	reader := NewFrameReader(strings.NewReader(input), maxSize)
+	var frames []string
+	for {
+		frame, err := reader.ReadFrame()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, err
+		}
+		frames = append(frames, string(frame))
+	}
+}
+
+func TestFrameReader(t *testing.T) {
+	frames, err := readFrames(t, "9 <1>1 a\nbc<2>line one\r\n\n<3>line two\n8 <4>three<5>last", 100)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"<1>1 a\nbc", "<2>line one", "", "<3>line two", "<4>three", "<5>last"}, frames)
+}
+
+func TestFrameReaderTruncatesLongFrames(t *testing.T) {
+	long := strings.Repeat("a", 30)
+	frames, err := readFrames(t, "30 "+long+"<1>"+long+"\n<2>short\n", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{long[:10], "<1>" + long[:7], "<2>short"}, frames)
+}
+
+func TestFrameReaderErrors(t *testing.T) {
+	_, err := readFrames(t, "12a <1>hello", 100)
+	assert.Error(t, err)
+	_, err = readFrames(t, "123456789012 <1>hello", 100)
+	assert.Error(t, err)
+	_, err = readFrames(t, "20 <1>hello", 100)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
@@ -0,0 +1,260 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// nilValue is the value of the empty fields of a RFC 5424 message.
+const nilValue = "-"
+
+// rfc3164TimestampLayout is the layout of the timestamps of RFC 3164 messages, which don't have a year.
+const rfc3164TimestampLayout = "Jan _2 15:04:05"
+
+// utf8BOM may prefix the content of a RFC 5424 message.
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+var errNoPriority = errors.New("the message doesn't start with a syslog priority")
+
+// syslogMessage is a message parsed from a RFC 5424 or a RFC 3164 frame.
+type syslogMessage struct {
+	facility       int
+	severity       int
+	timestamp      time.Time
+	hostname       string
+	appName        string
+	procID         string
+	msgID          string
+	structuredData []sdElement
+	content        []byte
+}
+
+// sdElement is an element of the structured data of a RFC 5424 message, for
+// instance [exampleSDID@32473 iut="3" eventSource="Application"].
+type sdElement struct {
+	id     string
+	params []sdParam
+}
+
+type sdParam struct {
+	name  string
+	value string
+}
+
+// parse parses a syslog frame, RFC 5424 messages are detected by their version
+// following the priority. The timestamps of RFC 3164 messages are in the year of now.
+func parse(frame []byte, now time.Time) (*syslogMessage, error) {
+	frame = bytes.TrimRight(frame, "\r\n\x00")
+	priority, rest, err := parsePriority(frame)
+	if err != nil {
+		return nil, err
+	}
+	msg := &syslogMessage{
+		facility: priority / 8,
+		severity: priority % 8,
+	}
+	if len(rest) > 1 && rest[0] == '1' && rest[1] == ' ' {
+		err = msg.parseRFC5424(rest[2:])
+	} else {
+		msg.parseRFC3164(rest, now)
+	}
+	return msg, err
+}
+
+// parsePriority parses the "<PRI>" header of a message.
+func parsePriority(frame []byte) (int, []byte, error) {
+	if len(frame) < 3 || frame[0] != '<' {
+		return 0, nil, errNoPriority
+	}
+	// the priority has at most 3 digits
+	header := frame
+	if len(header) > 5 {
+		header = header[:5]
+	}
+	end := bytes.IndexByte(header, '>')
+	if end < 2 {
+		return 0, nil, errNoPriority
+	}
+	priority, err := strconv.Atoi(string(frame[1:end]))
+	if err != nil || priority < 0 || priority > 191 {
+		return 0, nil, errNoPriority
+	}
+	return priority, frame[end+1:], nil
+}
+
+// parseRFC5424 parses "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]",
+// the part of a RFC 5424 message following "<PRI>VERSION ".
+func (m *syslogMessage) parseRFC5424(b []byte) error {
+	var fields [5]string
+	for i := range fields {
+		end := bytes.IndexByte(b, ' ')
+		if end < 0 {
+			return fmt.Errorf("truncated RFC 5424 header")
+		}
+		if value := string(b[:end]); value != nilValue {
+			fields[i] = value
+		}
+		b = b[end+1:]
+	}
+	if fields[0] != "" {
+		timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid RFC 5424 timestamp %q: %v", fields[0], err)
+		}
+		m.timestamp = timestamp
+	}
+	m.hostname, m.appName, m.procID, m.msgID = fields[1], fields[2], fields[3], fields[4]
+
+	structuredData, rest, err := parseStructuredData(b)
+	if err != nil {
+		return err
+	}
+	m.structuredData = structuredData
+	if len(rest) > 0 && rest[0] == ' ' {
+		rest = rest[1:]
+	}
+	m.content = bytes.TrimPrefix(rest, utf8BOM)
+	return nil
+}
+
+// parseStructuredData parses the structured data of a RFC 5424 message,
+// either "-" or a list of "[SD-ID PARAM-NAME="PARAM-VALUE" ...]".
+func parseStructuredData(b []byte) ([]sdElement, []byte, error) {
+	if len(b) > 0 && b[0] == '-' {
+		return nil, b[1:], nil
+	}
+	var elements []sdElement
+	for len(b) > 0 && b[0] == '[' {
+		end := bytes.IndexAny(b, " ]")
+		if end < 0 {
+			return nil, nil, fmt.Errorf("truncated structured data")
+		}
+		element := sdElement{id: string(b[1:end])}
+		b = b[end:]
+		for b[0] == ' ' {
+			b = b[1:]
+			name := bytes.Index(b, []byte(`="`))
+			if name < 0 {
+				return nil, nil, fmt.Errorf("invalid structured data parameter in %s", element.id)
+			}
+			param := sdParam{name: string(b[:name])}
+			value, rest, err := parseParamValue(b[name+2:])
+			if err != nil {
+				return nil, nil, err
+			}
+			param.value = value
+			element.params = append(element.params, param)
+			b = rest
+			if len(b) == 0 {
+				return nil, nil, fmt.Errorf("truncated structured data")
+			}
+		}
+		if b[0] != ']' {
+			return nil, nil, fmt.Errorf("invalid structured data element %s", element.id)
+		}
+		b = b[1:]
+		elements = append(elements, element)
+	}
+	if len(elements) == 0 {
+		return nil, nil, fmt.Errorf("invalid structured data")
+	}
+	return elements, b, nil
+}
+
+// parseParamValue parses a parameter value up to its closing quote, where '"', '\' and ']' are escaped with '\'.
+func parseParamValue(b []byte) (string, []byte, error) {
+	var value []byte
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '\\' && i+1 < len(b) && (b[i+1] == '"' || b[i+1] == '\\' || b[i+1] == ']'):
+			i++
+			value = append(value, b[i])
+		case b[i] == '"':
+			return string(value), b[i+1:], nil
+		default:
+			value = append(value, b[i])
+		}
+	}
+	return "", nil, fmt.Errorf("truncated structured data parameter value")
+}
+
+// parseRFC3164 parses "TIMESTAMP HOSTNAME TAG[PID]: MSG", the part of a RFC 3164 message
+// following "<PRI>". As RFC 3164 is only a description of the existing practices, every
+// part is optional and what can't be parsed is kept in the content.
+func (m *syslogMessage) parseRFC3164(b []byte, now time.Time) {
+	m.content = b
+
+	var timestamp time.Time
+	var err error
+	if end := bytes.IndexByte(b, ' '); end > 0 {
+		// some senders use RFC 3339 timestamps
+		timestamp, err = time.Parse(time.RFC3339Nano, string(b[:end]))
+		if err == nil {
+			b = b[end+1:]
+		}
+	}
+	if timestamp.IsZero() && len(b) > len(rfc3164TimestampLayout) && b[len(rfc3164TimestampLayout)] == ' ' {
+		timestamp, err = time.ParseInLocation(rfc3164TimestampLayout, string(b[:len(rfc3164TimestampLayout)]), now.Location())
+		if err == nil {
+			timestamp = timestamp.AddDate(now.Year(), 0, 0)
+			if timestamp.After(now.AddDate(0, 0, 1)) {
+				// a message of December received in January
+				timestamp = timestamp.AddDate(-1, 0, 0)
+			}
+			b = b[len(rfc3164TimestampLayout)+1:]
+		}
+	}
+	if timestamp.IsZero() {
+		// without a timestamp the header can't be told from the content
+		return
+	}
+	m.timestamp = timestamp
+	m.content = b
+
+	if appName, procID, rest, ok := parseTag(b); ok {
+		// no hostname
+		m.appName, m.procID, m.content = appName, procID, rest
+		return
+	}
+	if end := bytes.IndexByte(b, ' '); end > 0 {
+		m.hostname = string(b[:end])
+		m.content = b[end+1:]
+		if appName, procID, rest, ok := parseTag(m.content); ok {
+			m.appName, m.procID, m.content = appName, procID, rest
+		}
+	}
+}
+
+// parseTag parses the "TAG[PID]: " or "TAG: " prefix of the content of a RFC 3164 message.
+func parseTag(b []byte) (string, string, []byte, bool) {
+	end := bytes.IndexAny(b, " :[")
+	if end <= 0 {
+		return "", "", nil, false
+	}
+	appName := string(b[:end])
+	var procID string
+	if b[end] == '[' {
+		pidEnd := bytes.IndexByte(b[end:], ']')
+		if pidEnd < 0 {
+			return "", "", nil, false
+		}
+		procID = string(b[end+1 : end+pidEnd])
+		end += pidEnd + 1
+	}
+	if end >= len(b) || b[end] != ':' {
+		return "", "", nil, false
+	}
+	rest := b[end+1:]
+	if len(rest) > 0 && rest[0] == ' ' {
+		rest = rest[1:]
+	}
+	return appName, procID, rest, true
+}
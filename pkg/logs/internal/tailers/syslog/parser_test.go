@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var parseTestNow = time.Date(2022, time.March, 15, 12, 0, 0, 0, time.UTC)
+
+func TestParseRFC5424(t *testing.T) {
+	msg, err := parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Application"][examplePriority@32473 class="high \"one\" \] \\"] `+"\xef\xbb\xbf"+`An application event log entry...`+"\n"), parseTestNow)
+	require.NoError(t, err)
+	assert.Equal(t, 20, msg.facility)
+	assert.Equal(t, 5, msg.severity)
+	assert.Equal(t, time.Date(2003, time.October, 11, 22, 14, 15, 3000000, time.UTC), msg.timestamp)
+	assert.Equal(t, "mymachine.example.com", msg.hostname)
+	assert.Equal(t, "evntslog", msg.appName)
+	assert.Equal(t, "1234", msg.procID)
+	assert.Equal(t, "ID47", msg.msgID)
+	assert.Equal(t, []sdElement{
+		{id: "exampleSDID@32473", params: []sdParam{{"iut", "3"}, {"eventSource", "Application"}}},
+		{id: "examplePriority@32473", params: []sdParam{{"class", `high "one" ] \`}}},
+	}, msg.structuredData)
+	assert.Equal(t, "An application event log entry...", string(msg.content))
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	msg, err := parse([]byte(`<34>1 - - - - - -`), parseTestNow)
+	require.NoError(t, err)
+	assert.Equal(t, 4, msg.facility)
+	assert.Equal(t, 2, msg.severity)
+	assert.True(t, msg.timestamp.IsZero())
+	assert.Empty(t, msg.hostname)
+	assert.Empty(t, msg.appName)
+	assert.Empty(t, msg.procID)
+	assert.Empty(t, msg.msgID)
+	assert.Empty(t, msg.structuredData)
+	assert.Empty(t, msg.content)
+}
+
+func TestParseRFC5424Errors(t *testing.T) {
+	for _, frame := range []string{
+		`<34>1 2003-10-11T22:14:15.003Z host app`,
+		`<34>1 yesterday host app - - - message`,
+		`<34>1 - host app - - message`,
+		`<34>1 - host app - - [id param="value`,
+		`<34>1 - host app - - [id param]`,
+		`<34>1 - host app - - [id`,
+	} {
+		_, err := parse([]byte(frame), parseTestNow)
+		assert.Error(t, err, frame)
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	for name, test := range map[string]struct {
+		frame     string
+		timestamp time.Time
+		hostname  string
+		appName   string
+		procID    string
+		content   string
+	}{
+		"full": {
+			frame:     "<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8",
+			timestamp: time.Date(2021, time.October, 11, 22, 14, 15, 0, time.UTC),
+			hostname:  "mymachine",
+			appName:   "su",
+			procID:    "123",
+			content:   "'su root' failed for lonvick on /dev/pts/8",
+		},
+		"padded day": {
+			frame:     "<34>Mar  5 22:14:15 mymachine su: hello",
+			timestamp: time.Date(2022, time.March, 5, 22, 14, 15, 0, time.UTC),
+			hostname:  "mymachine",
+			appName:   "su",
+			content:   "hello",
+		},
+		"no hostname": {
+			frame:     "<34>Mar 15 11:14:15 cron[42]: job done",
+			timestamp: time.Date(2022, time.March, 15, 11, 14, 15, 0, time.UTC),
+			appName:   "cron",
+			procID:    "42",
+			content:   "job done",
+		},
+		"no tag": {
+			frame:     "<34>Mar 15 11:14:15 mymachine job done",
+			timestamp: time.Date(2022, time.March, 15, 11, 14, 15, 0, time.UTC),
+			hostname:  "mymachine",
+			content:   "job done",
+		},
+		"rfc3339 timestamp": {
+			frame:     "<34>2022-03-15T11:14:15.5Z mymachine app: hello",
+			timestamp: time.Date(2022, time.March, 15, 11, 14, 15, 500000000, time.UTC),
+			hostname:  "mymachine",
+			appName:   "app",
+			content:   "hello",
+		},
+		"no timestamp": {
+			frame:   "<34>mymachine app: hello",
+			content: "mymachine app: hello",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			msg, err := parse([]byte(test.frame), parseTestNow)
+			require.NoError(t, err)
+			assert.Equal(t, 4, msg.facility)
+			assert.Equal(t, 2, msg.severity)
+			assert.True(t, test.timestamp.Equal(msg.timestamp), "%v != %v", test.timestamp, msg.timestamp)
+			assert.Equal(t, test.hostname, msg.hostname)
+			assert.Equal(t, test.appName, msg.appName)
+			assert.Equal(t, test.procID, msg.procID)
+			assert.Equal(t, test.content, string(msg.content))
+		})
+	}
+}
+
+func TestParsePriorityErrors(t *testing.T) {
+	for _, frame := range []string{"", "hello", "<>", "<34", "<1000>1 - - - - - -", "<192>hello", "<a>hello"} {
+		_, err := parse([]byte(frame), parseTestNow)
+		assert.Equal(t, errNoPriority, err, frame)
+	}
+}
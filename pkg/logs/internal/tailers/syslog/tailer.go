@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// syslogIntegration is the source of the messages, it is still overridden by the
+// integration config when defined.
+const syslogIntegration = "syslog"
+
+// facilityNames are the names of the syslog facilities, indexed by their code.
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// severityStatusMapping represents the 1:1 mapping between syslog severities and statuses.
+var severityStatusMapping = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// Tailer reads syslog messages from a net.Conn and parses them. It uses a `read`
+// callback returning one frame at a time to be generic over the types of connections
+// and their framing.
+type Tailer struct {
+	source     *sources.LogSource
+	Conn       net.Conn
+	outputChan chan *message.Message
+	read       func() ([]byte, error)
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewTailer returns a new Tailer
+func NewTailer(source *sources.LogSource, conn net.Conn, outputChan chan *message.Message, read func() ([]byte, error)) *Tailer {
+	return &Tailer{
+		source:     source,
+		Conn:       conn,
+		outputChan: outputChan,
+		read:       read,
+		stop:       make(chan struct{}, 1),
+		done:       make(chan struct{}, 1),
+	}
+}
+
+// Start starts reading the messages from the connection.
+func (t *Tailer) Start() {
+	go t.readForever()
+}
+
+// Stop stops the tailer and waits for the messages being read to be forwarded.
+func (t *Tailer) Stop() {
+	t.stop <- struct{}{}
+	t.Conn.Close()
+	<-t.done
+}
+
+// readForever reads the frames from conn and forwards them to the output channel.
+func (t *Tailer) readForever() {
+	defer func() {
+		t.Conn.Close()
+		t.done <- struct{}{}
+	}()
+	for {
+		select {
+		case <-t.stop:
+			// stop reading data from the connection
+			return
+		default:
+			frame, err := t.read()
+			if err != nil && err == io.EOF {
+				// connection has been closed client-side, stop from reading new data
+				return
+			}
+			if err != nil {
+				// an error occurred, stop from reading new data
+				log.Warnf("Couldn't read syslog message from connection: %v", err)
+				return
+			}
+			if len(frame) == 0 {
+				continue
+			}
+			t.source.RecordBytes(int64(len(frame)))
+			t.outputChan <- t.toMessage(frame, time.Now())
+		}
+	}
+}
+
+// toMessage transforms a syslog frame into a message. The frames which can't be
+// parsed are forwarded as is.
+func (t *Tailer) toMessage(frame []byte, now time.Time) *message.Message {
+	parsed, err := parse(frame, now)
+	if err != nil {
+		log.Debugf("Couldn't parse syslog message, forwarding it as is: %v", err)
+		return message.NewMessageWithSource(frame, message.StatusInfo, t.source, now.UnixNano())
+	}
+
+	origin := message.NewOrigin(t.source)
+	origin.SetSource(syslogIntegration)
+	origin.SetService(parsed.appName)
+	origin.SetTags(getTags(parsed))
+
+	msg := message.NewMessage(parsed.content, origin, severityStatusMapping[parsed.severity], now.UnixNano())
+	if !parsed.timestamp.IsZero() {
+		msg.Timestamp = parsed.timestamp.UTC()
+	}
+	msg.Hostname = parsed.hostname
+	return msg
+}
+
+// getTags returns the tags of a parsed message: its facility, application name, process
+// ID, message ID and its structured data as "<SD-ID>.<PARAM-NAME>:<PARAM-VALUE>".
+func getTags(parsed *syslogMessage) []string {
+	tags := []string{"syslog_facility:" + facilityNames[parsed.facility]}
+	if parsed.appName != "" {
+		tags = append(tags, "syslog_appname:"+parsed.appName)
+	}
+	if parsed.procID != "" {
+		tags = append(tags, "syslog_procid:"+parsed.procID)
+	}
+	if parsed.msgID != "" {
+		tags = append(tags, "syslog_msgid:"+parsed.msgID)
+	}
+	for _, element := range parsed.structuredData {
+		for _, param := range element.params {
+			tags = append(tags, element.id+"."+param.name+":"+param.value)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestTailerForwardsParsedMessages(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType})
+	outputChan := make(chan *message.Message, 10)
+	r, w := net.Pipe()
+	frames := NewFrameReader(r, 9000)
+	tailer := NewTailer(source, r, outputChan, frames.ReadFrame)
+	tailer.Start()
+
+	w.Write([]byte(`<190>1 2003-10-11T22:14:15.003+02:00 host app 42 ID47 [origin ip="10.0.0.1"] hello` + "\n")) //nolint:errcheck
+	w.Write([]byte("not syslog\n"))                                                                              //nolint:errcheck
+
+	msg := <-outputChan
+	assert.Equal(t, "hello", string(msg.Content))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Equal(t, time.Date(2003, time.October, 11, 20, 14, 15, 3000000, time.UTC), msg.Timestamp)
+	assert.Equal(t, "host", msg.Hostname)
+	assert.Equal(t, "app", msg.Origin.Service())
+	assert.Equal(t, "syslog", msg.Origin.Source())
+	assert.Equal(t, []string{"syslog_facility:local7", "syslog_appname:app", "syslog_procid:42", "syslog_msgid:ID47", "origin.ip:10.0.0.1"}, msg.Origin.Tags())
+
+	// the messages which can't be parsed are forwarded as is
+	msg = <-outputChan
+	assert.Equal(t, "not syslog", string(msg.Content))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Empty(t, msg.Hostname)
+
+	w.Close()
+	tailer.Stop()
+	assert.Equal(t, int64(len(`<190>1 2003-10-11T22:14:15.003+02:00 host app 42 ID47 [origin ip="10.0.0.1"] hello`)+len("not syslog")), source.BytesRead.Load())
+}
+
+func TestTailerUsesTheConfiguredServiceAndSource(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Service: "network", Source: "cisco"})
+	tailer := NewTailer(source, nil, nil, nil)
+	msg := tailer.toMessage([]byte("<187>Oct 11 22:14:15 switch1 %LINK-3-UPDOWN: Interface down"), time.Now())
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "network", msg.Origin.Service())
+	assert.Equal(t, "cisco", msg.Origin.Source())
+	assert.Equal(t, "switch1", msg.Hostname)
+	assert.Equal(t, "Interface down", string(msg.Content))
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package kubeaudit parses the audit events of the Kubernetes API server, read from
+// its audit log or received from its webhook backend, into log messages.
+package kubeaudit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// The stages of the audit events, see https://kubernetes.io/docs/tasks/debug/debug-cluster/audit/
+const (
+	StageRequestReceived  = "RequestReceived"
+	StageResponseStarted  = "ResponseStarted"
+	StageResponseComplete = "ResponseComplete"
+	StagePanic            = "Panic"
+)
+
+// Event holds the fields of an audit.k8s.io event used to build its log message.
+type Event struct {
+	APIVersion     string           `json:"apiVersion"`
+	Kind           string           `json:"kind"`
+	AuditID        string           `json:"auditID"`
+	Stage          string           `json:"stage"`
+	Verb           string           `json:"verb"`
+	RequestURI     string           `json:"requestURI"`
+	User           UserInfo         `json:"user"`
+	ObjectRef      *ObjectReference `json:"objectRef"`
+	ResponseStatus *ResponseStatus  `json:"responseStatus"`
+	StageTimestamp time.Time        `json:"stageTimestamp"`
+}
+
+// UserInfo is the user who sent the request.
+type UserInfo struct {
+	Username string `json:"username"`
+}
+
+// ObjectReference is the object targeted by the request.
+type ObjectReference struct {
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	APIGroup    string `json:"apiGroup"`
+}
+
+// ResponseStatus is the status of the response, only set from the ResponseStarted stage.
+type ResponseStatus struct {
+	Code int `json:"code"`
+}
+
+// Parse parses an audit event, serialized as JSON.
+func Parse(content []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(content, &event); err != nil {
+		return nil, err
+	}
+	if event.Kind != "Event" || event.AuditID == "" {
+		return nil, fmt.Errorf("not an audit event")
+	}
+	return &event, nil
+}
+
+// Tags returns the tags of the event: its verb, user, resource, namespace and response code.
+func (e *Event) Tags() []string {
+	var tags []string
+	if e.Verb != "" {
+		tags = append(tags, "kube_audit_verb:"+e.Verb)
+	}
+	if e.User.Username != "" {
+		tags = append(tags, "kube_audit_user:"+e.User.Username)
+	}
+	if e.ObjectRef != nil {
+		if e.ObjectRef.Resource != "" {
+			tags = append(tags, "kube_audit_resource:"+e.ObjectRef.Resource)
+		}
+		if e.ObjectRef.Subresource != "" {
+			tags = append(tags, "kube_audit_subresource:"+e.ObjectRef.Subresource)
+		}
+		if e.ObjectRef.Namespace != "" {
+			tags = append(tags, "kube_namespace:"+e.ObjectRef.Namespace)
+		}
+	}
+	if e.ResponseStatus != nil && e.ResponseStatus.Code != 0 {
+		tags = append(tags, "kube_audit_response_code:"+strconv.Itoa(e.ResponseStatus.Code))
+	}
+	return tags
+}
+
+// Status returns the status of the log message of the event, from its response code.
+func (e *Event) Status() string {
+	switch {
+	case e.Stage == StagePanic:
+		return message.StatusError
+	case e.ResponseStatus == nil:
+		return message.StatusInfo
+	case e.ResponseStatus.Code >= 500:
+		return message.StatusError
+	case e.ResponseStatus.Code >= 400:
+		return message.StatusWarning
+	default:
+		return message.StatusInfo
+	}
+}
@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kubeaudit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const responseComplete = `{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"4a3b2c1d","stage":"ResponseComplete",` +
+	`"requestURI":"/api/v1/namespaces/default/pods/web/exec","verb":"create","user":{"username":"jane","groups":["system:authenticated"]},` +
+	`"sourceIPs":["10.0.0.1"],"objectRef":{"resource":"pods","namespace":"default","name":"web","apiVersion":"v1","subresource":"exec"},` +
+	`"responseStatus":{"metadata":{},"code":101},"requestReceivedTimestamp":"2022-03-01T10:00:00.000000Z","stageTimestamp":"2022-03-01T10:00:01.500000Z"}`
+
+func TestParse(t *testing.T) {
+	event, err := Parse([]byte(responseComplete))
+	require.NoError(t, err)
+	assert.Equal(t, "4a3b2c1d", event.AuditID)
+	assert.Equal(t, StageResponseComplete, event.Stage)
+	assert.Equal(t, "create", event.Verb)
+	assert.Equal(t, "jane", event.User.Username)
+	assert.Equal(t, "web", event.ObjectRef.Name)
+	assert.Equal(t, 101, event.ResponseStatus.Code)
+	assert.Equal(t, time.Date(2022, 3, 1, 10, 0, 1, 500000000, time.UTC), event.StageTimestamp)
+
+	_, err = Parse([]byte(`not json`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"kind":"Pod","metadata":{"name":"web"}}`))
+	assert.Error(t, err)
+}
+
+func TestTags(t *testing.T) {
+	event, err := Parse([]byte(responseComplete))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"kube_audit_verb:create",
+		"kube_audit_user:jane",
+		"kube_audit_resource:pods",
+		"kube_audit_subresource:exec",
+		"kube_namespace:default",
+		"kube_audit_response_code:101",
+	}, event.Tags())
+
+	// non resource requests have no object reference
+	event = &Event{Verb: "get", User: UserInfo{Username: "system:anonymous"}, ResponseStatus: &ResponseStatus{Code: 200}}
+	assert.Equal(t, []string{"kube_audit_verb:get", "kube_audit_user:system:anonymous", "kube_audit_response_code:200"}, event.Tags())
+}
+
+func TestStatus(t *testing.T) {
+	assert.Equal(t, message.StatusInfo, (&Event{}).Status())
+	assert.Equal(t, message.StatusInfo, (&Event{ResponseStatus: &ResponseStatus{Code: 201}}).Status())
+	assert.Equal(t, message.StatusWarning, (&Event{ResponseStatus: &ResponseStatus{Code: 404}}).Status())
+	assert.Equal(t, message.StatusError, (&Event{ResponseStatus: &ResponseStatus{Code: 503}}).Status())
+	assert.Equal(t, message.StatusError, (&Event{Stage: StagePanic}).Status())
+}
@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kubeaudit
+
+import (
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// maxSeenAuditIDs bounds the number of audit IDs remembered to deduplicate the stages
+// of the requests, the oldest ones are forgotten first.
+const maxSeenAuditIDs = 10000
+
+// Processor enriches the log messages of the audit events and drops the duplicate
+// stages of a request: the RequestReceived stage, whose fields are all repeated by the
+// next stages, and the stages following the first one sent, for instance the
+// ResponseComplete stage of a long-running request after its ResponseStarted stage.
+//
+// The RequestReceived stage is generated before the request is handled, so it has no
+// response status, and the API server generates a ResponseStarted, ResponseComplete or
+// Panic stage with the same fields once the request is handled. Sending it would double
+// the volume of the audit logs without adding any information, which is also why the
+// audit policies usually omit it with omitStages. Only the requests of an API server
+// stopped while handling them are not logged.
+type Processor struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+	// order holds the seen audit IDs in a ring buffer, next being the oldest one.
+	order []string
+	next  int
+}
+
+// NewProcessor returns a new Processor.
+func NewProcessor() *Processor {
+	return &Processor{
+		seen:  make(map[string]struct{}),
+		order: make([]string, 0, maxSeenAuditIDs),
+	}
+}
+
+// Process adds the tags, the status and the timestamp of its audit event to the message.
+// It returns false if the event is a duplicate stage and the message must be dropped, the
+// messages which are not audit events are kept unchanged.
+func (p *Processor) Process(msg *message.Message) bool {
+	event, err := Parse(msg.Content)
+	if err != nil {
+		return true
+	}
+	if p.isDuplicate(event) {
+		return false
+	}
+	msg.Origin.AddTags(event.Tags()...)
+	msg.SetStatus(event.Status())
+	if !event.StageTimestamp.IsZero() {
+		msg.Timestamp = event.StageTimestamp.UTC()
+	}
+	return true
+}
+
+// isDuplicate returns true if the event is a RequestReceived stage or if a stage of its
+// request was already sent.
+func (p *Processor) isDuplicate(event *Event) bool {
+	if event.Stage == StageRequestReceived {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, found := p.seen[event.AuditID]; found {
+		return true
+	}
+	if len(p.order) < maxSeenAuditIDs {
+		p.order = append(p.order, event.AuditID)
+	} else {
+		delete(p.seen, p.order[p.next])
+		p.order[p.next] = event.AuditID
+		p.next = (p.next + 1) % maxSeenAuditIDs
+	}
+	p.seen[event.AuditID] = struct{}{}
+	return false
+}
+
+// forget forgets the audit ID of a message which couldn't be sent, so that the event
+// is not dropped as a duplicate when it is received again.
+func (p *Processor) forget(msg *message.Message) {
+	event, err := Parse(msg.Content)
+	if err != nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.seen, event.AuditID)
+}
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kubeaudit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newMessage(content string) *message.Message {
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.KubernetesAuditType})
+	return message.NewMessageWithSource([]byte(content), message.StatusInfo, source, 0)
+}
+
+func auditEvent(auditID, stage string) string {
+	return fmt.Sprintf(`{"kind":"Event","apiVersion":"audit.k8s.io/v1","auditID":%q,"stage":%q,"verb":"watch"}`, auditID, stage)
+}
+
+func TestProcessEnrichesTheMessage(t *testing.T) {
+	p := NewProcessor()
+	msg := newMessage(responseComplete)
+	assert.True(t, p.Process(msg))
+	assert.Equal(t, responseComplete, string(msg.Content))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Equal(t, time.Date(2022, 3, 1, 10, 0, 1, 500000000, time.UTC), msg.Timestamp)
+	assert.Contains(t, msg.Origin.Tags(), "kube_audit_user:jane")
+
+	msg = newMessage("not an audit event")
+	assert.True(t, p.Process(msg))
+	assert.True(t, msg.Timestamp.IsZero())
+	assert.Empty(t, msg.Origin.Tags())
+}
+
+func TestProcessDropsTheDuplicateStages(t *testing.T) {
+	p := NewProcessor()
+	assert.False(t, p.Process(newMessage(auditEvent("a", StageRequestReceived))))
+	assert.True(t, p.Process(newMessage(auditEvent("a", StageResponseStarted))))
+	assert.False(t, p.Process(newMessage(auditEvent("a", StageResponseComplete))))
+	assert.False(t, p.Process(newMessage(auditEvent("b", StageRequestReceived))))
+	assert.True(t, p.Process(newMessage(auditEvent("b", StageResponseComplete))))
+	assert.True(t, p.Process(newMessage(auditEvent("c", StagePanic))))
+}
+
+func TestProcessForgetsTheOldestAuditIDs(t *testing.T) {
+	p := NewProcessor()
+	for i := 0; i < maxSeenAuditIDs+1; i++ {
+		assert.True(t, p.Process(newMessage(auditEvent(fmt.Sprint(i), StageResponseStarted))))
+	}
+	assert.Len(t, p.seen, maxSeenAuditIDs)
+	assert.True(t, p.Process(newMessage(auditEvent("0", StageResponseComplete))))
+	assert.False(t, p.Process(newMessage(auditEvent(fmt.Sprint(maxSeenAuditIDs), StageResponseComplete))))
+}
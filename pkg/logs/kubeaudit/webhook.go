@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kubeaudit
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// maxRequestSize bounds the size of the event lists sent by the webhook backend.
+	maxRequestSize = 32 << 20
+	// shutdownTimeout is the time given to the pending requests to complete on stop.
+	shutdownTimeout = 5 * time.Second
+)
+
+// eventList is the body of the requests of the webhook backend.
+type eventList struct {
+	Kind  string            `json:"kind"`
+	Items []json.RawMessage `json:"items"`
+}
+
+// WebhookServer receives the audit events sent by the webhook backend of the API server,
+// on the port of its source, and forwards them to the output channel. The requests are
+// authenticated by their client certificate and/or their bearer token, as configured
+// by the source.
+type WebhookServer struct {
+	source     *sources.LogSource
+	outputChan chan *message.Message
+	processor  *Processor
+	token      string
+	listener   net.Listener
+	server     *http.Server
+}
+
+// NewWebhookServer returns a new WebhookServer.
+func NewWebhookServer(source *sources.LogSource, outputChan chan *message.Message) *WebhookServer {
+	return &WebhookServer{
+		source:     source,
+		outputChan: outputChan,
+		processor:  NewProcessor(),
+	}
+}
+
+// Start starts to receive the audit events, with TLS if the source has a certificate.
+func (s *WebhookServer) Start() {
+	log.Infof("Starting Kubernetes audit webhook on port %d", s.source.Config.Port)
+	tlsConfig, err := s.setupAuthentication()
+	if err != nil {
+		log.Errorf("Can't start Kubernetes audit webhook on port %d: %v", s.source.Config.Port, err)
+		s.source.Status.Error(err)
+		return
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.source.Config.Port))
+	if err != nil {
+		log.Errorf("Can't start Kubernetes audit webhook on port %d: %v", s.source.Config.Port, err)
+		s.source.Status.Error(err)
+		return
+	}
+	s.listener = listener
+	s.server = &http.Server{Handler: s, TLSConfig: tlsConfig}
+	s.source.Status.Success()
+	go func() {
+		if s.source.Config.TLSCertFile != "" {
+			err = s.server.ServeTLS(listener, s.source.Config.TLSCertFile, s.source.Config.TLSKeyFile)
+		} else {
+			log.Warnf("Kubernetes audit webhook on port %d receives the events over plain HTTP", s.source.Config.Port)
+			err = s.server.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Errorf("Kubernetes audit webhook on port %d stopped: %v", s.source.Config.Port, err)
+			s.source.Status.Error(err)
+		}
+	}()
+}
+
+// setupAuthentication reads the bearer token expected in the requests and returns the
+// TLS config verifying the client certificates, if the source has them.
+func (s *WebhookServer) setupAuthentication() (*tls.Config, error) {
+	if s.source.Config.BearerTokenFile != "" {
+		token, err := os.ReadFile(s.source.Config.BearerTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		s.token = strings.TrimSpace(string(token))
+		if s.token == "" {
+			return nil, fmt.Errorf("the bearer token file %s is empty", s.source.Config.BearerTokenFile)
+		}
+	}
+	if s.source.Config.TLSClientCAFile == "" {
+		return nil, nil
+	}
+	ca, err := os.ReadFile(s.source.Config.TLSClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificate found in the client CA file " + s.source.Config.TLSClientCAFile)
+	}
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// Stop stops the server once the pending requests complete.
+func (s *WebhookServer) Stop() {
+	if s.server == nil {
+		return
+	}
+	log.Infof("Stopping Kubernetes audit webhook on port %d", s.source.Config.Port)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Warnf("Could not stop Kubernetes audit webhook on port %d: %v", s.source.Config.Port, err)
+	}
+}
+
+// ServeHTTP forwards the events of an event list. The request fails if the events can't
+// all be forwarded, the API server then sends them again and those already forwarded
+// are dropped as duplicates.
+func (s *WebhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var events eventList
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&events); err != nil || events.Kind != "EventList" {
+		http.Error(w, "invalid audit event list", http.StatusBadRequest)
+		return
+	}
+
+	for _, event := range events.Items {
+		msg := message.NewMessageWithSource(event, message.StatusInfo, s.source, time.Now().UnixNano())
+		if !s.processor.Process(msg) {
+			continue
+		}
+		select {
+		case s.outputChan <- msg:
+		case <-r.Context().Done():
+			s.processor.forget(msg)
+			http.Error(w, "request canceled", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// authorized returns true if the request has the expected bearer token, if any.
+func (s *WebhookServer) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	token := r.Header.Get("Authorization")
+	if !strings.HasPrefix(token, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token[len("Bearer "):]), []byte(s.token)) == 1
+}
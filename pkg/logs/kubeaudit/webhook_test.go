@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kubeaudit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newEventList(events ...string) string {
+	return fmt.Sprintf(`{"kind":"EventList","apiVersion":"audit.k8s.io/v1","metadata":{},"items":[%s]}`, strings.Join(events, ","))
+}
+
+func TestWebhookServer(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.KubernetesAuditType, AllowInsecureHTTP: true})
+	outputChan := make(chan *message.Message, 10)
+	server := NewWebhookServer(source, outputChan)
+	server.Start()
+	defer server.Stop()
+	require.True(t, source.Status.IsSuccess())
+
+	url := fmt.Sprintf("http://%s/", server.listener.Addr())
+	resp, err := http.Post(url, "application/json", strings.NewReader(newEventList(
+		auditEvent("a", StageRequestReceived),
+		responseComplete,
+		auditEvent("a", StageResponseComplete),
+	)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Len(t, outputChan, 2)
+	msg := <-outputChan
+	assert.Equal(t, responseComplete, string(msg.Content))
+	assert.Equal(t, source, msg.Origin.LogSource)
+	assert.Contains(t, msg.Origin.Tags(), "kube_audit_verb:create")
+	msg = <-outputChan
+	assert.Equal(t, auditEvent("a", StageResponseComplete), string(msg.Content))
+}
+
+func TestWebhookServerRejectsInvalidRequests(t *testing.T) {
+	server := NewWebhookServer(sources.NewLogSource("", &config.LogsConfig{}), make(chan *message.Message, 10))
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	for _, body := range []string{"not json", `{"kind":"Event","auditID":"a"}`} {
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+	assert.Empty(t, server.outputChan)
+}
+
+func TestWebhookServerForgetsTheEventsNotSent(t *testing.T) {
+	server := NewWebhookServer(sources.NewLogSource("", &config.LogsConfig{}), make(chan *message.Message))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rec := httptest.NewRecorder()
+	body := newEventList(auditEvent("a", StageResponseComplete))
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)).WithContext(ctx))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.True(t, server.processor.Process(newMessage(auditEvent("a", StageResponseComplete))))
+}
+
+func TestWebhookServerBearerToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret\n"), 0600))
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.KubernetesAuditType, BearerTokenFile: tokenFile, AllowInsecureHTTP: true})
+	server := NewWebhookServer(source, make(chan *message.Message, 10))
+	server.Start()
+	defer server.Stop()
+	require.True(t, source.Status.IsSuccess())
+
+	for authorization, code := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"Bearer other":  http.StatusUnauthorized,
+		"Bearer secret": http.StatusOK,
+	} {
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/", server.listener.Addr()), strings.NewReader(newEventList(responseComplete)))
+		require.NoError(t, err)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, code, resp.StatusCode, authorization)
+	}
+	assert.Len(t, server.outputChan, 1)
+}
+
+func TestWebhookServerClientCertificate(t *testing.T) {
+	certFile, keyFile := generateTestCertificate(t)
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.KubernetesAuditType, TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: certFile})
+	server := NewWebhookServer(source, make(chan *message.Message, 10))
+	server.Start()
+	defer server.Stop()
+	require.True(t, source.Status.IsSuccess())
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	url := fmt.Sprintf("https://%s/", server.listener.Addr())
+	post := func(certificates []tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			Certificates:       certificates,
+			InsecureSkipVerify: true,
+		}}}
+		return client.Post(url, "application/json", strings.NewReader(newEventList(responseComplete)))
+	}
+
+	// the requests without a client certificate are rejected during the handshake
+	_, err = post(nil)
+	assert.Error(t, err)
+
+	resp, err := post([]tls.Certificate{cert})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, server.outputChan, 1)
+}
+
+func TestWebhookServerInvalidClientCA(t *testing.T) {
+	certFile, keyFile := generateTestCertificate(t)
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.KubernetesAuditType, TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: keyFile})
+	server := NewWebhookServer(source, make(chan *message.Message, 10))
+	server.Start()
+	defer server.Stop()
+	assert.True(t, source.Status.IsError())
+	assert.Nil(t, server.listener)
+}
+
+// generateTestCertificate writes a self-signed certificate and its key, and returns their paths.
+func generateTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600))
+	return certFile, keyFile
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	"github.com/DataDog/datadog-agent/pkg/metadata/inventories"
+	pkgmetrics "github.com/DataDog/datadog-agent/pkg/metrics"
 	"go.uber.org/atomic"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -54,13 +55,14 @@ var (
 // instead of directly using it.
 // The parameter serverless indicates whether or not this Logs Agent is running
 // in a serverless environment.
-func Start(ac *autodiscovery.AutoConfig) (*Agent, error) {
-	return start(ac, false)
+// The metrics generated from the logs by the processing rules are submitted to metricSink.
+func Start(ac *autodiscovery.AutoConfig, metricSink pkgmetrics.MetricSampleSink) (*Agent, error) {
+	return start(ac, metricSink, false)
 }
 
 // StartServerless starts a Serverless instance of the Logs Agent.
-func StartServerless() (*Agent, error) {
-	return start(nil, true)
+func StartServerless(metricSink pkgmetrics.MetricSampleSink) (*Agent, error) {
+	return start(nil, metricSink, true)
 }
 
 // buildEndpoints builds endpoints for the logs agent
@@ -75,7 +77,7 @@ func buildEndpoints(serverless bool) (*config.Endpoints, error) {
 	return config.BuildEndpointsWithVectorOverride(httpConnectivity, intakeTrackType, AgentJSONIntakeProtocol, config.DefaultIntakeOrigin)
 }
 
-func start(ac *autodiscovery.AutoConfig, serverless bool) (*Agent, error) {
+func start(ac *autodiscovery.AutoConfig, metricSink pkgmetrics.MetricSampleSink, serverless bool) (*Agent, error) {
 	if IsAgentRunning() {
 		return agent, nil
 	}
@@ -118,11 +120,11 @@ func start(ac *autodiscovery.AutoConfig, serverless bool) (*Agent, error) {
 	if !serverless {
 		// regular logs agent
 		log.Info("Starting logs-agent...")
-		agent = NewAgent(sources, services, processingRules, endpoints)
+		agent = NewAgent(sources, services, processingRules, metricSink, endpoints)
 	} else {
 		// serverless logs agent
 		log.Info("Starting a serverless logs-agent...")
-		agent = NewServerless(sources, services, processingRules, endpoints)
+		agent = NewServerless(sources, services, processingRules, metricSink, endpoints)
 	}
 
 	agent.Start()
@@ -32,11 +32,17 @@ type Message struct {
 	status             string
 	IngestionTimestamp int64
 	// Optional. Must be UTC. If not provided, time.Now().UTC() will be used
-	// Used in the Serverless Agent
+	// Used in the Serverless Agent and for the OTLP logs
 	Timestamp time.Time
 	// Optional.
 	// Used in the Serverless Agent
 	Lambda *Lambda
+	// Optional. If not provided, the hostname of the agent will be used
+	// Used for the OTLP logs
+	Hostname string
+	// Optional. The number of identical messages collapsed into this one
+	// Used by the dedupe processing rules
+	RepeatCount int
 }
 
 // Lambda is a struct storing information about the Lambda function and function execution.
@@ -84,6 +90,11 @@ func (m *Message) GetStatus() string {
 	return m.status
 }
 
+// SetStatus sets the status of the message.
+func (m *Message) SetStatus(status string) {
+	m.status = status
+}
+
 // GetLatency returns the latency delta from ingestion time until now
 func (m *Message) GetLatency() int64 {
 	return time.Now().UnixNano() - m.IngestionTimestamp
@@ -94,6 +105,9 @@ func (m *Message) GetHostname() string {
 	if m.Lambda != nil {
 		return m.Lambda.ARN
 	}
+	if m.Hostname != "" {
+		return m.Hostname
+	}
 	hname, err := hostname.Get(context.TODO())
 	if err != nil {
 		// this scenario is not likely to happen since
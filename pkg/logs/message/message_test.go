@@ -32,6 +32,11 @@ func TestGetHostnameLambda(t *testing.T) {
 	assert.Equal(t, "testHostName", message.GetHostname())
 }
 
+func TestGetHostnameOverride(t *testing.T) {
+	message := Message{Hostname: "otlpHostName"}
+	assert.Equal(t, "otlpHostName", message.GetHostname())
+}
+
 func TestGetHostname(t *testing.T) {
 	os.Setenv("DD_HOSTNAME", "testHostnameFromEnvVar")
 	defer os.Unsetenv("DD_HOSTNAME")
@@ -16,9 +16,12 @@ type Origin struct {
 	Identifier string
 	LogSource  *sources.LogSource
 	Offset     string
-	service    string
-	source     string
-	tags       []string
+	// Fingerprint identifies the content of the file the message comes from,
+	// it is empty when file fingerprinting is disabled.
+	Fingerprint string
+	service     string
+	source      string
+	tags        []string
 }
 
 // NewOrigin returns a new Origin
@@ -90,6 +93,13 @@ func (o *Origin) SetTags(tags []string) {
 	o.tags = tags
 }
 
+// AddTags adds tags to the origin. The slice of the tags set previously is not modified.
+func (o *Origin) AddTags(tags ...string) {
+	allTags := make([]string, 0, len(o.tags)+len(tags))
+	allTags = append(allTags, o.tags...)
+	o.tags = append(allTags, tags...)
+}
+
 // SetSource sets the source of the origin.
 func (o *Origin) SetSource(source string) {
 	o.source = source
@@ -5,6 +5,8 @@
 
 package message
 
+import "strings"
+
 // Status values
 const (
 	StatusEmergency = "emergency"
@@ -17,6 +19,32 @@ const (
 	StatusDebug     = "debug"
 )
 
+// statusNames maps the common names of the log levels to statuses.
+var statusNames = map[string]string{
+	"emerg":       StatusEmergency,
+	"emergency":   StatusEmergency,
+	"alert":       StatusAlert,
+	"crit":        StatusCritical,
+	"critical":    StatusCritical,
+	"fatal":       StatusCritical,
+	"err":         StatusError,
+	"error":       StatusError,
+	"warn":        StatusWarning,
+	"warning":     StatusWarning,
+	"notice":      StatusNotice,
+	"info":        StatusInfo,
+	"information": StatusInfo,
+	"debug":       StatusDebug,
+	"trace":       StatusDebug,
+}
+
+// StatusFromName returns the status of a log level name, for instance WARNING or err,
+// and false when the name isn't known.
+func StatusFromName(name string) (string, bool) {
+	status, found := statusNames[strings.ToLower(name)]
+	return status, found
+}
+
 // Syslog severity levels
 var (
 	SevEmergency = []byte("<40>")
@@ -33,3 +33,22 @@ func TestStatusToSeverity(t *testing.T) {
 	// default value should be "info"
 	assert.Equal(t, 0, bytes.Compare(SevInfo, StatusToSeverity("foo")))
 }
+
+func TestStatusFromName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"EMERG":   StatusEmergency,
+		"Fatal":   StatusCritical,
+		"err":     StatusError,
+		"WARNING": StatusWarning,
+		"notice":  StatusNotice,
+		"info":    StatusInfo,
+		"trace":   StatusDebug,
+	} {
+		status, found := StatusFromName(name)
+		assert.True(t, found, name)
+		assert.Equal(t, expected, status, name)
+	}
+
+	_, found := StatusFromName("verbose")
+	assert.False(t, found)
+}
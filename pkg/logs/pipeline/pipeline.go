@@ -8,15 +8,19 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/local"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 // Pipeline processes and sends messages to the backend
@@ -30,6 +34,7 @@ type Pipeline struct {
 // NewPipeline returns a new Pipeline
 func NewPipeline(outputChan chan *message.Payload,
 	processingRules []*config.ProcessingRule,
+	metricSink metrics.MetricSampleSink,
 	endpoints *config.Endpoints,
 	destinationsContext *client.DestinationsContext,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
@@ -44,7 +49,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	var logsSender *sender.Sender
 
 	strategy := getStrategy(strategyInput, senderInput, endpoints, serverless, pipelineID)
-	logsSender = sender.NewSender(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize)
+	logsSender = sender.NewSenderWithDiskSpool(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize, getDiskSpool(endpoints, serverless, pipelineID), endpoints.GetReliableEndpoints())
 
 	var encoder processor.Encoder
 	if serverless {
@@ -58,7 +63,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	}
 
 	inputChan := make(chan *message.Message, config.ChanSize)
-	processor := processor.New(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver)
+	processor := processor.New(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver, metricSink)
 
 	return &Pipeline{
 		InputChan: inputChan,
@@ -93,29 +98,66 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 
 	if endpoints.UseHTTP {
 		for i, endpoint := range endpoints.GetReliableEndpoints() {
+			if endpoint.IsLocal() {
+				reliable = append(reliable, newLocalDestination(endpoint, destinationsContext, true, pipelineID))
+				continue
+			}
 			telemetryName := fmt.Sprintf("logs_%d_reliable_%d", pipelineID, i)
 			reliable = append(reliable, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, true, telemetryName))
 		}
 		for i, endpoint := range endpoints.GetUnReliableEndpoints() {
+			if endpoint.IsLocal() {
+				additionals = append(additionals, newLocalDestination(endpoint, destinationsContext, false, pipelineID))
+				continue
+			}
 			telemetryName := fmt.Sprintf("logs_%d_unreliable_%d", pipelineID, i)
 			additionals = append(additionals, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, false, telemetryName))
 		}
 		return client.NewDestinations(reliable, additionals)
 	}
 	for _, endpoint := range endpoints.GetReliableEndpoints() {
+		if endpoint.IsLocal() {
+			reliable = append(reliable, newLocalDestination(endpoint, destinationsContext, true, pipelineID))
+			continue
+		}
 		reliable = append(reliable, tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext, true))
 	}
 	for _, endpoint := range endpoints.GetUnReliableEndpoints() {
+		if endpoint.IsLocal() {
+			additionals = append(additionals, newLocalDestination(endpoint, destinationsContext, false, pipelineID))
+			continue
+		}
 		additionals = append(additionals, tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext, false))
 	}
 	return client.NewDestinations(reliable, additionals)
 }
 
+// newLocalDestination returns a destination writing to the file or to stdout of the local endpoint,
+// each pipeline writes to its own file, for instance logs.0.json for logs.json.
+func newLocalDestination(endpoint config.Endpoint, destinationsContext *client.DestinationsContext, shouldRetry bool, pipelineID int) client.Destination {
+	if endpoint.Type == config.FileEndpointType {
+		ext := filepath.Ext(endpoint.Path)
+		endpoint.Path = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(endpoint.Path, ext), pipelineID, ext)
+	}
+	return local.NewDestination(endpoint, destinationsContext, shouldRetry)
+}
+
+// getDiskSpool returns the settings of the disk spool of the pipeline, each pipeline has its own
+// directory. It returns nil when the payloads are not spooled.
+func getDiskSpool(endpoints *config.Endpoints, serverless bool, pipelineID int) *config.DiskSpoolConfig {
+	if endpoints.DiskSpool == nil || !endpoints.UseHTTP || serverless {
+		return nil
+	}
+	diskSpool := *endpoints.DiskSpool
+	diskSpool.Path = filepath.Join(diskSpool.Path, fmt.Sprintf("pipeline_%d", pipelineID))
+	return &diskSpool
+}
+
 func getStrategy(inputChan chan *message.Message, outputChan chan *message.Payload, endpoints *config.Endpoints, serverless bool, pipelineID int) sender.Strategy {
 	if endpoints.UseHTTP || serverless {
 		encoder := sender.IdentityContentType
 		if endpoints.Main.UseCompression {
-			encoder = sender.NewGzipContentEncoding(endpoints.Main.CompressionLevel)
+			encoder = sender.NewContentEncoding(endpoints.Main.CompressionKind, endpoints.Main.CompressionLevel)
 		}
 		return sender.NewBatchStrategy(inputChan, outputChan, sender.ArraySerializer, endpoints.BatchWait, endpoints.BatchMaxSize, endpoints.BatchMaxContentSize, "logs", encoder)
 	}
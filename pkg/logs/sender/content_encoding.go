@@ -6,8 +6,8 @@
 package sender
 
 import (
-	"bytes"
-	"compress/gzip"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // ContentEncoding encodes the payload
@@ -29,45 +29,70 @@ func (c *identityContentType) encode(payload []byte) ([]byte, error) {
 	return payload, nil
 }
 
-// GzipContentEncoding encodes the payload using gzip algorithm
-type GzipContentEncoding struct {
-	level int
+// NewContentEncoding returns the content encoding compressing payloads with the given compression kind,
+// gzip being used when the kind is not supported or can't be used.
+func NewContentEncoding(kind string, level int) ContentEncoding {
+	switch kind {
+	case compression.NoneKind:
+		return IdentityContentType
+	case compression.ZstdKind:
+		encoding, err := NewZstdContentEncoding(level)
+		if err == nil {
+			return encoding
+		}
+		log.Warnf("Cannot use the %s logs compression, using %s instead: %v", kind, compression.GzipKind, err)
+	case compression.GzipKind, "":
+	default:
+		log.Warnf("Unsupported logs compression kind %q, using %s instead", kind, compression.GzipKind)
+	}
+	encoding, err := NewGzipContentEncoding(level)
+	if err != nil {
+		log.Warnf("Cannot use the %s logs compression, sending uncompressed payloads: %v", compression.GzipKind, err)
+		return IdentityContentType
+	}
+	return encoding
 }
 
-// NewGzipContentEncoding creates a new Gzip content type
-func NewGzipContentEncoding(level int) *GzipContentEncoding {
-	if level < gzip.NoCompression {
-		level = gzip.NoCompression
-	} else if level > gzip.BestCompression {
-		level = gzip.BestCompression
-	}
+// compressionContentEncoding encodes the payload using a compression strategy
+type compressionContentEncoding struct {
+	strategy compression.Compressor
+}
 
-	return &GzipContentEncoding{
-		level,
-	}
+func (c *compressionContentEncoding) name() string {
+	return c.strategy.ContentEncoding()
 }
 
-func (c *GzipContentEncoding) name() string {
-	return "gzip"
+func (c *compressionContentEncoding) encode(payload []byte) ([]byte, error) {
+	return c.strategy.Compress(payload)
 }
 
-func (c *GzipContentEncoding) encode(payload []byte) ([]byte, error) {
-	var compressedPayload bytes.Buffer
-	gzipWriter, err := gzip.NewWriterLevel(&compressedPayload, c.level)
-	if err != nil {
-		return nil, err
-	}
-	_, err = gzipWriter.Write(payload)
-	if err != nil {
-		return nil, err
-	}
-	err = gzipWriter.Flush()
+// GzipContentEncoding encodes the payload using gzip algorithm
+type GzipContentEncoding struct {
+	compressionContentEncoding
+}
+
+// NewGzipContentEncoding creates a new Gzip content type
+func NewGzipContentEncoding(level int) (*GzipContentEncoding, error) {
+	// the level is clamped to the valid gzip range by the compressor
+	strategy, err := compression.NewCompressor(compression.GzipKind, level)
 	if err != nil {
 		return nil, err
 	}
-	err = gzipWriter.Close()
+	return &GzipContentEncoding{compressionContentEncoding{strategy}}, nil
+}
+
+// ZstdContentEncoding encodes the payload using the stable (v1) zstd format
+type ZstdContentEncoding struct {
+	compressionContentEncoding
+}
+
+// NewZstdContentEncoding creates a new Zstd content type, zstd being unavailable in the agents
+// built without cgo
+func NewZstdContentEncoding(level int) (*ZstdContentEncoding, error) {
+	// the level is clamped to the valid zstd range by the compressor
+	strategy, err := compression.NewCompressor(compression.ZstdKind, level)
 	if err != nil {
 		return nil, err
 	}
-	return compressedPayload.Bytes(), nil
+	return &ZstdContentEncoding{compressionContentEncoding{strategy}}, nil
 }
@@ -29,7 +29,9 @@ func TestIdentityContentTypeName(t *testing.T) {
 func TestGzipContentEncoding(t *testing.T) {
 	payload := []byte("my payload")
 
-	encodedPayload, err := NewGzipContentEncoding(gzip.BestCompression).encode(payload)
+	encoding, err := NewGzipContentEncoding(gzip.BestCompression)
+	assert.Nil(t, err)
+	encodedPayload, err := encoding.encode(payload)
 	assert.Nil(t, err)
 
 	decompressedPayload, err := decompress(encodedPayload)
@@ -39,7 +41,9 @@ func TestGzipContentEncoding(t *testing.T) {
 }
 
 func TestGzipContentEncodingName(t *testing.T) {
-	assert.Equal(t, NewGzipContentEncoding(gzip.BestCompression).name(), "gzip")
+	encoding, err := NewGzipContentEncoding(gzip.BestCompression)
+	assert.Nil(t, err)
+	assert.Equal(t, encoding.name(), "gzip")
 }
 
 func decompress(payload []byte) ([]byte, error) {
@@ -56,3 +60,10 @@ func decompress(payload []byte) ([]byte, error) {
 
 	return buffer.Bytes(), nil
 }
+
+func TestNewContentEncoding(t *testing.T) {
+	assert.Equal(t, "gzip", NewContentEncoding("gzip", 6).name())
+	assert.Equal(t, "identity", NewContentEncoding("none", 6).name())
+	// unsupported kinds fall back to gzip
+	assert.Equal(t, "gzip", NewContentEncoding("lz4", 6).name())
+}
@@ -0,0 +1,22 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !cgo
+// +build !cgo
+
+package sender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZstdContentEncodingWithoutCgo(t *testing.T) {
+	// the zstd library requires cgo, the encoding falls back to gzip
+	_, err := NewZstdContentEncoding(3)
+	assert.Error(t, err)
+	assert.Equal(t, "gzip", NewContentEncoding("zstd", 6).name())
+}
@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build cgo
+// +build cgo
+
+package sender
+
+import (
+	"testing"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZstdContentEncoding(t *testing.T) {
+	payload := []byte("my payload")
+
+	encoding, err := NewZstdContentEncoding(3)
+	assert.Nil(t, err)
+	encodedPayload, err := encoding.encode(payload)
+	assert.Nil(t, err)
+
+	decompressedPayload, err := zstd.Decompress(nil, encodedPayload)
+	assert.Nil(t, err)
+
+	assert.Equal(t, payload, decompressedPayload)
+	assert.Equal(t, "zstd", encoding.name())
+}
+
+func TestNewZstdContentEncoding(t *testing.T) {
+	assert.Equal(t, "zstd", NewContentEncoding("zstd", 6).name())
+}
@@ -10,10 +10,12 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // DestinationSender wraps a destination to send messages blocking on a full buffer, but not blocking when
-// a destination is retrying
+// a destination is retrying. With a disk spool, the payloads are spooled while the destination is retrying
+// and sent in order once it recovers.
 type DestinationSender struct {
 	input             chan *message.Payload
 	destination       client.Destination
@@ -23,10 +25,20 @@ type DestinationSender struct {
 	lastRetryState    bool
 	cancelSendChan    chan struct{}
 	lastSendSucceeded bool
+
+	// spool is nil when the payloads are not spooled on disk
+	spool     *diskSpool
+	spoolWake chan struct{}
+	drainStop chan struct{}
+	drainDone chan struct{}
 }
 
 // NewDestinationSender creates a new DestinationSender
 func NewDestinationSender(destination client.Destination, output chan *message.Payload, bufferSize int) *DestinationSender {
+	return newDestinationSender(destination, output, bufferSize, nil)
+}
+
+func newDestinationSender(destination client.Destination, output chan *message.Payload, bufferSize int, spool *diskSpool) *DestinationSender {
 	inputChan := make(chan *message.Payload, bufferSize)
 	retryReader := make(chan bool, 1)
 	stopChan := destination.Start(inputChan, output, retryReader)
@@ -40,8 +52,17 @@ func NewDestinationSender(destination client.Destination, output chan *message.P
 		lastRetryState:    false,
 		cancelSendChan:    nil,
 		lastSendSucceeded: false,
+		spool:             spool,
 	}
 	d.startRetryReader()
+	if spool != nil {
+		d.spoolWake = make(chan struct{}, 1)
+		d.drainStop = make(chan struct{})
+		d.drainDone = make(chan struct{})
+		go d.drainSpool()
+		// send the payloads spooled by a previous run
+		d.wakeSpool()
+	}
 
 	return d
 }
@@ -58,21 +79,35 @@ func (d *DestinationSender) startRetryReader() {
 			}
 			d.lastRetryState = v
 			d.retryLock.Unlock()
+			if !v && d.spool != nil {
+				d.wakeSpool()
+			}
 		}
 	}()
 }
 
 // Stop stops the DestinationSender
 func (d *DestinationSender) Stop() {
+	// the payloads still spooled are sent on the next run
+	if d.spool != nil {
+		close(d.drainStop)
+		<-d.drainDone
+	}
 	close(d.input)
 	<-d.stopChan
 	close(d.retryReader)
 }
 
 // Send sends a payload and blocks if the input is full. It will not block if the destination
-// is retrying payloads and will cancel the blocking attempt if the retry state changes
+// is retrying payloads and will cancel the blocking attempt if the retry state changes.
+// With a disk spool, the payload is spooled instead when the destination is retrying,
+// or when older payloads are still spooled.
 func (d *DestinationSender) Send(payload *message.Payload) bool {
 	d.lastSendSucceeded = false
+	if d.spool != nil && (d.isRetrying() || d.spool.len() > 0) {
+		return d.spoolPayload(payload)
+	}
+
 	d.retryLock.Lock()
 	d.cancelSendChan = make(chan struct{}, 1)
 	isRetrying := d.lastRetryState
@@ -93,9 +128,66 @@ func (d *DestinationSender) Send(payload *message.Payload) bool {
 		case <-d.cancelSendChan:
 		}
 	}
+	if d.spool != nil {
+		return d.spoolPayload(payload)
+	}
 	return false
 }
 
+func (d *DestinationSender) isRetrying() bool {
+	d.retryLock.Lock()
+	defer d.retryLock.Unlock()
+	return d.lastRetryState
+}
+
+// spoolPayload appends the payload to the disk spool and returns true if it succeeded.
+func (d *DestinationSender) spoolPayload(payload *message.Payload) bool {
+	if err := d.spool.push(payload); err != nil {
+		log.Warnf("Can't spool a logs payload on disk: %v", err)
+		return false
+	}
+	d.lastSendSucceeded = true
+	d.wakeSpool()
+	return true
+}
+
+func (d *DestinationSender) wakeSpool() {
+	select {
+	case d.spoolWake <- struct{}{}:
+	default:
+	}
+}
+
+// drainSpool sends the spooled payloads in order while the destination is not retrying.
+// A payload is removed from the spool once the destination accepted it, the auditor
+// is only updated once it is sent.
+func (d *DestinationSender) drainSpool() {
+	defer close(d.drainDone)
+	for {
+		select {
+		case <-d.spoolWake:
+		case <-d.drainStop:
+			return
+		}
+		for !d.isRetrying() {
+			payload, name, err := d.spool.peek()
+			if err != nil {
+				log.Warn(err)
+				continue
+			}
+			if payload == nil {
+				break
+			}
+			select {
+			case d.input <- payload:
+				d.spool.remove(name)
+			case <-d.drainStop:
+				return
+			}
+		}
+	}
+}
+
 // NonBlockingSend tries to send the payload and fails silently if the input is full.
 // returns false if the buffer is full - true if successful.
 func (d *DestinationSender) NonBlockingSend(payload *message.Payload) bool {
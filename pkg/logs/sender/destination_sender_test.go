@@ -8,9 +8,11 @@ package sender
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockDestination struct {
@@ -132,3 +134,56 @@ func TestDestinationSenderDeadlock(t *testing.T) {
 	wg.Wait()
 	close(dest.input)
 }
+
+func TestDestinationSenderSpool(t *testing.T) {
+	output := make(chan *message.Payload)
+	dest := &mockDestination{}
+	spool, err := newDiskSpool(t.TempDir(), 1024*1024, time.Hour)
+	require.NoError(t, err)
+	d := newDestinationSender(dest, output, 0, spool)
+
+	dest.isRetrying <- true
+	assert.Eventually(t, d.isRetrying, time.Second, time.Millisecond)
+
+	// the payloads are spooled instead of blocking while the destination is retrying
+	for _, content := range []string{"a", "b", "c"} {
+		assert.True(t, d.Send(&message.Payload{Encoded: []byte(content)}))
+		assert.True(t, d.lastSendSucceeded)
+	}
+	assert.Equal(t, 3, spool.len())
+
+	// they are sent in order once it recovers, before the new payloads
+	dest.isRetrying <- false
+	assert.Equal(t, []byte("a"), (<-dest.input).Encoded)
+	assert.True(t, d.Send(&message.Payload{Encoded: []byte("d")}))
+	for _, content := range []string{"b", "c", "d"} {
+		assert.Equal(t, []byte(content), (<-dest.input).Encoded)
+	}
+	assert.Eventually(t, func() bool { return spool.len() == 0 }, time.Second, time.Millisecond)
+
+	go func() {
+		for range dest.input {
+		}
+		dest.stopChan <- struct{}{}
+	}()
+	d.Stop()
+}
+
+func TestDestinationSenderSpoolOnCancel(t *testing.T) {
+	output := make(chan *message.Payload)
+	dest := &mockDestination{}
+	spool, err := newDiskSpool(t.TempDir(), 1024*1024, time.Hour)
+	require.NoError(t, err)
+	d := newDestinationSender(dest, output, 0, spool)
+
+	sendSucceeded := make(chan bool)
+	// Send blocks because the input is full
+	go func() {
+		sendSucceeded <- d.Send(&message.Payload{})
+	}()
+	dest.isRetrying <- true
+
+	// the payload is spooled when the destination starts retrying
+	assert.True(t, <-sendSucceeded)
+	assert.Equal(t, 1, spool.len())
+}
@@ -0,0 +1,304 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const spoolFileExtension = ".payload"
+
+var (
+	tlmSpooledPayloads = telemetry.NewCounter("logs_sender", "spooled_payloads", []string{}, "Payloads spooled on disk")
+	tlmSpoolDropped    = telemetry.NewCounter("logs_sender", "spool_dropped_payloads", []string{"reason"}, "Payloads dropped from the disk spool")
+	tlmSpoolSize       = telemetry.NewGauge("logs_sender", "spool_size_bytes", []string{"path"}, "Size of the payloads spooled on disk")
+)
+
+// spooledPayload is the content of a spool file.
+type spooledPayload struct {
+	Encoded       []byte           `json:"encoded"`
+	Encoding      string           `json:"encoding,omitempty"`
+	UnencodedSize int              `json:"unencoded_size"`
+	Messages      []spooledMessage `json:"messages"`
+}
+
+// spooledMessage holds what the auditor needs to commit the offset of a message
+// once the payload is sent, the content of the message is already in the encoded payload.
+type spooledMessage struct {
+	Identifier         string `json:"identifier,omitempty"`
+	Offset             string `json:"offset,omitempty"`
+	Fingerprint        string `json:"fingerprint,omitempty"`
+	TailingMode        string `json:"tailing_mode,omitempty"`
+	IngestionTimestamp int64  `json:"ingestion_timestamp,omitempty"`
+}
+
+type spoolFile struct {
+	name    string
+	size    int64
+	created time.Time
+}
+
+// diskSpool is a FIFO of payloads stored on disk, one file per payload. When it
+// is full the oldest payloads are dropped, as well as the payloads older than maxAge.
+type diskSpool struct {
+	path    string
+	maxSize int64
+	maxAge  time.Duration
+
+	mu     sync.Mutex
+	files  []spoolFile
+	size   int64
+	nextID uint64
+}
+
+// newDiskSpool creates the spool directory and reloads the payloads spooled by a previous run.
+func newDiskSpool(path string, maxSize int64, maxAge time.Duration) (*diskSpool, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	s := &diskSpool{
+		path:    path,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+	}
+	if err := s.reloadExistingFiles(); err != nil {
+		return nil, err
+	}
+	if len(s.files) > 0 {
+		log.Infof("Found %d logs payloads spooled in %s, they will be sent first", len(s.files), path)
+	}
+	return s, nil
+}
+
+func (s *diskSpool) reloadExistingFiles() error {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, spoolFileExtension) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, spoolFileExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		s.files = append(s.files, spoolFile{name: name, size: info.Size(), created: info.ModTime()})
+		s.size += info.Size()
+		if id >= s.nextID {
+			s.nextID = id + 1
+		}
+	}
+	// the names are zero-padded, so the lexical order is the order of the payloads
+	sort.Slice(s.files, func(i, j int) bool { return s.files[i].name < s.files[j].name })
+	s.updateTelemetry()
+	return nil
+}
+
+// push appends a payload to the spool.
+func (s *diskSpool) push(payload *message.Payload) error {
+	data, err := json.Marshal(newSpooledPayload(payload))
+	if err != nil {
+		return err
+	}
+	size := int64(len(data))
+	if size > s.maxSize {
+		return fmt.Errorf("the payload of %d bytes is larger than the spool", size)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropExpired(time.Now())
+	for len(s.files) > 0 && s.size+size > s.maxSize {
+		s.drop("full")
+	}
+
+	name := fmt.Sprintf("%020d%s", s.nextID, spoolFileExtension)
+	tmpPath := filepath.Join(s.path, name+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(s.path, name)); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	s.nextID++
+	s.files = append(s.files, spoolFile{name: name, size: size, created: time.Now()})
+	s.size += size
+	tlmSpooledPayloads.Inc()
+	s.updateTelemetry()
+	return nil
+}
+
+// peek returns the oldest payload and the name of its file, to remove it once
+// sent. It returns a nil payload when the spool is empty. The unreadable files
+// are dropped.
+func (s *diskSpool) peek() (*message.Payload, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropExpired(time.Now())
+	if len(s.files) == 0 {
+		return nil, "", nil
+	}
+	name := s.files[0].name
+	data, err := os.ReadFile(filepath.Join(s.path, name))
+	if err != nil {
+		s.drop("invalid")
+		return nil, "", fmt.Errorf("can't read the spool file %s: %v", name, err)
+	}
+	spooled := &spooledPayload{}
+	if err := json.Unmarshal(data, spooled); err != nil {
+		s.drop("invalid")
+		return nil, "", fmt.Errorf("can't decode the spool file %s: %v", name, err)
+	}
+	return spooled.toPayload(), name, nil
+}
+
+// remove removes the file of a sent payload. The file may have been dropped
+// already while the payload was being sent.
+func (s *diskSpool) remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.files) > 0 && s.files[0].name == name {
+		s.removeOldest()
+		s.updateTelemetry()
+	}
+}
+
+// len returns the number of spooled payloads.
+func (s *diskSpool) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files)
+}
+
+func (s *diskSpool) dropExpired(now time.Time) {
+	for len(s.files) > 0 && s.maxAge > 0 && now.Sub(s.files[0].created) > s.maxAge {
+		s.drop("expired")
+	}
+}
+
+// drop removes the oldest payload without sending it.
+func (s *diskSpool) drop(reason string) {
+	log.Warnf("Dropping the logs payload %s from the spool %s: %s", s.files[0].name, s.path, reason)
+	tlmSpoolDropped.Inc(reason)
+	s.removeOldest()
+	s.updateTelemetry()
+}
+
+func (s *diskSpool) removeOldest() {
+	file := s.files[0]
+	if err := os.Remove(filepath.Join(s.path, file.name)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Can't remove the spool file %s: %v", file.name, err)
+	}
+	s.files = s.files[1:]
+	s.size -= file.size
+}
+
+func (s *diskSpool) updateTelemetry() {
+	tlmSpoolSize.Set(float64(s.size), s.path)
+}
+
+func newSpooledPayload(payload *message.Payload) *spooledPayload {
+	spooled := &spooledPayload{
+		Encoded:       payload.Encoded,
+		Encoding:      payload.Encoding,
+		UnencodedSize: payload.UnencodedSize,
+		Messages:      make([]spooledMessage, 0, len(payload.Messages)),
+	}
+	for _, msg := range payload.Messages {
+		spooledMsg := spooledMessage{IngestionTimestamp: msg.IngestionTimestamp}
+		if msg.Origin != nil {
+			spooledMsg.Identifier = msg.Origin.Identifier
+			spooledMsg.Offset = msg.Origin.Offset
+			spooledMsg.Fingerprint = msg.Origin.Fingerprint
+			if msg.Origin.LogSource != nil && msg.Origin.LogSource.Config != nil {
+				spooledMsg.TailingMode = msg.Origin.LogSource.Config.TailingMode
+			}
+		}
+		spooled.Messages = append(spooled.Messages, spooledMsg)
+	}
+	return spooled
+}
+
+// toPayload returns the payload to send. Its messages only hold what the auditor needs.
+func (s *spooledPayload) toPayload() *message.Payload {
+	payload := &message.Payload{
+		Encoded:       s.Encoded,
+		Encoding:      s.Encoding,
+		UnencodedSize: s.UnencodedSize,
+		Messages:      make([]*message.Message, 0, len(s.Messages)),
+	}
+	logSources := make(map[string]*sources.LogSource)
+	for _, spooledMsg := range s.Messages {
+		source, found := logSources[spooledMsg.TailingMode]
+		if !found {
+			source = &sources.LogSource{Config: &config.LogsConfig{TailingMode: spooledMsg.TailingMode}}
+			logSources[spooledMsg.TailingMode] = source
+		}
+		origin := message.NewOrigin(source)
+		origin.Identifier = spooledMsg.Identifier
+		origin.Offset = spooledMsg.Offset
+		origin.Fingerprint = spooledMsg.Fingerprint
+		payload.Messages = append(payload.Messages, message.NewMessage(nil, origin, "", spooledMsg.IngestionTimestamp))
+	}
+	return payload
+}
+
+// spoolDirNames returns the names of the spool directories of the endpoints. A name is derived
+// from the address of its endpoint, so that the spooled payloads are sent to the same endpoint
+// after the endpoints are reordered, added or removed. The endpoints which only differ by their
+// API key are numbered in their order.
+func spoolDirNames(endpoints []config.Endpoint) []string {
+	names := make([]string, len(endpoints))
+	seen := make(map[string]int, len(endpoints))
+	for i, endpoint := range endpoints {
+		name := spoolDirName(endpoint)
+		if n := seen[name]; n > 0 {
+			names[i] = fmt.Sprintf("%s_%d", name, n)
+		} else {
+			names[i] = name
+		}
+		seen[name]++
+	}
+	return names
+}
+
+// spoolDirName returns a hash of the address of the endpoint: its host, its port and the
+// settings building the path of its URL, or the path of a local endpoint.
+func spoolDirName(endpoint config.Endpoint) string {
+	identity := fmt.Sprintf("%s|%d|%t|%d|%s|%s|%s", endpoint.Host, endpoint.Port, endpoint.UseSSL, endpoint.Version, endpoint.TrackType, endpoint.Protocol, endpoint.Origin)
+	if endpoint.IsLocal() {
+		identity = endpoint.Type + "|" + endpoint.Path
+	}
+	sum := sha256.Sum256([]byte(identity))
+	return "endpoint_" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,151 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func spoolTestPayload(content string, offset string) *message.Payload {
+	source := sources.NewLogSource("", &config.LogsConfig{TailingMode: "beginning"})
+	origin := message.NewOrigin(source)
+	origin.Identifier = "file:/var/log/app.log"
+	origin.Offset = offset
+	origin.Fingerprint = "4:f00d"
+	return &message.Payload{
+		Messages:      []*message.Message{message.NewMessage([]byte(content), origin, message.StatusInfo, 1234)},
+		Encoded:       []byte(content),
+		Encoding:      "gzip",
+		UnencodedSize: len(content),
+	}
+}
+
+func TestDiskSpool(t *testing.T) {
+	path := t.TempDir()
+	spool, err := newDiskSpool(path, 1024, time.Hour)
+	require.NoError(t, err)
+
+	payload, _, err := spool.peek()
+	assert.NoError(t, err)
+	assert.Nil(t, payload)
+
+	for i, content := range []string{"a", "b", "c"} {
+		require.NoError(t, spool.push(spoolTestPayload(content, string(rune('1'+i)))))
+	}
+	assert.Equal(t, 3, spool.len())
+
+	// the payloads are reloaded by the next run
+	spool, err = newDiskSpool(path, 1024, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 3, spool.len())
+	require.NoError(t, spool.push(spoolTestPayload("d", "4")))
+
+	for i, content := range []string{"a", "b", "c", "d"} {
+		payload, name, err := spool.peek()
+		require.NoError(t, err)
+		require.NotNil(t, payload)
+		assert.Equal(t, []byte(content), payload.Encoded)
+		assert.Equal(t, "gzip", payload.Encoding)
+		assert.Equal(t, 1, payload.UnencodedSize)
+		require.Len(t, payload.Messages, 1)
+		msg := payload.Messages[0]
+		assert.Equal(t, "file:/var/log/app.log", msg.Origin.Identifier)
+		assert.Equal(t, string(rune('1'+i)), msg.Origin.Offset)
+		assert.Equal(t, "4:f00d", msg.Origin.Fingerprint)
+		assert.Equal(t, "beginning", msg.Origin.LogSource.Config.TailingMode)
+		assert.Equal(t, int64(1234), msg.IngestionTimestamp)
+		spool.remove(name)
+	}
+	assert.Equal(t, 0, spool.len())
+	entries, err := os.ReadDir(path)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestDiskSpoolMaxSize(t *testing.T) {
+	spool, err := newDiskSpool(t.TempDir(), 1024, time.Hour)
+	require.NoError(t, err)
+
+	assert.Error(t, spool.push(spoolTestPayload(string(make([]byte, 2048)), "1")))
+
+	large := string(make([]byte, 150))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, spool.push(spoolTestPayload(large, string(rune('1'+i)))))
+	}
+	// the oldest payloads are dropped to make room
+	assert.Equal(t, 2, spool.len())
+	assert.LessOrEqual(t, spool.size, int64(1024))
+	payload, name, err := spool.peek()
+	require.NoError(t, err)
+	assert.Equal(t, "2", payload.Messages[0].Origin.Offset)
+
+	// a payload dropped while being sent is not removed twice
+	require.NoError(t, spool.push(spoolTestPayload(large, "4")))
+	spool.remove(name)
+	assert.Equal(t, 2, spool.len())
+}
+
+func TestDiskSpoolMaxAge(t *testing.T) {
+	spool, err := newDiskSpool(t.TempDir(), 1024, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.push(spoolTestPayload("a", "1")))
+	require.NoError(t, spool.push(spoolTestPayload("b", "2")))
+	spool.files[0].created = time.Now().Add(-2 * time.Minute)
+
+	payload, _, err := spool.peek()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), payload.Encoded)
+	assert.Equal(t, 1, spool.len())
+}
+
+func TestDiskSpoolInvalidFile(t *testing.T) {
+	path := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(path, "00000000000000000001"+spoolFileExtension), []byte("{"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "unrelated.txt"), []byte("{"), 0600))
+
+	spool, err := newDiskSpool(path, 1024, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, spool.len())
+	require.NoError(t, spool.push(spoolTestPayload("a", "1")))
+
+	_, _, err = spool.peek()
+	assert.Error(t, err)
+	payload, _, err := spool.peek()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), payload.Encoded)
+	assert.FileExists(t, filepath.Join(path, "unrelated.txt"))
+}
+
+func TestSpoolDirNames(t *testing.T) {
+	main := config.Endpoint{APIKey: "key1", Host: "agent-http-intake.logs.datadoghq.com", Port: 443, UseSSL: true}
+	eu := config.Endpoint{APIKey: "key2", Host: "agent-http-intake.logs.datadoghq.eu", Port: 443, UseSSL: true}
+	otherKey := main
+	otherKey.APIKey = "key3"
+	file := config.Endpoint{Type: config.FileEndpointType, Path: "/var/log/datadog/logs.json"}
+
+	names := spoolDirNames([]config.Endpoint{main, eu, file})
+	assert.Len(t, names, 3)
+	assert.NotEqual(t, names[0], names[1])
+	assert.NotEqual(t, names[0], names[2])
+
+	// the names do not depend on the position of the endpoints nor on their API key
+	assert.Equal(t, []string{names[1], names[0]}, spoolDirNames([]config.Endpoint{eu, main}))
+	assert.Equal(t, []string{names[0]}, spoolDirNames([]config.Endpoint{otherKey}))
+
+	// the endpoints with the same address are numbered
+	assert.Equal(t, []string{names[0], names[0] + "_1"}, spoolDirNames([]config.Endpoint{main, otherKey}))
+}
@@ -6,12 +6,15 @@
 package sender
 
 import (
+	"path/filepath"
 	"strconv"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 var (
@@ -33,16 +36,29 @@ type Sender struct {
 	destinations *client.Destinations
 	done         chan struct{}
 	bufferSize   int
+	diskSpool    *config.DiskSpoolConfig
+	// reliableEndpoints are the endpoints of the reliable destinations, which identify their spools.
+	reliableEndpoints []config.Endpoint
 }
 
 // NewSender returns a new sender.
 func NewSender(inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int) *Sender {
+	return NewSenderWithDiskSpool(inputChan, outputChan, destinations, bufferSize, nil, nil)
+}
+
+// NewSenderWithDiskSpool returns a new sender spooling the payloads of the reliable destinations
+// on disk while they are retrying. Each reliable destination has its own spool in a subdirectory
+// of diskSpool.Path named after its endpoint, reliableEndpoints holds the endpoints of the reliable
+// destinations in the same order. The payloads are not spooled if diskSpool is nil.
+func NewSenderWithDiskSpool(inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int, diskSpool *config.DiskSpoolConfig, reliableEndpoints []config.Endpoint) *Sender {
 	return &Sender{
-		inputChan:    inputChan,
-		outputChan:   outputChan,
-		destinations: destinations,
-		done:         make(chan struct{}),
-		bufferSize:   bufferSize,
+		inputChan:         inputChan,
+		outputChan:        outputChan,
+		destinations:      destinations,
+		done:              make(chan struct{}),
+		bufferSize:        bufferSize,
+		diskSpool:         diskSpool,
+		reliableEndpoints: reliableEndpoints,
 	}
 }
 
@@ -59,7 +75,7 @@ func (s *Sender) Stop() {
 }
 
 func (s *Sender) run() {
-	reliableDestinations := buildDestinationSenders(s.destinations.Reliable, s.outputChan, s.bufferSize)
+	reliableDestinations := s.buildReliableDestinationSenders()
 
 	sink := additionalDestinationsSink(s.bufferSize)
 	unreliableDestinations := buildDestinationSenders(s.destinations.Unreliable, sink, s.bufferSize)
@@ -128,6 +144,27 @@ func additionalDestinationsSink(bufferSize int) chan *message.Payload {
 	return sink
 }
 
+func (s *Sender) buildReliableDestinationSenders() []*DestinationSender {
+	if s.diskSpool == nil {
+		return buildDestinationSenders(s.destinations.Reliable, s.outputChan, s.bufferSize)
+	}
+	if len(s.reliableEndpoints) != len(s.destinations.Reliable) {
+		log.Errorf("Can't identify the logs disk spools of %d destinations from %d endpoints, the payloads won't be spooled", len(s.destinations.Reliable), len(s.reliableEndpoints))
+		return buildDestinationSenders(s.destinations.Reliable, s.outputChan, s.bufferSize)
+	}
+	destinationSenders := []*DestinationSender{}
+	names := spoolDirNames(s.reliableEndpoints)
+	for i, destination := range s.destinations.Reliable {
+		path := filepath.Join(s.diskSpool.Path, names[i])
+		spool, err := newDiskSpool(path, s.diskSpool.MaxSize, s.diskSpool.MaxAge)
+		if err != nil {
+			log.Errorf("Can't create the logs disk spool %s, the payloads won't be spooled: %v", path, err)
+		}
+		destinationSenders = append(destinationSenders, newDestinationSender(destination, s.outputChan, s.bufferSize, spool))
+	}
+	return destinationSenders
+}
+
 func buildDestinationSenders(destinations []client.Destination, output chan *message.Payload, bufferSize int) []*DestinationSender {
 	destinationSenders := []*DestinationSender{}
 	for _, destination := range destinations {
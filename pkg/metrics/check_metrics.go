@@ -128,6 +128,34 @@ func (cm *CheckMetrics) RemoveExpired(timestamp float64) {
 	tlmCheckMetricsRemovedStateful.Add(removed)
 }
 
+// SaveStates returns the states of the stateful metrics, by context key.
+func (cm *CheckMetrics) SaveStates() map[ckey.ContextKey]MetricState {
+	states := make(map[ckey.ContextKey]MetricState)
+	for key, m := range cm.metrics {
+		if metric, ok := m.(restorableMetric); ok {
+			if state, ok := metric.saveState(); ok {
+				states[key] = state
+			}
+		}
+	}
+	return states
+}
+
+// RestoreState creates the metric with contextKey from a state returned by SaveStates.
+// An existing metric is not overwritten.
+func (cm *CheckMetrics) RestoreState(contextKey ckey.ContextKey, state MetricState) error {
+	if _, ok := cm.metrics[contextKey]; ok {
+		return nil
+	}
+	metric, err := newRestoredMetric(state)
+	if err != nil {
+		return err
+	}
+	cm.metrics[contextKey] = metric
+	checkMetricsAddSampleTelemetry.Inc(true)
+	return nil
+}
+
 // CheckMetricsTelemetryAccumulator aggregates telemetry collected from multiple
 // CheckMetrics instances.
 type CheckMetricsTelemetryAccumulator struct {
@@ -89,3 +89,37 @@ func TestCheckMetricsNoExpiry(t *testing.T) {
 	assert.Contains(t, cm.metrics, ckey.ContextKey(3))
 	assert.Contains(t, cm.metrics, ckey.ContextKey(4))
 }
+
+func TestCheckMetricsSaveRestoreStates(t *testing.T) {
+	cm := NewCheckMetrics(true, 1000*time.Second)
+	t0 := 16_0000_0000.0
+
+	cm.AddSample(1, &MetricSample{Mtype: GaugeType, Value: 1}, t0, 1)
+	cm.AddSample(2, &MetricSample{Mtype: RateType, Value: 10}, t0, 1)
+	cm.AddSample(3, &MetricSample{Mtype: MonotonicCountType, Value: 5}, t0, 1)
+	cm.AddSample(3, &MetricSample{Mtype: MonotonicCountType, Value: 7}, t0, 1)
+	cm.Flush(t0 + 15)
+
+	states := cm.SaveStates()
+	assert.Equal(t, map[ckey.ContextKey]MetricState{
+		2: {Mtype: RateType, Value: 10, Timestamp: t0},
+		3: {Mtype: MonotonicCountType, Value: 7},
+	}, states)
+
+	restored := NewCheckMetrics(true, 1000*time.Second)
+	for key, state := range states {
+		assert.NoError(t, restored.RestoreState(key, state))
+	}
+	assert.Error(t, restored.RestoreState(4, MetricState{Mtype: GaugeType}))
+
+	// the first samples after the restore are flushed
+	restored.AddSample(2, &MetricSample{Mtype: RateType, Value: 40}, t0+15, 1)
+	restored.AddSample(3, &MetricSample{Mtype: MonotonicCountType, Value: 10}, t0+15, 1)
+	series, errors := restored.Flush(t0 + 30)
+	assert.Empty(t, errors)
+	values := map[ckey.ContextKey]float64{}
+	for _, serie := range series {
+		values[serie.ContextKey] = serie.Points[0].Value
+	}
+	assert.Equal(t, map[ckey.ContextKey]float64{2: 2, 3: 3}, values)
+}
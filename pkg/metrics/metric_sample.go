@@ -62,6 +62,14 @@ func (m MetricType) String() string {
 	}
 }
 
+// MetricSampleSink is a sink for metric samples, aggregated by a time sampler as
+// DogStatsD samples would be.
+type MetricSampleSink interface {
+	// AddMetricSamples copies the samples into the sink, the slice can be reused
+	// by the caller once the call returns.
+	AddMetricSamples(samples []MetricSample)
+}
+
 // MetricSampleContext allows to access a sample context data
 type MetricSampleContext interface {
 	GetName() string
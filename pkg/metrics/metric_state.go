@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import "fmt"
+
+// MetricState is the state kept between flushes by a stateful metric. It can be
+// restored in a new instance of the metric, for instance after a restart of the agent.
+type MetricState struct {
+	Mtype MetricType
+	// Value is the last sample of the metric
+	Value float64
+	// Timestamp is the timestamp of the last sample, when the metric tracks it
+	Timestamp float64
+}
+
+// restorableMetric is implemented by the stateful metrics whose state can be saved and restored
+type restorableMetric interface {
+	Metric
+	// saveState returns the state of the metric, or false when the metric has no state yet
+	saveState() (MetricState, bool)
+	restoreState(state MetricState)
+}
+
+// newRestoredMetric returns a new metric with the given state
+func newRestoredMetric(state MetricState) (restorableMetric, error) {
+	var metric restorableMetric
+	switch state.Mtype {
+	case RateType:
+		metric = &Rate{}
+	case MonotonicCountType:
+		metric = &MonotonicCount{}
+	default:
+		return nil, fmt.Errorf("the state of the %s metrics can't be restored", state.Mtype)
+	}
+	metric.restoreState(state)
+	return metric, nil
+}
@@ -75,3 +75,20 @@ func (mc *MonotonicCount) flush(timestamp float64) ([]*Serie, error) {
 func (mc *MonotonicCount) isStateful() bool {
 	return true
 }
+
+func (mc *MonotonicCount) saveState() (MetricState, bool) {
+	if mc.sampledSinceLastFlush {
+		return MetricState{Mtype: MonotonicCountType, Value: mc.currentSample}, true
+	}
+	if mc.hasPreviousSample {
+		return MetricState{Mtype: MonotonicCountType, Value: mc.previousSample}, true
+	}
+	return MetricState{}, false
+}
+
+// restoreState restores the previous sample, the next flush returns the increase
+// from the restored sample.
+func (mc *MonotonicCount) restoreState(state MetricState) {
+	mc.previousSample = state.Value
+	mc.hasPreviousSample = true
+}
@@ -53,3 +53,17 @@ func (r *Rate) flush(timestamp float64) ([]*Serie, error) {
 func (r *Rate) isStateful() bool {
 	return true
 }
+
+func (r *Rate) saveState() (MetricState, bool) {
+	if r.timestamp != 0 {
+		return MetricState{Mtype: RateType, Value: r.sample, Timestamp: r.timestamp}, true
+	}
+	if r.previousTimestamp != 0 {
+		return MetricState{Mtype: RateType, Value: r.previousSample, Timestamp: r.previousTimestamp}, true
+	}
+	return MetricState{}, false
+}
+
+func (r *Rate) restoreState(state MetricState) {
+	r.previousSample, r.previousTimestamp = state.Value, state.Timestamp
+}
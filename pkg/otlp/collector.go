@@ -13,6 +13,8 @@ import (
 	"fmt"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/otlp/internal/logsagentexporter"
 	"github.com/DataDog/datadog-agent/pkg/otlp/internal/serializerexporter"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/DataDog/datadog-agent/pkg/util/flavor"
@@ -35,7 +37,7 @@ var (
 	pipelineError = atomic.NewError(nil)
 )
 
-func getComponents(s serializer.MetricSerializer) (
+func getComponents(s serializer.MetricSerializer, logsAgentChannel chan *message.Message) (
 	component.Factories,
 	error,
 ) {
@@ -56,6 +58,7 @@ func getComponents(s serializer.MetricSerializer) (
 	exporters, err := component.MakeExporterFactoryMap(
 		otlpexporter.NewFactory(),
 		serializerexporter.NewFactory(s),
+		logsagentexporter.NewFactory(logsAgentChannel),
 		loggingexporter.NewFactory(),
 	)
 	if err != nil {
@@ -97,6 +100,8 @@ type PipelineConfig struct {
 	MetricsEnabled bool
 	// TracesEnabled states whether OTLP traces support is enabled.
 	TracesEnabled bool
+	// LogsEnabled states whether OTLP logs support is enabled.
+	LogsEnabled bool
 	// Debug contains debug configurations.
 	Debug map[string]interface{}
 
@@ -129,14 +134,15 @@ type CollectorStatus struct {
 	ErrorMessage string
 }
 
-// NewPipeline defines a new OTLP pipeline.
-func NewPipeline(cfg PipelineConfig, s serializer.MetricSerializer) (*Pipeline, error) {
+// NewPipeline defines a new OTLP pipeline. The OTLP logs are sent to logsAgentChannel,
+// the input of a logs agent pipeline.
+func NewPipeline(cfg PipelineConfig, s serializer.MetricSerializer, logsAgentChannel chan *message.Message) (*Pipeline, error) {
 	buildInfo, err := getBuildInfo()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get build info: %w", err)
 	}
 
-	factories, err := getComponents(s)
+	factories, err := getComponents(s, logsAgentChannel)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get components: %w", err)
 	}
@@ -177,15 +183,24 @@ func (p *Pipeline) Stop() {
 	p.col.Shutdown()
 }
 
-// BuildAndStart builds and starts an OTLP pipeline
-func BuildAndStart(ctx context.Context, cfg config.Config, s serializer.MetricSerializer) (*Pipeline, error) {
+// BuildAndStart builds and starts an OTLP pipeline. logsAgentChannel is nil when
+// the logs agent isn't running.
+func BuildAndStart(ctx context.Context, cfg config.Config, s serializer.MetricSerializer, logsAgentChannel chan *message.Message) (*Pipeline, error) {
 	pcfg, err := FromAgentConfig(cfg)
 	if err != nil {
 		pipelineError.Store(fmt.Errorf("config error: %w", err))
 		return nil, pipelineError.Load()
 	}
+	if pcfg.LogsEnabled && logsAgentChannel == nil {
+		log.Warn("OTLP logs support is enabled but the logs agent is not running, the OTLP logs will be rejected")
+		pcfg.LogsEnabled = false
+		if !pcfg.MetricsEnabled && !pcfg.TracesEnabled {
+			pipelineError.Store(fmt.Errorf("config error: the logs agent must be enabled to enable only the OTLP logs"))
+			return nil, pipelineError.Load()
+		}
+	}
 
-	p, err := NewPipeline(pcfg, s)
+	p, err := NewPipeline(pcfg, s, logsAgentChannel)
 	if err != nil {
 		pipelineError.Store(fmt.Errorf("failed to build pipeline: %w", err))
 		return nil, pipelineError.Load()
@@ -347,3 +347,13 @@ func TestFromAgentConfigMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestFromAgentConfigLogs(t *testing.T) {
+	cfg, err := testutil.LoadConfig("./testdata/logs/enabled.yaml")
+	require.NoError(t, err)
+	pcfg, err := FromAgentConfig(cfg)
+	require.NoError(t, err)
+	assert.True(t, pcfg.LogsEnabled)
+	assert.False(t, pcfg.MetricsEnabled)
+	assert.False(t, pcfg.TracesEnabled)
+}
@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2021-present Datadog, Inc.
+
+package logsagentexporter
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	conventions "go.opentelemetry.io/collector/semconv/v1.6.1"
+	"go.uber.org/zap"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/otlp/model/attributes"
+	"github.com/DataDog/datadog-agent/pkg/otlp/model/source"
+)
+
+const (
+	// logSourceName is the name and the ddsource of the logs received through OTLP
+	logSourceName = "otlp_log_ingestion"
+
+	// attributes added to the content of the logs
+	messageKey        = "message"
+	severityTextKey   = "otel.severity_text"
+	severityNumberKey = "otel.severity_number"
+	otelTraceIDKey    = "otel.trace_id"
+	otelSpanIDKey     = "otel.span_id"
+	ddTraceIDKey      = "dd.trace_id"
+	ddSpanIDKey       = "dd.span_id"
+)
+
+// exporter translates the OTLP log records into logs agent messages and sends
+// them to a logs agent pipeline, which applies the processing rules and sends
+// them to the intake.
+type exporter struct {
+	logger           *zap.Logger
+	logsAgentChannel chan *message.Message
+	logSource        *sources.LogSource
+}
+
+func newExporter(logger *zap.Logger, logsAgentChannel chan *message.Message) *exporter {
+	return &exporter{
+		logger:           logger,
+		logsAgentChannel: logsAgentChannel,
+		logSource:        sources.NewLogSource(logSourceName, &config.LogsConfig{Source: logSourceName}),
+	}
+}
+
+// ConsumeLogs sends the log records to the logs agent pipeline.
+func (e *exporter) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		origin, hostname := e.originFromResource(rl.Resource())
+		sls := rl.ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			lrs := sls.At(j).LogRecords()
+			for k := 0; k < lrs.Len(); k++ {
+				msg, err := newMessage(lrs.At(k), origin, hostname)
+				if err != nil {
+					e.logger.Debug("Dropping an OTLP log record which can't be converted", zap.Error(err))
+					continue
+				}
+				select {
+				case e.logsAgentChannel <- msg:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// originFromResource returns the origin shared by the log records of a resource,
+// with its service and tags, and the hostname of the resource if any.
+func (e *exporter) originFromResource(res pcommon.Resource) (*message.Origin, string) {
+	attrs := res.Attributes()
+	origin := message.NewOrigin(e.logSource)
+	if service, ok := attrs.Get(conventions.AttributeServiceName); ok {
+		origin.SetService(service.AsString())
+	}
+	origin.SetTags(attributes.TagsFromAttributes(attrs))
+
+	var hostname string
+	if src, ok := attributes.SourceFromAttributes(attrs, false); ok && src.Kind == source.HostnameKind {
+		hostname = src.Identifier
+	}
+	return origin, hostname
+}
+
+// newMessage converts a log record. The content of the message is a JSON object
+// holding the body and the attributes of the record, its severity and its trace
+// and span IDs, both in the OpenTelemetry and the Datadog formats.
+func newMessage(lr plog.LogRecord, origin *message.Origin, hostname string) (*message.Message, error) {
+	content := lr.Attributes().AsRaw()
+	if body := lr.Body(); body.Type() == pcommon.ValueTypeMap {
+		for key, value := range body.MapVal().AsRaw() {
+			content[key] = value
+		}
+	} else {
+		content[messageKey] = body.AsString()
+	}
+
+	if text := lr.SeverityText(); text != "" {
+		content[severityTextKey] = text
+	}
+	if number := lr.SeverityNumber(); number != plog.SeverityNumberUNDEFINED {
+		content[severityNumberKey] = int32(number)
+	}
+	if traceID := lr.TraceID(); !traceID.IsEmpty() {
+		bytes := traceID.Bytes()
+		content[otelTraceIDKey] = traceID.HexString()
+		// Datadog trace IDs are the lower 64 bits of the OpenTelemetry ones
+		content[ddTraceIDKey] = strconv.FormatUint(binary.BigEndian.Uint64(bytes[8:]), 10)
+	}
+	if spanID := lr.SpanID(); !spanID.IsEmpty() {
+		bytes := spanID.Bytes()
+		content[otelSpanIDKey] = spanID.HexString()
+		content[ddSpanIDKey] = strconv.FormatUint(binary.BigEndian.Uint64(bytes[:]), 10)
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := message.NewMessage(data, origin, statusFromSeverity(lr.SeverityNumber(), lr.SeverityText()), time.Now().UnixNano())
+	msg.Hostname = hostname
+	if timestamp := lr.Timestamp(); timestamp != 0 {
+		msg.Timestamp = timestamp.AsTime().UTC()
+	} else if timestamp := lr.ObservedTimestamp(); timestamp != 0 {
+		msg.Timestamp = timestamp.AsTime().UTC()
+	}
+	return msg, nil
+}
+
+// statusFromSeverity returns the status of a log record from its severity number,
+// or from its severity text when the number is not set.
+func statusFromSeverity(number plog.SeverityNumber, text string) string {
+	switch {
+	case number >= plog.SeverityNumberFATAL:
+		return message.StatusCritical
+	case number >= plog.SeverityNumberERROR:
+		return message.StatusError
+	case number >= plog.SeverityNumberWARN:
+		return message.StatusWarning
+	case number >= plog.SeverityNumberINFO:
+		return message.StatusInfo
+	case number >= plog.SeverityNumberTRACE:
+		return message.StatusDebug
+	}
+
+	switch strings.ToLower(text) {
+	case "fatal", "critical", "crit":
+		return message.StatusCritical
+	case "error", "err":
+		return message.StatusError
+	case "warn", "warning":
+		return message.StatusWarning
+	case "debug", "trace":
+		return message.StatusDebug
+	}
+	return message.StatusInfo
+}
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2021-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package logsagentexporter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configtest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestNewFactory(t *testing.T) {
+	factory := NewFactory(make(chan *message.Message))
+	cfg := factory.CreateDefaultConfig()
+	assert.NoError(t, configtest.CheckConfigStruct(cfg))
+
+	set := componenttest.NewNopExporterCreateSettings()
+	exp, err := factory.CreateLogsExporter(context.Background(), set, cfg)
+	assert.NoError(t, err)
+	assert.NotNil(t, exp)
+	_, err = factory.CreateMetricsExporter(context.Background(), set, cfg)
+	assert.Error(t, err)
+}
+
+func testLogs() plog.Logs {
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().InsertString("service.name", "my-service")
+	rl.Resource().Attributes().InsertString("deployment.environment", "prod")
+	rl.Resource().Attributes().InsertString("host.name", "my-host")
+	lrs := rl.ScopeLogs().AppendEmpty().LogRecords()
+
+	lr := lrs.AppendEmpty()
+	lr.Body().SetStringVal("something failed")
+	lr.SetSeverityNumber(plog.SeverityNumberERROR)
+	lr.SetSeverityText("ERROR")
+	lr.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(1656928800, 0)))
+	lr.SetTraceID(pcommon.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, 42}))
+	lr.SetSpanID(pcommon.NewSpanID([8]byte{0, 0, 0, 0, 0, 0, 1, 0}))
+	lr.Attributes().InsertString("http.method", "GET")
+
+	lr = lrs.AppendEmpty()
+	body := pcommon.NewValueMap()
+	body.MapVal().InsertString("event", "login")
+	body.CopyTo(lr.Body())
+	lr.SetSeverityText("warning")
+	return ld
+}
+
+func TestConsumeLogs(t *testing.T) {
+	logsAgentChannel := make(chan *message.Message, 10)
+	exp := newExporter(zap.NewNop(), logsAgentChannel)
+	require.NoError(t, exp.ConsumeLogs(context.Background(), testLogs()))
+	require.Len(t, logsAgentChannel, 2)
+
+	msg := <-logsAgentChannel
+	assert.JSONEq(t, `{
+		"message": "something failed",
+		"http.method": "GET",
+		"otel.severity_text": "ERROR",
+		"otel.severity_number": 17,
+		"otel.trace_id": "0102030405060708000000000000002a",
+		"otel.span_id": "0000000000000100",
+		"dd.trace_id": "42",
+		"dd.span_id": "256"
+	}`, string(msg.Content))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "my-host", msg.GetHostname())
+	assert.Equal(t, time.Unix(1656928800, 0).UTC(), msg.Timestamp)
+	assert.Equal(t, "my-service", msg.Origin.Service())
+	assert.Equal(t, logSourceName, msg.Origin.Source())
+	assert.ElementsMatch(t, []string{"service:my-service", "env:prod"}, msg.Origin.Tags())
+
+	msg = <-logsAgentChannel
+	assert.JSONEq(t, `{"event": "login", "otel.severity_text": "warning"}`, string(msg.Content))
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.True(t, msg.Timestamp.IsZero())
+}
+
+func TestConsumeLogsCanceled(t *testing.T) {
+	exp := newExporter(zap.NewNop(), make(chan *message.Message))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, exp.ConsumeLogs(ctx, testLogs()), context.Canceled)
+}
+
+func TestStatusFromSeverity(t *testing.T) {
+	tests := []struct {
+		number plog.SeverityNumber
+		text   string
+		status string
+	}{
+		{plog.SeverityNumberTRACE2, "", message.StatusDebug},
+		{plog.SeverityNumberDEBUG, "", message.StatusDebug},
+		{plog.SeverityNumberINFO4, "", message.StatusInfo},
+		{plog.SeverityNumberWARN, "ERROR", message.StatusWarning},
+		{plog.SeverityNumberERROR3, "", message.StatusError},
+		{plog.SeverityNumberFATAL, "", message.StatusCritical},
+		{plog.SeverityNumberUNDEFINED, "Fatal", message.StatusCritical},
+		{plog.SeverityNumberUNDEFINED, "err", message.StatusError},
+		{plog.SeverityNumberUNDEFINED, "TRACE", message.StatusDebug},
+		{plog.SeverityNumberUNDEFINED, "unknown", message.StatusInfo},
+		{plog.SeverityNumberUNDEFINED, "", message.StatusInfo},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.status, statusFromSeverity(test.number, test.text), "%v %q", test.number, test.text)
+	}
+}
@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2021-present Datadog, Inc.
+
+package logsagentexporter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const (
+	// TypeStr defines the logs agent exporter type string.
+	TypeStr = "logsagent"
+)
+
+var _ config.Exporter = (*exporterConfig)(nil)
+
+// exporterConfig defines configuration for the logs agent exporter.
+type exporterConfig struct {
+	// squash ensures fields are correctly decoded in embedded struct
+	config.ExporterSettings        `mapstructure:",squash"`
+	exporterhelper.TimeoutSettings `mapstructure:",squash"`
+	exporterhelper.QueueSettings   `mapstructure:",squash"`
+}
+
+// Validate configuration
+func (e *exporterConfig) Validate() error {
+	return e.QueueSettings.Validate()
+}
+
+func newDefaultConfig() config.Exporter {
+	return &exporterConfig{
+		ExporterSettings: config.NewExporterSettings(config.NewComponentID(TypeStr)),
+		// Disable timeout; the ConsumeLogs call only pushes the messages to the logs agent pipeline.
+		TimeoutSettings: exporterhelper.TimeoutSettings{Timeout: 0},
+		QueueSettings:   exporterhelper.NewDefaultQueueSettings(),
+	}
+}
+
+type factory struct {
+	logsAgentChannel chan *message.Message
+}
+
+// NewFactory creates a new logs agent exporter factory. The exporter sends
+// the log records to logsAgentChannel, the input of a logs agent pipeline.
+func NewFactory(logsAgentChannel chan *message.Message) component.ExporterFactory {
+	f := &factory{logsAgentChannel}
+
+	return component.NewExporterFactory(
+		TypeStr,
+		newDefaultConfig,
+		component.WithLogsExporter(f.createLogsExporter),
+	)
+}
+
+func (f *factory) createLogsExporter(_ context.Context, params component.ExporterCreateSettings, c config.Exporter) (component.LogsExporter, error) {
+	cfg := c.(*exporterConfig)
+
+	exp := newExporter(params.Logger, f.logsAgentChannel)
+	return exporterhelper.NewLogsExporter(cfg, params, exp.ConsumeLogs,
+		exporterhelper.WithQueue(cfg.QueueSettings),
+		exporterhelper.WithTimeout(cfg.TimeoutSettings),
+	)
+}
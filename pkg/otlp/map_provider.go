@@ -98,6 +98,34 @@ func buildMetricsMap(cfg PipelineConfig) (*confmap.Conf, error) {
 	return baseMap, err
 }
 
+// defaultLogsConfig is the logs OTLP pipeline configuration. Its batch processor
+// is distinct from the metrics one to flush the logs more often.
+const defaultLogsConfig string = `
+receivers:
+  otlp:
+
+processors:
+  batch/logs:
+    timeout: 5s
+
+exporters:
+  logsagent:
+
+service:
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    logs:
+      receivers: [otlp]
+      processors: [batch/logs]
+      exporters: [logsagent]
+`
+
+func buildLogsMap() (*confmap.Conf, error) {
+	return configutils.NewMapFromYAMLString(defaultLogsConfig)
+}
+
 func buildReceiverMap(otlpReceiverConfig map[string]interface{}) *confmap.Conf {
 	return confmap.NewFromStringMap(map[string]interface{}{
 		"receivers": map[string]interface{}{"otlp": otlpReceiverConfig},
@@ -121,6 +149,13 @@ func buildMap(cfg PipelineConfig) (*confmap.Conf, error) {
 		err = retMap.Merge(metricsMap)
 		errs = append(errs, err)
 	}
+	if cfg.LogsEnabled {
+		logsMap, err := buildLogsMap()
+		errs = append(errs, err)
+
+		err = retMap.Merge(logsMap)
+		errs = append(errs, err)
+	}
 	if cfg.DebugLogEnabled() {
 		m := map[string]interface{}{
 			"exporters": map[string]interface{}{
@@ -145,6 +180,14 @@ func buildMap(cfg PipelineConfig) (*confmap.Conf, error) {
 				m[key] = []interface{}{"logging"}
 			}
 		}
+		if cfg.LogsEnabled {
+			key := buildKey("service", "pipelines", "logs", "exporters")
+			if v, ok := retMap.Get(key).([]interface{}); ok {
+				m[key] = append(v, "logging")
+			} else {
+				m[key] = []interface{}{"logging"}
+			}
+		}
 		errs = append(errs, retMap.Merge(confmap.NewFromStringMap(m)))
 	}
 
@@ -12,6 +12,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/otlp/internal/testutil"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/stretchr/testify/assert"
@@ -494,6 +495,49 @@ func TestNewMap(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "only gRPC, only logs, logging info",
+			pcfg: PipelineConfig{
+				OTLPReceiverConfig: testutil.OTLPConfigFromPorts("bindhost", 1234, 0),
+				TracePort:          5003,
+				LogsEnabled:        true,
+				Debug: map[string]interface{}{
+					"loglevel": "info",
+				},
+			},
+			ocfg: map[string]interface{}{
+				"receivers": map[string]interface{}{
+					"otlp": map[string]interface{}{
+						"protocols": map[string]interface{}{
+							"grpc": map[string]interface{}{
+								"endpoint": "bindhost:1234",
+							},
+						},
+					},
+				},
+				"processors": map[string]interface{}{
+					"batch/logs": map[string]interface{}{
+						"timeout": "5s",
+					},
+				},
+				"exporters": map[string]interface{}{
+					"logsagent": nil,
+					"logging": map[string]interface{}{
+						"loglevel": "info",
+					},
+				},
+				"service": map[string]interface{}{
+					"telemetry": map[string]interface{}{"metrics": map[string]interface{}{"level": "none"}},
+					"pipelines": map[string]interface{}{
+						"logs": map[string]interface{}{
+							"receivers":  []interface{}{"otlp"},
+							"processors": []interface{}{"batch/logs"},
+							"exporters":  []interface{}{"logsagent", "logging"},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, testInstance := range tests {
@@ -512,6 +556,7 @@ func TestUnmarshal(t *testing.T) {
 		TracePort:          5001,
 		MetricsEnabled:     true,
 		TracesEnabled:      true,
+		LogsEnabled:        true,
 		Metrics: map[string]interface{}{
 			"delta_ttl":                                2000,
 			"resource_attributes_as_tags":              true,
@@ -524,7 +569,7 @@ func TestUnmarshal(t *testing.T) {
 		},
 	})
 	require.NoError(t, err)
-	components, err := getComponents(&serializer.MockSerializer{})
+	components, err := getComponents(&serializer.MockSerializer{}, make(chan *message.Message))
 	require.NoError(t, err)
 
 	_, err = provider.Get(context.Background(), components)
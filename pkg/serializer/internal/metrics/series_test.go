@@ -22,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -305,7 +306,7 @@ func makeSeries(numItems, numPoints int) *IterableSeries {
 func TestMarshalSplitCompress(t *testing.T) {
 	series := makeSeries(10000, 50)
 
-	payloads, err := series.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := series.MarshalSplitCompress(marshaler.DefaultBufferContext(), compression.DefaultCompressor())
 	require.NoError(t, err)
 	// check that we got multiple payloads, so splitting occurred
 	require.Greater(t, len(payloads), 1)
@@ -326,7 +327,7 @@ func TestMarshalSplitCompressPointsLimit(t *testing.T) {
 	// ten series, each with 50 points, so two should fit in each payload
 	series := makeSeries(10, 50)
 
-	payloads, err := series.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := series.MarshalSplitCompress(marshaler.DefaultBufferContext(), compression.DefaultCompressor())
 	require.NoError(t, err)
 	require.Equal(t, 5, len(payloads))
 }
@@ -338,7 +339,7 @@ func TestMarshalSplitCompressPointsLimitTooBig(t *testing.T) {
 	mockConfig.Set("serializer_max_series_points_per_payload", 1)
 
 	series := makeSeries(1, 2)
-	payloads, err := series.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := series.MarshalSplitCompress(marshaler.DefaultBufferContext(), compression.DefaultCompressor())
 	require.NoError(t, err)
 	require.Len(t, payloads, 0)
 }
@@ -373,7 +374,7 @@ func TestPayloadsSeries(t *testing.T) {
 	originalLength := len(testSeries)
 	builder := stream.NewJSONPayloadBuilder(true)
 	iterableSeries := &IterableSeries{SerieSource: CreateSerieSource(testSeries)}
-	payloads, err := builder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig)
+	payloads, err := builder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig, compression.DefaultCompressor())
 	require.Nil(t, err)
 	var splitSeries = []Series{}
 	for _, compressedPayload := range payloads {
@@ -421,7 +422,7 @@ func BenchmarkPayloadsSeries(b *testing.B) {
 		// always record the result of Payloads to prevent
 		// the compiler eliminating the function call.
 		iterableSeries := &IterableSeries{SerieSource: CreateSerieSource(testSeries)}
-		r, _ = builder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig)
+		r, _ = builder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig, compression.DefaultCompressor())
 	}
 	// ensure we actually had to split
 	if len(r) != 13 {
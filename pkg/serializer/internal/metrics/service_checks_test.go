@@ -24,6 +24,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 func TestMarshalJSONServiceChecks(t *testing.T) {
@@ -78,7 +79,7 @@ func createServiceCheck(checkName string) *metrics.ServiceCheck {
 
 func buildPayload(t *testing.T, m marshaler.StreamJSONMarshaler) [][]byte {
 	builder := stream.NewJSONPayloadBuilder(true)
-	payloads, err := builder.Build(m)
+	payloads, err := builder.Build(m, compression.DefaultCompressor())
 	assert.NoError(t, err)
 	var uncompressedPayloads [][]byte
 
@@ -175,7 +176,7 @@ func benchmarkJSONPayloadBuilderServiceCheck(b *testing.B, numberOfItem int) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		payloadBuilder.Build(serviceChecks)
+		payloadBuilder.Build(serviceChecks, compression.DefaultCompressor())
 	}
 }
 
@@ -210,7 +211,7 @@ func benchmarkPayloadsServiceCheck(b *testing.B, numberOfItem int) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		split.Payloads(serviceChecks, true, split.JSONMarshalFct)
+		split.Payloads(serviceChecks, true, split.JSONMarshalFct, compression.DefaultCompressor())
 	}
 }
 
@@ -19,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -87,7 +88,7 @@ func TestSketchSeriesMarshalSplitCompressEmpty(t *testing.T) {
 
 	sl := SketchSeriesList{SketchesSource: metrics.NewSketchesSourceTest()}
 	payload, _ := sl.Marshal()
-	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext(), compression.DefaultCompressor())
 
 	assert.Nil(t, err)
 
@@ -119,7 +120,7 @@ func TestSketchSeriesMarshalSplitCompressItemTooBigIsDropped(t *testing.T) {
 	})
 
 	serializer := SketchSeriesList{SketchesSource: sl}
-	payloads, err := serializer.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := serializer.MarshalSplitCompress(marshaler.DefaultBufferContext(), compression.DefaultCompressor())
 
 	assert.Nil(t, err)
 
@@ -148,7 +149,7 @@ func TestSketchSeriesMarshalSplitCompress(t *testing.T) {
 	payload, _ := serializer1.Marshal()
 	sl.Reset()
 	serializer2 := SketchSeriesList{SketchesSource: sl}
-	payloads, err := serializer2.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := serializer2.MarshalSplitCompress(marshaler.DefaultBufferContext(), compression.DefaultCompressor())
 	require.NoError(t, err)
 
 	reader := bytes.NewReader(*payloads[0])
@@ -194,7 +195,7 @@ func TestSketchSeriesMarshalSplitCompressSplit(t *testing.T) {
 	}
 
 	serializer := SketchSeriesList{SketchesSource: sl}
-	payloads, err := serializer.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := serializer.MarshalSplitCompress(marshaler.DefaultBufferContext(), compression.DefaultCompressor())
 	assert.Nil(t, err)
 
 	recoveredSketches := []gogen.SketchPayload{}
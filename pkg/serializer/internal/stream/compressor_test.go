@@ -18,6 +18,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 var (
@@ -56,7 +57,7 @@ func TestCompressorSimple(t *testing.T) {
 	c, err := NewCompressor(
 		&bytes.Buffer{}, &bytes.Buffer{},
 		maxPayloadSize, maxUncompressedSize,
-		[]byte("{["), []byte("]}"), []byte(","))
+		[]byte("{["), []byte("]}"), []byte(","), compression.DefaultCompressor())
 	require.NoError(t, err)
 
 	for i := 0; i < 5; i++ {
@@ -76,7 +77,7 @@ func TestOnePayloadSimple(t *testing.T) {
 	}
 
 	builder := NewJSONPayloadBuilder(true)
-	payloads, err := builder.Build(m)
+	payloads, err := builder.Build(m, compression.DefaultCompressor())
 	require.NoError(t, err)
 	require.Len(t, payloads, 1)
 
@@ -93,7 +94,7 @@ func TestMaxCompressedSizePayload(t *testing.T) {
 	defer resetDefaults()
 
 	builder := NewJSONPayloadBuilder(true)
-	payloads, err := builder.Build(m)
+	payloads, err := builder.Build(m, compression.DefaultCompressor())
 	require.NoError(t, err)
 	require.Len(t, payloads, 1)
 
@@ -110,7 +111,7 @@ func TestTwoPayload(t *testing.T) {
 	defer resetDefaults()
 
 	builder := NewJSONPayloadBuilder(true)
-	payloads, err := builder.Build(m)
+	payloads, err := builder.Build(m, compression.DefaultCompressor())
 	require.NoError(t, err)
 	require.Len(t, payloads, 2)
 
@@ -128,10 +129,30 @@ func TestLockedCompressorProducesSamePayloads(t *testing.T) {
 
 	builderLocked := NewJSONPayloadBuilder(true)
 	builderUnLocked := NewJSONPayloadBuilder(false)
-	payloads1, err := builderLocked.Build(m)
+	payloads1, err := builderLocked.Build(m, compression.DefaultCompressor())
 	require.NoError(t, err)
-	payloads2, err := builderUnLocked.Build(m)
+	payloads2, err := builderUnLocked.Build(m, compression.DefaultCompressor())
 	require.NoError(t, err)
 
 	require.Equal(t, payloadToString(*payloads1[0]), payloadToString(*payloads2[0]))
 }
+
+func TestOnePayloadZstd(t *testing.T) {
+	m := &marshaler.DummyMarshaller{
+		Items:  []string{"A", "B", "C"},
+		Header: "{[",
+		Footer: "]}",
+	}
+
+	strategy, err := compression.NewCompressor(compression.ZstdKind, 3)
+	require.NoError(t, err)
+
+	builder := NewJSONPayloadBuilder(true)
+	payloads, err := builder.Build(m, strategy)
+	require.NoError(t, err)
+	require.Len(t, payloads, 1)
+
+	p, err := strategy.Decompress(*payloads[0])
+	require.NoError(t, err)
+	require.Equal(t, "{[A,B,C]}", string(p))
+}
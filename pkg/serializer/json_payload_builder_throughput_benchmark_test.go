@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 func benchmarkJSONPayloadBuilderThroughput(points int, items int, tags int, runs int) { //nolint:unuse
@@ -27,7 +28,7 @@ func benchmarkJSONPayloadBuilderThroughput(points int, items int, tags int, runs
 
 	for i := 0; i < runs; i++ {
 		start := time.Now()
-		payloadBuilder.Build(series)
+		payloadBuilder.Build(series, compression.DefaultCompressor())
 		totalTime += time.Since(start)
 	}
 	avgTime := int64(totalTime) / int64(runs)
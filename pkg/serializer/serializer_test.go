@@ -28,17 +28,12 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
-var initialContentEncoding = compression.ContentEncoding
-
-func resetContentEncoding() {
-	compression.ContentEncoding = initialContentEncoding
-	initExtraHeaders()
-}
-
-func TestInitExtraHeadersNoopCompression(t *testing.T) {
-	compression.ContentEncoding = ""
-	defer resetContentEncoding()
+var (
+	jsonExtraHeadersWithCompression     http.Header
+	protobufExtraHeadersWithCompression http.Header
+)
 
+func TestInitExtraHeaders(t *testing.T) {
 	initExtraHeaders()
 
 	expected := make(http.Header)
@@ -49,44 +44,41 @@ func TestInitExtraHeadersNoopCompression(t *testing.T) {
 	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
 	expected.Set("Content-Type", protobufContentType)
 	assert.Equal(t, expected, protobufExtraHeaders)
+}
+
+func TestExtraHeadersNoopCompression(t *testing.T) {
+	strategy, err := compression.NewCompressor(compression.NoneKind, 0)
+	require.NoError(t, err)
 
 	// No "Content-Encoding" header
-	expected = make(http.Header)
+	expected := make(http.Header)
 	expected.Set("Content-Type", jsonContentType)
-	assert.Equal(t, expected, jsonExtraHeadersWithCompression)
+	assert.Equal(t, expected, extraHeadersWithCompression(jsonExtraHeaders, strategy))
 
 	expected = make(http.Header)
 	expected.Set("Content-Type", protobufContentType)
 	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
-	assert.Equal(t, expected, protobufExtraHeadersWithCompression)
+	assert.Equal(t, expected, extraHeadersWithCompression(protobufExtraHeaders, strategy))
 }
 
-func TestInitExtraHeadersWithCompression(t *testing.T) {
-	compression.ContentEncoding = "zstd"
-	defer resetContentEncoding()
-
-	initExtraHeaders()
+func TestExtraHeadersWithCompression(t *testing.T) {
+	strategy, err := compression.NewCompressor(compression.ZstdKind, 1)
+	require.NoError(t, err)
 
+	// "Content-Encoding" header present with correct value
 	expected := make(http.Header)
 	expected.Set("Content-Type", jsonContentType)
-	assert.Equal(t, expected, jsonExtraHeaders)
+	expected.Set("Content-Encoding", "zstd")
+	assert.Equal(t, expected, extraHeadersWithCompression(jsonExtraHeaders, strategy))
 
 	expected = make(http.Header)
 	expected.Set("Content-Type", protobufContentType)
+	expected.Set("Content-Encoding", "zstd")
 	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
-	assert.Equal(t, expected, protobufExtraHeaders)
-
-	// "Content-Encoding" header present with correct value
-	expected = make(http.Header)
-	expected.Set("Content-Type", jsonContentType)
-	expected.Set("Content-Encoding", compression.ContentEncoding)
-	assert.Equal(t, expected, jsonExtraHeadersWithCompression)
+	assert.Equal(t, expected, extraHeadersWithCompression(protobufExtraHeaders, strategy))
 
-	expected = make(http.Header)
-	expected.Set("Content-Type", protobufContentType)
-	expected.Set("Content-Encoding", compression.ContentEncoding)
-	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
-	assert.Equal(t, expected, protobufExtraHeadersWithCompression)
+	// the base headers are left untouched
+	assert.Empty(t, jsonExtraHeaders.Get("Content-Encoding"))
 }
 
 func TestAgentPayloadVersion(t *testing.T) {
@@ -104,6 +96,8 @@ var (
 )
 
 func init() {
+	jsonExtraHeadersWithCompression = extraHeadersWithCompression(jsonExtraHeaders, compression.DefaultCompressor())
+	protobufExtraHeadersWithCompression = extraHeadersWithCompression(protobufExtraHeaders, compression.DefaultCompressor())
 	jsonPayloads, _ = mkPayloads(jsonString, true)
 	protobufPayloads, _ = mkPayloads(protobufString, true)
 }
@@ -112,9 +106,9 @@ type testPayload struct{}
 
 func (p *testPayload) MarshalJSON() ([]byte, error) { return jsonString, nil }
 func (p *testPayload) Marshal() ([]byte, error)     { return protobufString, nil }
-func (p *testPayload) MarshalSplitCompress(bufferContext *marshaler.BufferContext) ([]*[]byte, error) {
+func (p *testPayload) MarshalSplitCompress(bufferContext *marshaler.BufferContext, strategy compression.Compressor) ([]*[]byte, error) {
 	payloads := forwarder.Payloads{}
-	payload, err := compression.Compress(protobufString)
+	payload, err := strategy.Compress(protobufString)
 	if err != nil {
 		return nil, err
 	}
@@ -296,6 +290,37 @@ func TestSendSeries(t *testing.T) {
 	f.AssertExpectations(t)
 }
 
+func TestSendSeriesWithPerEndpointCompression(t *testing.T) {
+	config.Datadog.Set("use_v2_api.series", true)
+	defer config.Datadog.Set("use_v2_api.series", false)
+	config.Datadog.Set("serializer_compressor_kind_per_endpoint", map[string]string{"series_v2": "zstd"})
+	defer config.Datadog.Set("serializer_compressor_kind_per_endpoint", map[string]string{})
+
+	zstdStrategy, err := compression.NewCompressor(compression.ZstdKind, 1)
+	require.NoError(t, err)
+
+	f := &forwarder.MockedForwarder{}
+	matcher := mock.MatchedBy(func(payloads forwarder.Payloads) bool {
+		for _, compressedPayload := range payloads {
+			payload, err := zstdStrategy.Decompress(*compressedPayload)
+			if err != nil {
+				return false
+			}
+			if reflect.DeepEqual([]byte{0xa, 0xa, 0xa, 0x6, 0xa, 0x4, 0x68, 0x6f, 0x73, 0x74, 0x28, 0x3}, payload) {
+				return true
+			}
+		}
+		return false
+	})
+	f.On("SubmitSeries", matcher, extraHeadersWithCompression(protobufExtraHeaders, zstdStrategy)).Return(nil).Times(1)
+
+	s := NewSerializer(f, nil, nil)
+
+	err = s.SendIterableSeries(metricsserializer.CreateSerieSource(metrics.Series{&metrics.Serie{}}))
+	require.Nil(t, err)
+	f.AssertExpectations(t)
+}
+
 func TestSendSketch(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
 
@@ -18,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/stretchr/testify/require"
 )
 
@@ -76,13 +77,13 @@ func BenchmarkSeries(b *testing.B) {
 	bufferContext := marshaler.DefaultBufferContext()
 	pb := func(series metrics.Series) (forwarder.Payloads, error) {
 		iterableSeries := &metricsserializer.IterableSeries{SerieSource: metricsserializer.CreateSerieSource(series)}
-		return iterableSeries.MarshalSplitCompress(bufferContext)
+		return iterableSeries.MarshalSplitCompress(bufferContext, compression.DefaultCompressor())
 	}
 
 	payloadBuilder := stream.NewJSONPayloadBuilder(true)
 	json := func(series metrics.Series) (forwarder.Payloads, error) {
 		iterableSeries := &metricsserializer.IterableSeries{SerieSource: metricsserializer.CreateSerieSource(series)}
-		return payloadBuilder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig)
+		return payloadBuilder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig, compression.DefaultCompressor())
 	}
 
 	for _, items := range []int{5, 10, 100, 500, 1000, 10000, 100000} {
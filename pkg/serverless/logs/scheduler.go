@@ -6,9 +6,11 @@
 package logs
 
 import (
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/logs"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/schedulers/channel"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -17,8 +19,13 @@ import (
 var logsScheduler *channel.Scheduler
 
 // SetupLogAgent sets up the logs agent to handle messages on the given channel.
-func SetupLogAgent(logChannel chan *config.ChannelMessage) {
-	agent, err := logs.StartServerless()
+// The metrics generated from the logs are submitted to the demultiplexer, if any.
+func SetupLogAgent(logChannel chan *config.ChannelMessage, demux aggregator.Demultiplexer) {
+	var metricSink metrics.MetricSampleSink
+	if demux != nil {
+		metricSink = aggregator.NewTimeSamplerSink(demux)
+	}
+	agent, err := logs.StartServerless(metricSink)
 	if err != nil {
 		log.Error("Could not start an instance of the Logs Agent:", err)
 		return
@@ -41,10 +41,12 @@ type Agent struct {
 	ClientStatsAggregator *stats.ClientStatsAggregator
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	RuleFilter            *filters.RuleFilter
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
+	TailSampler           *sampler.TailSampler // nil when tail sampling is disabled
 	EventProcessor        *event.Processor
 	TraceWriter           *writer.TraceWriter
 	StatsWriter           *writer.StatsWriter
@@ -86,6 +88,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsChan),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		RuleFilter:            filters.NewRuleFilter(conf.FilterRules),
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf),
 		RareSampler:           sampler.NewRareSampler(conf),
@@ -99,6 +102,9 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		conf:                  conf,
 		ctx:                   ctx,
 	}
+	if conf.TailSampling.Enabled {
+		agnt.TailSampler = sampler.NewTailSampler(conf, agnt.flushTailChunks)
+	}
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf)
 	return agnt
@@ -110,6 +116,7 @@ func (a *Agent) Run() {
 		a.Receiver,
 		a.Concentrator,
 		a.ClientStatsAggregator,
+		a.RuleFilter,
 		a.PrioritySampler,
 		a.ErrorsSampler,
 		a.NoPrioritySampler,
@@ -118,6 +125,9 @@ func (a *Agent) Run() {
 	} {
 		starter.Start()
 	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+	}
 
 	go a.TraceWriter.Run()
 	go a.StatsWriter.Run()
@@ -141,6 +151,10 @@ func (a *Agent) FlushSync() {
 		log.Errorf("Error flushing stats: %s", err.Error())
 		return
 	}
+	if a.TailSampler != nil {
+		// the traces buffered by the TailSampler are sampled to be flushed by the TraceWriter
+		a.TailSampler.Flush()
+	}
 	if err := a.TraceWriter.FlushSync(); err != nil {
 		log.Errorf("Error flushing traces: %s", err.Error())
 		return
@@ -168,17 +182,23 @@ func (a *Agent) loop() {
 			if err := a.Receiver.Stop(); err != nil {
 				log.Error(err)
 			}
+			a.OTLPReceiver.Stop()
+			if a.TailSampler != nil {
+				// flush the buffered traces before stopping the TraceWriter; the traces still
+				// being processed are sampled as soon as they are added
+				a.TailSampler.Stop()
+			}
 			for _, stopper := range []interface{ Stop() }{
 				a.Concentrator,
 				a.ClientStatsAggregator,
 				a.TraceWriter,
 				a.StatsWriter,
+				a.RuleFilter,
 				a.PrioritySampler,
 				a.ErrorsSampler,
 				a.NoPrioritySampler,
 				a.RareSampler,
 				a.EventProcessor,
-				a.OTLPReceiver,
 				a.obfuscator,
 				a.obfuscator,
 				a.cardObfuscator,
@@ -201,6 +221,7 @@ func (a *Agent) Process(p *api.Payload) {
 	defer timing.Since("datadog.trace_agent.internal.process_payload_ms", now)
 	ts := p.Source
 	ss := new(writer.SampledChunks)
+	var tailChunks []*sampler.TailChunk
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats, a.conf)
 
 	p.TracerPayload.Env = traceutil.NormalizeTag(p.TracerPayload.Env)
@@ -244,6 +265,15 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
+		action := a.RuleFilter.Match(chunk, root)
+		if action == filters.ActionDrop {
+			log.Debugf("Trace rejected by filter rules. root: %v", root)
+			ts.TracesFiltered.Inc()
+			ts.SpansFiltered.Add(tracen)
+			p.RemoveChunk(i)
+			continue
+		}
+
 		// Extra sanitization steps of the trace.
 		for _, span := range chunk.Spans {
 			for k, v := range a.conf.GlobalTags {
@@ -303,7 +333,17 @@ func (a *Agent) Process(p *api.Payload) {
 			statsInput.Traces = append(statsInput.Traces, pt)
 		}
 
-		numEvents, keep, filteredChunk := a.sample(now, ts, pt)
+		numEvents, keep, filteredChunk := a.sample(now, ts, pt, action == filters.ActionKeep)
+		if a.TailSampler != nil && filteredChunk != nil {
+			// the chunk is held until its complete trace is sampled by the TailSampler
+			tc := &sampler.TailChunk{Chunk: chunk, Root: root, Sampled: filteredChunk, NumEvents: numEvents}
+			if !keep && numEvents == 0 {
+				tc.Sampled = nil
+			}
+			tailChunks = append(tailChunks, tc)
+			p.RemoveChunk(i)
+			continue
+		}
 		if !keep {
 			if numEvents == 0 {
 				// the trace was dropped and no analyzed span were kept
@@ -340,6 +380,56 @@ func (a *Agent) Process(p *api.Payload) {
 	if len(statsInput.Traces) > 0 {
 		a.Concentrator.In <- statsInput
 	}
+	if len(tailChunks) > 0 {
+		header := new(pb.TracerPayload)
+		*header = *p.TracerPayload
+		header.Chunks = nil
+		for _, tc := range tailChunks {
+			tc.Payload = header
+		}
+		a.TailSampler.Add(now, tailChunks)
+	}
+}
+
+// flushTailChunks sends the chunks sampled by the TailSampler to the TraceWriter, in payloads
+// grouping the chunks received together.
+func (a *Agent) flushTailChunks(chunks []*sampler.TailChunk) {
+	byPayload := make(map[*pb.TracerPayload]*writer.SampledChunks)
+	var order []*pb.TracerPayload
+	send := func(header *pb.TracerPayload) {
+		ss := byPayload[header]
+		delete(byPayload, header)
+		if ss.Size > 0 {
+			a.TraceWriter.In <- ss
+		}
+	}
+	for _, tc := range chunks {
+		if tc.Sampled == nil {
+			continue
+		}
+		ss, ok := byPayload[tc.Payload]
+		if !ok {
+			ss = &writer.SampledChunks{TracerPayload: new(pb.TracerPayload)}
+			*ss.TracerPayload = *tc.Payload
+			byPayload[tc.Payload] = ss
+			order = append(order, tc.Payload)
+		}
+		ss.TracerPayload.Chunks = append(ss.TracerPayload.Chunks, tc.Sampled)
+		if !tc.Sampled.DroppedTrace {
+			ss.SpanCount += int64(len(tc.Sampled.Spans))
+		}
+		ss.EventCount += tc.NumEvents
+		ss.Size += tc.Sampled.Msgsize()
+		if ss.Size > writer.MaxPayloadSize {
+			// payload size is getting big; flush what we have so far
+			send(tc.Payload)
+		}
+	}
+	for _, header := range order {
+		if _, ok := byPayload[header]; ok {
+			send(header)
+		}
+	}
 }
 
 // newChunksArray creates a new array which will point only to sampled chunks.
@@ -431,7 +521,9 @@ func (a *Agent) ProcessStats(in pb.ClientStatsPayload, lang, tracerVersion strin
 }
 
 // sample reports the number of events found in pt and whether the chunk should be kept as a trace.
-func (a *Agent) sample(now time.Time, ts *info.TagStats, pt traceutil.ProcessedTrace) (numEvents int64, keep bool, filteredChunk *pb.TraceChunk) {
+// The chunk is kept when forceKeep is set, unless its priority is negative: a trace dropped by the
+// user in the tracer is never revived by a keep rule.
+func (a *Agent) sample(now time.Time, ts *info.TagStats, pt traceutil.ProcessedTrace, forceKeep bool) (numEvents int64, keep bool, filteredChunk *pb.TraceChunk) {
 	priority, hasPriority := sampler.GetSamplingPriority(pt.TraceChunk)
 
 	if hasPriority {
@@ -444,7 +536,8 @@ func (a *Agent) sample(now time.Time, ts *info.TagStats, pt traceutil.ProcessedT
 		return 0, false, nil
 	}
 
-	sampled := a.runSamplers(now, pt, hasPriority)
+	// the samplers are run even when the chunk is kept by force, to account for it
+	sampled := a.runSamplers(now, pt, hasPriority) || forceKeep
 
 	filteredChunk = pt.TraceChunk
 	if !sampled {
@@ -393,6 +393,76 @@ func TestProcess(t *testing.T) {
 		// without missing a trace
 		assert.Equal(t, gotCount, 3)
 	})
+
+	t.Run("filter_rules", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.FilterRules = []*config.FilterRule{
+			{Name: "health", Action: config.FilterRuleDrop, Tags: []string{"http.url:.*/health"}},
+			{Name: "gold", Action: config.FilterRuleKeep, Tags: []string{"customer_tier:gold"}, AnySpan: true},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		health := testutil.TraceChunkWithSpanAndPriority(&pb.Span{TraceID: 1, SpanID: 1, Service: "a", Meta: map[string]string{"http.url": "http://localhost/health"}}, 2)
+		gold := testutil.TraceChunkWithSpansAndPriority([]*pb.Span{
+			{TraceID: 2, SpanID: 2, Service: "a"},
+			{TraceID: 2, SpanID: 3, ParentID: 2, Service: "a", Meta: map[string]string{"customer_tier": "gold"}},
+		}, 0)
+		other := testutil.TraceChunkWithSpanAndPriority(&pb.Span{TraceID: 3, SpanID: 4, Service: "a"}, 2)
+		// the traces dropped by the user are not kept by the keep rules
+		userDropped := testutil.TraceChunkWithSpanAndPriority(&pb.Span{TraceID: 4, SpanID: 5, Service: "a", Meta: map[string]string{"customer_tier": "gold"}}, -1)
+		ts := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunks([]*pb.TraceChunk{health, gold, other, userDropped}),
+			Source:        ts,
+		})
+
+		require.Len(t, agnt.TraceWriter.In, 1)
+		ss := <-agnt.TraceWriter.In
+		assert.ElementsMatch(t, []*pb.TraceChunk{gold, other}, ss.TracerPayload.Chunks)
+		assert.False(t, gold.DroppedTrace)
+		assert.EqualValues(t, 1, ts.TracesFiltered.Load())
+		assert.EqualValues(t, 1, ts.SpansFiltered.Load())
+	})
+
+	t.Run("tail_sampling", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.RareSamplerDisabled = true
+		cfg.TailSampling.Enabled = true
+		cfg.TailSampling.Policies = []config.TailSamplingPolicy{{Name: "vip", Type: config.TailSamplingTag, Key: "vip"}}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+		agnt.TailSampler.Start()
+
+		// the chunks of the trace 1 are dropped by the head samplers, but the trace is kept by
+		// the tail sampler as one of its spans matches the policy
+		parent := testutil.TraceChunkWithSpanAndPriority(&pb.Span{TraceID: 1, SpanID: 1, Service: "a"}, 0)
+		child := testutil.TraceChunkWithSpanAndPriority(&pb.Span{TraceID: 1, SpanID: 2, ParentID: 1, Service: "b", Meta: map[string]string{"vip": "true"}}, 0)
+		other := testutil.TraceChunkWithSpanAndPriority(&pb.Span{TraceID: 2, SpanID: 3, Service: "a"}, 0)
+		for _, chunks := range [][]*pb.TraceChunk{{parent, other}, {child}} {
+			agnt.Process(&api.Payload{
+				TracerPayload: testutil.TracerPayloadWithChunks(chunks),
+				Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+			})
+		}
+		assert.Len(t, agnt.TraceWriter.In, 0)
+
+		agnt.TailSampler.Stop()
+		require.Len(t, agnt.TraceWriter.In, 2)
+		for _, want := range []*pb.TraceChunk{parent, child} {
+			ss := <-agnt.TraceWriter.In
+			require.Len(t, ss.TracerPayload.Chunks, 1)
+			got := ss.TracerPayload.Chunks[0]
+			assert.Equal(t, want.Spans[0].SpanID, got.Spans[0].SpanID)
+			assert.False(t, got.DroppedTrace)
+			assert.Equal(t, "vip", got.Tags["_dd.tail_sampled"])
+			assert.EqualValues(t, 1, ss.SpanCount)
+		}
+	})
 }
 
 func spansToChunk(spans ...*pb.Span) *pb.TraceChunk {
@@ -948,6 +1018,7 @@ func TestPartialSamplingFree(t *testing.T) {
 		Concentrator:      stats.NewConcentrator(cfg, statsChan, time.Now()),
 		Blacklister:       filters.NewBlacklister(cfg.Ignore["resource"]),
 		Replacer:          filters.NewReplacer(cfg.ReplaceTags),
+		RuleFilter:        filters.NewRuleFilter(cfg.FilterRules),
 		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
 		ErrorsSampler:     sampler.NewErrorsSampler(cfg),
 		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
@@ -1546,7 +1617,7 @@ func TestSampleWithPriorityNone(t *testing.T) {
 	numEvents, keep, _ := agnt.sample(time.Now(), info.NewReceiverStats().GetTagStats(info.Tags{}), traceutil.ProcessedTrace{
 		TraceChunk: testutil.TraceChunkWithSpan(span),
 		Root:       span,
-	})
+	}, false)
 	assert.True(t, keep) // Score Sampler should keep the trace.
 	assert.EqualValues(t, numEvents, 0)
 }
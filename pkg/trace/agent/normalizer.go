@@ -139,6 +139,13 @@ func normalize(ts *info.TagStats, s *pb.Span) error {
 			delete(s.Meta, "http.status_code")
 		}
 	}
+	if tid, ok := s.Meta[traceutil.TraceIDHighKey]; ok {
+		if _, valid := traceutil.GetTraceIDHigh(s); !valid {
+			ts.SpansMalformed.InvalidTraceIDHigh.Inc()
+			log.Debugf("Fixing malformed trace. Trace ID upper bits are invalid (reason:invalid_trace_id_high), dropping invalid %s=%s: %s", traceutil.TraceIDHighKey, tid, s)
+			delete(s.Meta, traceutil.TraceIDHighKey)
+		}
+	}
 	return nil
 }
 
@@ -191,6 +191,20 @@ func TestNormalizeNoTraceID(t *testing.T) {
 	assert.Equal(t, tsDropped(&info.TracesDropped{TraceIDZero: *atomic.NewInt64(1)}), ts)
 }
 
+func TestNormalizeTraceIDHigh(t *testing.T) {
+	ts := newTagStats()
+	s := newTestSpan()
+	s.Meta[traceutil.TraceIDHighKey] = "5af7183fb1d4cf5f"
+	assert.NoError(t, normalize(ts, s))
+	assert.Equal(t, "5af7183fb1d4cf5f", s.Meta[traceutil.TraceIDHighKey])
+	assert.Equal(t, newTagStats(), ts)
+
+	s.Meta[traceutil.TraceIDHighKey] = "not-hexadecimal!"
+	assert.NoError(t, normalize(ts, s))
+	assert.NotContains(t, s.Meta, traceutil.TraceIDHighKey)
+	assert.Equal(t, tsMalformed(&info.SpansMalformed{InvalidTraceIDHigh: *atomic.NewInt64(1)}), ts)
+}
+
 func TestNormalizeComponent2Name(t *testing.T) {
 	ts := newTagStats()
 	assert := assert.New(t)
@@ -7,6 +7,7 @@ package api
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"expvar"
@@ -28,6 +29,7 @@ import (
 	"time"
 
 	"github.com/tinylib/msgp/msgp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 
 	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/appsec"
@@ -70,6 +72,7 @@ type HTTPReceiver struct {
 	server         *http.Server
 	statsProcessor StatsProcessor
 	appsecHandler  http.Handler
+	otlp           *OTLPReceiver // converts the spans translated from other tracing systems
 
 	rateLimiterResponse int // HTTP status code when refusing
 
@@ -96,6 +99,7 @@ func NewHTTPReceiver(conf *config.AgentConfig, dynConf *sampler.DynamicConfig, o
 		conf:           conf,
 		dynConf:        dynConf,
 		appsecHandler:  appsecHandler,
+		otlp:           NewOTLPReceiver(out, conf),
 
 		rateLimiterResponse: rateLimiterResponse,
 
@@ -579,6 +583,11 @@ func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.
 		ClientDroppedP0s:       droppedTracesFromHeader(req.Header, ts),
 	}
 
+	r.send(payload)
+}
+
+// send sends the payload to the output channel, without blocking.
+func (r *HTTPReceiver) send(payload *Payload) {
 	select {
 	case r.out <- payload:
 		// ok
@@ -596,6 +605,50 @@ func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.
 	}
 }
 
+// handleTranslatedTraces handles the traces of another tracing system, which decode translates
+// into OpenTelemetry traces from the request body and its media type. The traces are then converted
+// like the ones received by the OTLP receiver and tagged with the given endpoint version.
+func (r *HTTPReceiver) handleTranslatedTraces(w http.ResponseWriter, req *http.Request, endpointVersion string, decode func(body []byte, mediaType string) (ptrace.Traces, error)) {
+	defer timing.Since("datadog.trace_agent.receiver.serve_"+endpointVersion+"_ms", time.Now())
+	tags := []string{"handler:traces", "v:" + endpointVersion}
+	body := req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gzipr, err := gzip.NewReader(body)
+		if err != nil {
+			httpDecodingError(err, tags, w)
+			return
+		}
+		defer gzipr.Close()
+		body = gzipr
+	}
+	rd := apiutil.NewLimitedReader(body, r.conf.MaxRequestBytes)
+	slurp, err := ioutil.ReadAll(rd)
+	if err == nil {
+		var traces ptrace.Traces
+		if traces, err = decode(slurp, getMediaType(req)); err == nil {
+			r.receiveTranslatedTraces(traces, req.Header, endpointVersion, rd.Count)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+	httpDecodingError(err, tags, w)
+	log.Errorf("Cannot decode %s traces payload: %v", endpointVersion, err)
+}
+
+// receiveTranslatedTraces sends a payload for each resource of the translated traces.
+func (r *HTTPReceiver) receiveTranslatedTraces(traces ptrace.Traces, header http.Header, endpointVersion string, size int64) {
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		payload, _ := r.otlp.convertResourceSpans(traces.ResourceSpans().At(i), header, endpointVersion, r.Stats)
+		ts := payload.Source
+		ts.TracesReceived.Add(int64(len(payload.TracerPayload.Chunks)))
+		if i == 0 {
+			ts.TracesBytes.Add(size)
+		}
+		ts.PayloadAccepted.Inc()
+		r.send(payload)
+	}
+}
+
 // runMetaHook runs the pb.MetaHook on all spans from traces.
 func runMetaHook(chunks []*pb.TraceChunk) {
 	hook, ok := pb.MetaHook()
@@ -94,6 +94,8 @@ func TestInfoHandler(t *testing.T) {
 		AnalyzedSpansByService:      map[string]map[string]float64{"X": {"Y": 2.4}},
 		DDAgentBin:                  "/path/to/core/agent",
 		Obfuscation:                 obfCfg,
+		ZipkinReceiverEnabled:       true,
+		JaegerReceiverEnabled:       true,
 		TelemetryConfig: &config.TelemetryConfig{
 			Enabled: true,
 			Endpoints: []*config.Endpoint{
@@ -123,6 +125,8 @@ func TestInfoHandler(t *testing.T) {
 		"/v0.4/services",
 		"/v0.5/traces",
 		"/v0.7/traces",
+		"/api/v2/spans",
+		"/api/traces",
 		"/profiling/v1/input",
 		"/telemetry/proxy/",
 		"/v0.6/stats",
@@ -183,6 +187,8 @@ func TestInfoHandler(t *testing.T) {
 		"/v0.4/services",
 		"/v0.5/traces",
 		"/v0.7/traces",
+		"/api/v2/spans",
+		"/api/traces",
 		"/profiling/v1/input",
 		"/telemetry/proxy/",
 		"/v0.6/stats",
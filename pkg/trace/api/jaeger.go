@@ -0,0 +1,551 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// jaegerBatch is a batch of Jaeger spans emitted by a process.
+type jaegerBatch struct {
+	process jaegerProcess
+	spans   []jaegerSpan
+}
+
+// jaegerProcess describes the process emitting the spans.
+type jaegerProcess struct {
+	serviceName string
+	tags        []jaegerTag
+}
+
+// jaegerSpan is a Jaeger span, its times are in nanoseconds.
+type jaegerSpan struct {
+	traceIDHigh   uint64
+	traceIDLow    uint64
+	spanID        uint64
+	parentSpanID  uint64
+	operationName string
+	references    []jaegerSpanRef
+	flags         uint32
+	start         int64
+	duration      int64
+	tags          []jaegerTag
+	logs          []jaegerLog
+}
+
+// jaegerSpanRef is a reference of a span to another span, its parent for a child-of reference.
+type jaegerSpanRef struct {
+	childOf     bool
+	traceIDHigh uint64
+	traceIDLow  uint64
+	spanID      uint64
+}
+
+// jaegerLog is a log of a span, its time is in nanoseconds.
+type jaegerLog struct {
+	timestamp int64
+	fields    []jaegerTag
+}
+
+// jaegerTag is a typed key-value pair.
+type jaegerTag struct {
+	key   string
+	value interface{} // string, float64, bool, int64 or []byte
+}
+
+// jaegerDebugFlag is the flag of the debug spans, which must be kept.
+const jaegerDebugFlag = 2
+
+// handleJaeger handles the Jaeger spans, encoded with the Thrift binary protocol like for the
+// Jaeger collector, or in protobuf.
+func (r *HTTPReceiver) handleJaeger(w http.ResponseWriter, req *http.Request) {
+	r.handleTranslatedTraces(w, req, "jaeger", func(body []byte, mediaType string) (ptrace.Traces, error) {
+		var batches []jaegerBatch
+		var err error
+		switch mediaType {
+		case "application/x-protobuf":
+			batches, err = decodeJaegerProto(body)
+		case "application/x-thrift", "application/vnd.apache.thrift.binary":
+			batches, err = decodeJaegerThrift(body)
+		default:
+			err = fmt.Errorf("unsupported content type %q", mediaType)
+		}
+		if err != nil {
+			return ptrace.Traces{}, err
+		}
+		return jaegerToTraces(batches), nil
+	})
+}
+
+// decodeJaegerThrift decodes a batch of Jaeger spans encoded with the Thrift binary protocol, as
+// sent to the /api/traces endpoint of the Jaeger collector, see
+// https://github.com/jaegertracing/jaeger-idl/blob/main/thrift/jaeger.thrift
+func decodeJaegerThrift(b []byte) ([]jaegerBatch, error) {
+	var batch jaegerBatch
+	r := &thriftReader{b: b}
+	r.readStruct(func(typ byte, id int16) {
+		switch {
+		case id == 1 && typ == thriftStruct:
+			batch.process = readJaegerThriftProcess(r)
+		case id == 2 && typ == thriftList:
+			r.readList(thriftStruct, func() {
+				batch.spans = append(batch.spans, readJaegerThriftSpan(r))
+			})
+		default:
+			r.skip(typ)
+		}
+	})
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []jaegerBatch{batch}, nil
+}
+
+func readJaegerThriftProcess(r *thriftReader) jaegerProcess {
+	var process jaegerProcess
+	r.readStruct(func(typ byte, id int16) {
+		switch {
+		case id == 1 && typ == thriftString:
+			process.serviceName = r.readString()
+		case id == 2 && typ == thriftList:
+			process.tags = readJaegerThriftTags(r)
+		default:
+			r.skip(typ)
+		}
+	})
+	return process
+}
+
+func readJaegerThriftSpan(r *thriftReader) jaegerSpan {
+	var span jaegerSpan
+	r.readStruct(func(typ byte, id int16) {
+		switch {
+		case id == 1 && typ == thriftI64:
+			span.traceIDLow = uint64(r.readI64())
+		case id == 2 && typ == thriftI64:
+			span.traceIDHigh = uint64(r.readI64())
+		case id == 3 && typ == thriftI64:
+			span.spanID = uint64(r.readI64())
+		case id == 4 && typ == thriftI64:
+			span.parentSpanID = uint64(r.readI64())
+		case id == 5 && typ == thriftString:
+			span.operationName = r.readString()
+		case id == 6 && typ == thriftList:
+			r.readList(thriftStruct, func() {
+				span.references = append(span.references, readJaegerThriftSpanRef(r))
+			})
+		case id == 7 && typ == thriftI32:
+			span.flags = uint32(r.readI32())
+		case id == 8 && typ == thriftI64:
+			span.start = r.readI64() * 1000
+		case id == 9 && typ == thriftI64:
+			span.duration = r.readI64() * 1000
+		case id == 10 && typ == thriftList:
+			span.tags = readJaegerThriftTags(r)
+		case id == 11 && typ == thriftList:
+			r.readList(thriftStruct, func() {
+				var log jaegerLog
+				r.readStruct(func(typ byte, id int16) {
+					switch {
+					case id == 1 && typ == thriftI64:
+						log.timestamp = r.readI64() * 1000
+					case id == 2 && typ == thriftList:
+						log.fields = readJaegerThriftTags(r)
+					default:
+						r.skip(typ)
+					}
+				})
+				span.logs = append(span.logs, log)
+			})
+		default:
+			r.skip(typ)
+		}
+	})
+	return span
+}
+
+func readJaegerThriftSpanRef(r *thriftReader) jaegerSpanRef {
+	var ref jaegerSpanRef
+	r.readStruct(func(typ byte, id int16) {
+		switch {
+		case id == 1 && typ == thriftI32:
+			ref.childOf = r.readI32() == 0
+		case id == 2 && typ == thriftI64:
+			ref.traceIDLow = uint64(r.readI64())
+		case id == 3 && typ == thriftI64:
+			ref.traceIDHigh = uint64(r.readI64())
+		case id == 4 && typ == thriftI64:
+			ref.spanID = uint64(r.readI64())
+		default:
+			r.skip(typ)
+		}
+	})
+	return ref
+}
+
+// readJaegerThriftTags reads a list of tags, whose values are typed by their vType field:
+// 0 for strings, 1 for doubles, 2 for booleans, 3 for longs and 4 for binaries.
+func readJaegerThriftTags(r *thriftReader) []jaegerTag {
+	var tags []jaegerTag
+	r.readList(thriftStruct, func() {
+		var tag jaegerTag
+		var vType int32
+		var str string
+		var double float64
+		var boolean bool
+		var long int64
+		var binary []byte
+		r.readStruct(func(typ byte, id int16) {
+			switch {
+			case id == 1 && typ == thriftString:
+				tag.key = r.readString()
+			case id == 2 && typ == thriftI32:
+				vType = r.readI32()
+			case id == 3 && typ == thriftString:
+				str = r.readString()
+			case id == 4 && typ == thriftDouble:
+				double = r.readDouble()
+			case id == 5 && typ == thriftBool:
+				boolean = r.readBool()
+			case id == 6 && typ == thriftI64:
+				long = r.readI64()
+			case id == 7 && typ == thriftString:
+				binary = r.readBinary()
+			default:
+				r.skip(typ)
+			}
+		})
+		switch vType {
+		case 0:
+			tag.value = str
+		case 1:
+			tag.value = double
+		case 2:
+			tag.value = boolean
+		case 3:
+			tag.value = long
+		case 4:
+			tag.value = binary
+		}
+		tags = append(tags, tag)
+	})
+	return tags
+}
+
+// decodeJaegerProto decodes a batch of Jaeger spans encoded in protobuf, see model.Batch in
+// https://github.com/jaegertracing/jaeger-idl/blob/main/proto/api_v2/model.proto
+// The spans with their own process are returned in separate batches.
+func decodeJaegerProto(b []byte) ([]jaegerBatch, error) {
+	batch := jaegerBatch{}
+	var batches []jaegerBatch
+	err := readProtoMessage(b, func(num protowire.Number, f protoField) error {
+		switch num {
+		case 1:
+			span, process, err := decodeJaegerProtoSpan(f.bytes)
+			if err != nil {
+				return err
+			}
+			if process != nil {
+				batches = append(batches, jaegerBatch{process: *process, spans: []jaegerSpan{span}})
+			} else {
+				batch.spans = append(batch.spans, span)
+			}
+		case 2:
+			process, err := decodeJaegerProtoProcess(f.bytes)
+			if err != nil {
+				return err
+			}
+			batch.process = process
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(batch.spans) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+func decodeJaegerProtoSpan(b []byte) (jaegerSpan, *jaegerProcess, error) {
+	var span jaegerSpan
+	var process *jaegerProcess
+	err := readProtoMessage(b, func(num protowire.Number, f protoField) error {
+		var err error
+		switch num {
+		case 1:
+			span.traceIDHigh, span.traceIDLow = protoTraceID(f.bytes)
+		case 2:
+			span.spanID = protoSpanID(f.bytes)
+		case 3:
+			span.operationName = f.string()
+		case 4:
+			var ref jaegerSpanRef
+			ref.childOf = true
+			err = readProtoMessage(f.bytes, func(num protowire.Number, f protoField) error {
+				switch num {
+				case 1:
+					ref.traceIDHigh, ref.traceIDLow = protoTraceID(f.bytes)
+				case 2:
+					ref.spanID = protoSpanID(f.bytes)
+				case 3:
+					ref.childOf = f.num == 0
+				}
+				return nil
+			})
+			span.references = append(span.references, ref)
+		case 5:
+			span.flags = uint32(f.num)
+		case 6:
+			span.start, err = decodeProtoTimestamp(f.bytes)
+		case 7:
+			span.duration, err = decodeProtoTimestamp(f.bytes)
+		case 8:
+			var tag jaegerTag
+			tag, err = decodeJaegerProtoTag(f.bytes)
+			span.tags = append(span.tags, tag)
+		case 9:
+			var log jaegerLog
+			err = readProtoMessage(f.bytes, func(num protowire.Number, f protoField) error {
+				var err error
+				switch num {
+				case 1:
+					log.timestamp, err = decodeProtoTimestamp(f.bytes)
+				case 2:
+					var tag jaegerTag
+					tag, err = decodeJaegerProtoTag(f.bytes)
+					log.fields = append(log.fields, tag)
+				}
+				return err
+			})
+			span.logs = append(span.logs, log)
+		case 10:
+			var p jaegerProcess
+			p, err = decodeJaegerProtoProcess(f.bytes)
+			process = &p
+		}
+		return err
+	})
+	return span, process, err
+}
+
+func decodeJaegerProtoProcess(b []byte) (jaegerProcess, error) {
+	var process jaegerProcess
+	err := readProtoMessage(b, func(num protowire.Number, f protoField) error {
+		switch num {
+		case 1:
+			process.serviceName = f.string()
+		case 2:
+			tag, err := decodeJaegerProtoTag(f.bytes)
+			if err != nil {
+				return err
+			}
+			process.tags = append(process.tags, tag)
+		}
+		return nil
+	})
+	return process, err
+}
+
+// decodeJaegerProtoTag decodes a KeyValue, whose value is typed by its v_type field: 0 for
+// strings, 1 for booleans, 2 for int64, 3 for float64 and 4 for binaries.
+func decodeJaegerProtoTag(b []byte) (jaegerTag, error) {
+	var tag jaegerTag
+	var vType uint64
+	var str, binary []byte
+	var num uint64
+	err := readProtoMessage(b, func(n protowire.Number, f protoField) error {
+		switch n {
+		case 1:
+			tag.key = f.string()
+		case 2:
+			vType = f.num
+		case 3:
+			str = f.bytes
+		case 4, 5, 6:
+			num = f.num
+		case 7:
+			binary = f.bytes
+		}
+		return nil
+	})
+	switch vType {
+	case 0:
+		tag.value = string(str)
+	case 1:
+		tag.value = protoField{num: num}.bool()
+	case 2:
+		tag.value = protoField{num: num}.int64()
+	case 3:
+		tag.value = protoField{num: num}.double()
+	case 4:
+		tag.value = binary
+	}
+	return tag, err
+}
+
+// decodeProtoTimestamp decodes a google.protobuf.Timestamp or a google.protobuf.Duration into
+// nanoseconds.
+func decodeProtoTimestamp(b []byte) (int64, error) {
+	var seconds, nanos int64
+	err := readProtoMessage(b, func(num protowire.Number, f protoField) error {
+		switch num {
+		case 1:
+			seconds = f.int64()
+		case 2:
+			nanos = int64(int32(f.num))
+		}
+		return nil
+	})
+	return seconds*1e9 + nanos, err
+}
+
+// protoTraceID returns the high and the low parts of a trace ID encoded as 16 big-endian bytes.
+func protoTraceID(b []byte) (high, low uint64) {
+	if len(b) != 16 {
+		return 0, protoSpanID(b)
+	}
+	return binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+}
+
+// protoSpanID returns a span ID encoded as 8 big-endian bytes.
+func protoSpanID(b []byte) uint64 {
+	if len(b) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// jaegerToTraces converts the Jaeger batches into OpenTelemetry traces, with a resource by batch.
+func jaegerToTraces(batches []jaegerBatch) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	for _, batch := range batches {
+		rspans := traces.ResourceSpans().AppendEmpty()
+		rattrs := rspans.Resource().Attributes()
+		for _, tag := range batch.process.tags {
+			putJaegerTag(rattrs, tag)
+		}
+		if batch.process.serviceName != "" {
+			rattrs.UpsertString(semconv.AttributeServiceName, batch.process.serviceName)
+		}
+		out := rspans.ScopeSpans().AppendEmpty().Spans()
+		for _, span := range batch.spans {
+			convertJaegerSpan(span, out.AppendEmpty())
+		}
+	}
+	return traces
+}
+
+// convertJaegerSpan converts the Jaeger span in into the OpenTelemetry span out, following the
+// mapping of the OpenTelemetry Collector's Jaeger receiver.
+func convertJaegerSpan(in jaegerSpan, out ptrace.Span) {
+	var traceID [16]byte
+	binary.BigEndian.PutUint64(traceID[:8], in.traceIDHigh)
+	binary.BigEndian.PutUint64(traceID[8:], in.traceIDLow)
+	out.SetTraceID(pcommon.NewTraceID(traceID))
+	out.SetSpanID(pcommon.NewSpanID(uint64ToSpanID(in.spanID)))
+	parentID := in.parentSpanID
+	if parentID == 0 {
+		for _, ref := range in.references {
+			if ref.childOf && ref.traceIDHigh == in.traceIDHigh && ref.traceIDLow == in.traceIDLow {
+				parentID = ref.spanID
+				break
+			}
+		}
+	}
+	if parentID != 0 {
+		out.SetParentSpanID(pcommon.NewSpanID(uint64ToSpanID(parentID)))
+	}
+	out.SetName(in.operationName)
+	out.SetStartTimestamp(pcommon.Timestamp(in.start))
+	out.SetEndTimestamp(pcommon.Timestamp(in.start + in.duration))
+
+	attrs := out.Attributes()
+	if in.flags&jaegerDebugFlag != 0 {
+		attrs.UpsertInt("sampling.priority", 2)
+	}
+	for _, tag := range in.tags {
+		switch v := tag.value.(type) {
+		case string:
+			if tag.key == "span.kind" {
+				out.SetKind(jaegerKind(v))
+				continue
+			}
+			if tag.key == "w3c.tracestate" {
+				out.SetTraceState(ptrace.TraceState(v))
+				continue
+			}
+			if setStatusFromTag(out, tag.key, v) {
+				continue
+			}
+		case bool:
+			if tag.key == "error" {
+				setStatusFromTag(out, tag.key, strconv.FormatBool(v))
+				continue
+			}
+		}
+		putJaegerTag(attrs, tag)
+	}
+	for _, log := range in.logs {
+		event := out.Events().AppendEmpty()
+		event.SetTimestamp(pcommon.Timestamp(log.timestamp))
+		for _, field := range log.fields {
+			if v, ok := field.value.(string); ok && field.key == "event" {
+				event.SetName(v)
+				continue
+			}
+			putJaegerTag(event.Attributes(), field)
+		}
+	}
+}
+
+func putJaegerTag(attrs pcommon.Map, tag jaegerTag) {
+	switch v := tag.value.(type) {
+	case string:
+		attrs.UpsertString(tag.key, v)
+	case float64:
+		attrs.UpsertDouble(tag.key, v)
+	case bool:
+		attrs.UpsertBool(tag.key, v)
+	case int64:
+		attrs.UpsertInt(tag.key, v)
+	case []byte:
+		attrs.UpsertMBytes(tag.key, v)
+	}
+}
+
+func jaegerKind(kind string) ptrace.SpanKind {
+	switch strings.ToLower(kind) {
+	case "client":
+		return ptrace.SpanKindClient
+	case "server":
+		return ptrace.SpanKindServer
+	case "producer":
+		return ptrace.SpanKindProducer
+	case "consumer":
+		return ptrace.SpanKindConsumer
+	case "internal":
+		return ptrace.SpanKindInternal
+	default:
+		return ptrace.SpanKindUnspecified
+	}
+}
+
+func uint64ToSpanID(id uint64) [8]byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], id)
+	return b
+}
@@ -0,0 +1,265 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// thriftWriter writes the values of a payload with the Thrift binary protocol.
+type thriftWriter struct {
+	bytes.Buffer
+}
+
+func (w *thriftWriter) field(typ byte, id int16) {
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, id)
+}
+
+func (w *thriftWriter) stop() { w.WriteByte(thriftStop) }
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(thriftI32, id)
+	binary.Write(w, binary.BigEndian, v)
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(thriftI64, id)
+	binary.Write(w, binary.BigEndian, v)
+}
+
+func (w *thriftWriter) str(id int16, v string) {
+	w.field(thriftString, id)
+	binary.Write(w, binary.BigEndian, int32(len(v)))
+	w.WriteString(v)
+}
+
+func (w *thriftWriter) list(id int16, elemType byte, size int32) {
+	w.field(thriftList, id)
+	w.WriteByte(elemType)
+	binary.Write(w, binary.BigEndian, size)
+}
+
+func (w *thriftWriter) stringTag(key, value string) {
+	w.str(1, key)
+	w.i32(2, 0)
+	w.str(3, value)
+	w.stop()
+}
+
+func (w *thriftWriter) boolTag(key string, value bool) {
+	w.str(1, key)
+	w.i32(2, 2)
+	w.field(thriftBool, 5)
+	if value {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+	w.stop()
+}
+
+// jaegerThriftTestPayload returns a Batch of the Jaeger collector with a server span and its
+// child client span.
+func jaegerThriftTestPayload() []byte {
+	var w thriftWriter
+	// process
+	w.field(thriftStruct, 1)
+	w.str(1, "frontend")
+	w.list(2, thriftStruct, 1)
+	w.stringTag("hostname", "web-1")
+	w.stop()
+	// spans
+	w.list(2, thriftStruct, 2)
+	{
+		w.i64(1, 0x463d5c4b3d3f6f6e)
+		w.i64(2, 0x5af7183fb1d4cf5f)
+		w.i64(3, 0x352bff9a74ca9ad2)
+		w.i64(4, 0)
+		w.str(5, "get /users")
+		w.i32(7, jaegerDebugFlag)
+		w.i64(8, 1556604172355737)
+		w.i64(9, 1431)
+		w.list(10, thriftStruct, 4)
+		w.stringTag("span.kind", "server")
+		w.stringTag("http.method", "GET")
+		w.stringTag("http.route", "/users")
+		w.boolTag("error", true)
+		// an unknown field is skipped
+		w.field(thriftMap, 42)
+		w.WriteByte(thriftString)
+		w.WriteByte(thriftI32)
+		binary.Write(&w, binary.BigEndian, int32(1))
+		binary.Write(&w, binary.BigEndian, int32(1))
+		w.WriteString("k")
+		binary.Write(&w, binary.BigEndian, int32(1))
+		w.list(11, thriftStruct, 1)
+		w.i64(1, 1556604172355800)
+		w.list(2, thriftStruct, 2)
+		w.stringTag("event", "retry")
+		w.stringTag("attempt", "2")
+		w.stop()
+		w.stop()
+	}
+	{
+		w.i64(1, 0x463d5c4b3d3f6f6e)
+		w.i64(2, 0x5af7183fb1d4cf5f)
+		w.i64(3, 0x6b221d5bc9e6496c)
+		w.i64(4, 0)
+		w.str(5, "select")
+		w.list(6, thriftStruct, 1)
+		w.i32(1, 0) // CHILD_OF
+		w.i64(2, 0x463d5c4b3d3f6f6e)
+		w.i64(3, 0x5af7183fb1d4cf5f)
+		w.i64(4, 0x352bff9a74ca9ad2)
+		w.stop()
+		w.i64(8, 1556604172355800)
+		w.i64(9, 100)
+		w.list(10, thriftStruct, 3)
+		w.stringTag("span.kind", "client")
+		w.stringTag("db.system", "postgresql")
+		w.stringTag("peer.service", "postgres")
+		w.stop()
+	}
+	w.stop()
+	return w.Bytes()
+}
+
+func TestJaegerThrift(t *testing.T) {
+	rcv := newTestReceiverFromConfig(newTestReceiverConfig())
+	req := httptest.NewRequest(http.MethodPost, "/api/traces", bytes.NewReader(jaegerThriftTestPayload()))
+	req.Header.Set("Content-Type", "application/x-thrift")
+	rec := httptest.NewRecorder()
+	rcv.handleJaeger(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	require.Len(t, rcv.out, 1)
+	spans := spansByService(t, rcv.out)
+
+	server := spans["frontend"]
+	require.NotNil(t, server)
+	assert.Equal(t, uint64(0x463d5c4b3d3f6f6e), server.TraceID)
+	assert.Equal(t, uint64(0x352bff9a74ca9ad2), server.SpanID)
+	assert.Equal(t, uint64(0), server.ParentID)
+	assert.Equal(t, int64(1556604172355737000), server.Start)
+	assert.Equal(t, int64(1431000), server.Duration)
+	assert.Equal(t, "GET /users", server.Resource)
+	assert.Equal(t, "web", server.Type)
+	assert.Equal(t, int32(1), server.Error)
+	assert.Equal(t, "web-1", server.Meta["hostname"])
+	assert.Equal(t, float64(2), server.Metrics["_sampling_priority_v1"])
+	assert.Contains(t, server.Meta["events"], `"name":"retry"`)
+	assert.Contains(t, server.Meta["events"], `"attempt":"2"`)
+
+	client := spans["postgres"]
+	require.NotNil(t, client)
+	assert.Equal(t, uint64(0x352bff9a74ca9ad2), client.ParentID)
+	assert.Equal(t, "db", client.Type)
+	assert.Equal(t, int32(0), client.Error)
+}
+
+func TestJaegerThriftInvalid(t *testing.T) {
+	payload := jaegerThriftTestPayload()
+	for name, body := range map[string][]byte{
+		"truncated": payload[:len(payload)/2],
+		"list-size": {thriftList, 0, 2, thriftStruct, 0x7f, 0xff, 0xff, 0xff},
+		"type":      {99, 0, 1},
+	} {
+		_, err := decodeJaegerThrift(body)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestJaegerProto(t *testing.T) {
+	appendBytes := func(b []byte, num protowire.Number, v []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, v)
+	}
+	appendVarint := func(b []byte, num protowire.Number, v uint64) []byte {
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v)
+	}
+	tag := func(key, value string) []byte {
+		return appendBytes(appendBytes(nil, 1, []byte(key)), 3, []byte(value))
+	}
+	traceID := []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}
+
+	var ref []byte
+	ref = appendBytes(ref, 1, traceID)
+	ref = appendBytes(ref, 2, []byte{0, 0, 0, 0, 0, 0, 0, 3})
+	var errorTag []byte
+	errorTag = appendBytes(errorTag, 1, []byte("error"))
+	errorTag = appendVarint(errorTag, 2, 1)
+	errorTag = appendVarint(errorTag, 4, 1)
+
+	var span []byte
+	span = appendBytes(span, 1, traceID)
+	span = appendBytes(span, 2, []byte{0, 0, 0, 0, 0, 0, 0, 4})
+	span = appendBytes(span, 3, []byte("consume"))
+	span = appendBytes(span, 4, ref)
+	span = appendBytes(span, 6, appendVarint(appendVarint(nil, 1, 1556604172), 2, 355737000))
+	span = appendBytes(span, 7, appendVarint(nil, 2, 1431000))
+	span = appendBytes(span, 8, tag("span.kind", "consumer"))
+	span = appendBytes(span, 8, errorTag)
+	// a span with its own process
+	var otherSpan []byte
+	otherSpan = appendBytes(otherSpan, 1, traceID)
+	otherSpan = appendBytes(otherSpan, 2, []byte{0, 0, 0, 0, 0, 0, 0, 5})
+	otherSpan = appendBytes(otherSpan, 10, appendBytes(nil, 1, []byte("other")))
+
+	var payload []byte
+	payload = appendBytes(payload, 1, span)
+	payload = appendBytes(payload, 1, otherSpan)
+	payload = appendBytes(payload, 2, appendBytes(appendBytes(nil, 1, []byte("worker")), 2, tag("version", "1.2")))
+
+	batches, err := decodeJaegerProto(payload)
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Equal(t, "other", batches[0].process.serviceName)
+	assert.Equal(t, "worker", batches[1].process.serviceName)
+	assert.Equal(t, []jaegerTag{{key: "version", value: "1.2"}}, batches[1].process.tags)
+	require.Len(t, batches[1].spans, 1)
+	assert.Equal(t, jaegerSpan{
+		traceIDHigh:   1,
+		traceIDLow:    2,
+		spanID:        4,
+		operationName: "consume",
+		references:    []jaegerSpanRef{{childOf: true, traceIDHigh: 1, traceIDLow: 2, spanID: 3}},
+		start:         1556604172355737000,
+		duration:      1431000,
+		tags:          []jaegerTag{{key: "span.kind", value: "consumer"}, {key: "error", value: true}},
+	}, batches[1].spans[0])
+
+	traces := jaegerToTraces(batches)
+	require.Equal(t, 2, traces.ResourceSpans().Len())
+	out := traces.ResourceSpans().At(1).ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, ptrace.SpanKindConsumer, out.Kind())
+	assert.Equal(t, ptrace.StatusCodeError, out.Status().Code())
+	assert.Equal(t, [8]byte{7: 3}, out.ParentSpanID().Bytes())
+	assert.Equal(t, 0, out.Attributes().Len())
+
+	_, err = decodeJaegerProto(payload[:len(payload)-1])
+	assert.Error(t, err)
+}
+
+func TestJaegerUnsupportedContentType(t *testing.T) {
+	rcv := newTestReceiverFromConfig(newTestReceiverConfig())
+	req := httptest.NewRequest(http.MethodPost, "/api/traces", bytes.NewReader(jaegerThriftTestPayload()))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	rcv.handleJaeger(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, rcv.out)
+}
@@ -210,6 +210,27 @@ func (o *OTLPReceiver) processRequest(protocol string, header http.Header, in pt
 
 // ReceiveResourceSpans processes the given rspans and returns the source that it identified from processing them.
 func (o *OTLPReceiver) ReceiveResourceSpans(rspans ptrace.ResourceSpans, header http.Header, protocol string) source.Source {
+	p, src := o.convertResourceSpans(rspans, header, fmt.Sprintf("opentelemetry_%s_v1", protocol), nil)
+	tags := p.Source.AsTags()
+	var spancount int64
+	for _, chunk := range p.TracerPayload.Chunks {
+		spancount += int64(len(chunk.Spans))
+	}
+	metrics.Count("datadog.trace_agent.otlp.spans", spancount, tags, 1)
+	metrics.Count("datadog.trace_agent.otlp.traces", int64(len(p.TracerPayload.Chunks)), tags, 1)
+	select {
+	case o.out <- p:
+		// 👍
+	default:
+		log.Warn("Payload in channel full. Dropped 1 payload.")
+	}
+	return src
+}
+
+// convertResourceSpans converts the given rspans into a payload and returns it along with the source
+// that it identified from processing them. The payload is tagged with the given endpoint version and
+// its stats are those of the receiver stats rstats, or new stats if rstats is nil.
+func (o *OTLPReceiver) convertResourceSpans(rspans ptrace.ResourceSpans, header http.Header, endpointVersion string, rstats *info.ReceiverStats) (*Payload, source.Source) {
 	// each rspans is coming from a different resource and should be considered
 	// a separate payload; typically there is only one item in this slice
 	attr := rspans.Resource().Attributes()
@@ -241,27 +262,29 @@ func (o *OTLPReceiver) ReceiveResourceSpans(rspans ptrace.ResourceSpans, header
 	if containerID == "" {
 		containerID = fastHeaderGet(header, headerContainerID)
 	}
-	tagstats := &info.TagStats{
-		Tags: info.Tags{
-			Lang:            lang,
-			LangVersion:     fastHeaderGet(header, headerLangVersion),
-			Interpreter:     fastHeaderGet(header, headerLangInterpreter),
-			LangVendor:      fastHeaderGet(header, headerLangInterpreterVendor),
-			TracerVersion:   fmt.Sprintf("otlp-%s", rattr[string(semconv.AttributeTelemetrySDKVersion)]),
-			EndpointVersion: fmt.Sprintf("opentelemetry_%s_v1", protocol),
-		},
-		Stats: info.NewStats(),
-	}
-	tracesByID := make(map[uint64]pb.Trace)
-	priorityByID := make(map[uint64]float64)
-	var spancount int64
+	tags := info.Tags{
+		Lang:            lang,
+		LangVersion:     fastHeaderGet(header, headerLangVersion),
+		Interpreter:     fastHeaderGet(header, headerLangInterpreter),
+		LangVendor:      fastHeaderGet(header, headerLangInterpreterVendor),
+		TracerVersion:   fmt.Sprintf("otlp-%s", rattr[string(semconv.AttributeTelemetrySDKVersion)]),
+		EndpointVersion: endpointVersion,
+	}
+	var tagstats *info.TagStats
+	if rstats != nil {
+		tagstats = rstats.GetTagStats(tags)
+	} else {
+		tagstats = &info.TagStats{Tags: tags, Stats: info.NewStats()}
+	}
+	// traces are grouped by their full 128-bit ID, their lower 64 bits may be shared
+	tracesByID := make(map[[16]byte]pb.Trace)
+	priorityByID := make(map[[16]byte]float64)
 	for i := 0; i < rspans.ScopeSpans().Len(); i++ {
 		libspans := rspans.ScopeSpans().At(i)
 		lib := libspans.Scope()
 		for i := 0; i < libspans.Spans().Len(); i++ {
-			spancount++
 			span := libspans.Spans().At(i)
-			traceID := traceIDToUint64(span.TraceID().Bytes())
+			traceID := span.TraceID().Bytes()
 			if tracesByID[traceID] == nil {
 				tracesByID[traceID] = pb.Trace{}
 			}
@@ -292,9 +315,6 @@ func (o *OTLPReceiver) ReceiveResourceSpans(rspans ptrace.ResourceSpans, header
 			tracesByID[traceID] = append(tracesByID[traceID], ddspan)
 		}
 	}
-	tags := tagstats.AsTags()
-	metrics.Count("datadog.trace_agent.otlp.spans", spancount, tags, 1)
-	metrics.Count("datadog.trace_agent.otlp.traces", int64(len(tracesByID)), tags, 1)
 	traceChunks := make([]*pb.TraceChunk, 0, len(tracesByID))
 	p := Payload{
 		Source: tagstats,
@@ -350,13 +370,7 @@ func (o *OTLPReceiver) ReceiveResourceSpans(rspans ptrace.ResourceSpans, header
 			}
 		}
 	}
-	select {
-	case o.out <- &p:
-		// 👍
-	default:
-		log.Warn("Payload in channel full. Dropped 1 payload.")
-	}
-	return src
+	return &p, src
 }
 
 // marshalEvents marshals events into JSON.
@@ -489,6 +503,9 @@ func (o *OTLPReceiver) convertSpan(rattr map[string]string, lib pcommon.Instrume
 		}
 		return true
 	})
+	// the upper bits of the trace ID take precedence over the ones set in the attributes
+	delete(span.Meta, traceutil.TraceIDHighKey)
+	traceutil.SetTraceIDHigh(span, traceIDHighToUint64(traceID))
 	if ctags := attributes.ContainerTagFromAttributes(span.Meta); ctags != "" {
 		setMetaOTLP(span, tagContainersTags, ctags)
 	}
@@ -643,6 +660,11 @@ func traceIDToUint64(b [16]byte) uint64 {
 	return binary.BigEndian.Uint64(b[len(b)-8:])
 }
 
+// traceIDHighToUint64 returns the upper 64 bits of the trace ID b.
+func traceIDHighToUint64(b [16]byte) uint64 {
+	return binary.BigEndian.Uint64(b[:8])
+}
+
 func spanIDToUint64(b [8]byte) uint64 {
 	return binary.BigEndian.Uint64(b[:])
 }
@@ -338,6 +338,39 @@ func TestOTLPReceiveResourceSpans(t *testing.T) {
 				}
 			},
 		},
+		{
+			in: []testutil.OTLPResourceSpan{
+				{
+					Spans: []*testutil.OTLPSpan{
+						{
+							TraceID: [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+							Name:    "first",
+						},
+						{
+							TraceID:    [16]byte{8, 7, 6, 5, 4, 3, 2, 1, 9, 10, 11, 12, 13, 14, 15, 16},
+							Name:       "second",
+							Attributes: map[string]interface{}{"_dd.p.tid": "invalid"},
+						},
+						{
+							TraceID: [16]byte{0, 0, 0, 0, 0, 0, 0, 0, 9, 10, 11, 12, 13, 14, 15, 16},
+							Name:    "third",
+						},
+					},
+				},
+			},
+			fn: func(out *pb.TracerPayload) {
+				// the traces sharing their lower 64 bits are kept apart
+				require.Len(out.Chunks, 3)
+				tids := make(map[string]bool)
+				for _, chunk := range out.Chunks {
+					require.Len(chunk.Spans, 1)
+					require.Equal(uint64(0x90a0b0c0d0e0f10), chunk.Spans[0].TraceID)
+					tid, ok := chunk.Spans[0].Meta["_dd.p.tid"]
+					tids[tid] = ok
+				}
+				require.Equal(map[string]bool{"0102030405060708": true, "0807060504030201": true, "": false}, tids)
+			},
+		},
 	} {
 		t.Run("", func(t *testing.T) {
 			rcv.ReceiveResourceSpans(testutil.NewOTLPTracesRequest(tt.in).Traces().ResourceSpans().At(0), http.Header{}, "agent_tests")
@@ -782,6 +815,7 @@ func TestOTLPConvertSpan(t *testing.T) {
 				Error:    1,
 				Meta: map[string]string{
 					"name":                    "john",
+					"_dd.p.tid":               "72df520af2bde7a5",
 					"otel.trace_id":           "72df520af2bde7a5240031ead750e5f3",
 					"env":                     "staging",
 					"otel.status_code":        "STATUS_CODE_ERROR",
@@ -864,6 +898,7 @@ func TestOTLPConvertSpan(t *testing.T) {
 					"name":                    "john",
 					"env":                     "prod",
 					"deployment.environment":  "prod",
+					"_dd.p.tid":               "72df520af2bde7a5",
 					"otel.trace_id":           "72df520af2bde7a5240031ead750e5f3",
 					"otel.status_code":        "STATUS_CODE_ERROR",
 					"otel.status_description": "Error",
@@ -955,6 +990,7 @@ func TestOTLPConvertSpan(t *testing.T) {
 					"service.version":         "v1.2.3",
 					"w3c.tracestate":          "state",
 					"version":                 "v1.2.3",
+					"_dd.p.tid":               "72df520af2bde7a5",
 					"otel.trace_id":           "72df520af2bde7a5240031ead750e5f3",
 					"events":                  "[{\"time_unix_nano\":123,\"name\":\"boom\",\"attributes\":{\"message\":\"Out of memory\",\"accuracy\":\"2.4\"},\"dropped_attributes_count\":2},{\"time_unix_nano\":456,\"name\":\"exception\",\"attributes\":{\"exception.message\":\"Out of memory\",\"exception.type\":\"mem\",\"exception.stacktrace\":\"1/2/3\"},\"dropped_attributes_count\":2}]",
 					"error.msg":               "Out of memory",
@@ -1015,6 +1051,7 @@ func TestOTLPConvertSpan(t *testing.T) {
 					"otel.library.name":               "ddtracer",
 					"otel.library.version":            "v2",
 					"name":                            "john",
+					"_dd.p.tid":                       "72df520af2bde7a5",
 					"otel.trace_id":                   "72df520af2bde7a5240031ead750e5f3",
 				},
 				Metrics: map[string]float64{
@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoField is a field of a protobuf message. Depending on its wire type, its value
+// is in num (varint and fixed types) or in bytes (length-delimited type).
+type protoField struct {
+	typ   protowire.Type
+	num   uint64
+	bytes []byte
+}
+
+func (f protoField) string() string { return string(f.bytes) }
+
+func (f protoField) int64() int64 { return int64(f.num) }
+
+func (f protoField) bool() bool { return f.num != 0 }
+
+func (f protoField) double() float64 { return math.Float64frombits(f.num) }
+
+// readProtoMessage reads the fields of the protobuf message b, calling readField with the number
+// and the value of each of them. The groups, deprecated, are skipped.
+func readProtoMessage(b []byte, readField func(num protowire.Number, f protoField) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		f := protoField{typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.num, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.num, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.num = uint64(v)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := readField(num, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build go1.18
+// +build go1.18
+
+package api
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func FuzzReadProtoMessage(f *testing.F) {
+	var span []byte
+	span = protowire.AppendTag(span, 1, protowire.BytesType)
+	span = protowire.AppendBytes(span, []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2})
+	span = protowire.AppendTag(span, 6, protowire.Fixed64Type)
+	span = protowire.AppendFixed64(span, 1556604172355800)
+	span = protowire.AppendTag(span, 7, protowire.VarintType)
+	span = protowire.AppendVarint(span, 100)
+	var message []byte
+	message = protowire.AppendTag(message, 1, protowire.BytesType)
+	message = protowire.AppendBytes(message, span)
+	f.Add(message)
+	f.Add([]byte{0x0b, 0x0c})
+	f.Add([]byte{0x0a, 0xff, 0xff, 0xff, 0xff, 0x0f})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// the nested messages are read like the top-level one
+		var read func(b []byte, depth int) error
+		read = func(b []byte, depth int) error {
+			return readProtoMessage(b, func(_ protowire.Number, f protoField) error {
+				if f.typ == protowire.BytesType && depth < 8 {
+					_ = read(f.bytes, depth+1)
+				}
+				return nil
+			})
+		}
+		_ = read(b, 0)
+
+		if spans, err := decodeZipkinProto(b); err == nil {
+			_, _ = zipkinToTraces(spans)
+		}
+		if batches, err := decodeJaegerProto(b); err == nil {
+			jaegerToTraces(batches)
+		}
+	})
+}
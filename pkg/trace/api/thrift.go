@@ -0,0 +1,192 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Thrift types, as encoded by the binary protocol.
+const (
+	thriftStop   byte = 0
+	thriftBool   byte = 2
+	thriftByte   byte = 3
+	thriftDouble byte = 4
+	thriftI16    byte = 6
+	thriftI32    byte = 8
+	thriftI64    byte = 10
+	thriftString byte = 11
+	thriftStruct byte = 12
+	thriftMap    byte = 13
+	thriftSet    byte = 14
+	thriftList   byte = 15
+)
+
+// thriftMaxDepth bounds the nesting of the skipped values.
+const thriftMaxDepth = 64
+
+var (
+	errThriftShortBuffer  = errors.New("thrift: unexpected end of payload")
+	errThriftInvalidSize  = errors.New("thrift: invalid size")
+	errThriftInvalidType  = errors.New("thrift: invalid type")
+	errThriftDepthReached = errors.New("thrift: maximum depth reached")
+)
+
+// thriftReader reads the values of a payload encoded with the Thrift binary protocol. The
+// first error is kept in err, the values read afterwards are zero values.
+type thriftReader struct {
+	b   []byte
+	err error
+}
+
+func (r *thriftReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = errThriftShortBuffer
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *thriftReader) readByte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *thriftReader) readBool() bool {
+	return r.readByte() != 0
+}
+
+func (r *thriftReader) readI16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *thriftReader) readI32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *thriftReader) readI64() int64 {
+	if b := r.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (r *thriftReader) readDouble() float64 {
+	return math.Float64frombits(uint64(r.readI64()))
+}
+
+func (r *thriftReader) readBinary() []byte {
+	return r.next(int(r.readI32()))
+}
+
+func (r *thriftReader) readString() string {
+	return string(r.readBinary())
+}
+
+// readListHeader reads the header of a list or of a set and returns the type and the number
+// of its elements.
+func (r *thriftReader) readListHeader() (elemType byte, size int) {
+	elemType = r.readByte()
+	size = int(r.readI32())
+	if r.err == nil && (size < 0 || size > len(r.b)) {
+		// each element takes at least one byte
+		r.err = errThriftInvalidSize
+	}
+	if r.err != nil {
+		return thriftStop, 0
+	}
+	return elemType, size
+}
+
+// readStruct reads the fields of a struct, calling readField with the type and the ID of
+// each of them. readField must read the value of the field, or skip it.
+func (r *thriftReader) readStruct(readField func(typ byte, id int16)) {
+	for r.err == nil {
+		typ := r.readByte()
+		if typ == thriftStop {
+			return
+		}
+		id := r.readI16()
+		if r.err != nil {
+			return
+		}
+		readField(typ, id)
+	}
+}
+
+// readList reads the elements of a list of the given type, calling readElem for each of
+// them. The list is skipped if its elements have another type.
+func (r *thriftReader) readList(elemType byte, readElem func()) {
+	typ, size := r.readListHeader()
+	for i := 0; i < size && r.err == nil; i++ {
+		if typ != elemType {
+			r.skip(typ)
+			continue
+		}
+		readElem()
+	}
+}
+
+// skip skips a value of the given type.
+func (r *thriftReader) skip(typ byte) {
+	r.skipDepth(typ, 0)
+}
+
+func (r *thriftReader) skipDepth(typ byte, depth int) {
+	if depth > thriftMaxDepth {
+		r.err = errThriftDepthReached
+		return
+	}
+	switch typ {
+	case thriftBool, thriftByte:
+		r.next(1)
+	case thriftI16:
+		r.next(2)
+	case thriftI32:
+		r.next(4)
+	case thriftDouble, thriftI64:
+		r.next(8)
+	case thriftString:
+		r.readBinary()
+	case thriftStruct:
+		r.readStruct(func(typ byte, _ int16) { r.skipDepth(typ, depth+1) })
+	case thriftMap:
+		keyType := r.readByte()
+		valueType := r.readByte()
+		size := int(r.readI32())
+		if r.err == nil && (size < 0 || size > len(r.b)) {
+			r.err = errThriftInvalidSize
+		}
+		for i := 0; i < size && r.err == nil; i++ {
+			r.skipDepth(keyType, depth+1)
+			r.skipDepth(valueType, depth+1)
+		}
+	case thriftSet, thriftList:
+		elemType, size := r.readListHeader()
+		for i := 0; i < size && r.err == nil; i++ {
+			r.skipDepth(elemType, depth+1)
+		}
+	default:
+		if r.err == nil {
+			r.err = errThriftInvalidType
+		}
+	}
+}
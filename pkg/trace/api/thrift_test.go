@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build go1.18
+// +build go1.18
+
+package api
+
+import (
+	"testing"
+)
+
+func FuzzThriftReader(f *testing.F) {
+	f.Add(jaegerThriftTestPayload())
+	f.Add([]byte{thriftList, 0, 1, 0x7f, 0xff, 0xff, 0xff})
+	f.Add([]byte{thriftMap, 0, 1, thriftString, thriftStruct, 0, 0, 0, 1, 0, 0, 0, 0, thriftStop})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// skipping a value never reads past the payload
+		r := thriftReader{b: b}
+		r.skip(thriftStruct)
+		if r.err == nil && len(r.b) > len(b) {
+			t.Fatalf("read %d bytes out of %d", len(b)-len(r.b), len(b))
+		}
+
+		batches, err := decodeJaegerThrift(b)
+		if err != nil {
+			return
+		}
+		jaegerToTraces(batches)
+	})
+}
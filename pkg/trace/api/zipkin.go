@@ -0,0 +1,343 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// zipkinSpan is a span of the Zipkin v2 API, see https://zipkin.io/zipkin-api/#/default/post_spans
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ID             string             `json:"id"`
+	ParentID       string             `json:"parentId"`
+	Name           string             `json:"name"`
+	Kind           string             `json:"kind"`
+	Timestamp      uint64             `json:"timestamp"` // in microseconds
+	Duration       uint64             `json:"duration"`  // in microseconds
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+	Debug          bool               `json:"debug"`
+}
+
+// zipkinEndpoint is the network context of a node in the service graph.
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+// zipkinAnnotation is an event explaining latency with a timestamp.
+type zipkinAnnotation struct {
+	Timestamp uint64 `json:"timestamp"` // in microseconds
+	Value     string `json:"value"`
+}
+
+// zipkinProtoKinds maps the kinds of the Zipkin protobuf spans to the ones of the JSON spans.
+var zipkinProtoKinds = map[uint64]string{
+	1: "CLIENT",
+	2: "SERVER",
+	3: "PRODUCER",
+	4: "CONSUMER",
+}
+
+// handleZipkin handles the Zipkin v2 spans, encoded in JSON or in protobuf.
+func (r *HTTPReceiver) handleZipkin(w http.ResponseWriter, req *http.Request) {
+	r.handleTranslatedTraces(w, req, "zipkin_v2", func(body []byte, mediaType string) (ptrace.Traces, error) {
+		var spans []zipkinSpan
+		var err error
+		if mediaType == "application/x-protobuf" {
+			spans, err = decodeZipkinProto(body)
+		} else {
+			spans, err = decodeZipkinJSON(body)
+		}
+		if err != nil {
+			return ptrace.Traces{}, err
+		}
+		return zipkinToTraces(spans)
+	})
+}
+
+// decodeZipkinJSON decodes a list of Zipkin v2 spans encoded in JSON.
+func decodeZipkinJSON(b []byte) ([]zipkinSpan, error) {
+	var spans []zipkinSpan
+	if err := json.Unmarshal(b, &spans); err != nil {
+		return nil, err
+	}
+	return spans, nil
+}
+
+// decodeZipkinProto decodes a list of Zipkin v2 spans encoded in protobuf, see
+// https://github.com/openzipkin/zipkin-api/blob/master/zipkin.proto
+func decodeZipkinProto(b []byte) ([]zipkinSpan, error) {
+	var spans []zipkinSpan
+	err := readProtoMessage(b, func(num protowire.Number, f protoField) error {
+		if num != 1 || f.typ != protowire.BytesType {
+			return nil
+		}
+		span, err := decodeZipkinProtoSpan(f.bytes)
+		if err != nil {
+			return err
+		}
+		spans = append(spans, span)
+		return nil
+	})
+	return spans, err
+}
+
+func decodeZipkinProtoSpan(b []byte) (zipkinSpan, error) {
+	var span zipkinSpan
+	err := readProtoMessage(b, func(num protowire.Number, f protoField) error {
+		var err error
+		switch num {
+		case 1:
+			span.TraceID = hex.EncodeToString(f.bytes)
+		case 2:
+			span.ParentID = hex.EncodeToString(f.bytes)
+		case 3:
+			span.ID = hex.EncodeToString(f.bytes)
+		case 4:
+			span.Kind = zipkinProtoKinds[f.num]
+		case 5:
+			span.Name = f.string()
+		case 6:
+			span.Timestamp = f.num
+		case 7:
+			span.Duration = f.num
+		case 8:
+			span.LocalEndpoint, err = decodeZipkinProtoEndpoint(f.bytes)
+		case 9:
+			span.RemoteEndpoint, err = decodeZipkinProtoEndpoint(f.bytes)
+		case 10:
+			var annotation zipkinAnnotation
+			err = readProtoMessage(f.bytes, func(num protowire.Number, f protoField) error {
+				switch num {
+				case 1:
+					annotation.Timestamp = f.num
+				case 2:
+					annotation.Value = f.string()
+				}
+				return nil
+			})
+			span.Annotations = append(span.Annotations, annotation)
+		case 11:
+			var k, v string
+			err = readProtoMessage(f.bytes, func(num protowire.Number, f protoField) error {
+				switch num {
+				case 1:
+					k = f.string()
+				case 2:
+					v = f.string()
+				}
+				return nil
+			})
+			if span.Tags == nil {
+				span.Tags = make(map[string]string)
+			}
+			span.Tags[k] = v
+		case 12:
+			span.Debug = f.bool()
+		}
+		return err
+	})
+	return span, err
+}
+
+func decodeZipkinProtoEndpoint(b []byte) (*zipkinEndpoint, error) {
+	var endpoint zipkinEndpoint
+	err := readProtoMessage(b, func(num protowire.Number, f protoField) error {
+		switch num {
+		case 1:
+			endpoint.ServiceName = f.string()
+		case 2:
+			endpoint.IPv4 = net.IP(f.bytes).String()
+		case 3:
+			endpoint.IPv6 = net.IP(f.bytes).String()
+		case 4:
+			endpoint.Port = int(f.num)
+		}
+		return nil
+	})
+	return &endpoint, err
+}
+
+// zipkinToTraces converts the Zipkin spans into OpenTelemetry traces, with a resource by
+// local service name.
+func zipkinToTraces(spans []zipkinSpan) (ptrace.Traces, error) {
+	traces := ptrace.NewTraces()
+	byService := make(map[string]ptrace.SpanSlice)
+	for _, zspan := range spans {
+		var service string
+		if zspan.LocalEndpoint != nil {
+			service = zspan.LocalEndpoint.ServiceName
+		}
+		out, ok := byService[service]
+		if !ok {
+			rspans := traces.ResourceSpans().AppendEmpty()
+			if service != "" {
+				rspans.Resource().Attributes().InsertString(semconv.AttributeServiceName, service)
+			}
+			out = rspans.ScopeSpans().AppendEmpty().Spans()
+			byService[service] = out
+		}
+		if err := convertZipkinSpan(zspan, out.AppendEmpty()); err != nil {
+			return traces, err
+		}
+	}
+	return traces, nil
+}
+
+// convertZipkinSpan converts the Zipkin span in into the OpenTelemetry span out, following the
+// mapping of the OpenTelemetry Collector's Zipkin receiver.
+func convertZipkinSpan(in zipkinSpan, out ptrace.Span) error {
+	traceID, err := hexTraceID(in.TraceID)
+	if err != nil {
+		return err
+	}
+	out.SetTraceID(pcommon.NewTraceID(traceID))
+	spanID, err := hexSpanID(in.ID)
+	if err != nil {
+		return err
+	}
+	out.SetSpanID(pcommon.NewSpanID(spanID))
+	if in.ParentID != "" {
+		parentID, err := hexSpanID(in.ParentID)
+		if err != nil {
+			return err
+		}
+		out.SetParentSpanID(pcommon.NewSpanID(parentID))
+	}
+	out.SetName(in.Name)
+	out.SetKind(zipkinKind(in.Kind))
+	out.SetStartTimestamp(pcommon.Timestamp(in.Timestamp * 1000))
+	out.SetEndTimestamp(pcommon.Timestamp((in.Timestamp + in.Duration) * 1000))
+
+	attrs := out.Attributes()
+	if in.LocalEndpoint != nil {
+		putEndpointAttributes(attrs, in.LocalEndpoint, semconv.AttributeNetHostIP, semconv.AttributeNetHostPort)
+	}
+	if in.RemoteEndpoint != nil {
+		if in.RemoteEndpoint.ServiceName != "" {
+			attrs.UpsertString(semconv.AttributePeerService, in.RemoteEndpoint.ServiceName)
+		}
+		putEndpointAttributes(attrs, in.RemoteEndpoint, semconv.AttributeNetPeerIP, semconv.AttributeNetPeerPort)
+	}
+	if in.Debug {
+		attrs.UpsertInt("sampling.priority", 2)
+	}
+	for k, v := range in.Tags {
+		if !setStatusFromTag(out, k, v) {
+			attrs.UpsertString(k, v)
+		}
+	}
+	for _, annotation := range in.Annotations {
+		event := out.Events().AppendEmpty()
+		event.SetTimestamp(pcommon.Timestamp(annotation.Timestamp * 1000))
+		event.SetName(annotation.Value)
+	}
+	return nil
+}
+
+func putEndpointAttributes(attrs pcommon.Map, endpoint *zipkinEndpoint, ipKey, portKey string) {
+	if endpoint.IPv4 != "" {
+		attrs.UpsertString(ipKey, endpoint.IPv4)
+	} else if endpoint.IPv6 != "" {
+		attrs.UpsertString(ipKey, endpoint.IPv6)
+	}
+	if endpoint.Port != 0 {
+		attrs.UpsertInt(portKey, int64(endpoint.Port))
+	}
+}
+
+func zipkinKind(kind string) ptrace.SpanKind {
+	switch strings.ToUpper(kind) {
+	case "CLIENT":
+		return ptrace.SpanKindClient
+	case "SERVER":
+		return ptrace.SpanKindServer
+	case "PRODUCER":
+		return ptrace.SpanKindProducer
+	case "CONSUMER":
+		return ptrace.SpanKindConsumer
+	default:
+		return ptrace.SpanKindUnspecified
+	}
+}
+
+// setStatusFromTag sets the status of the span from the tag k, reporting whether it is a
+// status tag: the error tag of Zipkin and Jaeger, or the status tags of OpenTelemetry.
+func setStatusFromTag(span ptrace.Span, k, v string) bool {
+	switch k {
+	case "error":
+		if v == "false" {
+			return true
+		}
+		span.Status().SetCode(ptrace.StatusCodeError)
+		if v != "true" && v != "" && span.Status().Message() == "" {
+			span.Status().SetMessage(v)
+		}
+	case semconv.OtelStatusCode:
+		switch strings.ToUpper(v) {
+		case "ERROR":
+			span.Status().SetCode(ptrace.StatusCodeError)
+		case "OK":
+			span.Status().SetCode(ptrace.StatusCodeOk)
+		}
+	case semconv.OtelStatusDescription:
+		span.Status().SetMessage(v)
+	default:
+		return false
+	}
+	return true
+}
+
+// hexTraceID parses a trace ID of 16 or 32 hexadecimal characters.
+func hexTraceID(s string) ([16]byte, error) {
+	var id [16]byte
+	if len(s) == 0 || len(s) > 32 {
+		return id, fmt.Errorf("invalid trace ID %q", s)
+	}
+	if len(s) > 16 {
+		high, err := strconv.ParseUint(s[:len(s)-16], 16, 64)
+		if err != nil {
+			return id, fmt.Errorf("invalid trace ID %q", s)
+		}
+		binary.BigEndian.PutUint64(id[:8], high)
+		s = s[len(s)-16:]
+	}
+	low, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return id, fmt.Errorf("invalid trace ID %q", s)
+	}
+	binary.BigEndian.PutUint64(id[8:], low)
+	return id, nil
+}
+
+// hexSpanID parses a span ID of up to 16 hexadecimal characters.
+func hexSpanID(s string) ([8]byte, error) {
+	var id [8]byte
+	v, err := strconv.ParseUint(s, 16, 64)
+	if err != nil || len(s) == 0 {
+		return id, fmt.Errorf("invalid span ID %q", s)
+	}
+	binary.BigEndian.PutUint64(id[:], v)
+	return id, nil
+}
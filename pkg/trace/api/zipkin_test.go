@@ -0,0 +1,233 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+const zipkinTestPayload = `[
+	{
+		"traceId": "5af7183fb1d4cf5f463d5c4b3d3f6f6e",
+		"id": "352bff9a74ca9ad2",
+		"name": "get /users",
+		"kind": "SERVER",
+		"timestamp": 1556604172355737,
+		"duration": 1431,
+		"localEndpoint": {"serviceName": "frontend", "ipv4": "192.168.99.1", "port": 8080},
+		"remoteEndpoint": {"ipv4": "172.19.0.2", "port": 58648},
+		"tags": {"http.method": "GET", "http.route": "/users", "http.status_code": "500", "error": "internal error"},
+		"annotations": [{"timestamp": 1556604172355800, "value": "wr"}],
+		"debug": true
+	},
+	{
+		"traceId": "463d5c4b3d3f6f6e",
+		"parentId": "352bff9a74ca9ad2",
+		"id": "6b221d5bc9e6496c",
+		"name": "select",
+		"kind": "CLIENT",
+		"timestamp": 1556604172355800,
+		"duration": 100,
+		"localEndpoint": {"serviceName": "backend"},
+		"remoteEndpoint": {"serviceName": "postgres"},
+		"tags": {"db.system": "postgresql"}
+	}
+]`
+
+// spansByService returns the spans of the payloads sent to out by service name.
+func spansByService(t *testing.T, out chan *Payload) map[string]*pb.Span {
+	spans := make(map[string]*pb.Span)
+	for len(out) > 0 {
+		p := <-out
+		for _, chunk := range p.TracerPayload.Chunks {
+			for _, span := range chunk.Spans {
+				spans[span.Service] = span
+			}
+		}
+	}
+	return spans
+}
+
+func TestZipkinJSON(t *testing.T) {
+	rcv := newTestReceiverFromConfig(newTestReceiverConfig())
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", strings.NewReader(zipkinTestPayload))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	rcv.handleZipkin(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	require.Len(t, rcv.out, 2)
+	spans := spansByService(t, rcv.out)
+
+	server := spans["frontend"]
+	require.NotNil(t, server)
+	assert.Equal(t, uint64(0x463d5c4b3d3f6f6e), server.TraceID)
+	assert.Equal(t, uint64(0x352bff9a74ca9ad2), server.SpanID)
+	assert.Equal(t, uint64(0), server.ParentID)
+	assert.Equal(t, int64(1556604172355737000), server.Start)
+	assert.Equal(t, int64(1431000), server.Duration)
+	assert.Equal(t, "GET /users", server.Resource)
+	assert.Equal(t, "web", server.Type)
+	assert.Equal(t, "opentelemetry.server", server.Name)
+	assert.Equal(t, int32(1), server.Error)
+	assert.Equal(t, "internal error", server.Meta["error.msg"])
+	assert.NotContains(t, server.Meta, "error")
+	assert.Equal(t, "192.168.99.1", server.Meta["net.host.ip"])
+	assert.Equal(t, float64(8080), server.Metrics["net.host.port"])
+	assert.Equal(t, "172.19.0.2", server.Meta["net.peer.ip"])
+	assert.Equal(t, float64(2), server.Metrics["_sampling_priority_v1"])
+	assert.Equal(t, "5af7183fb1d4cf5f463d5c4b3d3f6f6e", server.Meta["otel.trace_id"])
+	assert.Equal(t, "5af7183fb1d4cf5f", server.Meta["_dd.p.tid"])
+	assert.Contains(t, server.Meta["events"], `"name":"wr"`)
+
+	// the client spans are attributed to their peer service, like the OTLP ones
+	client := spans["postgres"]
+	require.NotNil(t, client)
+	assert.Equal(t, uint64(0x352bff9a74ca9ad2), client.ParentID)
+	assert.Equal(t, "db", client.Type)
+	assert.Equal(t, "select", client.Resource)
+	assert.NotContains(t, client.Meta, "_dd.p.tid")
+	assert.Equal(t, int32(0), client.Error)
+}
+
+func TestZipkinGzip(t *testing.T) {
+	var body bytes.Buffer
+	gzipw := gzip.NewWriter(&body)
+	_, err := gzipw.Write([]byte(zipkinTestPayload))
+	require.NoError(t, err)
+	require.NoError(t, gzipw.Close())
+
+	rcv := newTestReceiverFromConfig(newTestReceiverConfig())
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", &body)
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	rcv.handleZipkin(rec, req)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, rcv.out, 2)
+}
+
+func TestZipkinInvalid(t *testing.T) {
+	rcv := newTestReceiverFromConfig(newTestReceiverConfig())
+	for _, body := range []string{
+		`{"traceId": "1"}`,
+		`[{"traceId": "not hex", "id": "1"}]`,
+		`[{"traceId": "1", "id": ""}]`,
+	} {
+		rec := httptest.NewRecorder()
+		rcv.handleZipkin(rec, httptest.NewRequest(http.MethodPost, "/api/v2/spans", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+	assert.Empty(t, rcv.out)
+}
+
+func TestTranslatedTracesEndpointsEnabled(t *testing.T) {
+	post := func(conf *config.AgentConfig, path string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(zipkinTestPayload))
+		req.Header.Set("Content-Type", "application/json")
+		newTestReceiverFromConfig(conf).buildMux().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// the endpoints are disabled by default
+	conf := newTestReceiverConfig()
+	assert.Equal(t, http.StatusNotFound, post(conf, "/api/v2/spans"))
+	assert.Equal(t, http.StatusNotFound, post(conf, "/api/traces"))
+
+	conf.ZipkinReceiverEnabled = true
+	conf.JaegerReceiverEnabled = true
+	assert.Equal(t, http.StatusAccepted, post(conf, "/api/v2/spans"))
+	// the JSON encoding is not supported by the Jaeger receiver
+	assert.Equal(t, http.StatusBadRequest, post(conf, "/api/traces"))
+}
+
+func TestZipkinProto(t *testing.T) {
+	appendBytes := func(b []byte, num protowire.Number, v []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, v)
+	}
+	appendVarint := func(b []byte, num protowire.Number, v uint64) []byte {
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v)
+	}
+
+	var endpoint []byte
+	endpoint = appendBytes(endpoint, 1, []byte("frontend"))
+	endpoint = appendBytes(endpoint, 2, []byte{10, 0, 0, 1})
+	endpoint = appendVarint(endpoint, 4, 8080)
+	var tag []byte
+	tag = appendBytes(tag, 1, []byte("http.method"))
+	tag = appendBytes(tag, 2, []byte("POST"))
+	var annotation []byte
+	annotation = protowire.AppendTag(annotation, 1, protowire.Fixed64Type)
+	annotation = protowire.AppendFixed64(annotation, 1556604172355800)
+	annotation = appendBytes(annotation, 2, []byte("ws"))
+
+	var span []byte
+	span = appendBytes(span, 1, []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2})
+	span = appendBytes(span, 2, []byte{0, 0, 0, 0, 0, 0, 0, 3})
+	span = appendBytes(span, 3, []byte{0, 0, 0, 0, 0, 0, 0, 4})
+	span = appendVarint(span, 4, 2)
+	span = appendBytes(span, 5, []byte("post /orders"))
+	span = protowire.AppendTag(span, 6, protowire.Fixed64Type)
+	span = protowire.AppendFixed64(span, 1556604172355737)
+	span = appendVarint(span, 7, 1431)
+	span = appendBytes(span, 8, endpoint)
+	span = appendBytes(span, 10, annotation)
+	span = appendBytes(span, 11, tag)
+	span = appendVarint(span, 12, 1)
+	payload := appendBytes(nil, 1, span)
+
+	spans, err := decodeZipkinProto(payload)
+	require.NoError(t, err)
+	assert.Equal(t, []zipkinSpan{{
+		TraceID:       "00000000000000010000000000000002",
+		ParentID:      "0000000000000003",
+		ID:            "0000000000000004",
+		Kind:          "SERVER",
+		Name:          "post /orders",
+		Timestamp:     1556604172355737,
+		Duration:      1431,
+		LocalEndpoint: &zipkinEndpoint{ServiceName: "frontend", IPv4: "10.0.0.1", Port: 8080},
+		Annotations:   []zipkinAnnotation{{Timestamp: 1556604172355800, Value: "ws"}},
+		Tags:          map[string]string{"http.method": "POST"},
+		Debug:         true,
+	}}, spans)
+
+	_, err = decodeZipkinProto(payload[:len(payload)-1])
+	assert.Error(t, err)
+}
+
+func TestHexIDs(t *testing.T) {
+	traceID, err := hexTraceID("1")
+	require.NoError(t, err)
+	assert.Equal(t, [16]byte{15: 1}, traceID)
+	traceID, err = hexTraceID("10000000000000002")
+	require.NoError(t, err)
+	assert.Equal(t, [16]byte{7: 1, 15: 2}, traceID)
+	_, err = hexTraceID("")
+	assert.Error(t, err)
+	_, err = hexTraceID(strings.Repeat("1", 33))
+	assert.Error(t, err)
+
+	spanID, err := hexSpanID("ff")
+	require.NoError(t, err)
+	assert.Equal(t, [8]byte{7: 0xff}, spanID)
+	_, err = hexSpanID(strings.Repeat("1", 17))
+	assert.Error(t, err)
+}
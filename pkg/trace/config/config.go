@@ -8,6 +8,7 @@ package config
 import (
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"net/url"
@@ -67,6 +68,84 @@ type OTLP struct {
 	UsePreviewHostnameLogic bool `mapstructure:"-"`
 }
 
+// The types of the tail sampling policies.
+const (
+	// TailSamplingLatency keeps the traces lasting at least the Latency of the policy.
+	TailSamplingLatency = "latency"
+	// TailSamplingError keeps the traces with an error on any of their spans.
+	TailSamplingError = "error"
+	// TailSamplingTag keeps the traces with a span tagged with the Key and the Value of the policy.
+	TailSamplingTag = "tag"
+	// TailSamplingServiceRate keeps the Rate of the traces going through the Service of the policy.
+	TailSamplingServiceRate = "service_rate"
+)
+
+// TailSamplingConfig holds the configuration for the tail-based sampling of the traces.
+type TailSamplingConfig struct {
+	// Enabled reports whether the chunks are buffered to sample their traces once complete.
+	Enabled bool `mapstructure:"enabled"`
+
+	// DecisionWait is the time waited after receiving the first chunk of a trace to sample it.
+	DecisionWait time.Duration `mapstructure:"decision_wait"`
+
+	// MaxBufferBytes caps the size of the buffered chunks. The oldest traces are sampled early
+	// when it is reached.
+	MaxBufferBytes int `mapstructure:"max_buffer_bytes"`
+
+	// Policies keep the traces matching any of them, in addition to the ones kept by the other samplers.
+	Policies []TailSamplingPolicy `mapstructure:"policies"`
+}
+
+// TailSamplingPolicy is a rule keeping the complete traces matching it.
+type TailSamplingPolicy struct {
+	// Name identifies the policy in the telemetry. It defaults to the type of the policy.
+	Name string `mapstructure:"name"`
+
+	// Type is the type of the policy: latency, error, tag or service_rate.
+	Type string `mapstructure:"type"`
+
+	// Latency is the minimum duration of the traces kept by a latency policy.
+	Latency time.Duration `mapstructure:"latency"`
+
+	// Key and Value are the tag of the spans matched by a tag policy. Any value matches when
+	// Value is empty.
+	Key   string `mapstructure:"key"`
+	Value string `mapstructure:"value"`
+
+	// Service and Rate are the service of the spans matched by a service_rate policy and the rate
+	// of their traces to keep.
+	Service string  `mapstructure:"service"`
+	Rate    float64 `mapstructure:"rate"`
+}
+
+// Validate returns an error if the policy p is invalid, and sets its default name.
+func (p *TailSamplingPolicy) Validate() error {
+	switch p.Type {
+	case TailSamplingLatency:
+		if p.Latency <= 0 {
+			return errors.New("latency must be positive")
+		}
+	case TailSamplingError:
+	case TailSamplingTag:
+		if p.Key == "" {
+			return errors.New("key is required")
+		}
+	case TailSamplingServiceRate:
+		if p.Service == "" {
+			return errors.New("service is required")
+		}
+		if p.Rate < 0 || p.Rate > 1 {
+			return errors.New("rate must be between 0 and 1")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", p.Type)
+	}
+	if p.Name == "" {
+		p.Name = p.Type
+	}
+	return nil
+}
+
 // ObfuscationConfig holds the configuration for obfuscating sensitive data
 // for various span types.
 type ObfuscationConfig struct {
@@ -223,6 +302,65 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// The actions of the filter rules.
+const (
+	// FilterRuleDrop drops the traces matching the rule.
+	FilterRuleDrop = "drop"
+	// FilterRuleKeep keeps the traces matching the rule, bypassing the samplers. The traces
+	// dropped by the user in the tracer (negative sampling priority) are not kept.
+	FilterRuleKeep = "keep"
+)
+
+// FilterRule specifies a rule dropping or keeping the traces whose root span, or any span
+// when AnySpan is set, matches all of its conditions. The rules are evaluated before sampling,
+// in order, the first matching one applying.
+type FilterRule struct {
+	// Name identifies the rule in the telemetry and the info output. It is required.
+	Name string `mapstructure:"name"`
+
+	// Action is the action applied to the matching traces: drop or keep.
+	Action string `mapstructure:"action"`
+
+	// Service and Operation are regexp patterns which must match the whole service and
+	// operation name of the span.
+	Service   string `mapstructure:"service"`
+	Operation string `mapstructure:"operation"`
+
+	// Tags is a list of "key" or "key:pattern" strings. The span must have all the keys, with
+	// values matching the whole regexp patterns.
+	Tags []string `mapstructure:"tags"`
+
+	// MinDuration and MaxDuration bound the duration of the span, MaxDuration being excluded.
+	MinDuration time.Duration `mapstructure:"min_duration"`
+	MaxDuration time.Duration `mapstructure:"max_duration"`
+
+	// HTTPStatusCodes is a list of HTTP status codes ("404") or classes ("5xx"), one of which
+	// must match the http.status_code tag of the span.
+	HTTPStatusCodes []string `mapstructure:"http_status_codes"`
+
+	// AnySpan makes the rule match the traces with any span matching its conditions, instead
+	// of their root span only.
+	AnySpan bool `mapstructure:"any_span"`
+}
+
+const (
+	// MaxStatsTagDimensions is the maximum number of span tags used as stats aggregation dimensions.
+	MaxStatsTagDimensions = 10
+	// DefaultStatsTagCardinality is the default maximum number of values of a stats tag dimension.
+	DefaultStatsTagCardinality = 100
+)
+
+// StatsTagDimension specifies a span tag by which the stats are aggregated, on top of the
+// service, name, resource, type, status code and synthetics dimensions.
+type StatsTagDimension struct {
+	// Tag is the key of the span tag.
+	Tag string `mapstructure:"tag"`
+
+	// MaxCardinality is the maximum number of distinct values of the tag in a stats bucket.
+	// The spans with other values are aggregated under a single overflow value.
+	MaxCardinality int `mapstructure:"max_cardinality"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -307,6 +445,8 @@ type AgentConfig struct {
 	// Concentrator
 	BucketInterval   time.Duration // the size of our pre-aggregation per bucket
 	ExtraAggregators []string
+	// StatsTagDimensions holds the span tags used as extra stats aggregation dimensions.
+	StatsTagDimensions []StatsTagDimension
 
 	// Sampler configuration
 	ExtraSampleRate float64
@@ -321,6 +461,9 @@ type AgentConfig struct {
 	RareSamplerCooldownPeriod time.Duration
 	RareSamplerCardinality    int
 
+	// TailSampling holds the configuration for the tail-based sampling.
+	TailSampling TailSamplingConfig
+
 	// Receiver
 	ReceiverHost    string
 	ReceiverPort    int
@@ -386,9 +529,17 @@ type AgentConfig struct {
 	// RejectTags specifies a list of tags which must be absent on the root span in order for a trace to be accepted.
 	RejectTags []*Tag
 
+	// FilterRules specifies a list of rules dropping or keeping the traces based on their spans.
+	FilterRules []*FilterRule
+
 	// OTLPReceiver holds the configuration for OpenTelemetry receiver.
 	OTLPReceiver *OTLP
 
+	// ZipkinReceiverEnabled reports whether the Zipkin v2 spans are accepted on /api/v2/spans.
+	ZipkinReceiverEnabled bool
+	// JaegerReceiverEnabled reports whether the Jaeger spans are accepted on /api/traces.
+	JaegerReceiverEnabled bool
+
 	// ProfilingProxy specifies settings for the profiling proxy.
 	ProfilingProxy ProfilingProxyConfig
 
@@ -455,6 +606,11 @@ func New() *AgentConfig {
 		RareSamplerCooldownPeriod: 5 * time.Minute,
 		RareSamplerCardinality:    200,
 
+		TailSampling: TailSamplingConfig{
+			DecisionWait:   10 * time.Second,
+			MaxBufferBytes: 50 * 1024 * 1024, // 50MB
+		},
+
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
 		MaxRequestBytes:        50 * 1024 * 1024, // 50MB
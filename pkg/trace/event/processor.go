@@ -8,6 +8,7 @@ package event
 import (
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 )
 
 // Processor is responsible for all the logic surrounding extraction and sampling of APM events from processed traces.
@@ -54,6 +55,7 @@ func (p *Processor) Process(root *pb.Span, t *pb.TraceChunk) (numEvents, numExtr
 	clientSampleRate := sampler.GetClientRate(root)
 	preSampleRate := sampler.GetPreSampleRate(root)
 	priority := sampler.SamplingPriority(t.Priority)
+	traceIDHigh, hasTraceIDHigh := traceutil.GetTraceIDHigh(root)
 	events := []*pb.Span{}
 
 	for _, span := range t.Spans {
@@ -61,7 +63,11 @@ func (p *Processor) Process(root *pb.Span, t *pb.TraceChunk) (numEvents, numExtr
 		if !ok {
 			continue
 		}
-		if !sampler.SampleByRate(span.TraceID, extractionRate) {
+		if _, ok := traceutil.GetTraceIDHigh(span); !ok && hasTraceIDHigh {
+			// events are sampled, and sent when the trace is dropped, with their full trace ID
+			traceutil.SetTraceIDHigh(span, traceIDHigh)
+		}
+		if !sampler.SampleSpanByRate(span, extractionRate) {
 			continue
 		}
 
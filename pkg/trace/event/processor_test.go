@@ -12,6 +12,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -142,6 +143,25 @@ func TestProcessor(t *testing.T) {
 	}
 }
 
+func TestProcessorTraceIDHigh(t *testing.T) {
+	p := newProcessor([]Extractor{&MockExtractor{Rate: 1}}, &MockEventSampler{Rate: 1})
+	testSpans := createTestSpans("test", "test")
+	testChunk := testutil.TraceChunkWithSpans(testSpans)
+	root := testSpans[0]
+	traceutil.SetTraceIDHigh(root, 0x5af7183fb1d4cf5f)
+	testChunk.DroppedTrace = true
+
+	p.Start()
+	numEvents, _ := p.Process(root, testChunk)
+	p.Stop()
+
+	// the events are sent without their trace, with its full ID
+	assert.EqualValues(t, len(testSpans), numEvents)
+	for _, event := range testChunk.Spans {
+		assert.Equal(t, "5af7183fb1d4cf5f", event.Meta[traceutil.TraceIDHighKey])
+	}
+}
+
 type MockExtractor struct {
 	Rate float64
 }
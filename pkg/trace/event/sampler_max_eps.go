@@ -77,7 +77,7 @@ func (s *maxEPSSampler) Sample(event *pb.Span) (sampled bool, rate float64) {
 	if currentEPS > s.maxEPS {
 		rate = s.maxEPS / currentEPS
 	}
-	sampled = sampler.SampleByRate(event.TraceID, rate)
+	sampled = sampler.SampleSpanByRate(event, rate)
 	return
 }
 
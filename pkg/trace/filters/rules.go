@@ -0,0 +1,285 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+	"go.uber.org/atomic"
+)
+
+const tagStatusCode = "http.status_code"
+
+// Action is the outcome of the filter rules for a trace chunk.
+type Action int
+
+const (
+	// ActionNone means that no rule matched the chunk, which is sampled as usual.
+	ActionNone Action = iota
+	// ActionDrop means that the chunk must be dropped.
+	ActionDrop
+	// ActionKeep means that the chunk must be kept, bypassing the samplers.
+	ActionKeep
+)
+
+// RuleFilter drops or keeps the trace chunks matching its rules, evaluated in order.
+type RuleFilter struct {
+	rules []*filterRule
+
+	exit    chan struct{}
+	stopped chan struct{}
+}
+
+// filterRule is a compiled config.FilterRule.
+type filterRule struct {
+	name        string
+	action      Action
+	service     *regexp.Regexp
+	operation   *regexp.Regexp
+	tags        []tagMatcher
+	minDuration time.Duration
+	maxDuration time.Duration
+	statusCodes []string
+	anySpan     bool
+
+	// traces and spans count the chunks matched by the rule, and their spans, since the start.
+	traces *atomic.Int64
+	spans  *atomic.Int64
+	// reportedTraces and reportedSpans hold the counts reported by the last report.
+	reportedTraces int64
+	reportedSpans  int64
+}
+
+// tagMatcher matches the spans having the tag key, with a value matching value if not nil.
+type tagMatcher struct {
+	key   string
+	value *regexp.Regexp
+}
+
+// NewRuleFilter returns a RuleFilter with as many rules as possible compiled from the given ones.
+func NewRuleFilter(rules []*config.FilterRule) *RuleFilter {
+	f := &RuleFilter{
+		exit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	for i, r := range rules {
+		rule, err := compileFilterRule(r)
+		if err != nil {
+			log.Errorf("Invalid filter rule #%d %q: %v", i, r.Name, err)
+			continue
+		}
+		f.rules = append(f.rules, rule)
+	}
+	return f
+}
+
+func compileFilterRule(r *config.FilterRule) (*filterRule, error) {
+	rule := &filterRule{
+		name:        r.Name,
+		minDuration: r.MinDuration,
+		maxDuration: r.MaxDuration,
+		anySpan:     r.AnySpan,
+		traces:      atomic.NewInt64(0),
+		spans:       atomic.NewInt64(0),
+	}
+	switch r.Action {
+	case config.FilterRuleDrop:
+		rule.action = ActionDrop
+	case config.FilterRuleKeep:
+		rule.action = ActionKeep
+	default:
+		return nil, fmt.Errorf("unknown action %q", r.Action)
+	}
+	if rule.name == "" {
+		return nil, errors.New("name is required")
+	}
+	var err error
+	if rule.service, err = compileFullMatch(r.Service); err != nil {
+		return nil, err
+	}
+	if rule.operation, err = compileFullMatch(r.Operation); err != nil {
+		return nil, err
+	}
+	for _, tag := range r.Tags {
+		k, v := splitTag(tag)
+		m := tagMatcher{key: k}
+		if m.value, err = compileFullMatch(v); err != nil {
+			return nil, err
+		}
+		rule.tags = append(rule.tags, m)
+	}
+	for _, code := range r.HTTPStatusCodes {
+		code = strings.ToLower(code)
+		if len(code) != 3 || code[0] < '1' || code[0] > '5' {
+			return nil, fmt.Errorf("invalid HTTP status code %q", code)
+		}
+		if code[1:] != "xx" {
+			if _, err := strconv.Atoi(code); err != nil {
+				return nil, fmt.Errorf("invalid HTTP status code %q", code)
+			}
+		}
+		rule.statusCodes = append(rule.statusCodes, code)
+	}
+	return rule, nil
+}
+
+// compileFullMatch compiles the regexp pattern p to match whole strings, returning nil when p is empty.
+func compileFullMatch(p string) (*regexp.Regexp, error) {
+	if p == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + p + ")$")
+}
+
+// splitTag splits a "key:value" tag, the value being empty for a "key" tag.
+func splitTag(tag string) (k, v string) {
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		return strings.TrimSpace(tag[:i]), strings.TrimSpace(tag[i+1:])
+	}
+	return strings.TrimSpace(tag), ""
+}
+
+// Match returns the action of the first rule matching the chunk, whose root span is root,
+// and counts the chunk as matched by this rule.
+func (f *RuleFilter) Match(chunk *pb.TraceChunk, root *pb.Span) Action {
+	for _, r := range f.rules {
+		if r.matchesChunk(chunk, root) {
+			r.traces.Inc()
+			r.spans.Add(int64(len(chunk.Spans)))
+			return r.action
+		}
+	}
+	return ActionNone
+}
+
+func (r *filterRule) matchesChunk(chunk *pb.TraceChunk, root *pb.Span) bool {
+	if !r.anySpan {
+		return r.matches(root)
+	}
+	for _, span := range chunk.Spans {
+		if r.matches(span) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the span matches all the conditions of the rule.
+func (r *filterRule) matches(span *pb.Span) bool {
+	if r.service != nil && !r.service.MatchString(span.Service) {
+		return false
+	}
+	if r.operation != nil && !r.operation.MatchString(span.Name) {
+		return false
+	}
+	for _, tag := range r.tags {
+		v, ok := span.Meta[tag.key]
+		if !ok || (tag.value != nil && !tag.value.MatchString(v)) {
+			return false
+		}
+	}
+	if r.minDuration > 0 && time.Duration(span.Duration) < r.minDuration {
+		return false
+	}
+	if r.maxDuration > 0 && time.Duration(span.Duration) >= r.maxDuration {
+		return false
+	}
+	if len(r.statusCodes) > 0 && !r.matchesStatusCode(span) {
+		return false
+	}
+	return true
+}
+
+func (r *filterRule) matchesStatusCode(span *pb.Span) bool {
+	code, ok := span.Meta[tagStatusCode]
+	if !ok {
+		v, ok := span.Metrics[tagStatusCode]
+		if !ok {
+			return false
+		}
+		code = strconv.Itoa(int(v))
+	}
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range r.statusCodes {
+		if c == code || (c[1:] == "xx" && c[0] == code[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Start starts reporting the number of chunks matched by the rules.
+func (f *RuleFilter) Start() {
+	go func() {
+		defer watchdog.LogOnPanic()
+		statsTicker := time.NewTicker(10 * time.Second)
+		defer statsTicker.Stop()
+		for {
+			select {
+			case <-statsTicker.C:
+				f.report()
+			case <-f.exit:
+				close(f.stopped)
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops reporting, after a last report.
+func (f *RuleFilter) Stop() {
+	close(f.exit)
+	<-f.stopped
+	f.report()
+}
+
+// report reports the chunks matched by the rules since the last report in the telemetry, and
+// their totals in the info.
+func (f *RuleFilter) report() {
+	if len(f.rules) == 0 {
+		return
+	}
+	stats := make([]info.FilterRuleStats, 0, len(f.rules))
+	for _, r := range f.rules {
+		traces, spans := r.traces.Load(), r.spans.Load()
+		tags := []string{"rule:" + r.name, "action:" + r.action.String()}
+		metrics.Count("datadog.trace_agent.filter_rules.traces", traces-r.reportedTraces, tags, 1)
+		metrics.Count("datadog.trace_agent.filter_rules.spans", spans-r.reportedSpans, tags, 1)
+		r.reportedTraces, r.reportedSpans = traces, spans
+		stats = append(stats, info.FilterRuleStats{
+			Name:   r.name,
+			Action: r.action.String(),
+			Traces: traces,
+			Spans:  spans,
+		})
+	}
+	info.UpdateFilterRules(stats)
+}
+
+// String returns the name of the action as set in the configuration.
+func (a Action) String() string {
+	switch a {
+	case ActionDrop:
+		return config.FilterRuleDrop
+	case ActionKeep:
+		return config.FilterRuleKeep
+	default:
+		return "none"
+	}
+}
@@ -0,0 +1,193 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleFilterMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.FilterRule
+		span *pb.Span
+		want bool
+	}{
+		{
+			name: "service",
+			rule: config.FilterRule{Service: "web-.*"},
+			span: &pb.Span{Service: "web-store"},
+			want: true,
+		},
+		{
+			name: "service-partial",
+			rule: config.FilterRule{Service: "web"},
+			span: &pb.Span{Service: "web-store"},
+			want: false,
+		},
+		{
+			name: "operation",
+			rule: config.FilterRule{Operation: "http.request"},
+			span: &pb.Span{Name: "http.request"},
+			want: true,
+		},
+		{
+			name: "tag-key",
+			rule: config.FilterRule{Tags: []string{"customer_tier"}},
+			span: &pb.Span{Meta: map[string]string{"customer_tier": "silver"}},
+			want: true,
+		},
+		{
+			name: "tag-value",
+			rule: config.FilterRule{Tags: []string{"http.url:.*/health"}},
+			span: &pb.Span{Meta: map[string]string{"http.url": "http://localhost:8080/health"}},
+			want: true,
+		},
+		{
+			name: "tag-value-mismatch",
+			rule: config.FilterRule{Tags: []string{"http.url:.*/health"}},
+			span: &pb.Span{Meta: map[string]string{"http.url": "http://localhost:8080/health/details"}},
+			want: false,
+		},
+		{
+			name: "tags-all",
+			rule: config.FilterRule{Tags: []string{"customer_tier:gold", "region"}},
+			span: &pb.Span{Meta: map[string]string{"customer_tier": "gold"}},
+			want: false,
+		},
+		{
+			name: "min-duration",
+			rule: config.FilterRule{MinDuration: time.Second},
+			span: &pb.Span{Duration: int64(time.Second)},
+			want: true,
+		},
+		{
+			name: "max-duration",
+			rule: config.FilterRule{MaxDuration: time.Second},
+			span: &pb.Span{Duration: int64(time.Second)},
+			want: false,
+		},
+		{
+			name: "status-code",
+			rule: config.FilterRule{HTTPStatusCodes: []string{"404"}},
+			span: &pb.Span{Meta: map[string]string{"http.status_code": "404"}},
+			want: true,
+		},
+		{
+			name: "status-code-class",
+			rule: config.FilterRule{HTTPStatusCodes: []string{"404", "5XX"}},
+			span: &pb.Span{Meta: map[string]string{"http.status_code": "503"}},
+			want: true,
+		},
+		{
+			name: "status-code-metric",
+			rule: config.FilterRule{HTTPStatusCodes: []string{"2xx"}},
+			span: &pb.Span{Metrics: map[string]float64{"http.status_code": 200}},
+			want: true,
+		},
+		{
+			name: "status-code-missing",
+			rule: config.FilterRule{HTTPStatusCodes: []string{"2xx"}},
+			span: &pb.Span{},
+			want: false,
+		},
+		{
+			name: "all-conditions",
+			rule: config.FilterRule{Service: "web", Operation: "http.request", Tags: []string{"http.method:GET"}, HTTPStatusCodes: []string{"200"}},
+			span: &pb.Span{Service: "web", Name: "http.request", Meta: map[string]string{"http.method": "GET", "http.status_code": "200"}},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Name = tt.name
+			tt.rule.Action = config.FilterRuleDrop
+			f := NewRuleFilter([]*config.FilterRule{&tt.rule})
+			assert.Len(t, f.rules, 1)
+			chunk := &pb.TraceChunk{Spans: []*pb.Span{tt.span}}
+			want := ActionNone
+			if tt.want {
+				want = ActionDrop
+			}
+			assert.Equal(t, want, f.Match(chunk, tt.span))
+		})
+	}
+}
+
+func TestRuleFilterAnySpan(t *testing.T) {
+	root := &pb.Span{SpanID: 1, Service: "web"}
+	child := &pb.Span{SpanID: 2, ParentID: 1, Service: "db", Meta: map[string]string{"customer_tier": "gold"}}
+	chunk := &pb.TraceChunk{Spans: []*pb.Span{root, child}}
+
+	f := NewRuleFilter([]*config.FilterRule{{Name: "gold", Action: config.FilterRuleKeep, Tags: []string{"customer_tier:gold"}}})
+	assert.Equal(t, ActionNone, f.Match(chunk, root))
+
+	f = NewRuleFilter([]*config.FilterRule{{Name: "gold", Action: config.FilterRuleKeep, Tags: []string{"customer_tier:gold"}, AnySpan: true}})
+	assert.Equal(t, ActionKeep, f.Match(chunk, root))
+}
+
+func TestRuleFilterOrder(t *testing.T) {
+	f := NewRuleFilter([]*config.FilterRule{
+		{Name: "gold", Action: config.FilterRuleKeep, Tags: []string{"customer_tier:gold"}},
+		{Name: "health", Action: config.FilterRuleDrop, Tags: []string{"http.url:.*/health"}},
+	})
+	for _, tt := range []struct {
+		meta map[string]string
+		want Action
+	}{
+		{map[string]string{"customer_tier": "gold", "http.url": "/health"}, ActionKeep},
+		{map[string]string{"customer_tier": "silver", "http.url": "/health"}, ActionDrop},
+		{map[string]string{"customer_tier": "silver", "http.url": "/checkout"}, ActionNone},
+	} {
+		span := &pb.Span{Meta: tt.meta}
+		assert.Equal(t, tt.want, f.Match(&pb.TraceChunk{Spans: []*pb.Span{span}}, span))
+	}
+	assert.EqualValues(t, 1, f.rules[0].traces.Load())
+	assert.EqualValues(t, 1, f.rules[1].traces.Load())
+}
+
+func TestRuleFilterInvalid(t *testing.T) {
+	f := NewRuleFilter([]*config.FilterRule{
+		{Name: "no-action"},
+		{Name: "unknown-action", Action: "sample"},
+		{Action: config.FilterRuleDrop},
+		{Name: "service", Action: config.FilterRuleDrop, Service: "("},
+		{Name: "tag", Action: config.FilterRuleDrop, Tags: []string{"k:("}},
+		{Name: "status-code", Action: config.FilterRuleDrop, HTTPStatusCodes: []string{"6xx"}},
+		{Name: "status-code-length", Action: config.FilterRuleDrop, HTTPStatusCodes: []string{"40"}},
+		{Name: "status-code-digits", Action: config.FilterRuleDrop, HTTPStatusCodes: []string{"4x4"}},
+		{Name: "valid", Action: config.FilterRuleDrop, HTTPStatusCodes: []string{"4xx"}},
+	})
+	assert.Len(t, f.rules, 1)
+	assert.Equal(t, "valid", f.rules[0].name)
+}
+
+func TestRuleFilterReport(t *testing.T) {
+	f := NewRuleFilter([]*config.FilterRule{
+		{Name: "health", Action: config.FilterRuleDrop, Tags: []string{"http.url:.*/health"}},
+		{Name: "gold", Action: config.FilterRuleKeep, Tags: []string{"customer_tier:gold"}},
+	})
+	span := &pb.Span{Meta: map[string]string{"http.url": "/health"}}
+	chunk := &pb.TraceChunk{Spans: []*pb.Span{span, {}}}
+	f.Match(chunk, span)
+	f.Match(chunk, span)
+	f.report()
+	f.Match(chunk, span)
+	assert.EqualValues(t, 2, f.rules[0].reportedTraces)
+	assert.EqualValues(t, 4, f.rules[0].reportedSpans)
+	f.Start()
+	f.Stop()
+
+	assert.EqualValues(t, 3, f.rules[0].reportedTraces)
+	assert.EqualValues(t, 6, f.rules[0].reportedSpans)
+	assert.EqualValues(t, 0, f.rules[1].reportedTraces)
+}
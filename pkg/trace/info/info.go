@@ -37,6 +37,7 @@ var (
 	watchdogInfo     watchdog.Info
 	rateByService    map[string]float64
 	rateLimiterStats RateLimiterStats
+	filterRules      []FilterRuleStats
 	start            = time.Now()
 	once             sync.Once
 	infoTmpl         *template.Template
@@ -78,6 +79,14 @@ const (
   WARNING: Rate-limiter keep percentage: {{percent .Status.RateLimiter.TargetRate}} %
   {{end}}
 
+  {{if .Status.FilterRules}}
+  --- Filter rules ---
+
+  {{ range $i, $r := .Status.FilterRules }}
+  Rule '{{ $r.Name }}' ({{ $r.Action }}): {{ $r.Traces }} traces, {{ $r.Spans }} spans
+  {{end}}
+
+  {{end}}
   --- Writer stats (1 min) ---
 
   Traces: {{.Status.TraceWriter.Payloads}} payloads, {{.Status.TraceWriter.Traces}} traces, {{if gt .Status.TraceWriter.Events.Load 0}}{{.Status.TraceWriter.Events.Load}} events, {{end}}{{.Status.TraceWriter.Bytes}} bytes
@@ -188,6 +197,31 @@ func publishRateLimiterStats() interface{} {
 	return rateLimiterStats
 }
 
+// FilterRuleStats contains the number of traces matched by a filter rule since the start.
+type FilterRuleStats struct {
+	// Name is the name of the rule.
+	Name string
+	// Action is the action of the rule: drop or keep.
+	Action string
+	// Traces is the number of trace chunks matched by the rule.
+	Traces int64
+	// Spans is the number of spans of the trace chunks matched by the rule.
+	Spans int64
+}
+
+// UpdateFilterRules updates internal stats about the filter rules.
+func UpdateFilterRules(frs []FilterRuleStats) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	filterRules = frs
+}
+
+func publishFilterRules() interface{} {
+	infoMu.RLock()
+	defer infoMu.RUnlock()
+	return filterRules
+}
+
 func publishUptime() interface{} {
 	return int(time.Since(start) / time.Second)
 }
@@ -219,6 +253,7 @@ func InitInfo(conf *config.AgentConfig) error {
 		expvar.Publish("ratebyservice", expvar.Func(publishRateByService))
 		expvar.Publish("watchdog", expvar.Func(publishWatchdogInfo))
 		expvar.Publish("ratelimiter", expvar.Func(publishRateLimiterStats))
+		expvar.Publish("filter_rules", expvar.Func(publishFilterRules))
 
 		// copy the config to ensure we don't expose sensitive data such as API keys
 		c := *conf
@@ -276,6 +311,7 @@ type StatusInfo struct {
 	StatsWriter   StatsWriterInfo    `json:"stats_writer"`
 	Watchdog      watchdog.Info      `json:"watchdog"`
 	RateLimiter   RateLimiterStats   `json:"ratelimiter"`
+	FilterRules   []FilterRuleStats  `json:"filter_rules"`
 	Config        config.AgentConfig `json:"config"`
 }
 
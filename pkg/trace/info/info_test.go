@@ -417,6 +417,7 @@ func TestPublishReceiverStats(t *testing.T) {
 				atom(10),
 				atom(11),
 				atom(12),
+				atom(13),
 			},
 			TracesFiltered:     atom(4),
 			TracesPriorityNone: atom(5),
@@ -470,6 +471,7 @@ func TestPublishReceiverStats(t *testing.T) {
 				"InvalidStartDate":      10.0,
 				"InvalidDuration":       11.0,
 				"InvalidHTTPStatusCode": 12.0,
+				"InvalidTraceIDHigh":    13.0,
 			},
 			"SpansReceived": 10.0,
 			"TracerVersion": "",
@@ -515,3 +517,18 @@ func TestPublishRateLimiterStats(t *testing.T) {
 			"RecentTracesDropped": 4.0,
 		})
 }
+
+func TestPublishFilterRules(t *testing.T) {
+	filterRules = []FilterRuleStats{{Name: "health", Action: "drop", Traces: 1, Spans: 2}}
+	defer func() { filterRules = nil }()
+
+	testExpvarPublish(t, publishFilterRules,
+		[]interface{}{
+			map[string]interface{}{
+				"Name":   "health",
+				"Action": "drop",
+				"Traces": 1.0,
+				"Spans":  2.0,
+			},
+		})
+}
@@ -268,6 +268,8 @@ type SpansMalformed struct {
 	InvalidDuration atomic.Int64
 	// InvalidHTTPStatusCode is when a span's metadata contains an invalid http status code
 	InvalidHTTPStatusCode atomic.Int64
+	// InvalidTraceIDHigh is when a span's metadata contains invalid upper bits of a 128-bit trace ID
+	InvalidTraceIDHigh atomic.Int64
 }
 
 // tagValues converts SpansMalformed into a map representation with keys matching standardized names for all reasons
@@ -285,6 +287,7 @@ func (s *SpansMalformed) tagValues() map[string]int64 {
 		"invalid_start_date":       s.InvalidStartDate.Load(),
 		"invalid_duration":         s.InvalidDuration.Load(),
 		"invalid_http_status_code": s.InvalidHTTPStatusCode.Load(),
+		"invalid_trace_id_high":    s.InvalidTraceIDHigh.Load(),
 	}
 }
 
@@ -408,6 +411,7 @@ func (s *Stats) update(recent *Stats) {
 	s.SpansMalformed.InvalidStartDate.Add(recent.SpansMalformed.InvalidStartDate.Load())
 	s.SpansMalformed.InvalidDuration.Add(recent.SpansMalformed.InvalidDuration.Load())
 	s.SpansMalformed.InvalidHTTPStatusCode.Add(recent.SpansMalformed.InvalidHTTPStatusCode.Load())
+	s.SpansMalformed.InvalidTraceIDHigh.Add(recent.SpansMalformed.InvalidTraceIDHigh.Load())
 	s.TracesFiltered.Add(recent.TracesFiltered.Load())
 	s.TracesPriorityNone.Add(recent.TracesPriorityNone.Load())
 	s.ClientDroppedP0Traces.Add(recent.ClientDroppedP0Traces.Load())
@@ -445,6 +449,7 @@ func (s *Stats) reset() {
 	s.SpansMalformed.InvalidStartDate.Store(0)
 	s.SpansMalformed.InvalidDuration.Store(0)
 	s.SpansMalformed.InvalidHTTPStatusCode.Store(0)
+	s.SpansMalformed.InvalidTraceIDHigh.Store(0)
 	s.TracesFiltered.Store(0)
 	s.TracesPriorityNone.Store(0)
 	s.ClientDroppedP0Traces.Store(0)
@@ -58,6 +58,7 @@ func TestSpansMalformed(t *testing.T) {
 			"service_truncate":         0,
 			"invalid_start_date":       0,
 			"invalid_http_status_code": 0,
+			"invalid_trace_id_high":    0,
 			"invalid_duration":         0,
 			"duplicate_span_id":        0,
 			"service_empty":            1,
@@ -243,6 +244,7 @@ func TestReceiverStats(t *testing.T) {
 		stats.SpansMalformed.InvalidStartDate.Store(10)
 		stats.SpansMalformed.InvalidDuration.Store(11)
 		stats.SpansMalformed.InvalidHTTPStatusCode.Store(12)
+		stats.SpansMalformed.InvalidTraceIDHigh.Store(13)
 		return &ReceiverStats{
 			Stats: map[Tags]*TagStats{
 				tags: {
@@ -255,7 +257,7 @@ func TestReceiverStats(t *testing.T) {
 
 	t.Run("Publish", func(t *testing.T) {
 		testStats().Publish()
-		assert.EqualValues(t, statsclient.counts.Load(), 39)
+		assert.EqualValues(t, statsclient.counts.Load(), 40)
 	})
 
 	t.Run("reset", func(t *testing.T) {
@@ -10,6 +10,7 @@ import (
 	"math"
 
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 )
 
 const (
@@ -73,6 +74,17 @@ func SampleByRate(traceID uint64, rate float64) bool {
 	return true
 }
 
+// SampleSpanByRate returns whether to keep the trace of the span s, based on its trace ID and a sampling rate.
+// The upper bits of the 128-bit trace IDs are mixed with the lower ones, so that the traces sharing their lower
+// bits get their own decisions, while the 64-bit trace IDs get the same decisions as with SampleByRate.
+func SampleSpanByRate(s *pb.Span, rate float64) bool {
+	traceID := s.TraceID
+	if high, ok := traceutil.GetTraceIDHigh(s); ok {
+		traceID ^= high * samplerHasher
+	}
+	return SampleByRate(traceID, rate)
+}
+
 // GetSamplingPriority returns the value of the sampling priority metric set on this span and a boolean indicating if
 // such a metric was actually found or not.
 func GetSamplingPriority(t *pb.TraceChunk) (SamplingPriority, bool) {
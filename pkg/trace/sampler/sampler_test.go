@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleSpanByRate(t *testing.T) {
+	t.Run("64-bit", func(t *testing.T) {
+		for i := 0; i < 1000; i++ {
+			span := &pb.Span{TraceID: rand.Uint64()}
+			assert.Equal(t, SampleByRate(span.TraceID, 0.5), SampleSpanByRate(span, 0.5))
+		}
+	})
+
+	t.Run("128-bit", func(t *testing.T) {
+		// the traces sharing their lower bits are sampled independently
+		const n = 10000
+		traceID := rand.Uint64()
+		var kept int
+		for i := 0; i < n; i++ {
+			span := &pb.Span{TraceID: traceID}
+			traceutil.SetTraceIDHigh(span, rand.Uint64())
+			if SampleSpanByRate(span, 0.3) {
+				kept++
+			}
+			// the decisions are consistent for a trace
+			assert.Equal(t, SampleSpanByRate(span, 0.3), SampleSpanByRate(&pb.Span{TraceID: traceID, Meta: span.Meta}, 0.3))
+		}
+		assert.InDelta(t, 0.3, float64(kept)/n, 0.03)
+	})
+}
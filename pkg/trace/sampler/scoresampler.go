@@ -76,8 +76,7 @@ func (s *ScoreSampler) Sample(now time.Time, trace pb.Trace, root *pb.Span, env
 func (s *ScoreSampler) applySampleRate(root *pb.Span, rate float64) bool {
 	initialRate := GetGlobalRate(root)
 	newRate := initialRate * rate
-	traceID := root.TraceID
-	sampled := SampleByRate(traceID, newRate)
+	sampled := SampleSpanByRate(root, newRate)
 	if sampled {
 		s.countSample()
 		setMetric(root, s.samplingRateKey, rate)
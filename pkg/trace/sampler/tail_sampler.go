@@ -0,0 +1,315 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+	"go.uber.org/atomic"
+)
+
+const (
+	// tailSampledKey is the tag of the chunks dropped by the other samplers and kept by the tail
+	// sampler, set to the name of the policy keeping their trace, or to tailSampledByTrace when
+	// another chunk of their trace was kept.
+	tailSampledKey     = "_dd.tail_sampled"
+	tailSampledByTrace = "trace"
+	// tailTickPeriod is the maximum period at which the expired traces are sampled.
+	tailTickPeriod = time.Second
+)
+
+// TailChunk is a chunk buffered by the TailSampler, along with the outcome of the other samplers.
+type TailChunk struct {
+	// Chunk is the complete chunk.
+	Chunk *pb.TraceChunk
+	// Root is the root span of the chunk.
+	Root *pb.Span
+	// Sampled is the chunk to send: Chunk itself when it was kept, its analyzed spans when it was
+	// dropped, or nil when nothing is left to send. The TailSampler sets it to Chunk when it keeps
+	// the trace.
+	Sampled *pb.TraceChunk
+	// NumEvents is the number of analyzed spans of the chunk.
+	NumEvents int64
+	// Payload holds the fields of the payload of the chunk, without its chunks.
+	Payload *pb.TracerPayload
+}
+
+// tailTraceID is a full 128-bit trace ID.
+type tailTraceID struct {
+	high, low uint64
+}
+
+// tailTrace holds the buffered chunks of a trace.
+type tailTrace struct {
+	id     tailTraceID
+	expire time.Time
+	chunks []*TailChunk
+	size   int
+}
+
+// tailPolicy is a compiled TailSamplingPolicy.
+type tailPolicy struct {
+	config.TailSamplingPolicy
+	// kept counts the traces kept by the policy.
+	kept *atomic.Int64
+}
+
+// TailSampler buffers the chunks of the traces for a decision window, then samples the complete
+// traces: a trace is kept when any of its chunks was kept by the other samplers, or when it matches
+// any of the policies. All the chunks of the kept traces are sent, while only the analyzed spans of
+// the dropped chunks of the other traces are. The buffer is capped in size, the oldest traces being
+// sampled early when the cap is reached.
+type TailSampler struct {
+	wait     time.Duration
+	maxSize  int
+	policies []tailPolicy
+	// flush receives the sampled chunks, to be sent.
+	flush func([]*TailChunk)
+
+	mu     sync.Mutex
+	traces map[tailTraceID]*tailTrace
+	// queue holds the traces by expiration order.
+	queue []*tailTrace
+	size  int
+	// stopping is set once the TailSampler is stopped, the traces added afterwards being
+	// sampled right away.
+	stopping bool
+
+	kept    *atomic.Int64
+	dropped *atomic.Int64
+	evicted *atomic.Int64
+
+	exit    chan struct{}
+	stopped chan struct{}
+}
+
+// NewTailSampler returns a TailSampler sampling the traces with conf.TailSampling and calling
+// flush with the sampled chunks.
+func NewTailSampler(conf *config.AgentConfig, flush func([]*TailChunk)) *TailSampler {
+	s := &TailSampler{
+		wait:    conf.TailSampling.DecisionWait,
+		maxSize: conf.TailSampling.MaxBufferBytes,
+		flush:   flush,
+		traces:  make(map[tailTraceID]*tailTrace),
+		kept:    atomic.NewInt64(0),
+		dropped: atomic.NewInt64(0),
+		evicted: atomic.NewInt64(0),
+		exit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	for _, p := range conf.TailSampling.Policies {
+		s.policies = append(s.policies, tailPolicy{TailSamplingPolicy: p, kept: atomic.NewInt64(0)})
+	}
+	return s
+}
+
+// Start starts sampling the expired traces.
+func (s *TailSampler) Start() {
+	go func() {
+		defer watchdog.LogOnPanic()
+		tickPeriod := tailTickPeriod
+		if s.wait > 0 && s.wait < tickPeriod {
+			tickPeriod = s.wait
+		}
+		ticker := time.NewTicker(tickPeriod)
+		defer ticker.Stop()
+		statsTicker := time.NewTicker(10 * time.Second)
+		defer statsTicker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.sampleExpired(now)
+			case <-statsTicker.C:
+				s.report()
+			case <-s.exit:
+				close(s.stopped)
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops sampling the expired traces, and samples all the buffered ones. The traces added
+// after Stop are sampled right away, without waiting for their decision window.
+func (s *TailSampler) Stop() {
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+	close(s.exit)
+	<-s.stopped
+	s.Flush()
+	s.report()
+}
+
+// Flush samples all the buffered traces, without waiting for their decision window.
+func (s *TailSampler) Flush() {
+	var sampled []*TailChunk
+	s.mu.Lock()
+	for len(s.queue) > 0 {
+		sampled = append(sampled, s.sampleTrace(s.pop())...)
+	}
+	s.mu.Unlock()
+	if len(sampled) > 0 {
+		s.flush(sampled)
+	}
+}
+
+// Add buffers the chunks until their traces are sampled.
+func (s *TailSampler) Add(now time.Time, chunks []*TailChunk) {
+	var sampled []*TailChunk
+	s.mu.Lock()
+	for _, c := range chunks {
+		id := tailTraceID{low: c.Root.TraceID}
+		id.high, _ = traceutil.GetTraceIDHigh(c.Root)
+		t, ok := s.traces[id]
+		if !ok {
+			t = &tailTrace{id: id, expire: now.Add(s.wait)}
+			s.traces[id] = t
+			s.queue = append(s.queue, t)
+		}
+		size := c.Chunk.Msgsize()
+		t.chunks = append(t.chunks, c)
+		t.size += size
+		s.size += size
+	}
+	for s.size > s.maxSize && len(s.queue) > 0 {
+		// the buffer is full, sample the oldest traces early
+		s.evicted.Inc()
+		sampled = append(sampled, s.sampleTrace(s.pop())...)
+	}
+	for s.stopping && len(s.queue) > 0 {
+		// nothing samples the buffered traces anymore
+		sampled = append(sampled, s.sampleTrace(s.pop())...)
+	}
+	s.mu.Unlock()
+	if len(sampled) > 0 {
+		s.flush(sampled)
+	}
+}
+
+// sampleExpired samples the traces whose decision window is over.
+func (s *TailSampler) sampleExpired(now time.Time) {
+	var sampled []*TailChunk
+	s.mu.Lock()
+	for len(s.queue) > 0 && !s.queue[0].expire.After(now) {
+		sampled = append(sampled, s.sampleTrace(s.pop())...)
+	}
+	s.mu.Unlock()
+	if len(sampled) > 0 {
+		s.flush(sampled)
+	}
+}
+
+// pop removes the oldest trace from the buffer. s.mu must be held.
+func (s *TailSampler) pop() *tailTrace {
+	t := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	delete(s.traces, t.id)
+	s.size -= t.size
+	return t
+}
+
+// sampleTrace samples the trace t and returns its chunks, with their Sampled chunk set to the
+// complete one when it is kept.
+func (s *TailSampler) sampleTrace(t *tailTrace) []*TailChunk {
+	var reason string
+	for _, c := range t.chunks {
+		if c.Sampled == c.Chunk {
+			reason = tailSampledByTrace
+			break
+		}
+	}
+	if reason == "" {
+		for _, p := range s.policies {
+			if matches(p, t) {
+				p.kept.Inc()
+				reason = p.Name
+				break
+			}
+		}
+	}
+	if reason == "" {
+		s.dropped.Inc()
+		return t.chunks
+	}
+	s.kept.Inc()
+	for _, c := range t.chunks {
+		if c.Sampled == c.Chunk {
+			continue
+		}
+		// the spans are shared with the stats computation, the chunk is tagged instead of its root
+		if c.Chunk.Tags == nil {
+			c.Chunk.Tags = make(map[string]string)
+		}
+		c.Chunk.Tags[tailSampledKey] = reason
+		c.Sampled = c.Chunk
+	}
+	return t.chunks
+}
+
+// matches reports whether the trace t matches the policy p.
+func matches(p tailPolicy, t *tailTrace) bool {
+	switch p.Type {
+	case config.TailSamplingLatency:
+		start, end := int64(math.MaxInt64), int64(math.MinInt64)
+		anySpan(t, func(span *pb.Span) bool {
+			if span.Start < start {
+				start = span.Start
+			}
+			if span.Start+span.Duration > end {
+				end = span.Start + span.Duration
+			}
+			return false
+		})
+		return end > start && time.Duration(end-start) >= p.Latency
+	case config.TailSamplingError:
+		return anySpan(t, func(span *pb.Span) bool { return span.Error != 0 })
+	case config.TailSamplingTag:
+		return anySpan(t, func(span *pb.Span) bool {
+			v, ok := span.Meta[p.Key]
+			return ok && (p.Value == "" || v == p.Value)
+		})
+	case config.TailSamplingServiceRate:
+		return anySpan(t, func(span *pb.Span) bool { return span.Service == p.Service }) &&
+			SampleSpanByRate(t.chunks[0].Root, p.Rate)
+	default:
+		return false
+	}
+}
+
+// anySpan reports whether any span of the trace t matches the predicate.
+func anySpan(t *tailTrace, predicate func(*pb.Span) bool) bool {
+	for _, c := range t.chunks {
+		for _, span := range c.Chunk.Spans {
+			if predicate(span) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *TailSampler) report() {
+	s.mu.Lock()
+	size, traces := s.size, len(s.traces)
+	s.mu.Unlock()
+	metrics.Count("datadog.trace_agent.sampler.tail.kept", s.kept.Swap(0), nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.dropped", s.dropped.Swap(0), nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.evicted", s.evicted.Swap(0), nil, 1)
+	for _, p := range s.policies {
+		metrics.Count("datadog.trace_agent.sampler.tail.policy_kept", p.kept.Swap(0), []string{"policy:" + p.Name}, 1)
+	}
+	metrics.Gauge("datadog.trace_agent.sampler.tail.buffer_bytes", float64(size), nil, 1)
+	metrics.Gauge("datadog.trace_agent.sampler.tail.buffer_traces", float64(traces), nil, 1)
+}
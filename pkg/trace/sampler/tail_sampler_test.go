@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTailSampler(policies ...config.TailSamplingPolicy) (*TailSampler, *[]*TailChunk) {
+	conf := config.New()
+	conf.TailSampling.Enabled = true
+	conf.TailSampling.Policies = policies
+	var flushed []*TailChunk
+	s := NewTailSampler(conf, func(chunks []*TailChunk) { flushed = append(flushed, chunks...) })
+	return s, &flushed
+}
+
+// newTailChunk returns a chunk of the trace traceID, head-kept or not.
+func newTailChunk(traceID uint64, kept bool, spans ...*pb.Span) *TailChunk {
+	for _, s := range spans {
+		s.TraceID = traceID
+	}
+	chunk := &pb.TraceChunk{Spans: spans}
+	c := &TailChunk{Chunk: chunk, Root: traceutil.GetRoot(spans)}
+	if kept {
+		c.Sampled = chunk
+	}
+	return c
+}
+
+func TestTailSamplerPolicies(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		policy config.TailSamplingPolicy
+		spans  []*pb.Span
+		keep   bool
+	}{
+		{
+			name:   "latency",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingLatency, Latency: time.Second},
+			spans:  []*pb.Span{{SpanID: 1, Start: 0, Duration: 10}, {SpanID: 2, ParentID: 1, Start: 500, Duration: int64(time.Second)}},
+			keep:   true,
+		},
+		{
+			name:   "latency-short",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingLatency, Latency: time.Second},
+			spans:  []*pb.Span{{SpanID: 1, Start: 0, Duration: int64(time.Second) - 1}},
+			keep:   false,
+		},
+		{
+			name:   "error",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingError},
+			spans:  []*pb.Span{{SpanID: 1}, {SpanID: 2, ParentID: 1, Error: 1}},
+			keep:   true,
+		},
+		{
+			name:   "no-error",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingError},
+			spans:  []*pb.Span{{SpanID: 1}, {SpanID: 2, ParentID: 1}},
+			keep:   false,
+		},
+		{
+			name:   "tag-key",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingTag, Key: "customer"},
+			spans:  []*pb.Span{{SpanID: 1}, {SpanID: 2, ParentID: 1, Meta: map[string]string{"customer": "a"}}},
+			keep:   true,
+		},
+		{
+			name:   "tag-value",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingTag, Key: "customer", Value: "a"},
+			spans:  []*pb.Span{{SpanID: 1, Meta: map[string]string{"customer": "b"}}},
+			keep:   false,
+		},
+		{
+			name:   "service-rate",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingServiceRate, Service: "checkout", Rate: 1},
+			spans:  []*pb.Span{{SpanID: 1, Service: "web"}, {SpanID: 2, ParentID: 1, Service: "checkout"}},
+			keep:   true,
+		},
+		{
+			name:   "service-rate-zero",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingServiceRate, Service: "checkout", Rate: 0},
+			spans:  []*pb.Span{{SpanID: 1, Service: "checkout"}},
+			keep:   false,
+		},
+		{
+			name:   "service-rate-other",
+			policy: config.TailSamplingPolicy{Type: config.TailSamplingServiceRate, Service: "checkout", Rate: 1},
+			spans:  []*pb.Span{{SpanID: 1, Service: "web"}},
+			keep:   false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.policy.Validate())
+			s, flushed := newTestTailSampler(tt.policy)
+			now := time.Now()
+			c := newTailChunk(42, false, tt.spans...)
+			s.Add(now, []*TailChunk{c})
+			assert.Empty(t, *flushed)
+
+			s.sampleExpired(now.Add(s.wait))
+			assert.Len(t, *flushed, 1)
+			if tt.keep {
+				assert.Equal(t, c.Chunk, c.Sampled)
+				assert.Equal(t, tt.policy.Name, c.Chunk.Tags[tailSampledKey])
+				assert.EqualValues(t, 1, s.policies[0].kept.Load())
+			} else {
+				assert.Nil(t, c.Sampled)
+				assert.EqualValues(t, 1, s.dropped.Load())
+			}
+		})
+	}
+}
+
+func TestTailSamplerCompleteTrace(t *testing.T) {
+	s, flushed := newTestTailSampler()
+	now := time.Now()
+	analyzed := &pb.TraceChunk{DroppedTrace: true}
+	dropped := newTailChunk(42, false, &pb.Span{SpanID: 2, ParentID: 1})
+	dropped.Sampled = analyzed
+	other := newTailChunk(43, false, &pb.Span{SpanID: 3})
+	s.Add(now, []*TailChunk{dropped, other})
+	kept := newTailChunk(42, true, &pb.Span{SpanID: 1})
+	s.Add(now.Add(time.Second), []*TailChunk{kept})
+	assert.Len(t, s.traces, 2)
+
+	s.sampleExpired(now.Add(s.wait))
+	assert.Len(t, *flushed, 3)
+	assert.Empty(t, s.traces)
+	assert.Zero(t, s.size)
+
+	// the dropped chunk of the kept trace is sent complete
+	assert.Equal(t, dropped.Chunk, dropped.Sampled)
+	assert.Equal(t, tailSampledByTrace, dropped.Chunk.Tags[tailSampledKey])
+	assert.Equal(t, kept.Chunk, kept.Sampled)
+	assert.NotContains(t, kept.Chunk.Tags, tailSampledKey)
+	assert.Nil(t, other.Sampled)
+	assert.EqualValues(t, 1, s.kept.Load())
+	assert.EqualValues(t, 1, s.dropped.Load())
+}
+
+func TestTailSamplerTraceIDHigh(t *testing.T) {
+	s, flushed := newTestTailSampler()
+	now := time.Now()
+	kept := newTailChunk(42, true, &pb.Span{SpanID: 1})
+	traceutil.SetTraceIDHigh(kept.Root, 1)
+	dropped := newTailChunk(42, false, &pb.Span{SpanID: 2})
+	traceutil.SetTraceIDHigh(dropped.Root, 2)
+	s.Add(now, []*TailChunk{kept, dropped})
+	assert.Len(t, s.traces, 2)
+
+	s.sampleExpired(now.Add(s.wait))
+	assert.Len(t, *flushed, 2)
+	assert.Nil(t, dropped.Sampled)
+}
+
+func TestTailSamplerExpiration(t *testing.T) {
+	s, flushed := newTestTailSampler()
+	now := time.Now()
+	s.Add(now, []*TailChunk{newTailChunk(1, false, &pb.Span{SpanID: 1})})
+	s.Add(now.Add(time.Second), []*TailChunk{newTailChunk(2, false, &pb.Span{SpanID: 2})})
+
+	s.sampleExpired(now.Add(s.wait - time.Nanosecond))
+	assert.Empty(t, *flushed)
+	s.sampleExpired(now.Add(s.wait))
+	assert.Len(t, *flushed, 1)
+	assert.Len(t, s.traces, 1)
+	s.sampleExpired(now.Add(s.wait + time.Second))
+	assert.Len(t, *flushed, 2)
+	assert.Empty(t, s.traces)
+}
+
+func TestTailSamplerMaxBufferBytes(t *testing.T) {
+	s, flushed := newTestTailSampler(config.TailSamplingPolicy{Name: "error", Type: config.TailSamplingError})
+	c1 := newTailChunk(1, false, &pb.Span{SpanID: 1, Error: 1})
+	c2 := newTailChunk(2, false, &pb.Span{SpanID: 2})
+	s.maxSize = c1.Chunk.Msgsize() + c2.Chunk.Msgsize() - 1
+	now := time.Now()
+	s.Add(now, []*TailChunk{c1})
+	assert.Empty(t, *flushed)
+
+	// the oldest trace is sampled early to make room
+	s.Add(now, []*TailChunk{c2})
+	assert.Equal(t, []*TailChunk{c1}, *flushed)
+	assert.Equal(t, c1.Chunk, c1.Sampled)
+	assert.Equal(t, c2.Chunk.Msgsize(), s.size)
+	assert.EqualValues(t, 1, s.evicted.Load())
+}
+
+func TestTailSamplerStop(t *testing.T) {
+	s, flushed := newTestTailSampler()
+	s.Start()
+	s.Add(time.Now(), []*TailChunk{
+		newTailChunk(1, true, &pb.Span{SpanID: 1}),
+		newTailChunk(2, false, &pb.Span{SpanID: 2}),
+	})
+	s.Stop()
+	assert.Len(t, *flushed, 2)
+	assert.Empty(t, s.traces)
+}
+
+func TestTailSamplerFlush(t *testing.T) {
+	s, flushed := newTestTailSampler()
+	s.Add(time.Now(), []*TailChunk{newTailChunk(1, true, &pb.Span{SpanID: 1})})
+	s.Flush()
+	assert.Len(t, *flushed, 1)
+	assert.Empty(t, s.traces)
+	assert.Zero(t, s.size)
+}
+
+func TestTailSamplerAddAfterStop(t *testing.T) {
+	s, flushed := newTestTailSampler()
+	s.Start()
+	s.Stop()
+	// the traces still being processed when the agent stops are not held
+	s.Add(time.Now(), []*TailChunk{newTailChunk(1, true, &pb.Span{SpanID: 1})})
+	assert.Len(t, *flushed, 1)
+	assert.Empty(t, s.traces)
+}
@@ -33,6 +33,8 @@ type BucketsAggregationKey struct {
 	Type       string
 	StatusCode uint32
 	Synthetics bool
+	// Tags holds the comma-separated tags of the configured stats tag dimensions.
+	Tags string
 }
 
 // PayloadAggregationKey specifies the key by which a payload is aggregated.
@@ -81,6 +83,7 @@ func NewAggregationFromGroup(g pb.ClientGroupedStats) Aggregation {
 			Name:       g.Name,
 			StatusCode: g.HTTPStatusCode,
 			Synthetics: g.Synthetics,
+			Tags:       joinTags(g.Tags),
 		},
 	}
 }
@@ -44,6 +44,7 @@ type ClientStatsAggregator struct {
 	oldestTs      time.Time
 	agentEnv      string
 	agentHostname string
+	tagDimensions []config.StatsTagDimension
 
 	exit chan struct{}
 	done chan struct{}
@@ -58,6 +59,7 @@ func NewClientStatsAggregator(conf *config.AgentConfig, out chan pb.StatsPayload
 		out:           out,
 		agentEnv:      conf.DefaultEnv,
 		agentHostname: conf.Hostname,
+		tagDimensions: conf.StatsTagDimensions,
 		oldestTs:      alignAggTs(time.Now().Add(bucketDuration - oldestBucketStart)),
 		exit:          make(chan struct{}),
 		done:          make(chan struct{}),
@@ -130,11 +132,19 @@ func (a *ClientStatsAggregator) add(now time.Time, p pb.ClientStatsPayload) {
 		}
 		b, ok := a.buckets[ts.Unix()]
 		if !ok {
-			b = &bucket{ts: ts}
+			b = &bucket{ts: ts, tags: newTagDimensions(a.tagDimensions)}
 			a.buckets[ts.Unix()] = b
 		}
+		// the groups whose tags are filtered or capped may now share their key with other groups,
+		// the payload is then aggregated to merge their counts
+		var aggregate bool
+		for i, g := range clientBucket.Stats {
+			var changed bool
+			clientBucket.Stats[i].Tags, changed = b.tags.fromClientTags(g.Tags)
+			aggregate = aggregate || changed
+		}
 		p.Stats = []pb.ClientStatsBucket{clientBucket}
-		a.flush(b.add(p))
+		a.flush(b.add(p, aggregate))
 	}
 }
 
@@ -170,22 +180,27 @@ type bucket struct {
 	n int
 	// agg contains the aggregated Hits/Errors/Duration counts
 	agg map[PayloadAggregationKey]map[BucketsAggregationKey]*aggregatedCounts
+	// tags caps the values of the stats tag dimensions within the bucket
+	tags *tagDimensions
 }
 
-func (b *bucket) add(p pb.ClientStatsPayload) []pb.ClientStatsPayload {
+// add adds the payload to the bucket. A single payload is passed through unless aggregate is set.
+func (b *bucket) add(p pb.ClientStatsPayload, aggregate bool) []pb.ClientStatsPayload {
 	b.n++
-	if b.n == 1 {
+	if b.n == 1 && !aggregate {
 		b.first = p
 		return nil
 	}
-	// if it's the second payload we flush the first payload with counts trimmed
-	if b.n == 2 {
-		first := b.first
-		b.first = pb.ClientStatsPayload{}
+	if b.agg == nil {
 		b.agg = make(map[PayloadAggregationKey]map[BucketsAggregationKey]*aggregatedCounts, 2)
-		b.aggregateCounts(first)
-		b.aggregateCounts(p)
-		return []pb.ClientStatsPayload{trimCounts(first), trimCounts(p)}
+		// if the first payload was passed through we flush it with counts trimmed
+		if b.n == 2 {
+			first := b.first
+			b.first = pb.ClientStatsPayload{}
+			b.aggregateCounts(first)
+			b.aggregateCounts(p)
+			return []pb.ClientStatsPayload{trimCounts(first), trimCounts(p)}
+		}
 	}
 	b.aggregateCounts(p)
 	return []pb.ClientStatsPayload{trimCounts(p)}
@@ -218,7 +233,7 @@ func (b *bucket) aggregateCounts(p pb.ClientStatsPayload) {
 }
 
 func (b *bucket) flush() []pb.ClientStatsPayload {
-	if b.n == 1 {
+	if b.agg == nil {
 		return []pb.ClientStatsPayload{b.first}
 	}
 	return b.aggregationToPayloads()
@@ -236,6 +251,7 @@ func (b *bucket) aggregationToPayloads() []pb.ClientStatsPayload {
 				HTTPStatusCode: aggrKey.StatusCode,
 				Type:           aggrKey.Type,
 				Synthetics:     aggrKey.Synthetics,
+				Tags:           splitTags(aggrKey.Tags),
 				Hits:           counts.hits,
 				Errors:         counts.errors,
 				Duration:       counts.duration,
@@ -270,6 +286,7 @@ func newBucketAggregationKey(b pb.ClientGroupedStats) BucketsAggregationKey {
 		Type:       b.Type,
 		Synthetics: b.Synthetics,
 		StatusCode: b.HTTPStatusCode,
+		Tags:       joinTags(b.Tags),
 	}
 }
 
@@ -70,6 +70,9 @@ func getTestStatsWithStart(start time.Time) pb.ClientStatsPayload {
 	b := pb.ClientStatsBucket{}
 	fuzzer.Fuzz(&b)
 	b.Start = uint64(start.UnixNano())
+	for i := range b.Stats {
+		b.Stats[i].Tags = nil
+	}
 	p := pb.ClientStatsPayload{}
 	fuzzer.Fuzz(&p)
 	p.Tags = nil
@@ -332,6 +335,49 @@ func TestCountAggregation(t *testing.T) {
 	}
 }
 
+func TestAggregatorTagDimensions(t *testing.T) {
+	assert := assert.New(t)
+	a := newTestAggregator()
+	a.tagDimensions = []config.StatsTagDimension{{Tag: "peer.service", MaxCardinality: 1}}
+	payloadTime := time.Now().Truncate(bucketDuration)
+	insertionTime := payloadTime.Add(time.Second)
+
+	// a payload whose tags are kept is passed through
+	p := payloadWithCounts(payloadTime, BucketsAggregationKey{Service: "s"}, 1, 0, 10)
+	p.Stats[0].Stats[0].Tags = []string{"peer.service:users"}
+	a.add(insertionTime, deepCopy(p))
+	assert.Len(a.out, 0)
+	a.flushOnTime(payloadTime.Add(oldestBucketStart))
+	assert.Equal(wrapPayload(p), <-a.out)
+
+	// a payload whose tags are filtered or capped is aggregated
+	a = newTestAggregator()
+	a.tagDimensions = []config.StatsTagDimension{{Tag: "peer.service", MaxCardinality: 1}}
+	group := p.Stats[0].Stats[0]
+	p.Stats[0].Stats = []pb.ClientGroupedStats{group, group, group}
+	p.Stats[0].Stats[1].Tags = []string{"peer.service:billing", "host:a"}
+	p.Stats[0].Stats[2].Tags = []string{"peer.service:orders"}
+	a.add(insertionTime, deepCopy(p))
+	assert.Len(a.out, 1)
+	distributions := <-a.out
+	assert.Equal(keyDistributions, distributions.Stats[0].AgentAggregation)
+	var tags [][]string
+	for _, b := range distributions.Stats[0].Stats[0].Stats {
+		assert.Zero(b.Hits)
+		tags = append(tags, b.Tags)
+	}
+	assert.Equal([][]string{{"peer.service:users"}, {"peer.service:_overflow"}, {"peer.service:_overflow"}}, tags)
+
+	a.flushOnTime(payloadTime.Add(oldestBucketStart))
+	counts := <-a.out
+	assertAggCountsPayload(t, counts)
+	assert.ElementsMatch([]pb.ClientGroupedStats{
+		{Service: "s", Tags: []string{"peer.service:users"}, Hits: 1, Duration: 10},
+		{Service: "s", Tags: []string{"peer.service:_overflow"}, Hits: 2, Duration: 20},
+	}, counts.Stats[0].Stats[0].Stats)
+	assert.Len(a.buckets, 0)
+}
+
 func deepCopy(p pb.ClientStatsPayload) pb.ClientStatsPayload {
 	new := p
 	new.Stats = deepCopyStatsBucket(p.Stats)
@@ -46,6 +46,7 @@ type Concentrator struct {
 	mu            sync.Mutex
 	agentEnv      string
 	agentHostname string
+	tagDimensions []config.StatsTagDimension
 }
 
 // NewConcentrator initializes a new concentrator ready to be started
@@ -64,6 +65,7 @@ func NewConcentrator(conf *config.AgentConfig, out chan pb.StatsPayload, now tim
 		exit:          make(chan struct{}),
 		agentEnv:      conf.DefaultEnv,
 		agentHostname: conf.Hostname,
+		tagDimensions: conf.StatsTagDimensions,
 	}
 	return &c
 }
@@ -177,6 +179,7 @@ func (c *Concentrator) addNow(pt *traceutil.ProcessedTrace, containerID string)
 		b, ok := c.buckets[btime]
 		if !ok {
 			b = NewRawBucket(uint64(btime), uint64(c.bsize))
+			b.tags = newTagDimensions(c.tagDimensions)
 			c.buckets[btime] = b
 		}
 		b.HandleSpan(s, weight, isTop, pt.TraceChunk.Origin, aggKey)
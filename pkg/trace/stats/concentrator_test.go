@@ -8,6 +8,7 @@ package stats
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -528,3 +529,34 @@ func TestIgnoresPartialSpans(t *testing.T) {
 	stats := c.flushNow(now.UnixNano() + int64(c.bufferLen)*testBucketInterval)
 	assert.Empty(stats.GetStats())
 }
+
+func TestConcentratorTagDimensions(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+
+	var spans []*pb.Span
+	for i, peer := range []string{"users", "billing", "orders", "users", "payments"} {
+		span := testSpan(uint64(i+1), 0, 10, 0, "A1", "resource1", 0)
+		span.Meta = map[string]string{"peer.service": peer}
+		spans = append(spans, span)
+	}
+	spans = append(spans, testSpan(6, 0, 10, 0, "A1", "resource1", 0))
+	traceutil.ComputeTopLevel(spans)
+	testTrace := toProcessedTrace(spans, "none", "")
+
+	c := NewTestConcentrator(now)
+	c.tagDimensions = []config.StatsTagDimension{{Tag: "peer.service", MaxCardinality: 2}}
+	c.addNow(testTrace, "")
+
+	stats := c.flushNow(now.UnixNano() + int64(c.bufferLen)*testBucketInterval)
+	hits := make(map[string]uint64)
+	for _, b := range stats.Stats[0].Stats[0].Stats {
+		hits[strings.Join(b.Tags, ",")] += b.Hits
+	}
+	assert.Equal(map[string]uint64{
+		"":                       1,
+		"peer.service:users":     2,
+		"peer.service:billing":   1,
+		"peer.service:_overflow": 2,
+	}, hits)
+}
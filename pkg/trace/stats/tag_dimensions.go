@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// overflowTagValue is the value of a tag dimension aggregating the values past its cardinality cap.
+const overflowTagValue = "_overflow"
+
+// tagDimensions computes the tags of the stats groups from the span tags configured as extra
+// aggregation dimensions. It caps the number of distinct values of each dimension, the extra
+// values being aggregated under overflowTagValue. A nil tagDimensions has no dimensions.
+// It is not safe for concurrent use.
+type tagDimensions struct {
+	dims []tagDimension
+}
+
+type tagDimension struct {
+	key            string
+	maxCardinality int
+	// tags maps the values of the dimension seen so far to their normalized "key:value" tag.
+	tags     map[string]string
+	overflow string
+}
+
+// newTagDimensions returns the tagDimensions of the given dimensions, or nil if there are none.
+func newTagDimensions(dims []config.StatsTagDimension) *tagDimensions {
+	if len(dims) == 0 {
+		return nil
+	}
+	t := &tagDimensions{dims: make([]tagDimension, len(dims))}
+	for i, d := range dims {
+		maxCardinality := d.MaxCardinality
+		if maxCardinality <= 0 {
+			maxCardinality = config.DefaultStatsTagCardinality
+		}
+		t.dims[i] = tagDimension{
+			key:            d.Tag,
+			maxCardinality: maxCardinality,
+			tags:           make(map[string]string),
+			overflow:       traceutil.NormalizeTag(d.Tag + ":" + overflowTagValue),
+		}
+	}
+	return t
+}
+
+// tag returns the normalized tag of the value v of the dimension, or its overflow tag when the
+// dimension already has its maximum number of values.
+func (d *tagDimension) tag(v string) string {
+	if tag, ok := d.tags[v]; ok {
+		return tag
+	}
+	if len(d.tags) >= d.maxCardinality {
+		return d.overflow
+	}
+	tag := traceutil.NormalizeTag(d.key + ":" + v)
+	d.tags[v] = tag
+	return tag
+}
+
+// fromSpan returns the tags of the dimensions set on the span, in the order of the dimensions,
+// as an aggregation key.
+func (t *tagDimensions) fromSpan(s *pb.Span) string {
+	if t == nil {
+		return ""
+	}
+	var tags string
+	for i := range t.dims {
+		d := &t.dims[i]
+		v, ok := s.Meta[d.key]
+		if !ok || v == "" {
+			continue
+		}
+		if tags == "" {
+			tags = d.tag(v)
+		} else {
+			tags += "," + d.tag(v)
+		}
+	}
+	return tags
+}
+
+// fromClientTags returns the tags of the dimensions found in the tags of client grouped stats,
+// in the order of the dimensions, and reports whether they differ from tags.
+func (t *tagDimensions) fromClientTags(tags []string) ([]string, bool) {
+	if t == nil {
+		return nil, len(tags) > 0
+	}
+	var res []string
+	for i := range t.dims {
+		d := &t.dims[i]
+		for _, tag := range tags {
+			if len(tag) > len(d.key)+1 && tag[len(d.key)] == ':' && tag[:len(d.key)] == d.key {
+				res = append(res, d.tag(tag[len(d.key)+1:]))
+				break
+			}
+		}
+	}
+	if len(res) != len(tags) {
+		return res, true
+	}
+	for i := range res {
+		if res[i] != tags[i] {
+			return res, true
+		}
+	}
+	return res, false
+}
+
+// joinTags returns the aggregation key of the given tags. Normalized tags never contain commas.
+func joinTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+// splitTags returns the tags of an aggregation key.
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
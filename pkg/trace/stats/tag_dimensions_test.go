@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagDimensionsFromSpan(t *testing.T) {
+	assert := assert.New(t)
+	var none *tagDimensions
+	assert.Equal("", none.fromSpan(&pb.Span{Meta: map[string]string{"region": "us"}}))
+
+	d := newTagDimensions([]config.StatsTagDimension{
+		{Tag: "peer.service", MaxCardinality: 2},
+		{Tag: "region"},
+	})
+	assert.Equal(config.DefaultStatsTagCardinality, d.dims[1].maxCardinality)
+	for _, tt := range []struct {
+		meta map[string]string
+		want string
+	}{
+		{nil, ""},
+		{map[string]string{"region": "us", "peer.service": "Billing DB"}, "peer.service:billing_db,region:us"},
+		{map[string]string{"region": "eu", "other": "x"}, "region:eu"},
+		{map[string]string{"peer.service": "users"}, "peer.service:users"},
+		// the cardinality cap of peer.service is reached
+		{map[string]string{"peer.service": "orders", "region": "us"}, "peer.service:_overflow,region:us"},
+		{map[string]string{"peer.service": "Billing DB"}, "peer.service:billing_db"},
+	} {
+		assert.Equal(tt.want, d.fromSpan(&pb.Span{Meta: tt.meta}))
+	}
+}
+
+func TestTagDimensionsFromClientTags(t *testing.T) {
+	assert := assert.New(t)
+	var none *tagDimensions
+	tags, changed := none.fromClientTags(nil)
+	assert.Nil(tags)
+	assert.False(changed)
+	tags, changed = none.fromClientTags([]string{"region:us"})
+	assert.Nil(tags)
+	assert.True(changed)
+
+	d := newTagDimensions([]config.StatsTagDimension{
+		{Tag: "peer.service", MaxCardinality: 1},
+		{Tag: "region"},
+	})
+	for _, tt := range []struct {
+		tags    []string
+		want    []string
+		changed bool
+	}{
+		{nil, nil, false},
+		{[]string{"peer.service:users", "region:us"}, []string{"peer.service:users", "region:us"}, false},
+		{[]string{"region:eu", "peer.service:users"}, []string{"peer.service:users", "region:eu"}, true},
+		{[]string{"region:EU", "host:a", "regions:us"}, []string{"region:eu"}, true},
+		{[]string{"peer.service:orders"}, []string{"peer.service:_overflow"}, true},
+	} {
+		tags, changed := d.fromClientTags(tt.tags)
+		assert.Equal(tt.want, tags)
+		assert.Equal(tt.changed, changed, tt.tags)
+	}
+}
@@ -7,6 +7,8 @@ package traceutil
 
 import (
 	"bytes"
+	"fmt"
+	"strconv"
 
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 
@@ -23,6 +25,10 @@ const (
 	tracerTopLevelKey = "_dd.top_level"
 	// partialVersionKey is a metric carrying the snapshot seq number in the case the span is a partial snapshot
 	partialVersionKey = "_dd.partial_version"
+
+	// TraceIDHighKey is the meta carrying the upper 64 bits of a 128-bit trace ID, as 16 hexadecimal characters.
+	// The lower 64 bits are the TraceID of the span.
+	TraceIDHighKey = "_dd.p.tid"
 )
 
 // HasTopLevel returns true if span is top-level.
@@ -65,6 +71,28 @@ func SetTopLevel(s *pb.Span, topLevel bool) {
 	SetMetric(s, topLevelKey, 1)
 }
 
+// GetTraceIDHigh returns the upper 64 bits of the trace ID of the span s, and whether they are set and valid.
+func GetTraceIDHigh(s *pb.Span) (uint64, bool) {
+	v, ok := GetMeta(s, TraceIDHighKey)
+	if !ok || len(v) != 16 {
+		return 0, false
+	}
+	high, err := strconv.ParseUint(v, 16, 64)
+	if err != nil {
+		return 0, false
+	}
+	return high, true
+}
+
+// SetTraceIDHigh sets the upper 64 bits of the trace ID of the span s. Nothing is set for 64-bit trace IDs,
+// whose upper bits are zero.
+func SetTraceIDHigh(s *pb.Span, high uint64) {
+	if high == 0 {
+		return
+	}
+	SetMeta(s, TraceIDHighKey, fmt.Sprintf("%016x", high))
+}
+
 // SetMetric sets the metric at key to the val on the span s.
 func SetMetric(s *pb.Span, key string, val float64) {
 	if s.Metrics == nil {
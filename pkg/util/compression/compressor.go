@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// NoneKind disables compression
+	NoneKind = "none"
+	// ZlibKind compresses payloads with zlib (HTTP `deflate` content encoding)
+	ZlibKind = "zlib"
+	// GzipKind compresses payloads with gzip
+	GzipKind = "gzip"
+	// ZstdKind compresses payloads with the stable (v1) zstd format
+	ZstdKind = "zstd"
+)
+
+// DefaultZstdLevel is the zstd compression level used when none is configured
+const DefaultZstdLevel = 1
+
+// Compressor is a compression strategy that can be selected at runtime.
+type Compressor interface {
+	// Compress compresses src in a single call
+	Compress(src []byte) ([]byte, error)
+	// Decompress decompresses src in a single call
+	Decompress(src []byte) ([]byte, error)
+	// CompressBound returns the worst case size needed for a destination buffer
+	CompressBound(sourceLen int) int
+	// ContentEncoding returns the HTTP header value associated with the compression method,
+	// empty if no compression is applied
+	ContentEncoding() string
+	// NewStreamCompressor returns a writer compressing everything written to it into output
+	NewStreamCompressor(output *bytes.Buffer) StreamCompressor
+}
+
+// StreamCompressor compresses data incrementally. Flush must push all the pending data
+// to the underlying output so its length can be used to estimate the payload size.
+type StreamCompressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// NewCompressor returns the compression strategy for the given kind. The level is only
+// used by the strategies that support one (gzip and zstd). An empty kind selects the
+// compression the agent was built with.
+func NewCompressor(kind string, level int) (Compressor, error) {
+	switch strings.ToLower(kind) {
+	case "":
+		return NewCompressor(DefaultKind, level)
+	case NoneKind:
+		return &noopStrategy{}, nil
+	case ZlibKind:
+		return &zlibStrategy{}, nil
+	case GzipKind:
+		return newGzipStrategy(level), nil
+	case ZstdKind:
+		return newZstdCompressor(level)
+	default:
+		return nil, fmt.Errorf("unknown compression kind %q", kind)
+	}
+}
+
+// DefaultCompressor returns the compression strategy the agent was built with.
+func DefaultCompressor() Compressor {
+	c, _ := NewCompressor(DefaultKind, DefaultZstdLevel)
+	return c
+}
+
+// NewCompressorForContentEncoding returns the compression strategy matching the
+// value of an HTTP `Content-Encoding` header, as returned by `ContentEncoding`. It
+// is meant to decompress payloads, so the default compression level is used.
+func NewCompressorForContentEncoding(encoding string) (Compressor, error) {
+	switch strings.ToLower(encoding) {
+	case "":
+		return NewCompressor(NoneKind, DefaultZstdLevel)
+	case "deflate":
+		return NewCompressor(ZlibKind, DefaultZstdLevel)
+	case GzipKind, ZstdKind:
+		return NewCompressor(encoding, DefaultZstdLevel)
+	default:
+		return nil, fmt.Errorf("unknown content encoding %q", encoding)
+	}
+}
@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCompressorUnknownKind(t *testing.T) {
+	_, err := NewCompressor("lz4", 0)
+	assert.Error(t, err)
+	_, err = NewCompressorForContentEncoding("br")
+	assert.Error(t, err)
+}
+
+func TestCompressorRoundTrip(t *testing.T) {
+	for kind, encoding := range map[string]string{
+		NoneKind: "",
+		ZlibKind: "deflate",
+		GzipKind: "gzip",
+	} {
+		t.Run(kind, func(t *testing.T) {
+			testCompressorRoundTrip(t, kind, encoding)
+		})
+	}
+}
+
+// testCompressorRoundTrip compresses a payload with the compressor of the given kind and checks it
+// is decompressed by the same compressor and by the one matching its content encoding.
+func testCompressorRoundTrip(t *testing.T, kind string, encoding string) {
+	payload := []byte(strings.Repeat("{\"metric\":\"system.load.1\",\"points\":[[1,2]]},", 200))
+
+	c, err := NewCompressor(kind, 3)
+	require.NoError(t, err)
+	assert.Equal(t, encoding, c.ContentEncoding())
+
+	compressed, err := c.Compress(payload)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(compressed), c.CompressBound(len(payload)))
+
+	decompressed, err := c.Decompress(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decompressed)
+
+	decompressor, err := NewCompressorForContentEncoding(encoding)
+	require.NoError(t, err)
+	decompressed, err = decompressor.Decompress(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decompressed)
+
+	var output bytes.Buffer
+	w := c.NewStreamCompressor(&output)
+	_, err = w.Write(payload[:100])
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	_, err = w.Write(payload[100:])
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	decompressed, err = c.Decompress(output.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, payload, decompressed)
+}
@@ -0,0 +1,23 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !cgo
+// +build !cgo
+
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZstdCompressorWithoutCgo(t *testing.T) {
+	// the zstd library requires cgo
+	_, err := NewCompressor(ZstdKind, 3)
+	assert.Error(t, err)
+	_, err = NewCompressorForContentEncoding("zstd")
+	assert.Error(t, err)
+}
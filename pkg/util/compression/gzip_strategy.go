@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+)
+
+// gzip wraps the deflate stream with a 10 bytes header and an 8 bytes trailer, where zlib
+// only uses 2 and 4 bytes
+const gzipExtraWrapperSize = 12
+
+// gzipStrategy compresses payloads with gzip
+type gzipStrategy struct {
+	level int
+}
+
+func newGzipStrategy(level int) *gzipStrategy {
+	if level < gzip.NoCompression {
+		level = gzip.NoCompression
+	} else if level > gzip.BestCompression {
+		level = gzip.BestCompression
+	}
+	return &gzipStrategy{level: level}
+}
+
+func (s *gzipStrategy) Compress(src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := gzip.NewWriterLevel(&b, s.level)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(src)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (s *gzipStrategy) Decompress(src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+func (s *gzipStrategy) CompressBound(sourceLen int) int {
+	return zlibCompressBound(sourceLen) + gzipExtraWrapperSize
+}
+
+func (s *gzipStrategy) ContentEncoding() string {
+	return "gzip"
+}
+
+func (s *gzipStrategy) NewStreamCompressor(output *bytes.Buffer) StreamCompressor {
+	// the level is validated by newGzipStrategy so no error can be returned here
+	w, _ := gzip.NewWriterLevel(output, s.level)
+	return w
+}
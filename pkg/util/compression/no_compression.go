@@ -3,8 +3,8 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-//go:build !zlib && !zstd
-// +build !zlib,!zstd
+//go:build (!zlib && !zstd) || (!zlib && !cgo)
+// +build !zlib,!zstd !zlib,!cgo
 
 package compression
 
@@ -13,6 +13,9 @@ package compression
 // var instead of const to ease testing
 var ContentEncoding = ""
 
+// DefaultKind is the compression kind the agent was built with
+const DefaultKind = NoneKind
+
 // Compress will not compress anything
 func Compress(src []byte) ([]byte, error) {
 	return src, nil
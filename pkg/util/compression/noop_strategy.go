@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import "bytes"
+
+// noopStrategy does not compress anything
+type noopStrategy struct{}
+
+func (s *noopStrategy) Compress(src []byte) ([]byte, error) {
+	return src, nil
+}
+
+func (s *noopStrategy) Decompress(src []byte) ([]byte, error) {
+	return src, nil
+}
+
+func (s *noopStrategy) CompressBound(sourceLen int) int {
+	return sourceLen
+}
+
+func (s *noopStrategy) ContentEncoding() string {
+	return ""
+}
+
+func (s *noopStrategy) NewStreamCompressor(output *bytes.Buffer) StreamCompressor {
+	return &noopStreamCompressor{output}
+}
+
+type noopStreamCompressor struct {
+	*bytes.Buffer
+}
+
+func (c *noopStreamCompressor) Flush() error {
+	return nil
+}
+
+func (c *noopStreamCompressor) Close() error {
+	return nil
+}
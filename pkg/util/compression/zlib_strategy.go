@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io/ioutil"
+)
+
+// zlibStrategy compresses payloads with zlib
+type zlibStrategy struct{}
+
+func (s *zlibStrategy) Compress(src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	_, err := w.Write(src)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (s *zlibStrategy) Decompress(src []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+func (s *zlibStrategy) CompressBound(sourceLen int) int {
+	return zlibCompressBound(sourceLen)
+}
+
+func (s *zlibStrategy) ContentEncoding() string {
+	return "deflate"
+}
+
+func (s *zlibStrategy) NewStreamCompressor(output *bytes.Buffer) StreamCompressor {
+	return zlib.NewWriter(output)
+}
+
+func zlibCompressBound(sourceLen int) int {
+	// From https://code.woboq.org/gcc/zlib/compress.c.html#compressBound
+	return sourceLen + (sourceLen >> 12) + (sourceLen >> 14) + (sourceLen >> 25) + 13
+}
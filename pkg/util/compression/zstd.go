@@ -3,33 +3,29 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-//go:build zstd
-// +build zstd
+//go:build zstd && cgo
+// +build zstd,cgo
 
 package compression
 
-import (
-	zstd_0 "github.com/DataDog/zstd_0"
-)
-
-// TODO: the intake still uses a pre-v1 (unstable) version of the zstd compression format.
-// The agent shouldn't use zstd compression until the intake supports a stable v1 format.
-
 // ContentEncoding describes the HTTP header value associated with the compression method
 // var instead of const to ease testing
 var ContentEncoding = "zstd"
 
-// Compress will compress the data with zstd
+// DefaultKind is the compression kind the agent was built with
+const DefaultKind = ZstdKind
+
+// Compress will compress the data with the stable (v1) zstd format
 func Compress(src []byte) ([]byte, error) {
-	return zstd_0.Compress(nil, src)
+	return newZstdStrategy(DefaultZstdLevel).Compress(src)
 }
 
 // Decompress will decompress the data with zstd
 func Decompress(src []byte) ([]byte, error) {
-	return zstd_0.Decompress(nil, src)
+	return newZstdStrategy(DefaultZstdLevel).Decompress(src)
 }
 
 // CompressBound returns the worst case size needed for a destination buffer
 func CompressBound(sourceLen int) int {
-	return zstd_0.CompressBound(sourceLen)
+	return newZstdStrategy(DefaultZstdLevel).CompressBound(sourceLen)
 }
@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build cgo
+// +build cgo
+
+package compression
+
+import (
+	"bytes"
+
+	"github.com/DataDog/zstd"
+)
+
+// zstdStrategy compresses payloads with the stable (v1) zstd format
+type zstdStrategy struct {
+	level int
+}
+
+// newZstdCompressor returns the zstd compression strategy for the given level
+func newZstdCompressor(level int) (Compressor, error) {
+	return newZstdStrategy(level), nil
+}
+
+func newZstdStrategy(level int) *zstdStrategy {
+	if level < 1 {
+		level = DefaultZstdLevel
+	} else if level > zstd.BestCompression {
+		level = zstd.BestCompression
+	}
+	return &zstdStrategy{level: level}
+}
+
+func (s *zstdStrategy) Compress(src []byte) ([]byte, error) {
+	return zstd.CompressLevel(nil, src, s.level)
+}
+
+func (s *zstdStrategy) Decompress(src []byte) ([]byte, error) {
+	return zstd.Decompress(nil, src)
+}
+
+func (s *zstdStrategy) CompressBound(sourceLen int) int {
+	return zstd.CompressBound(sourceLen)
+}
+
+func (s *zstdStrategy) ContentEncoding() string {
+	return "zstd"
+}
+
+func (s *zstdStrategy) NewStreamCompressor(output *bytes.Buffer) StreamCompressor {
+	return zstd.NewWriterLevel(output, s.level)
+}
@@ -79,6 +79,11 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 		Hints: []string{"token"},
 		Repl:  []byte(`$1 "********"`),
 	}
+	encryptionKeyReplacer := Replacer{
+		Regex: matchYAMLKeyEnding(`encryption_key`),
+		Hints: []string{"encryption_key"},
+		Repl:  []byte(`$1 "********"`),
+	}
 	snmpReplacer := Replacer{
 		Regex: matchYAMLKey(`(community_string|authKey|privKey|community|authentication_key|privacy_key)`),
 		Hints: []string{"community_string", "authKey", "privKey", "community", "authentication_key", "privacy_key"},
@@ -104,6 +109,7 @@ func AddDefaultReplacers(scrubber *Scrubber) {
 	scrubber.AddReplacer(SingleLine, uriPasswordReplacer)
 	scrubber.AddReplacer(SingleLine, passwordReplacer)
 	scrubber.AddReplacer(SingleLine, tokenReplacer)
+	scrubber.AddReplacer(SingleLine, encryptionKeyReplacer)
 	scrubber.AddReplacer(SingleLine, snmpReplacer)
 	scrubber.AddReplacer(MultiLine, snmpMultilineReplacer)
 	scrubber.AddReplacer(MultiLine, certReplacer)
@@ -467,6 +467,7 @@ password: foo
 auth_token: bar
 auth_token_file_path: /foo/bar/baz
 kubelet_auth_token_path: /foo/bar/kube_token
+forwarder_storage_encryption_key: c2VjcmV0IGtleSBvZiAzMiBieXRlcyBmb3IgdGVzdHM=
 # comment to strip
 network_devices:
   snmp_traps:
@@ -481,6 +482,7 @@ password: "********"
 auth_token: "********"
 auth_token_file_path: /foo/bar/baz
 kubelet_auth_token_path: /foo/bar/kube_token
+forwarder_storage_encryption_key: "********"
 network_devices:
   snmp_traps:
     community_strings: "********"